@@ -7,6 +7,8 @@ import (
 	"strings"
 
 	"gitlab-list/internal/configuration"
+	"gitlab-list/internal/gitlab"
+	"gitlab-list/internal/goproxy"
 	"gitlab-list/internal/handler"
 	"gitlab-list/internal/repository"
 	"gitlab-list/internal/service"
@@ -19,42 +21,77 @@ func main() {
 		log.Fatal("Failed to load configuration:", err)
 	}
 
+	// One GitLab client per process so all requests share its rate limiter; tokens are set per request with WithToken
+	gitlabClient := gitlab.NewClientFromConfig(cfg)
+
 	// Initialize repository
-	gitlabRepo := repository.NewGitLabRepository(cfg)
-
-	// Initialize MongoDB cache (optional)
-	var mongoRepo *repository.MongoDBRepository
-	if cfg.MongoDBURI != "" {
-		var err error
-		mongoURI := cfg.GetMongoDBURI()
-		mongoRepo, err = repository.NewMongoDBRepository(mongoURI, cfg.MongoDBDatabase)
-		if err != nil {
-			log.Printf("Warning: Failed to connect to MongoDB: %v", err)
-			log.Println("Continuing without caching...")
-		} else {
-			log.Println("MongoDB cache initialized successfully")
-		}
+	gitlabRepo := repository.NewGitLabRepository(cfg, gitlabClient)
+
+	// Initialize the cache store (MongoDB, embedded bolt file or memory; see STORE)
+	store, err := repository.OpenStore(cfg)
+	if err != nil {
+		log.Fatal("Failed to open cache store:", err)
 	}
 
 	// Hash-based cache system - no TTL needed
 
 	// Initialize service
 	var projectService *service.ProjectService
-	if mongoRepo != nil {
-		projectService = service.NewProjectServiceWithCache(gitlabRepo, mongoRepo)
-		log.Println("Project service initialized with MongoDB caching")
+	if store != nil {
+		defer store.Close()
+		projectService = service.NewProjectServiceWithCache(gitlabRepo, store)
+		log.Println("Project service initialized with caching")
 	} else {
 		projectService = service.NewProjectService(gitlabRepo)
 		log.Println("Project service initialized without caching")
 	}
+	projectService.SetDetailWorkers(cfg.DetailWorkers)
+	projectService.SetBranches(cfg.Branches)
+	projectService.SetSnapshotRetention(service.SnapshotRetentionFromConfig(cfg))
+	projectService.SetCacheTTL(service.CacheTTLFromConfig(cfg))
+
+	// One module proxy client so version lookups share a cache
+	moduleProxy := goproxy.NewClientFromConfig(cfg, gitlabClient)
+	projectService.SetModuleProxy(moduleProxy)
+
+	// Cache loads and project updates run on a bounded job queue and are followed through /api/jobs
+	jobQueue := service.JobQueueFromConfig(cfg)
+	jobHandler := handler.NewJobHandler(jobQueue)
 
 	// Initialize handlers
-	projectHandler := handler.NewProjectHandler(projectService)
-	configHandler := handler.NewConfigHandler()
+	projectHandler := handler.NewProjectHandler(projectService, jobQueue)
+	configHandler := handler.NewConfigHandler(cfg)
 
 	// Initialize library updater
-	libraryUpdater := service.NewLibraryUpdater(cfg)
-	libraryUpdaterHandler := handler.NewLibraryUpdaterHandler(libraryUpdater)
+	libraryUpdater := service.NewLibraryUpdater(cfg, gitlabClient)
+	libraryUpdater.SetModuleProxy(moduleProxy)
+	libraryUpdaterHandler := handler.NewLibraryUpdaterHandler(libraryUpdater, jobQueue)
+
+	// Initialize vulnerability matching (OSV dump is loaded on first use)
+	vulnerabilityService := service.NewVulnerabilityService(projectService, libraryUpdater, cfg.OSVDatabase)
+	vulnerabilityHandler := handler.NewVulnerabilityHandler(vulnerabilityService, jobQueue)
+
+	// Initialize dependency policy evaluation (policy file is re-read on every request)
+	policyService := service.NewPolicyService(projectService, cfg.PolicyFile)
+	policyHandler := handler.NewPolicyHandler(policyService)
+
+	// Initialize dependency bump campaigns (updates run on the job queue, merge requests are tracked by the campaign-tracking job)
+	campaignService := service.NewCampaignService(store, projectService, libraryUpdater, jobQueue)
+	campaignHandler := handler.NewCampaignHandler(campaignService)
+
+	// Scheduled jobs are run by cmd/scheduler, or here with API_SCHEDULER; both share their state through the store
+	scheduleHandler := handler.NewScheduleHandler(service.NewScheduleService(store))
+	if cfg.APIScheduler {
+		scheduler := service.NewSchedulerService(store, cfg)
+		for _, job := range service.ConfiguredJobs(cfg, projectService, policyService, vulnerabilityService, campaignService) {
+			if err := scheduler.Register(job); err != nil {
+				log.Fatal("Failed to register job:", err)
+			}
+		}
+		if err := scheduler.Start(); err != nil {
+			log.Fatal("Failed to start scheduler:", err)
+		}
+	}
 
 	// Setup routes
 	mux := http.NewServeMux()
@@ -71,16 +108,45 @@ func main() {
 	mux.HandleFunc("/api/projects/changed", projectHandler.GetChangedProjects)
 	mux.HandleFunc("/api/projects/openapi", projectHandler.GetProjectsWithOpenAPI)
 	mux.HandleFunc("/api/projects/", func(w http.ResponseWriter, r *http.Request) {
-		// Handle both /api/projects/{id} and /api/projects/{id}/openapi
+		// Handle /api/projects/{id}, /api/projects/{id}/openapi and /api/projects/{id}/diff
 		path := r.URL.Path
 		if strings.HasSuffix(path, "/openapi") {
 			projectHandler.GetProjectOpenAPI(w, r)
+		} else if strings.HasSuffix(path, "/diff") {
+			projectHandler.GetProjectDiff(w, r)
 		} else {
 			projectHandler.GetProject(w, r)
 		}
 	})
 	mux.HandleFunc("/api/libraries", projectHandler.GetLibraries)
 
+	// Snapshot and trend routes
+	mux.HandleFunc("/api/snapshots", projectHandler.GetSnapshots)
+	mux.HandleFunc("/api/snapshots/compact", projectHandler.CompactSnapshots)
+	mux.HandleFunc("/api/trends/go-versions", projectHandler.GetGoVersionTrend)
+	mux.HandleFunc("/api/trends/library", projectHandler.GetLibraryAdoption)
+	mux.HandleFunc("/api/diff", projectHandler.GetSnapshotDiff)
+
+	// Vulnerability routes
+	mux.HandleFunc("/api/vulnerabilities", vulnerabilityHandler.GetVulnerabilities)
+	mux.HandleFunc("/api/vulnerabilities/reload", vulnerabilityHandler.ReloadDatabase)
+	mux.HandleFunc("/api/vulnerabilities/fix", vulnerabilityHandler.FixProject)
+
+	// Policy routes
+	mux.HandleFunc("/api/policy", policyHandler.GetViolations)
+
+	// Campaign routes
+	mux.HandleFunc("/api/campaigns", campaignHandler.HandleCampaigns)
+	mux.HandleFunc("/api/campaigns/", campaignHandler.HandleCampaign)
+
+	// Scheduled job routes
+	mux.HandleFunc("/api/schedule", scheduleHandler.ListJobs)
+	mux.HandleFunc("/api/schedule/", scheduleHandler.HandleJob)
+
+	// Queued job routes
+	mux.HandleFunc("/api/jobs", jobHandler.ListJobs)
+	mux.HandleFunc("/api/jobs/", jobHandler.HandleJob)
+
 	// Architecture routes
 	mux.HandleFunc("/api/architecture", projectHandler.GetArchitecture)
 	mux.HandleFunc("/api/architecture/full", projectHandler.GenerateFullArchitecture)
@@ -105,6 +171,7 @@ func main() {
 	mux.HandleFunc("/api/cache/clear", projectHandler.ClearCache)
 	mux.HandleFunc("/api/cache/stats", projectHandler.GetCacheStats)
 	mux.HandleFunc("/api/cache/refresh-project", projectHandler.RefreshProjectCache)
+	mux.HandleFunc("/api/locks", projectHandler.GetLeases)
 
 	// Search routes
 	mux.HandleFunc("/api/search/libraries", projectHandler.SearchLibraries)
@@ -1,26 +1,61 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 
+	"gitlab-list/internal"
 	"gitlab-list/internal/configuration"
+	"gitlab-list/internal/gitlab"
+	"gitlab-list/internal/policy"
 	"gitlab-list/internal/service/scanner"
 )
 
 func main() {
+	var (
+		policyFile string
+		ref        string
+		ignores    string
+	)
+	flag.StringVar(&policyFile, "policy", internal.Getenv("POLICY_FILE", ""), "YAML dependency policy to evaluate (default: run the client scan)")
+	flag.StringVar(&ref, "ref", internal.Getenv("REF", ""), "Git ref (branch/commit) to scan (default: repo default branch)")
+	flag.StringVar(&ignores, "ignore", internal.Getenv("IGNORE", "archived,sandbox"), "Comma-separated substrings to ignore in project path")
+	flag.Parse()
+
 	cfg, err := configuration.NewConfiguration()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
 		os.Exit(1)
 	}
+	client := gitlab.NewClientFromConfig(cfg)
+
+	if policyFile != "" {
+		p, err := policy.Load(policyFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load policy: %v\n", err)
+			os.Exit(1)
+		}
+
+		report := scanner.NewPolicyScanner(cfg, client).
+			SetPolicy(p).
+			SetRef(ref).
+			SetIgnore(internal.SplitCSV(ignores)...).
+			Evaluate()
+
+		fmt.Printf("%d projects checked: %d errors, %d warnings\n", report.Evaluated, report.Errors, report.Warnings)
+		if report.Errors > 0 {
+			os.Exit(1)
+		}
+		return
+	}
 
-	//scanner.NewGoScanner(cfg).
+	//scanner.NewGoScanner(cfg, client).
 	//	SetParams("1.21.0").
 	//	SetIgnore("client").
 	//	Scan()
 	//
-	scanner.NewClientScanner(cfg).
+	scanner.NewClientScanner(cfg, client).
 		SetPrefixes("git.prosoftke.sk/nghis/openapi/clients/go/nghisorganizationgoclient").
 		SetIgnore("archived", "sandbox").
 		SetPrintFullPath(false).
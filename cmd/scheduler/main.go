@@ -7,6 +7,7 @@ import (
 	"syscall"
 
 	"gitlab-list/internal/configuration"
+	"gitlab-list/internal/gitlab"
 	"gitlab-list/internal/repository"
 	"gitlab-list/internal/service"
 )
@@ -20,35 +21,46 @@ func main() {
 		log.Fatal("Failed to load configuration:", err)
 	}
 
+	// One GitLab client per process so all requests share its rate limiter; tokens are set per request with WithToken
+	gitlabClient := gitlab.NewClientFromConfig(cfg)
+
 	// Initialize repository
-	gitlabRepo := repository.NewGitLabRepository(cfg)
+	gitlabRepo := repository.NewGitLabRepository(cfg, gitlabClient)
 
-	// Initialize MongoDB cache (optional)
-	var mongoRepo *repository.MongoDBRepository
-	if cfg.MongoDBURI != "" {
-		var err error
-		mongoURI := cfg.GetMongoDBURI()
-		mongoRepo, err = repository.NewMongoDBRepository(mongoURI, cfg.MongoDBDatabase)
-		if err != nil {
-			log.Printf("Warning: Failed to connect to MongoDB: %v", err)
-			log.Println("Continuing without caching...")
-		} else {
-			log.Println("MongoDB cache initialized successfully")
-		}
+	// Initialize the cache store (MongoDB, embedded bolt file or memory; see STORE)
+	store, err := repository.OpenStore(cfg)
+	if err != nil {
+		log.Fatal("Failed to open cache store:", err)
 	}
 
 	// Initialize service
 	var projectService *service.ProjectService
-	if mongoRepo != nil {
-		projectService = service.NewProjectServiceWithCache(gitlabRepo, mongoRepo)
-		log.Println("Project service initialized with MongoDB caching")
+	if store != nil {
+		defer store.Close()
+		projectService = service.NewProjectServiceWithCache(gitlabRepo, store)
+		log.Println("Project service initialized with caching")
 	} else {
 		projectService = service.NewProjectService(gitlabRepo)
 		log.Println("Project service initialized without caching")
 	}
+	projectService.SetDetailWorkers(cfg.DetailWorkers)
+	projectService.SetBranches(cfg.Branches)
+	projectService.SetSnapshotRetention(service.SnapshotRetentionFromConfig(cfg))
+	projectService.SetCacheTTL(service.CacheTTLFromConfig(cfg))
+
+	// Services of the policy, vulnerability, report and campaign jobs; campaign updates are queued by the API
+	libraryUpdater := service.NewLibraryUpdater(cfg, gitlabClient)
+	vulnerabilityService := service.NewVulnerabilityService(projectService, libraryUpdater, cfg.OSVDatabase)
+	policyService := service.NewPolicyService(projectService, cfg.PolicyFile)
+	campaignService := service.NewCampaignService(store, projectService, libraryUpdater, nil)
 
 	// Initialize scheduler
-	scheduler := service.NewSchedulerService(projectService, cfg)
+	scheduler := service.NewSchedulerService(store, cfg)
+	for _, job := range service.ConfiguredJobs(cfg, projectService, policyService, vulnerabilityService, campaignService) {
+		if err := scheduler.Register(job); err != nil {
+			log.Fatal("Failed to register job:", err)
+		}
+	}
 
 	// Start scheduler
 	err = scheduler.Start()
@@ -56,7 +68,6 @@ func main() {
 		log.Fatal("Failed to start scheduler:", err)
 	}
 
-	log.Printf("Scheduler running with schedule: %s", scheduler.GetSchedule())
 	log.Printf("Next run: %s", scheduler.GetNextRunTime().Format("2006-01-02 15:04:05 MST"))
 
 	// Wait for interrupt signal
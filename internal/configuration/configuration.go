@@ -14,6 +14,8 @@ type Configuration struct {
 	Tag             string   `env:"TAG" env-default:"services"`
 	Port            string   `env:"PORT" env-default:"8080"`
 	Branches        []string `env:"BRANCHES" env-default:"default" env-separator:","`
+	Store           string   `env:"STORE" env-default:"mongodb"`             // mongodb, bolt, memory or none
+	StorePath       string   `env:"STORE_PATH" env-default:"gitlab-list.db"` // File of the bolt store
 	MongoDBURI      string   `env:"MONGODB_URI" env-default:"mongodb://localhost:27017"`
 	MongoDBUsername string   `env:"MONGODB_USERNAME"`
 	MongoDBPassword string   `env:"MONGODB_PASSWORD"`
@@ -21,6 +23,65 @@ type Configuration struct {
 	CacheTTL        string   `env:"CACHE_TTL" env-default:"24h"`
 	SyncSchedule    string   `env:"SYNC_SCHEDULE" env-default:"0 3 * * *"`
 	Timezone        string   `env:"TZ" env-default:"UTC"`
+
+	// GitLab API client tuning
+	GitLabTimeout            string `env:"GITLAB_TIMEOUT" env-default:"30s"`
+	GitLabMaxRetries         int    `env:"GITLAB_MAX_RETRIES" env-default:"4"`
+	GitLabMinBackoff         string `env:"GITLAB_MIN_BACKOFF" env-default:"500ms"`
+	GitLabMaxBackoff         string `env:"GITLAB_MAX_BACKOFF" env-default:"30s"`
+	GitLabRateLimitThreshold int    `env:"GITLAB_RATE_LIMIT_THRESHOLD" env-default:"5"`
+	DetailWorkers            int    `env:"DETAIL_WORKERS" env-default:"8"`
+
+	// Go module proxy used for library version lookups, same syntax as the go command
+	GoProxy         string `env:"GOPROXY" env-default:"https://proxy.golang.org,direct"`
+	GoPrivate       string `env:"GOPRIVATE"`
+	GoNoProxy       string `env:"GONOPROXY"`
+	GoNoSumDB       string `env:"GONOSUMDB"`
+	GoProxyCacheTTL string `env:"GOPROXY_CACHE_TTL" env-default:"1h"`
+	GoProxyTimeout  string `env:"GOPROXY_TIMEOUT" env-default:"15s"`
+
+	// Checks of library updates in the clone before they are pushed
+	VerifyUpdates     bool `env:"VERIFY_UPDATES" env-default:"true"`       // go mod tidy, go build and go vet; results go into the merge request
+	VerifyTests       bool `env:"VERIFY_TESTS" env-default:"false"`        // Also go test ./...
+	RequireGreenBuild bool `env:"REQUIRE_GREEN_BUILD" env-default:"false"` // Do not push updates whose verification failed
+
+	// Workspaces of library updates: each clone gets its own GOPATH and module cache
+	UpdateCommandTimeout string `env:"UPDATE_COMMAND_TIMEOUT" env-default:"10m"` // Longest run of one git or go command
+	UpdateGoFlags        string `env:"UPDATE_GOFLAGS"`                           // Added to GOFLAGS of the go commands
+
+	// OSV vulnerability dump on local disk: a zip archive or a directory of JSON entries
+	OSVDatabase string `env:"OSV_DB"`
+
+	// YAML dependency policy evaluated against cached projects
+	PolicyFile string `env:"POLICY_FILE"`
+
+	// Scheduled jobs: cron expression, enable flag and timeout of each (SYNC_SCHEDULE above for the sync)
+	SyncEnabled           bool   `env:"SYNC_ENABLED" env-default:"true"`
+	SyncTimeout           string `env:"SYNC_TIMEOUT" env-default:"2h"`
+	PolicySchedule        string `env:"POLICY_SCHEDULE" env-default:"30 3 * * *"`
+	PolicyEnabled         bool   `env:"POLICY_ENABLED" env-default:"false"`
+	PolicyTimeout         string `env:"POLICY_TIMEOUT" env-default:"10m"`
+	VulnerabilitySchedule string `env:"VULNERABILITY_SCHEDULE" env-default:"45 3 * * *"`
+	VulnerabilityEnabled  bool   `env:"VULNERABILITY_ENABLED" env-default:"false"`
+	VulnerabilityTimeout  string `env:"VULNERABILITY_TIMEOUT" env-default:"10m"`
+	CampaignSchedule      string `env:"CAMPAIGN_SCHEDULE" env-default:"*/15 * * * *"`
+	CampaignEnabled       bool   `env:"CAMPAIGN_ENABLED" env-default:"true"`
+	CampaignTimeout       string `env:"CAMPAIGN_TIMEOUT" env-default:"10m"`
+	ReportSchedule        string `env:"REPORT_SCHEDULE" env-default:"0 6 * * 1"`
+	ReportEnabled         bool   `env:"REPORT_ENABLED" env-default:"false"`
+	ReportTimeout         string `env:"REPORT_TIMEOUT" env-default:"10m"`
+	ReportDir             string `env:"REPORT_DIR"`                        // Weekly reports are also written here as Markdown
+	SchedulerPoll         string `env:"SCHEDULER_POLL" env-default:"15s"`  // How often the scheduler picks up API requests
+	APIScheduler          bool   `env:"API_SCHEDULER" env-default:"false"` // Run the scheduled jobs inside the API process
+
+	// Job queue of cache loads and project updates started through the API
+	JobWorkers   int    `env:"JOB_WORKERS" env-default:"2"`
+	JobQueueSize int    `env:"JOB_QUEUE_SIZE" env-default:"100"` // Waiting jobs; further submissions are rejected
+	JobRetention string `env:"JOB_RETENTION" env-default:"24h"`  // How long finished jobs and their results are kept
+
+	// Snapshot retention: keep every snapshot for SNAPSHOT_KEEP_ALL, then one per week until SNAPSHOT_KEEP_WEEKLY (0 = forever)
+	SnapshotKeepAll    string `env:"SNAPSHOT_KEEP_ALL" env-default:"720h"`
+	SnapshotKeepWeekly string `env:"SNAPSHOT_KEEP_WEEKLY" env-default:"8760h"`
 }
 
 func NewConfiguration() (*Configuration, error) {
@@ -0,0 +1,74 @@
+// internal/domain/campaign.go
+package domain
+
+import "time"
+
+// Campaign states
+const (
+	CampaignActive    = "active"    // Updates are queued or merge requests are open
+	CampaignCompleted = "completed" // Every project reached a final state
+	CampaignCanceled  = "canceled"
+)
+
+// Campaign project states
+const (
+	CampaignProjectPending        = "pending"  // Update job queued
+	CampaignProjectUpdating       = "updating" // Update job running
+	CampaignProjectOpened         = "opened"   // Merge request open
+	CampaignProjectPipelineFailed = "pipeline_failed"
+	CampaignProjectMerged         = "merged"
+	CampaignProjectClosed         = "closed" // Merge request closed without merging
+	CampaignProjectFailed         = "failed" // The update itself failed, no merge request
+	CampaignProjectCanceled       = "canceled"
+)
+
+// Campaign bumps a module, or the Go version, in every selected project with one merge request
+// per project module
+type Campaign struct {
+	ID         string            `json:"id" bson:"_id"`
+	Name       string            `json:"name" bson:"name"`
+	Module     string            `json:"module,omitempty" bson:"module,omitempty"`         // Module to bump, with Version
+	Version    string            `json:"version,omitempty" bson:"version,omitempty"`       // Target version of Module
+	GoVersion  string            `json:"go_version,omitempty" bson:"go_version,omitempty"` // Target Go version
+	Criteria   SearchCriteria    `json:"criteria" bson:"criteria"`                         // Project selector
+	BranchName string            `json:"branch_name" bson:"branch_name"`
+	Status     string            `json:"status" bson:"status"`
+	Projects   []CampaignProject `json:"projects" bson:"projects"`
+	Summary    map[string]int    `json:"summary,omitempty" bson:"-"` // Projects per state
+	CreatedAt  time.Time         `json:"created_at" bson:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at" bson:"updated_at"`
+}
+
+// CampaignProject is the update of one module of one project in a campaign
+type CampaignProject struct {
+	ProjectID    int         `json:"project_id" bson:"project_id"`
+	ProjectName  string      `json:"project_name" bson:"project_name"`
+	Path         string      `json:"path" bson:"path"`
+	ModuleDir    string      `json:"module_dir" bson:"module_dir"`
+	FromVersion  string      `json:"from_version" bson:"from_version"` // Module or Go version before the update
+	Status       string      `json:"status" bson:"status"`
+	JobID        string      `json:"job_id,omitempty" bson:"job_id,omitempty"` // Queued update job of the current attempt
+	Attempts     int         `json:"attempts" bson:"attempts"`
+	Error        string      `json:"error,omitempty" bson:"error,omitempty"`
+	MergeRequest *CampaignMR `json:"merge_request,omitempty" bson:"merge_request,omitempty"`
+	UpdatedAt    time.Time   `json:"updated_at" bson:"updated_at"`
+}
+
+// CampaignMR is the merge request of a campaign project as last seen on GitLab
+type CampaignMR struct {
+	IID            int    `json:"iid" bson:"iid"`
+	WebURL         string `json:"web_url" bson:"web_url"`
+	SourceBranch   string `json:"source_branch" bson:"source_branch"`
+	State          string `json:"state" bson:"state"` // opened, merged or closed
+	PipelineID     int    `json:"pipeline_id,omitempty" bson:"pipeline_id,omitempty"`
+	PipelineStatus string `json:"pipeline_status,omitempty" bson:"pipeline_status,omitempty"`
+}
+
+// Final reports whether the project needs no more tracking
+func (p CampaignProject) Final() bool {
+	switch p.Status {
+	case CampaignProjectMerged, CampaignProjectClosed, CampaignProjectFailed, CampaignProjectCanceled:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+// internal/domain/diff.go
+package domain
+
+import "time"
+
+// Change kinds used across a diff
+const (
+	ChangeAdded      = "added"
+	ChangeRemoved    = "removed"
+	ChangeUpgraded   = "upgraded"
+	ChangeDowngraded = "downgraded"
+	ChangeModified   = "modified" // Same version, different content (e.g. a replace or an OpenAPI spec)
+)
+
+// FleetDiff describes what changed between two snapshots, or between two refs of one project
+type FleetDiff struct {
+	From     DiffSide      `json:"from"`
+	To       DiffSide      `json:"to"`
+	Summary  DiffSummary   `json:"summary"`
+	Added    []ProjectRef  `json:"added_projects"`
+	Removed  []ProjectRef  `json:"removed_projects"`
+	Projects []ProjectDiff `json:"changed_projects"`
+	Edges    []EdgeChange  `json:"architecture_edges"`
+}
+
+// DiffSide names one side of a diff
+type DiffSide struct {
+	SnapshotID string    `json:"snapshot_id,omitempty"`
+	TakenAt    time.Time `json:"taken_at,omitempty"`
+	Ref        string    `json:"ref,omitempty"`
+}
+
+// DiffSummary counts the changes of a diff
+type DiffSummary struct {
+	ProjectsAdded       int `json:"projects_added"`
+	ProjectsRemoved     int `json:"projects_removed"`
+	ProjectsChanged     int `json:"projects_changed"`
+	GoVersionChanges    int `json:"go_version_changes"`
+	LibrariesAdded      int `json:"libraries_added"`
+	LibrariesRemoved    int `json:"libraries_removed"`
+	LibrariesUpgraded   int `json:"libraries_upgraded"`
+	LibrariesDowngraded int `json:"libraries_downgraded"`
+	OpenAPIChanges      int `json:"openapi_changes"`
+	EdgesAdded          int `json:"edges_added"`
+	EdgesRemoved        int `json:"edges_removed"`
+	EdgesModified       int `json:"edges_modified"`
+}
+
+// ProjectRef identifies a project record in a diff
+type ProjectRef struct {
+	ProjectID int    `json:"project_id"`
+	Name      string `json:"name"`
+	Path      string `json:"path_with_namespace"`
+	Ref       string `json:"ref,omitempty"`
+}
+
+// ProjectDiff lists the changes of one project
+type ProjectDiff struct {
+	ProjectRef
+	FromCommit string          `json:"from_commit,omitempty"`
+	ToCommit   string          `json:"to_commit,omitempty"`
+	GoVersions []GoChange      `json:"go_versions,omitempty"`
+	Modules    []ModuleChange  `json:"modules,omitempty"`
+	Libraries  []LibraryChange `json:"libraries,omitempty"`
+	OpenAPI    []SpecChange    `json:"openapi,omitempty"`
+}
+
+// GoChange is a change of a module's go directive
+type GoChange struct {
+	ModuleDir string `json:"module_dir"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Change    string `json:"change"` // upgraded or downgraded
+}
+
+// ModuleChange is a go.mod added to or removed from a project
+type ModuleChange struct {
+	ModuleDir string `json:"module_dir"`
+	Path      string `json:"path"`
+	Change    string `json:"change"` // added or removed
+}
+
+// LibraryChange is a requirement added, removed or moved to another version
+type LibraryChange struct {
+	ModuleDir string `json:"module_dir"`
+	Module    string `json:"module"`
+	From      string `json:"from,omitempty"`
+	To        string `json:"to,omitempty"`
+	Change    string `json:"change"`         // added, removed, upgraded, downgraded or modified
+	Bump      string `json:"bump,omitempty"` // major, minor, patch or prerelease for version changes
+	Indirect  bool   `json:"indirect,omitempty"`
+}
+
+// SpecChange is an OpenAPI spec added, removed or modified
+type SpecChange struct {
+	Path        string `json:"path"`
+	Change      string `json:"change"` // added, removed or modified
+	FromVersion string `json:"from_version,omitempty"`
+	ToVersion   string `json:"to_version,omitempty"`
+	Title       string `json:"title,omitempty"`
+}
+
+// EdgeChange is an architecture edge (service calls client) that appeared, disappeared or moved to
+// another client version
+type EdgeChange struct {
+	From        string `json:"from"` // Service label, e.g. "drg" or "drg/tools"
+	To          string `json:"to"`   // Client module path
+	Version     string `json:"version,omitempty"`
+	FromVersion string `json:"from_version,omitempty"` // Client version before a modified edge
+	Change      string `json:"change"`                 // added, removed or modified
+}
@@ -0,0 +1,61 @@
+// internal/domain/drift.go
+package domain
+
+import "time"
+
+// DriftReport is the fleet-wide view of library versions in use
+type DriftReport struct {
+	Ref         string         `json:"ref,omitempty"`
+	GeneratedAt time.Time      `json:"generated_at"`
+	Projects    int            `json:"projects"` // Cached projects considered
+	Libraries   []LibraryDrift `json:"libraries"`
+}
+
+// LibraryDrift lists every version of one library used across the fleet
+type LibraryDrift struct {
+	Module              string         `json:"module"`
+	Internal            bool           `json:"internal,omitempty"` // Hosted on our GitLab instance
+	LatestInUse         string         `json:"latest_in_use"`
+	LatestAvailable     string         `json:"latest_available,omitempty"`
+	LatestAvailableTime time.Time      `json:"latest_available_time,omitempty"`
+	Projects            int            `json:"projects"`  // Distinct projects using any version
+	MaxScore            int            `json:"max_score"` // Worst drift score among the versions
+	LookupError         string         `json:"lookup_error,omitempty"`
+	Versions            []VersionUsage `json:"versions"` // Newest first
+}
+
+// VersionUsage is one version of a library and the projects on it
+type VersionUsage struct {
+	Version  string            `json:"version"`
+	Time     time.Time         `json:"time,omitempty"` // Release time, when the proxy knows it
+	Drift    VersionDrift      `json:"drift"`
+	Projects []LibraryConsumer `json:"projects"`
+}
+
+// VersionDrift measures how far a version is behind the newest one.
+// Only the most significant component differs from zero: v1.2.3 against v2.0.1 is one major behind.
+type VersionDrift struct {
+	Target     string `json:"target"` // Latest available, or latest in use when the proxy has no answer
+	Major      int    `json:"major"`
+	Minor      int    `json:"minor"`
+	Patch      int    `json:"patch"`
+	DaysBehind int    `json:"days_behind"`
+	Score      int    `json:"score"` // 100 per major, 10 per minor, 1 per patch
+}
+
+// LibraryConsumer is a project module that requires a library
+type LibraryConsumer struct {
+	ProjectID int    `json:"project_id"`
+	Name      string `json:"name"`
+	Path      string `json:"path_with_namespace"`
+	ModuleDir string `json:"module_dir,omitempty"` // Set for modules outside the repository root
+	Indirect  bool   `json:"indirect,omitempty"`
+}
+
+// DriftOptions filters the drift report
+type DriftOptions struct {
+	Ref          string // Branch to report on; empty means each project's default branch
+	Prefix       string // Only libraries whose module path starts with this
+	InternalOnly bool   // Only libraries hosted on our GitLab instance
+	DirectOnly   bool   // Ignore indirect requirements
+}
@@ -0,0 +1,49 @@
+// internal/domain/job.go
+package domain
+
+import "time"
+
+// Job run states, shared by scheduled and queued jobs
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobPartial   = "partial" // Finished, but some items failed
+	JobFailed    = "failed"
+	JobTimedOut  = "timed_out"
+	JobCanceled  = "canceled"
+)
+
+// Job run triggers
+const (
+	TriggerSchedule = "schedule"
+	TriggerManual   = "manual"
+)
+
+// ScheduledJob is the shared state of one named scheduled job. The scheduler owns the
+// configuration fields and NextRun; Paused and TriggeredAt are set through the API.
+type ScheduledJob struct {
+	Name        string     `json:"name" bson:"_id"`
+	Description string     `json:"description" bson:"description"`
+	Schedule    string     `json:"schedule" bson:"schedule"` // Cron expression
+	Enabled     bool       `json:"enabled" bson:"enabled"`
+	Timeout     string     `json:"timeout" bson:"timeout"`
+	NextRun     time.Time  `json:"next_run,omitzero" bson:"next_run"`
+	Paused      bool       `json:"paused" bson:"paused"`
+	TriggeredAt *time.Time `json:"triggered_at,omitempty" bson:"triggered_at,omitempty"` // Pending "run now" request
+	UpdatedAt   time.Time  `json:"updated_at" bson:"updated_at"`                         // Last time the scheduler reported in
+}
+
+// JobRun records one run of a scheduled job
+type JobRun struct {
+	ID         string           `json:"id" bson:"_id"`
+	Job        string           `json:"job" bson:"job"`
+	Trigger    string           `json:"trigger" bson:"trigger"`
+	StartedAt  time.Time        `json:"started_at" bson:"started_at"`
+	FinishedAt time.Time        `json:"finished_at,omitzero" bson:"finished_at"`
+	Duration   string           `json:"duration,omitempty" bson:"duration,omitempty"`
+	Status     string           `json:"status" bson:"status"`
+	Error      string           `json:"error,omitempty" bson:"error,omitempty"`
+	Log        string           `json:"log,omitempty" bson:"log,omitempty"` // Last lines of the job output
+	Metrics    map[string]int64 `json:"metrics,omitempty" bson:"metrics,omitempty"`
+}
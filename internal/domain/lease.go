@@ -0,0 +1,21 @@
+// internal/domain/lease.go
+package domain
+
+import "time"
+
+// Lease is a named distributed lock kept in the cache store. A holder keeps it by renewing it
+// before ExpiresAt; Token grows with every acquisition, so writes can be fenced against holders
+// whose lease has meanwhile expired and been taken over.
+type Lease struct {
+	Name       string    `json:"name" bson:"_id"`
+	Holder     string    `json:"holder,omitempty" bson:"holder"` // Empty once released
+	Token      int64     `json:"token" bson:"token"`             // Fencing token
+	AcquiredAt time.Time `json:"acquired_at,omitzero" bson:"acquired_at"`
+	RenewedAt  time.Time `json:"renewed_at,omitzero" bson:"renewed_at"`
+	ExpiresAt  time.Time `json:"expires_at,omitzero" bson:"expires_at"`
+}
+
+// HeldAt reports whether the lease is held at now
+func (l Lease) HeldAt(now time.Time) bool {
+	return l.Holder != "" && l.ExpiresAt.After(now)
+}
@@ -5,31 +5,110 @@ import "time"
 
 // Project represents a GitLab project
 type Project struct {
-	ID            int       `json:"id"`
-	Name          string    `json:"name"`
-	Path          string    `json:"path_with_namespace"`
-	WebURL        string    `json:"web_url,omitempty"`
-	Description   string    `json:"description,omitempty"`
-	DefaultBranch string    `json:"default_branch,omitempty"`
-	CreatedAt     time.Time `json:"created_at,omitempty"`
-	UpdatedAt     time.Time `json:"updated_at,omitempty"`
-	GoVersion     string    `json:"go_version,omitempty"`
-	Libraries     []Library `json:"libraries,omitempty"`
-	OpenAPI       *OpenAPI  `json:"openapi,omitempty"`
+	ID            int             `json:"id"`
+	Name          string          `json:"name"`
+	Path          string          `json:"path_with_namespace"`
+	WebURL        string          `json:"web_url,omitempty"`
+	Description   string          `json:"description,omitempty"`
+	DefaultBranch string          `json:"default_branch,omitempty"`
+	EmptyRepo     bool            `json:"empty_repo,omitempty"`
+	Archived      bool            `json:"archived,omitempty"`
+	Ref           string          `json:"ref,omitempty"`        // Branch the details below were read from
+	CommitSHA     string          `json:"commit_sha,omitempty"` // Head commit of Ref when the details were read
+	CreatedAt     time.Time       `json:"created_at,omitempty"`
+	UpdatedAt     time.Time       `json:"updated_at,omitempty"`
+	LastActivity  time.Time       `json:"last_activity_at,omitempty"`
+	DetailsReadAt time.Time       `json:"details_read_at,omitempty"`
+	GoVersion     string          `json:"go_version,omitempty"` // Go version of the root module
+	Toolchain     string          `json:"toolchain,omitempty"`  // Toolchain of the root module, e.g. "go1.24.2"
+	Libraries     []Library       `json:"libraries,omitempty"`  // Requirements of the root module
+	Excludes      []ModuleVersion `json:"excludes,omitempty"`   // Exclude directives of the root module
+	Modules       []Module        `json:"modules,omitempty"`    // Every go.mod in the repository, root first
+	Workspace     *Workspace      `json:"workspace,omitempty"`  // Root go.work, if any
+	OpenAPISpecs  []OpenAPI       `json:"openapi_specs,omitempty"`
+}
+
+// Module represents a single go.mod inside a project's repository
+type Module struct {
+	Path        string          `json:"path"` // Module path from the module directive
+	Dir         string          `json:"dir"`  // Directory of go.mod relative to the repository root, "." for the root
+	GoVersion   string          `json:"go_version,omitempty"`
+	Toolchain   string          `json:"toolchain,omitempty"`
+	Libraries   []Library       `json:"libraries,omitempty"`
+	Excludes    []ModuleVersion `json:"excludes,omitempty"`
+	Retracts    []string        `json:"retracts,omitempty"`     // Retracted versions or "[low, high]" ranges
+	InWorkspace bool            `json:"in_workspace,omitempty"` // Listed in a go.work use directive
+}
+
+// ModuleVersion is a module path and version, as named by an exclude directive
+type ModuleVersion struct {
+	Path    string `json:"path"`
+	Version string `json:"version"`
+}
+
+// Workspace represents a go.work file
+type Workspace struct {
+	GoVersion string   `json:"go_version,omitempty"`
+	Use       []string `json:"use"` // Module directories relative to the repository root
+}
+
+// OnRef reports whether the project record was read from ref.
+// An empty ref matches the default branch, as do records cached before refs were tracked.
+func (p Project) OnRef(ref string) bool {
+	if ref == "" {
+		return p.Ref == "" || p.Ref == p.DefaultBranch
+	}
+	return p.Ref == ref
+}
+
+// GoModules returns the modules of the project. Records cached before modules were tracked
+// only carry the root module's data on the project itself; their module path is unknown and
+// left empty.
+func (p Project) GoModules() []Module {
+	if len(p.Modules) > 0 {
+		return p.Modules
+	}
+	return []Module{{
+		Dir:       ".", // repository root
+		GoVersion: p.GoVersion,
+		Toolchain: p.Toolchain,
+		Libraries: p.Libraries,
+		Excludes:  p.Excludes,
+	}}
+}
+
+// Branch represents a repository branch
+type Branch struct {
+	Name      string `json:"name"`
+	CommitSHA string `json:"commit_sha"`
+	Default   bool   `json:"default"`
 }
 
 // Library represents a Go module dependency
 type Library struct {
-	Name    string `json:"name"`
-	Version string `json:"version"`
-	Path    string `json:"path,omitempty"`
+	Name     string       `json:"name"`
+	Version  string       `json:"version"`
+	Path     string       `json:"path,omitempty"`
+	Indirect bool         `json:"indirect,omitempty"` // Marked "// indirect" in go.mod
+	Replace  *Replacement `json:"replace,omitempty"`  // Effective replace directive, if any
+}
+
+// Replacement is the target of a replace directive
+type Replacement struct {
+	Path    string `json:"path"`              // Module path, or directory for local replaces
+	Version string `json:"version,omitempty"` // Empty for local replaces
+	Local   bool   `json:"local,omitempty"`   // Replaced by a directory in the repository or on disk
 }
 
 // OpenAPI represents OpenAPI specification data
 type OpenAPI struct {
-	Content string `json:"content"` // Raw YAML content
-	Path    string `json:"path"`    // File path in repository
-	Found   bool   `json:"found"`   // Whether OpenAPI file was found
+	Content string   `json:"content"`           // Raw YAML/JSON content, bundled when the spec references other files
+	Path    string   `json:"path"`              // File path in repository
+	Found   bool     `json:"found"`             // Whether OpenAPI file was found
+	Format  string   `json:"format,omitempty"`  // "yaml" or "json"
+	Version string   `json:"version,omitempty"` // OpenAPI or Swagger version, e.g. "3.0.3" or "2.0"
+	Title   string   `json:"title,omitempty"`   // info.title of the spec
+	Files   []string `json:"files,omitempty"`   // Referenced files bundled into Content
 }
 
 // SearchCriteria represents search parameters for projects
@@ -41,4 +120,16 @@ type SearchCriteria struct {
 	VersionComparison   string `json:"version_comparison,omitempty"`
 	Group               string `json:"group,omitempty"`
 	Tag                 string `json:"tag,omitempty"`
+	Ref                 string `json:"ref,omitempty"`                  // Branch to search; empty means each project's default branch
+	Dependency          string `json:"dependency,omitempty"`           // "direct" or "indirect" requirements only
+	Replaced            string `json:"replaced,omitempty"`             // "true", "false" or "local" replace status of the requirement
+	Toolchain           string `json:"toolchain,omitempty"`            // Toolchain version, with or without the "go" prefix
+	ToolchainComparison string `json:"toolchain_comparison,omitempty"` // Same values as go_version_comparison
+	Exclude             string `json:"exclude,omitempty"`              // Module path named by an exclude directive
+}
+
+// HasModuleFilters reports whether the criteria filter on go.mod contents
+func (c SearchCriteria) HasModuleFilters() bool {
+	return c.GoVersion != "" || c.Library != "" || c.Dependency != "" || c.Replaced != "" ||
+		c.Toolchain != "" || c.Exclude != ""
 }
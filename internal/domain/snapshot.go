@@ -0,0 +1,108 @@
+// internal/domain/snapshot.go
+package domain
+
+import "time"
+
+// Snapshot summarizes one saved sync of the fleet
+type Snapshot struct {
+	ID       string    `json:"id"`
+	TakenAt  time.Time `json:"taken_at"`
+	Projects int       `json:"projects"` // Number of project records (one per project and ref)
+}
+
+// ProjectSnapshot is the immutable state of one project on one ref at sync time
+type ProjectSnapshot struct {
+	SnapshotID    string            `json:"snapshot_id" bson:"snapshot_id"`
+	TakenAt       time.Time         `json:"taken_at" bson:"taken_at"`
+	ProjectID     int               `json:"project_id" bson:"project_id"`
+	Name          string            `json:"name" bson:"name"`
+	Path          string            `json:"path_with_namespace" bson:"path"`
+	Ref           string            `json:"ref,omitempty" bson:"ref,omitempty"`
+	DefaultBranch string            `json:"default_branch,omitempty" bson:"default_branch,omitempty"`
+	CommitSHA     string            `json:"commit_sha,omitempty" bson:"commit_sha,omitempty"`
+	GoVersion     string            `json:"go_version,omitempty" bson:"go_version,omitempty"`     // Go version of the root module
+	OpenAPIHash   string            `json:"openapi_hash,omitempty" bson:"openapi_hash,omitempty"` // Hash over all specs
+	OpenAPISpecs  []OpenAPISnapshot `json:"openapi_specs,omitempty" bson:"openapi_specs,omitempty"`
+	Modules       []ModuleSnapshot  `json:"modules,omitempty" bson:"modules,omitempty"`
+}
+
+// OpenAPISnapshot fingerprints one OpenAPI spec at sync time
+type OpenAPISnapshot struct {
+	Path    string `json:"path" bson:"path"`
+	Version string `json:"version,omitempty" bson:"version,omitempty"` // OpenAPI or Swagger version
+	Title   string `json:"title,omitempty" bson:"title,omitempty"`
+	Hash    string `json:"hash" bson:"hash"` // md5 of the (bundled) content
+}
+
+// ModuleSnapshot is the state of one go.mod at sync time
+type ModuleSnapshot struct {
+	Dir       string    `json:"dir" bson:"dir"`
+	Path      string    `json:"path" bson:"path"`
+	GoVersion string    `json:"go_version,omitempty" bson:"go_version,omitempty"`
+	Toolchain string    `json:"toolchain,omitempty" bson:"toolchain,omitempty"`
+	Libraries []Library `json:"libraries,omitempty" bson:"libraries,omitempty"`
+}
+
+// OnRef reports whether the snapshot was read from ref; an empty ref matches the default branch
+func (p ProjectSnapshot) OnRef(ref string) bool {
+	if ref == "" {
+		return p.Ref == "" || p.Ref == p.DefaultBranch
+	}
+	return p.Ref == ref
+}
+
+// TrendOptions selects the snapshots a trend is computed from
+type TrendOptions struct {
+	Ref      string    // Branch to report on; empty means each project's default branch
+	Interval string    // "day", "week" (default) or "month"
+	From     time.Time // Zero for the oldest snapshot
+	To       time.Time // Zero for the newest snapshot
+}
+
+// GoVersionTrend is the share of projects on each Go version per period
+type GoVersionTrend struct {
+	Ref      string            `json:"ref,omitempty"`
+	Interval string            `json:"interval"`
+	Periods  []GoVersionPeriod `json:"periods"`
+}
+
+// GoVersionPeriod counts the projects on each Go version (major.minor) in the last snapshot of a period
+type GoVersionPeriod struct {
+	Start      time.Time          `json:"start"`
+	SnapshotID string             `json:"snapshot_id"`
+	TakenAt    time.Time          `json:"taken_at"`
+	Total      int                `json:"total"`
+	Versions   map[string]int     `json:"versions"`
+	Shares     map[string]float64 `json:"shares"` // Percent of Total
+}
+
+// LibraryAdoption tracks how projects moved to a library version over time
+type LibraryAdoption struct {
+	Module   string            `json:"module"`
+	Version  string            `json:"version"`
+	Ref      string            `json:"ref,omitempty"`
+	Interval string            `json:"interval"`
+	Curve    []AdoptionPoint   `json:"curve"`
+	Projects []ProjectAdoption `json:"projects"`
+}
+
+// AdoptionPoint is the adoption of a library version in the last snapshot of a period
+type AdoptionPoint struct {
+	Start      time.Time `json:"start"`
+	SnapshotID string    `json:"snapshot_id"`
+	TakenAt    time.Time `json:"taken_at"`
+	Using      int       `json:"using"`   // Projects requiring any version of the module
+	Adopted    int       `json:"adopted"` // Projects requiring the version or newer
+	Share      float64   `json:"share"`   // Percent of Using
+}
+
+// ProjectAdoption is when a project first required the library version or newer
+type ProjectAdoption struct {
+	ProjectID      int        `json:"project_id"`
+	Name           string     `json:"name"`
+	Path           string     `json:"path_with_namespace"`
+	AdoptedAt      *time.Time `json:"adopted_at,omitempty"`      // First snapshot with the version or newer; nil if not adopted yet
+	AdoptedVersion string     `json:"adopted_version,omitempty"` // Version required at AdoptedAt
+	CurrentVersion string     `json:"current_version"`           // Version in the newest snapshot
+	ModuleDir      string     `json:"module_dir,omitempty"`      // Project module requiring it, empty for the root
+}
@@ -0,0 +1,318 @@
+// internal/gitlab/client.go
+package gitlab
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"gitlab-list/internal/configuration"
+)
+
+// apiPrefix is the REST API root relative to the GitLab instance URL
+const apiPrefix = "/api/v4"
+
+// Options controls timeouts, retries and rate-limit handling of a Client
+type Options struct {
+	Timeout            time.Duration // per-request timeout
+	MaxRetries         int           // retries after the first attempt for 429/5xx and network errors
+	MinBackoff         time.Duration // base delay of the exponential backoff
+	MaxBackoff         time.Duration // upper bound of a single backoff delay
+	RateLimitThreshold int           // pause when RateLimit-Remaining drops to this value
+}
+
+// DefaultOptions returns the options used when none are configured
+func DefaultOptions() Options {
+	return Options{
+		Timeout:            30 * time.Second,
+		MaxRetries:         4,
+		MinBackoff:         500 * time.Millisecond,
+		MaxBackoff:         30 * time.Second,
+		RateLimitThreshold: 5,
+	}
+}
+
+// OptionsFromConfig builds client options from the configuration, falling back to defaults
+func OptionsFromConfig(cfg *configuration.Configuration) Options {
+	opts := DefaultOptions()
+	if d, err := time.ParseDuration(cfg.GitLabTimeout); err == nil && d > 0 {
+		opts.Timeout = d
+	}
+	if cfg.GitLabMaxRetries >= 0 {
+		opts.MaxRetries = cfg.GitLabMaxRetries
+	}
+	if d, err := time.ParseDuration(cfg.GitLabMinBackoff); err == nil && d > 0 {
+		opts.MinBackoff = d
+	}
+	if d, err := time.ParseDuration(cfg.GitLabMaxBackoff); err == nil && d > 0 {
+		opts.MaxBackoff = d
+	}
+	if cfg.GitLabRateLimitThreshold >= 0 {
+		opts.RateLimitThreshold = cfg.GitLabRateLimitThreshold
+	}
+	return opts
+}
+
+// Client performs authenticated requests against the REST API of a single GitLab instance
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+	opts       Options
+	limiter    *rateLimiter
+	counters   []*CallCounter
+}
+
+// NewClient creates a client for the GitLab instance at baseURL (e.g. "https://gitlab.example.com")
+func NewClient(baseURL, token string) *Client {
+	return NewClientWithOptions(baseURL, token, DefaultOptions())
+}
+
+// NewClientWithOptions creates a client with explicit timeout, retry and rate-limit options
+func NewClientWithOptions(baseURL, token string, opts Options) *Client {
+	return &Client{
+		baseURL:    normalizeBaseURL(baseURL),
+		token:      token,
+		httpClient: &http.Client{Timeout: opts.Timeout},
+		opts:       opts,
+		limiter:    newRateLimiter(opts.RateLimitThreshold),
+	}
+}
+
+// NewClientFromConfig creates a client for the configured GitLab instance and token
+func NewClientFromConfig(cfg *configuration.Configuration) *Client {
+	return NewClientWithOptions(cfg.GitLabURL, cfg.Token, OptionsFromConfig(cfg))
+}
+
+// WithToken returns a copy of the client that authenticates with the given token.
+// The copy shares the HTTP client and rate limiter with the original.
+func (c *Client) WithToken(token string) *Client {
+	clone := *c
+	clone.token = token
+	return &clone
+}
+
+// BaseURL returns the instance URL without the API prefix
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+// Host returns the host name of the GitLab instance (e.g. "gitlab.example.com")
+func (c *Client) Host() string {
+	u, err := url.Parse(c.baseURL)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// Token returns the token used to authenticate requests
+func (c *Client) Token() string {
+	return c.token
+}
+
+// APIURL builds an absolute API URL from a path such as "/projects/42"
+func (c *Client) APIURL(path string) string {
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		return path
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return c.baseURL + apiPrefix + path
+}
+
+// Do sends a request to the API and returns the raw response regardless of its status code.
+// body, when not nil, is encoded as JSON.
+func (c *Client) Do(method, path string, body interface{}) (*http.Response, error) {
+	return c.DoContext(context.Background(), method, path, body)
+}
+
+// DoContext is Do with a context. Rate-limited and 5xx responses as well as network errors are
+// retried with jittered exponential backoff; the last response is returned once retries run out.
+func (c *Client) DoContext(ctx context.Context, method, path string, body interface{}) (*http.Response, error) {
+	var payload []byte
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		payload = data
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := c.limiter.wait(ctx); err != nil {
+			return nil, err
+		}
+
+		resp, err := c.send(ctx, method, path, payload)
+		if err == nil {
+			c.limiter.observe(resp)
+		}
+
+		if attempt >= c.opts.MaxRetries || !c.shouldRetry(method, resp, err) {
+			return resp, err
+		}
+
+		delay := c.backoff(attempt)
+		if resp != nil {
+			if resp.StatusCode == http.StatusTooManyRequests {
+				delay = retryAfter(resp.Header, delay)
+			}
+			// Drain so the connection can be reused
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// send performs a single HTTP round trip
+func (c *Client) send(ctx context.Context, method, path string, payload []byte) (*http.Response, error) {
+	var reader io.Reader
+	if payload != nil {
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.APIURL(path), reader)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("PRIVATE-TOKEN", c.token)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	for _, counter := range c.counters {
+		counter.calls.Add(1)
+	}
+	return c.httpClient.Do(req)
+}
+
+// shouldRetry decides whether a failed attempt is worth repeating.
+// Non-idempotent requests are only retried when GitLab rejected them before processing (429).
+func (c *Client) shouldRetry(method string, resp *http.Response, err error) bool {
+	idempotent := method == http.MethodGet || method == http.MethodHead ||
+		method == http.MethodPut || method == http.MethodDelete
+
+	if err != nil {
+		return idempotent
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	return idempotent && resp.StatusCode >= 500
+}
+
+// backoff returns an exponential delay for the given attempt, jittered between half and full value
+func (c *Client) backoff(attempt int) time.Duration {
+	ceiling := c.opts.MinBackoff << uint(attempt)
+	if ceiling <= 0 || ceiling > c.opts.MaxBackoff {
+		ceiling = c.opts.MaxBackoff
+	}
+	half := ceiling / 2
+	if half <= 0 {
+		return ceiling
+	}
+	return half + time.Duration(rand.Int63n(int64(ceiling-half)))
+}
+
+// Get performs a GET request and returns an *APIError for any 4xx/5xx response
+func (c *Client) Get(path string) (*http.Response, error) {
+	return c.GetContext(context.Background(), path)
+}
+
+// GetContext is Get with a context
+func (c *Client) GetContext(ctx context.Context, path string) (*http.Response, error) {
+	return c.request(ctx, http.MethodGet, path, nil)
+}
+
+// GetJSON performs a GET request and decodes the JSON response into v
+func (c *Client) GetJSON(path string, v interface{}) error {
+	return c.SendJSON(http.MethodGet, path, nil, v)
+}
+
+// SendJSON sends body (if any) as JSON and decodes the response into v (if not nil)
+func (c *Client) SendJSON(method, path string, body, v interface{}) error {
+	return c.SendJSONContext(context.Background(), method, path, body, v)
+}
+
+// SendJSONContext is SendJSON with a context
+func (c *Client) SendJSONContext(ctx context.Context, method, path string, body, v interface{}) error {
+	resp, err := c.request(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if v == nil {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// GetRawFile downloads a repository file at the given ref (default branch when ref is empty)
+func (c *Client) GetRawFile(projectID int, filePath, ref string) ([]byte, error) {
+	return c.GetRawFileContext(context.Background(), projectID, filePath, ref)
+}
+
+// GetRawFileContext is GetRawFile with a context
+func (c *Client) GetRawFileContext(ctx context.Context, projectID int, filePath, ref string) ([]byte, error) {
+	path := fmt.Sprintf("/projects/%d/repository/files/%s/raw", projectID, url.PathEscape(filePath))
+	if strings.TrimSpace(ref) != "" {
+		path += "?ref=" + url.QueryEscape(ref)
+	}
+
+	resp, err := c.GetContext(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return io.ReadAll(resp.Body)
+}
+
+// request performs a request and converts 4xx/5xx responses into *APIError
+func (c *Client) request(ctx context.Context, method, path string, body interface{}) (*http.Response, error) {
+	resp, err := c.DoContext(ctx, method, path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		apiErr := &APIError{
+			Method:     method,
+			URL:        c.APIURL(path),
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Body:       string(data),
+		}
+		if resp.StatusCode == http.StatusTooManyRequests {
+			apiErr.RetryAfter = retryAfter(resp.Header, 0)
+		}
+		return nil, apiErr
+	}
+
+	return resp, nil
+}
+
+// normalizeBaseURL strips trailing slashes and an accidental "/api/v4" suffix
+func normalizeBaseURL(baseURL string) string {
+	baseURL = strings.TrimRight(strings.TrimSpace(baseURL), "/")
+	baseURL = strings.TrimSuffix(baseURL, apiPrefix)
+	return strings.TrimRight(baseURL, "/")
+}
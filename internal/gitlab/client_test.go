@@ -0,0 +1,193 @@
+// internal/gitlab/client_test.go
+package gitlab
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testOptions retries quickly so the tests do not wait on real backoff delays
+func testOptions(maxRetries int) Options {
+	return Options{
+		Timeout:    5 * time.Second,
+		MaxRetries: maxRetries,
+		MinBackoff: time.Millisecond,
+		MaxBackoff: 5 * time.Millisecond,
+	}
+}
+
+// sequenceServer answers the nth request with statuses[n], repeating the last one, and counts the requests
+func sequenceServer(t *testing.T, statuses []int, header http.Header) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1)) - 1
+		if n >= len(statuses) {
+			n = len(statuses) - 1
+		}
+		for key, values := range header {
+			w.Header()[key] = values
+		}
+		w.WriteHeader(statuses[n])
+		fmt.Fprintf(w, `{"attempt":%d}`, n+1)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestRequestRetries(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		statuses   []int
+		maxRetries int
+		wantStatus int // Status of the last response, an *APIError from 400 on
+		wantCalls  int32
+	}{
+		{"success", http.MethodGet, []int{200}, 4, 200, 1},
+		{"5xx retried", http.MethodGet, []int{500, 503, 200}, 4, 200, 3},
+		{"429 retried", http.MethodGet, []int{429, 200}, 4, 200, 2},
+		{"retries exhausted", http.MethodGet, []int{502}, 2, 502, 3},
+		{"no retries", http.MethodGet, []int{500, 200}, 0, 500, 1},
+		{"404 not retried", http.MethodGet, []int{404, 200}, 4, 404, 1},
+		{"POST 5xx not retried", http.MethodPost, []int{500, 200}, 4, 500, 1},
+		{"POST 429 retried", http.MethodPost, []int{429, 201}, 4, 201, 2},
+		{"PUT 5xx retried", http.MethodPut, []int{500, 200}, 4, 200, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, calls := sequenceServer(t, tt.statuses, http.Header{"Retry-After": {"0"}})
+			client := NewClientWithOptions(srv.URL, "token", testOptions(tt.maxRetries))
+
+			var out struct {
+				Attempt int `json:"attempt"`
+			}
+			err := client.SendJSON(tt.method, "/projects/1", nil, &out)
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("requests = %d, want %d", got, tt.wantCalls)
+			}
+			if tt.wantStatus >= 400 {
+				var apiErr *APIError
+				if !errors.As(err, &apiErr) {
+					t.Fatalf("error = %v, want an *APIError", err)
+				}
+				if apiErr.StatusCode != tt.wantStatus || apiErr.Method != tt.method {
+					t.Errorf("APIError = %s %d, want %s %d", apiErr.Method, apiErr.StatusCode, tt.method, tt.wantStatus)
+				}
+				if apiErr.URL != srv.URL+"/api/v4/projects/1" {
+					t.Errorf("APIError.URL = %s", apiErr.URL)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out.Attempt != int(tt.wantCalls) {
+				t.Errorf("decoded the response of attempt %d, want %d", out.Attempt, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestDoReturnsLastResponse(t *testing.T) {
+	srv, calls := sequenceServer(t, []int{503}, nil)
+	client := NewClientWithOptions(srv.URL, "token", testOptions(1))
+
+	resp, err := client.Do(http.MethodGet, "/version", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || calls.Load() != 2 {
+		t.Errorf("Do = %d after %d requests, want 503 after 2", resp.StatusCode, calls.Load())
+	}
+}
+
+func TestAPIErrorSentinels(t *testing.T) {
+	tests := []struct {
+		status       int
+		notFound     bool
+		unauthorized bool
+		rateLimited  bool
+		retryAfter   time.Duration
+	}{
+		{status: 400},
+		{status: 401, unauthorized: true},
+		{status: 403, unauthorized: true},
+		{status: 404, notFound: true},
+		{status: 429, rateLimited: true, retryAfter: 7 * time.Second},
+		{status: 500},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			srv, _ := sequenceServer(t, []int{tt.status}, http.Header{"Retry-After": {"7"}})
+			client := NewClientWithOptions(srv.URL, "token", testOptions(0))
+
+			_, err := client.GetRawFile(1, "go.mod", "main")
+			// Callers wrap the error; the sentinels still match through the wrapping
+			err = fmt.Errorf("failed to read go.mod: %w", err)
+
+			if got := IsNotFound(err); got != tt.notFound {
+				t.Errorf("IsNotFound = %t, want %t", got, tt.notFound)
+			}
+			if got := IsUnauthorized(err); got != tt.unauthorized {
+				t.Errorf("IsUnauthorized = %t, want %t", got, tt.unauthorized)
+			}
+			if got := IsRateLimited(err); got != tt.rateLimited {
+				t.Errorf("IsRateLimited = %t, want %t", got, tt.rateLimited)
+			}
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("error = %v, want an *APIError", err)
+			}
+			if apiErr.StatusCode != tt.status || apiErr.RetryAfter != tt.retryAfter {
+				t.Errorf("APIError = %d with RetryAfter %s, want %d with %s", apiErr.StatusCode, apiErr.RetryAfter, tt.status, tt.retryAfter)
+			}
+		})
+	}
+}
+
+func TestRequestHeaders(t *testing.T) {
+	var token, contentType string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, contentType = r.Header.Get("PRIVATE-TOKEN"), r.Header.Get("Content-Type")
+		w.Write([]byte("{}"))
+	}))
+	defer srv.Close()
+
+	// A base URL with the API prefix and a trailing slash is normalized
+	client := NewClientWithOptions(srv.URL+"/api/v4/", "first", testOptions(0)).WithToken("second")
+	if err := client.SendJSON(http.MethodPost, "/projects/1/merge_requests", map[string]string{"title": "x"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if token != "second" || contentType != "application/json" {
+		t.Errorf("PRIVATE-TOKEN = %q, Content-Type = %q", token, contentType)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	client := NewClientWithOptions("https://gitlab.example.com", "", Options{MinBackoff: 100 * time.Millisecond, MaxBackoff: time.Second})
+	tests := []struct {
+		attempt int
+		min     time.Duration
+		max     time.Duration
+	}{
+		{0, 50 * time.Millisecond, 100 * time.Millisecond},
+		{1, 100 * time.Millisecond, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond, 800 * time.Millisecond},
+		{4, 500 * time.Millisecond, time.Second}, // Capped at MaxBackoff
+		{70, 500 * time.Millisecond, time.Second},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			if d := client.backoff(tt.attempt); d < tt.min || d > tt.max {
+				t.Errorf("backoff(%d) = %s, want between %s and %s", tt.attempt, d, tt.min, tt.max)
+			}
+		}
+	}
+}
@@ -0,0 +1,22 @@
+// internal/gitlab/counter.go
+package gitlab
+
+import "sync/atomic"
+
+// CallCounter counts the HTTP requests sent by the clients it is attached to, retries included
+type CallCounter struct {
+	calls atomic.Int64
+}
+
+// Calls returns the number of requests counted so far
+func (c *CallCounter) Calls() int64 {
+	return c.calls.Load()
+}
+
+// WithCallCounter returns a copy of the client that also counts its requests in counter.
+// Counters already attached to the client keep counting.
+func (c *Client) WithCallCounter(counter *CallCounter) *Client {
+	clone := *c
+	clone.counters = append(append([]*CallCounter(nil), c.counters...), counter)
+	return &clone
+}
@@ -0,0 +1,59 @@
+// internal/gitlab/errors.go
+package gitlab
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Sentinel errors callers can branch on with errors.Is
+var (
+	ErrNotFound     = errors.New("gitlab: not found")
+	ErrUnauthorized = errors.New("gitlab: unauthorized")
+	ErrRateLimited  = errors.New("gitlab: rate limited")
+)
+
+// APIError is returned for any 4xx/5xx response that is not retried successfully
+type APIError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Status     string
+	Body       string
+	RetryAfter time.Duration // only set for 429 responses that carried Retry-After
+}
+
+// Error implements the error interface
+func (e *APIError) Error() string {
+	return fmt.Sprintf("GitLab API error: %s %s: %s\nResponse: %s", e.Method, e.URL, e.Status, e.Body)
+}
+
+// Is lets errors.Is match the sentinel errors by status code
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
+
+// IsNotFound reports whether err is a 404 from the GitLab API
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// IsUnauthorized reports whether err is a 401/403 from the GitLab API
+func IsUnauthorized(err error) bool {
+	return errors.Is(err, ErrUnauthorized)
+}
+
+// IsRateLimited reports whether err is a 429 from the GitLab API
+func IsRateLimited(err error) bool {
+	return errors.Is(err, ErrRateLimited)
+}
@@ -0,0 +1,98 @@
+// internal/gitlab/ratelimit.go
+package gitlab
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// rateLimiter tracks GitLab's RateLimit-* headers and pauses requests before the limit is hit.
+// It is shared by all clients derived from the same NewClient call.
+type rateLimiter struct {
+	mu           sync.Mutex
+	threshold    int
+	blockedUntil time.Time
+}
+
+func newRateLimiter(threshold int) *rateLimiter {
+	return &rateLimiter{threshold: threshold}
+}
+
+// wait blocks until requests are allowed again or ctx is done
+func (l *rateLimiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	until := l.blockedUntil
+	l.mu.Unlock()
+
+	delay := time.Until(until)
+	if delay <= 0 {
+		return nil
+	}
+	return sleep(ctx, delay)
+}
+
+// observe records the rate limit state reported by a response
+func (l *rateLimiter) observe(resp *http.Response) {
+	if resp.StatusCode == http.StatusTooManyRequests {
+		l.blockFor(retryAfter(resp.Header, time.Second))
+		return
+	}
+
+	remaining, err := strconv.Atoi(resp.Header.Get("RateLimit-Remaining"))
+	if err != nil || remaining > l.threshold {
+		return
+	}
+
+	// Close to the limit: hold further requests until the window resets
+	reset, err := strconv.ParseInt(resp.Header.Get("RateLimit-Reset"), 10, 64)
+	if err != nil {
+		l.blockFor(time.Second)
+		return
+	}
+	l.blockUntil(time.Unix(reset, 0))
+}
+
+func (l *rateLimiter) blockFor(d time.Duration) {
+	l.blockUntil(time.Now().Add(d))
+}
+
+func (l *rateLimiter) blockUntil(t time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if t.After(l.blockedUntil) {
+		l.blockedUntil = t
+	}
+}
+
+// retryAfter parses the Retry-After header (seconds or HTTP date), returning def when absent
+func retryAfter(h http.Header, def time.Duration) time.Duration {
+	v := h.Get("Retry-After")
+	if v == "" {
+		return def
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+		return 0
+	}
+	return def
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
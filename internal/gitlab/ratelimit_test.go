@@ -0,0 +1,149 @@
+// internal/gitlab/ratelimit_test.go
+package gitlab
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRetryAfter(t *testing.T) {
+	const def = 3 * time.Second
+	tests := []struct {
+		name  string
+		value string
+		min   time.Duration
+		max   time.Duration
+	}{
+		{"absent", "", def, def},
+		{"seconds", "12", 12 * time.Second, 12 * time.Second},
+		{"zero", "0", 0, 0},
+		{"HTTP date", time.Now().Add(time.Minute).UTC().Format(http.TimeFormat), 58 * time.Second, time.Minute},
+		{"HTTP date in the past", time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat), 0, 0},
+		{"garbage", "soon", def, def},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.value != "" {
+				header.Set("Retry-After", tt.value)
+			}
+			if got := retryAfter(header, def); got < tt.min || got > tt.max {
+				t.Errorf("retryAfter(%q) = %s, want between %s and %s", tt.value, got, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestRateLimiterObserve(t *testing.T) {
+	now := time.Now()
+	reset := now.Add(time.Hour).Truncate(time.Second)
+	tests := []struct {
+		name   string
+		status int
+		header map[string]string
+		want   time.Duration // How long requests are held from now; 0 when not at all
+	}{
+		{
+			name:   "plenty remaining",
+			status: 200,
+			header: map[string]string{"RateLimit-Remaining": "100", "RateLimit-Reset": strconv.FormatInt(reset.Unix(), 10)},
+		},
+		{
+			name:   "no rate limit headers",
+			status: 200,
+		},
+		{
+			name:   "at the threshold until the reset",
+			status: 200,
+			header: map[string]string{"RateLimit-Remaining": "5", "RateLimit-Reset": strconv.FormatInt(reset.Unix(), 10)},
+			want:   reset.Sub(now),
+		},
+		{
+			name:   "below the threshold without a reset",
+			status: 200,
+			header: map[string]string{"RateLimit-Remaining": "0"},
+			want:   time.Second,
+		},
+		{
+			name:   "429 with Retry-After",
+			status: 429,
+			header: map[string]string{"Retry-After": "30"},
+			want:   30 * time.Second,
+		},
+		{
+			name:   "429 without Retry-After",
+			status: 429,
+			want:   time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+			for key, value := range tt.header {
+				resp.Header.Set(key, value)
+			}
+			limiter := newRateLimiter(5)
+			limiter.observe(resp)
+
+			if tt.want == 0 {
+				if !limiter.blockedUntil.IsZero() {
+					t.Errorf("blocked until %s, want not blocked", limiter.blockedUntil)
+				}
+				return
+			}
+			if got := limiter.blockedUntil.Sub(now); got < tt.want || got > tt.want+time.Second {
+				t.Errorf("blocked for %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimiterKeepsLongestBlock(t *testing.T) {
+	limiter := newRateLimiter(5)
+	limiter.blockFor(time.Hour)
+	limiter.blockFor(time.Second)
+	if got := time.Until(limiter.blockedUntil); got < 59*time.Minute {
+		t.Errorf("blocked for %s, want the longer hour kept", got)
+	}
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	limiter := newRateLimiter(5)
+	if err := limiter.wait(context.Background()); err != nil {
+		t.Fatalf("wait when not blocked = %v", err)
+	}
+
+	limiter.blockFor(time.Hour)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := limiter.wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("wait while blocked = %v, want the context deadline", err)
+	}
+}
+
+// A response close to the limit holds the next request of every client sharing the limiter
+func TestRateLimitResetHoldsRequests(t *testing.T) {
+	reset := time.Now().Add(time.Hour)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("RateLimit-Remaining", "2")
+		w.Header().Set("RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+		w.Write([]byte("{}"))
+	}))
+	defer srv.Close()
+
+	client := NewClientWithOptions(srv.URL, "token", Options{Timeout: 5 * time.Second, RateLimitThreshold: 5})
+	if err := client.GetJSON("/version", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := client.WithToken("other").GetContext(ctx, "/version"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("request after the limit = %v, want it held until the context deadline", err)
+	}
+}
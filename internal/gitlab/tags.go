@@ -0,0 +1,68 @@
+// internal/gitlab/tags.go
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Tag represents a repository tag
+type Tag struct {
+	Name   string `json:"name"`
+	Commit struct {
+		ID            string    `json:"id"`
+		CommittedDate time.Time `json:"committed_date"`
+	} `json:"commit"`
+}
+
+// ListTags lists all tags of a project, following pagination
+func (c *Client) ListTags(projectID int) ([]Tag, error) {
+	return c.ListTagsContext(context.Background(), projectID)
+}
+
+// ListTagsContext is ListTags with a context
+func (c *Client) ListTagsContext(ctx context.Context, projectID int) ([]Tag, error) {
+	var out []Tag
+	page := 1
+
+	for {
+		path := fmt.Sprintf("/projects/%d/repository/tags?per_page=100&page=%d", projectID, page)
+
+		resp, err := c.GetContext(ctx, path)
+		if err != nil {
+			return out, err
+		}
+
+		var batch []Tag
+		err = json.NewDecoder(resp.Body).Decode(&batch)
+		resp.Body.Close()
+		if err != nil {
+			return out, fmt.Errorf("failed to decode repository tags: %w", err)
+		}
+		out = append(out, batch...)
+
+		// GitLab pagination via X-Next-Page header
+		next, _ := strconv.Atoi(resp.Header.Get("X-Next-Page"))
+		if next <= page {
+			break
+		}
+		page = next
+	}
+
+	return out, nil
+}
+
+// GetProjectID resolves a project path with namespace (e.g. "group/sub/project") to its ID
+func (c *Client) GetProjectID(projectPath string) (int, error) {
+	var payload struct {
+		ID int `json:"id"`
+	}
+	if err := c.GetJSON("/projects/"+url.PathEscape(projectPath), &payload); err != nil {
+		return 0, err
+	}
+	return payload.ID, nil
+}
@@ -0,0 +1,61 @@
+// internal/gitlab/tree.go
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// TreeEntry represents an entry from the GitLab repository tree API
+type TreeEntry struct {
+	ID   string `json:"id,omitempty"`
+	Path string `json:"path"`
+	Type string `json:"type"` // "blob" (file) or "tree" (directory)
+	Name string `json:"name,omitempty"`
+	Mode string `json:"mode,omitempty"`
+}
+
+// ListTree lists all files and directories of a repository recursively, following pagination.
+// If ref is empty, GitLab uses the default branch.
+func (c *Client) ListTree(projectID int, ref string) ([]TreeEntry, error) {
+	return c.ListTreeContext(context.Background(), projectID, ref)
+}
+
+// ListTreeContext is ListTree with a context
+func (c *Client) ListTreeContext(ctx context.Context, projectID int, ref string) ([]TreeEntry, error) {
+	var out []TreeEntry
+	page := 1
+
+	for {
+		path := fmt.Sprintf("/projects/%d/repository/tree?recursive=true&per_page=100&page=%d", projectID, page)
+		if strings.TrimSpace(ref) != "" {
+			path += "&ref=" + url.QueryEscape(ref)
+		}
+
+		resp, err := c.GetContext(ctx, path)
+		if err != nil {
+			return out, err
+		}
+
+		var batch []TreeEntry
+		err = json.NewDecoder(resp.Body).Decode(&batch)
+		resp.Body.Close()
+		if err != nil {
+			return out, fmt.Errorf("failed to decode repository tree: %w", err)
+		}
+		out = append(out, batch...)
+
+		// GitLab pagination via X-Next-Page header
+		next, _ := strconv.Atoi(resp.Header.Get("X-Next-Page"))
+		if next <= page {
+			break
+		}
+		page = next
+	}
+
+	return out, nil
+}
@@ -0,0 +1,103 @@
+// internal/gomod/discover.go
+package gomod
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// RootDir is the module directory of a go.mod at the repository root
+const RootDir = "."
+
+// ModuleDirs returns the directories of every go.mod in a repository tree listing, root first.
+// Directories the go command ignores (vendor, testdata, and names starting with "." or "_") are skipped.
+func ModuleDirs(paths []string) []string {
+	var dirs []string
+	for _, p := range paths {
+		if path.Base(p) != "go.mod" {
+			continue
+		}
+		dir := path.Dir(p)
+		if ignoredDir(dir) {
+			continue
+		}
+		dirs = append(dirs, dir)
+	}
+
+	sort.Slice(dirs, func(i, j int) bool {
+		if dirs[i] == RootDir || dirs[j] == RootDir {
+			return dirs[i] == RootDir && dirs[j] != RootDir
+		}
+		return dirs[i] < dirs[j]
+	})
+	return dirs
+}
+
+// GoModPath returns the repository path of the go.mod in dir
+func GoModPath(dir string) string {
+	return path.Join(CleanDir(dir), "go.mod")
+}
+
+// CleanDir normalises a module directory relative to the repository root ("" and "./x" become "." and "x")
+func CleanDir(dir string) string {
+	dir = path.Clean("/" + strings.TrimSpace(dir))
+	dir = strings.TrimPrefix(dir, "/")
+	if dir == "" {
+		return RootDir
+	}
+	return dir
+}
+
+// ModulePath returns the module path declared in a go.mod, or "" when there is none
+func ModulePath(data []byte) string {
+	return modfile.ModulePath(data)
+}
+
+// Workspace is the parsed content of a go.work file
+type Workspace struct {
+	GoVersion string
+	Use       []string // Module directories relative to the repository root
+}
+
+// ParseWork parses a go.work file located in workDir (relative to the repository root)
+// and resolves its use directives to repository-relative directories
+func ParseWork(workDir string, data []byte) (*Workspace, error) {
+	f, err := modfile.ParseWork(path.Join(workDir, "go.work"), data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse go.work: %w", err)
+	}
+
+	ws := &Workspace{}
+	if f.Go != nil {
+		ws.GoVersion = f.Go.Version
+	}
+	for _, use := range f.Use {
+		// Absolute paths point outside the repository and cannot be resolved
+		if path.IsAbs(use.Path) {
+			continue
+		}
+		dir := path.Join(workDir, use.Path)
+		if strings.HasPrefix(dir, "..") {
+			continue
+		}
+		ws.Use = append(ws.Use, CleanDir(dir))
+	}
+	return ws, nil
+}
+
+// ignoredDir reports whether the go command would skip modules under dir
+func ignoredDir(dir string) bool {
+	if dir == RootDir {
+		return false
+	}
+	for _, elem := range strings.Split(dir, "/") {
+		if elem == "vendor" || elem == "testdata" || strings.HasPrefix(elem, ".") || strings.HasPrefix(elem, "_") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+// internal/gomod/discover_test.go
+package gomod
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseWork(t *testing.T) {
+	tests := []struct {
+		name    string
+		workDir string
+		data    string
+		want    Workspace
+	}{
+		{
+			name:    "root workspace",
+			workDir: RootDir,
+			data: `go 1.22
+
+use (
+	.
+	./api
+	tools/lint
+)
+`,
+			want: Workspace{GoVersion: "1.22", Use: []string{RootDir, "api", "tools/lint"}},
+		},
+		{
+			name:    "workspace in a subdirectory",
+			workDir: "services",
+			data:    "go 1.21\n\nuse ./billing\nuse ../shared\nuse .\n",
+			want:    Workspace{GoVersion: "1.21", Use: []string{"services/billing", "shared", "services"}},
+		},
+		{
+			name:    "paths outside the repository are dropped",
+			workDir: "services",
+			data:    "go 1.21\n\nuse (\n\t../../elsewhere\n\t/abs/module\n\t./kept\n)\n",
+			want:    Workspace{GoVersion: "1.21", Use: []string{"services/kept"}},
+		},
+		{
+			name:    "empty directory means the root",
+			workDir: "",
+			data:    "use ./cmd/\n",
+			want:    Workspace{Use: []string{"cmd"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseWork(tt.workDir, []byte(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("ParseWork = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestModuleDirs(t *testing.T) {
+	paths := []string{
+		"tools/go.mod",
+		"README.md",
+		"go.mod",
+		"api/go.mod",
+		"api/go.sum",
+		"vendor/example.com/lib/go.mod",
+		"internal/testdata/fixture/go.mod",
+		".github/go.mod",
+		"_examples/go.mod",
+		"api/v2/go.mod",
+	}
+	want := []string{RootDir, "api", "api/v2", "tools"}
+	if got := ModuleDirs(paths); !reflect.DeepEqual(got, want) {
+		t.Errorf("ModuleDirs = %v, want %v", got, want)
+	}
+}
+
+func TestCleanDir(t *testing.T) {
+	tests := []struct {
+		dir  string
+		want string
+	}{
+		{"", RootDir},
+		{".", RootDir},
+		{"/", RootDir},
+		{"./api", "api"},
+		{" api/ ", "api"},
+		{"api/../tools", "tools"},
+		{"../outside", "outside"},
+	}
+	for _, tt := range tests {
+		if got := CleanDir(tt.dir); got != tt.want {
+			t.Errorf("CleanDir(%q) = %q, want %q", tt.dir, got, tt.want)
+		}
+		if got, want := GoModPath(tt.dir), tt.want+"/go.mod"; tt.want != RootDir && got != want {
+			t.Errorf("GoModPath(%q) = %q, want %q", tt.dir, got, want)
+		}
+	}
+	if got := GoModPath(""); got != "go.mod" {
+		t.Errorf("GoModPath(\"\") = %q, want go.mod", got)
+	}
+}
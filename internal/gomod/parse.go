@@ -0,0 +1,109 @@
+// internal/gomod/parse.go
+package gomod
+
+import (
+	"fmt"
+
+	"golang.org/x/mod/modfile"
+)
+
+// File is the parsed content of a go.mod file
+type File struct {
+	Module    string
+	GoVersion string
+	Toolchain string
+	Requires  []Require
+	Replaces  []Replace
+	Excludes  []Version
+	Retracts  []Retract
+}
+
+// Require is a single require directive
+type Require struct {
+	Path     string
+	Version  string
+	Indirect bool // Marked with an "// indirect" comment
+}
+
+// Replace is a single replace directive. OldVersion is empty when every version is replaced,
+// NewVersion is empty when the replacement is a local directory.
+type Replace struct {
+	OldPath    string
+	OldVersion string
+	NewPath    string
+	NewVersion string
+}
+
+// Local reports whether the replacement is a directory rather than a module
+func (r Replace) Local() bool {
+	return r.NewVersion == ""
+}
+
+// Version is a module path and version, as used by exclude directives
+type Version struct {
+	Path    string
+	Version string
+}
+
+// Retract is a single retract directive; Low equals High for a single version
+type Retract struct {
+	Low       string
+	High      string
+	Rationale string
+}
+
+// Parse parses a go.mod file. filePath is only used in error messages.
+func Parse(filePath string, data []byte) (*File, error) {
+	f, err := modfile.Parse(filePath, data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filePath, err)
+	}
+
+	file := &File{}
+	if f.Module != nil {
+		file.Module = f.Module.Mod.Path
+	}
+	if f.Go != nil {
+		file.GoVersion = f.Go.Version
+	}
+	if f.Toolchain != nil {
+		file.Toolchain = f.Toolchain.Name
+	}
+	for _, r := range f.Require {
+		file.Requires = append(file.Requires, Require{Path: r.Mod.Path, Version: r.Mod.Version, Indirect: r.Indirect})
+	}
+	for _, r := range f.Replace {
+		file.Replaces = append(file.Replaces, Replace{
+			OldPath:    r.Old.Path,
+			OldVersion: r.Old.Version,
+			NewPath:    r.New.Path,
+			NewVersion: r.New.Version,
+		})
+	}
+	for _, e := range f.Exclude {
+		file.Excludes = append(file.Excludes, Version{Path: e.Mod.Path, Version: e.Mod.Version})
+	}
+	for _, r := range f.Retract {
+		file.Retracts = append(file.Retracts, Retract{Low: r.Low, High: r.High, Rationale: r.Rationale})
+	}
+	return file, nil
+}
+
+// Replacement returns the replace directive that applies to path at version, or nil.
+// A directive for the exact version takes precedence over one for all versions.
+func (f *File) Replacement(path, version string) *Replace {
+	var wildcard *Replace
+	for i := range f.Replaces {
+		r := &f.Replaces[i]
+		if r.OldPath != path {
+			continue
+		}
+		if r.OldVersion == version {
+			return r
+		}
+		if r.OldVersion == "" {
+			wildcard = r
+		}
+	}
+	return wildcard
+}
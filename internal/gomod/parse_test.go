@@ -0,0 +1,182 @@
+// internal/gomod/parse_test.go
+package gomod
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want File
+	}{
+		{
+			name: "module and go only",
+			data: "module example.com/app\n\ngo 1.21\n",
+			want: File{Module: "example.com/app", GoVersion: "1.21"},
+		},
+		{
+			name: "single and block requires",
+			data: `module example.com/app
+
+go 1.22.1
+
+toolchain go1.22.3
+
+require example.com/single v1.0.0
+
+require (
+	example.com/direct v1.2.3
+	example.com/indirect v0.4.0 // indirect
+	example.com/pseudo v0.0.0-20240101000000-abcdefabcdef // indirect
+)
+`,
+			want: File{
+				Module:    "example.com/app",
+				GoVersion: "1.22.1",
+				Toolchain: "go1.22.3",
+				Requires: []Require{
+					{Path: "example.com/single", Version: "v1.0.0"},
+					{Path: "example.com/direct", Version: "v1.2.3"},
+					{Path: "example.com/indirect", Version: "v0.4.0", Indirect: true},
+					{Path: "example.com/pseudo", Version: "v0.0.0-20240101000000-abcdefabcdef", Indirect: true},
+				},
+			},
+		},
+		{
+			name: "other comments are not indirect",
+			data: "module example.com/app\n\nrequire example.com/lib v1.0.0 // pinned for the release\n",
+			want: File{
+				Module:   "example.com/app",
+				Requires: []Require{{Path: "example.com/lib", Version: "v1.0.0"}},
+			},
+		},
+		{
+			name: "versioned and local replaces",
+			data: `module example.com/app
+
+require (
+	example.com/fork v1.0.0
+	example.com/local v1.0.0
+)
+
+replace example.com/fork v1.0.0 => example.com/myfork v1.0.1
+
+replace (
+	example.com/fork => example.com/otherfork v2.0.0
+	example.com/local => ../local
+	example.com/sibling v1.2.0 => ./sibling
+)
+`,
+			want: File{
+				Module: "example.com/app",
+				Requires: []Require{
+					{Path: "example.com/fork", Version: "v1.0.0"},
+					{Path: "example.com/local", Version: "v1.0.0"},
+				},
+				Replaces: []Replace{
+					{OldPath: "example.com/fork", OldVersion: "v1.0.0", NewPath: "example.com/myfork", NewVersion: "v1.0.1"},
+					{OldPath: "example.com/fork", NewPath: "example.com/otherfork", NewVersion: "v2.0.0"},
+					{OldPath: "example.com/local", NewPath: "../local"},
+					{OldPath: "example.com/sibling", OldVersion: "v1.2.0", NewPath: "./sibling"},
+				},
+			},
+		},
+		{
+			name: "exclude and retract",
+			data: `module example.com/lib
+
+go 1.21
+
+exclude example.com/bad v1.1.0
+
+exclude (
+	example.com/bad v1.2.0
+	example.com/worse v0.3.0
+)
+
+retract v1.0.1 // Published by mistake
+
+retract (
+	[v1.3.0, v1.3.5] // Data race in the cache
+	v1.4.0
+)
+`,
+			want: File{
+				Module:    "example.com/lib",
+				GoVersion: "1.21",
+				Excludes: []Version{
+					{Path: "example.com/bad", Version: "v1.1.0"},
+					{Path: "example.com/bad", Version: "v1.2.0"},
+					{Path: "example.com/worse", Version: "v0.3.0"},
+				},
+				Retracts: []Retract{
+					{Low: "v1.0.1", High: "v1.0.1", Rationale: "Published by mistake"},
+					{Low: "v1.3.0", High: "v1.3.5", Rationale: "Data race in the cache"},
+					{Low: "v1.4.0", High: "v1.4.0"},
+				},
+			},
+		},
+		{
+			name: "no module directive",
+			data: "go 1.20\n",
+			want: File{GoVersion: "1.20"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse("go.mod", []byte(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("Parse =\n%+v\nwant\n%+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseError(t *testing.T) {
+	if _, err := Parse("sub/go.mod", []byte("module example.com/app\n\nrequire example.com/lib\n")); err == nil {
+		t.Fatal("Parse of a require without version succeeded")
+	}
+}
+
+func TestReplacement(t *testing.T) {
+	file, err := Parse("go.mod", []byte(`module example.com/app
+
+replace (
+	example.com/lib => example.com/fork v1.5.0
+	example.com/lib v1.2.0 => ../lib
+)
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path    string
+		version string
+		newPath string // "" when nothing is replaced
+		local   bool
+	}{
+		{"example.com/lib", "v1.2.0", "../lib", true},
+		{"example.com/lib", "v1.3.0", "example.com/fork", false},
+		{"example.com/lib", "", "example.com/fork", false},
+		{"example.com/other", "v1.2.0", "", false},
+	}
+	for _, tt := range tests {
+		r := file.Replacement(tt.path, tt.version)
+		if tt.newPath == "" {
+			if r != nil {
+				t.Errorf("Replacement(%s, %s) = %+v, want nil", tt.path, tt.version, *r)
+			}
+			continue
+		}
+		if r == nil || r.NewPath != tt.newPath || r.Local() != tt.local {
+			t.Errorf("Replacement(%s, %s) = %+v, want %s (local %t)", tt.path, tt.version, r, tt.newPath, tt.local)
+		}
+	}
+}
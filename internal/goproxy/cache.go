@@ -0,0 +1,58 @@
+// internal/goproxy/cache.go
+package goproxy
+
+import (
+	"sync"
+	"time"
+)
+
+// cache is a concurrency-safe map whose entries expire after a fixed TTL
+type cache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	value   interface{}
+	err     error
+	expires time.Time
+}
+
+func newCache(ttl time.Duration) *cache {
+	return &cache{ttl: ttl, entries: make(map[string]cacheEntry)}
+}
+
+// get returns the cached value and error for key, if present and not expired
+func (c *cache) get(key string) (interface{}, error, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, nil, false
+	}
+	if time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		return nil, nil, false
+	}
+	return entry.value, entry.err, true
+}
+
+// set stores a value, or an error that should not be retried before the TTL expires
+func (c *cache) set(key string, value interface{}, err error) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = cacheEntry{value: value, err: err, expires: time.Now().Add(c.ttl)}
+}
+
+// clear drops every entry
+func (c *cache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]cacheEntry)
+}
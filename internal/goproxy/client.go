@@ -0,0 +1,319 @@
+// internal/goproxy/client.go
+package goproxy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gitlab-list/internal/configuration"
+	"gitlab-list/internal/gitlab"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// Sentinel errors callers can branch on with errors.Is
+var (
+	ErrNotFound = errors.New("goproxy: module or version not found")
+	ErrDisabled = errors.New("goproxy: module lookups disabled by GOPROXY=off")
+)
+
+// errDirect is returned when the proxy list reaches "direct"; only the GitLab fallback can serve it
+var errDirect = errors.New("goproxy: direct fetch required")
+
+// SourceGitLab is the Info.Source of versions read from GitLab tags
+const SourceGitLab = "gitlab"
+
+// Info describes a single module version
+type Info struct {
+	Version string    `json:"version"`
+	Time    time.Time `json:"time,omitempty"`
+	Source  string    `json:"source,omitempty"` // Proxy URL or "gitlab"
+}
+
+// Client implements the read side of the GOPROXY protocol (/@v/list, /@latest, /@v/<ver>.info, /@v/<ver>.mod)
+// for http(s) and file:// proxies. Private modules hosted on the GitLab instance fall back to its tags.
+type Client struct {
+	settings   Settings
+	proxies    []proxyEntry
+	httpClient *http.Client
+	gitlab     *gitlab.Client
+	cache      *cache
+}
+
+// NewClient creates a proxy client. gitlabClient may be nil to disable the GitLab tag fallback.
+func NewClient(settings Settings, gitlabClient *gitlab.Client) *Client {
+	return &Client{
+		settings:   settings,
+		proxies:    parseProxyList(settings.Proxy),
+		httpClient: &http.Client{Timeout: settings.Timeout},
+		gitlab:     gitlabClient,
+		cache:      newCache(settings.CacheTTL),
+	}
+}
+
+// NewClientFromConfig creates a proxy client from the configured Go environment that reads private modules through gitlabClient
+func NewClientFromConfig(cfg *configuration.Configuration, gitlabClient *gitlab.Client) *Client {
+	return NewClient(SettingsFromConfig(cfg), gitlabClient)
+}
+
+// ClearCache drops every cached lookup
+func (c *Client) ClearCache() {
+	c.cache.clear()
+}
+
+// Versions returns the released and pre-release versions of a module in semver order
+func (c *Client) Versions(modPath string) ([]string, error) {
+	key := "list:" + modPath
+	if v, err, ok := c.cache.get(key); ok {
+		versions, _ := v.([]string)
+		return versions, err
+	}
+
+	versions, err := c.versions(modPath)
+	c.cacheResult(key, versions, err)
+	return versions, err
+}
+
+func (c *Client) versions(modPath string) ([]string, error) {
+	data, err := c.lookup(modPath, "@v/list")
+	if err == nil {
+		var versions []string
+		for _, line := range strings.Split(string(data), "\n") {
+			v := strings.TrimSpace(line)
+			if semver.IsValid(v) && !module.IsPseudoVersion(v) {
+				versions = append(versions, v)
+			}
+		}
+		semver.Sort(versions)
+		return versions, nil
+	}
+	if !c.useGitLab(modPath, err) {
+		return nil, err
+	}
+
+	tags, err := c.gitlabTags(modPath)
+	if err != nil {
+		return nil, err
+	}
+	return tags.list(), nil
+}
+
+// Latest returns the version the go command would pick for "@latest"
+func (c *Client) Latest(modPath string) (*Info, error) {
+	key := "latest:" + modPath
+	if v, err, ok := c.cache.get(key); ok {
+		info, _ := v.(*Info)
+		return info, err
+	}
+
+	info, err := c.latest(modPath)
+	c.cacheResult(key, info, err)
+	return info, err
+}
+
+func (c *Client) latest(modPath string) (*Info, error) {
+	// Like the go command, prefer the highest listed version and only ask @latest
+	// (which may answer with a pseudo-version) when nothing is tagged
+	versions, err := c.Versions(modPath)
+	if err != nil {
+		return nil, err
+	}
+	if latest := LatestOf(versions); latest != "" {
+		return c.Info(modPath, latest)
+	}
+	return c.info(modPath, "@latest")
+}
+
+// Info returns the metadata of a module version
+func (c *Client) Info(modPath, version string) (*Info, error) {
+	key := "info:" + modPath + "@" + version
+	if v, err, ok := c.cache.get(key); ok {
+		info, _ := v.(*Info)
+		return info, err
+	}
+
+	escaped, err := module.EscapeVersion(version)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version %q: %w", version, err)
+	}
+	info, err := c.info(modPath, "@v/"+escaped+".info")
+	c.cacheResult(key, info, err)
+	return info, err
+}
+
+// info fetches a .info document and falls back to the matching GitLab tag
+func (c *Client) info(modPath, suffix string) (*Info, error) {
+	data, source, err := c.lookupWithSource(modPath, suffix)
+	if err == nil {
+		var info Info
+		if err := json.Unmarshal(data, &info); err != nil {
+			return nil, fmt.Errorf("failed to decode %s/%s: %w", modPath, suffix, err)
+		}
+		info.Source = source
+		return &info, nil
+	}
+	if !c.useGitLab(modPath, err) {
+		return nil, err
+	}
+
+	tags, err := c.gitlabTags(modPath)
+	if err != nil {
+		return nil, err
+	}
+	if suffix == "@latest" {
+		if latest := LatestOf(tags.list()); latest != "" {
+			info := tags.versions[latest]
+			return &info, nil
+		}
+		return nil, fmt.Errorf("%s: no tagged versions: %w", modPath, ErrNotFound)
+	}
+	version, _ := module.UnescapeVersion(strings.TrimSuffix(strings.TrimPrefix(suffix, "@v/"), ".info"))
+	if info, ok := tags.versions[version]; ok {
+		return &info, nil
+	}
+	return nil, fmt.Errorf("%s@%s: %w", modPath, version, ErrNotFound)
+}
+
+// GoMod returns the go.mod file of a module version
+func (c *Client) GoMod(modPath, version string) ([]byte, error) {
+	key := "mod:" + modPath + "@" + version
+	if v, err, ok := c.cache.get(key); ok {
+		data, _ := v.([]byte)
+		return data, err
+	}
+
+	data, err := c.goMod(modPath, version)
+	c.cacheResult(key, data, err)
+	return data, err
+}
+
+func (c *Client) goMod(modPath, version string) ([]byte, error) {
+	escaped, err := module.EscapeVersion(version)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version %q: %w", version, err)
+	}
+	data, err := c.lookup(modPath, "@v/"+escaped+".mod")
+	if err == nil || !c.useGitLab(modPath, err) {
+		return data, err
+	}
+
+	tags, err := c.gitlabTags(modPath)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := tags.versions[version]; !ok {
+		return nil, fmt.Errorf("%s@%s: %w", modPath, version, ErrNotFound)
+	}
+	data, err = c.gitlab.GetRawFile(tags.projectID, tags.goModPath(), tags.tagName(version))
+	if gitlab.IsNotFound(err) {
+		// Modules without a go.mod get a synthesized one, as on the public proxy
+		return []byte(fmt.Sprintf("module %s\n", modPath)), nil
+	}
+	return data, err
+}
+
+// cacheResult caches successes and definitive misses; transient errors are retried on the next call
+func (c *Client) cacheResult(key string, value interface{}, err error) {
+	if err == nil || errors.Is(err, ErrNotFound) {
+		c.cache.set(key, value, err)
+	}
+}
+
+// lookup fetches <proxy>/<escaped module path>/<suffix> from the GOPROXY list
+func (c *Client) lookup(modPath, suffix string) ([]byte, error) {
+	data, _, err := c.lookupWithSource(modPath, suffix)
+	return data, err
+}
+
+func (c *Client) lookupWithSource(modPath, suffix string) ([]byte, string, error) {
+	escaped, err := module.EscapePath(modPath)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid module path %q: %w", modPath, err)
+	}
+	if c.settings.matches(c.settings.NoProxy, modPath) {
+		return nil, "", errDirect
+	}
+
+	lastErr := fmt.Errorf("%s: %w", modPath, ErrNotFound)
+	for _, proxy := range c.proxies {
+		switch proxy.url {
+		case "off":
+			return nil, "", ErrDisabled
+		case "direct":
+			return nil, "", errDirect
+		}
+
+		data, err := c.fetch(proxy.url, escaped+"/"+suffix)
+		if err == nil {
+			return data, proxy.url, nil
+		}
+		lastErr = err
+		if !proxy.fallThrough && !errors.Is(err, ErrNotFound) {
+			break
+		}
+	}
+	return nil, "", lastErr
+}
+
+// fetch reads a single proxy document over http(s) or from a file:// directory
+func (c *Client) fetch(proxyURL, rel string) ([]byte, error) {
+	if strings.HasPrefix(proxyURL, "file://") {
+		u, err := url.Parse(proxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL %q: %w", proxyURL, err)
+		}
+		data, err := os.ReadFile(filepath.Join(filepath.FromSlash(u.Path), filepath.FromSlash(rel)))
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%s/%s: %w", proxyURL, rel, ErrNotFound)
+		}
+		return data, err
+	}
+
+	resp, err := c.httpClient.Get(proxyURL + "/" + rel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s/%s: %w", proxyURL, rel, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s/%s: %w", proxyURL, rel, err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return nil, fmt.Errorf("%s/%s: %w", proxyURL, rel, ErrNotFound)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("proxy error: GET %s/%s: %s", proxyURL, rel, resp.Status)
+	}
+	return data, nil
+}
+
+// LatestOf returns the highest release in versions, or the highest pre-release when there is no release
+func LatestOf(versions []string) string {
+	var latest, latestPre string
+	for _, v := range versions {
+		if !semver.IsValid(v) {
+			continue
+		}
+		if semver.Prerelease(v) == "" {
+			if latest == "" || semver.Compare(v, latest) > 0 {
+				latest = v
+			}
+		} else if latestPre == "" || semver.Compare(v, latestPre) > 0 {
+			latestPre = v
+		}
+	}
+	if latest != "" {
+		return latest
+	}
+	return latestPre
+}
@@ -0,0 +1,355 @@
+// internal/goproxy/client_test.go
+package goproxy
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab-list/internal/gitlab"
+)
+
+// fileProxy writes files (relative to the proxy root) to a directory and returns its file:// URL
+func fileProxy(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return "file://" + filepath.ToSlash(dir)
+}
+
+// statusProxy answers every request with status
+func statusProxy(t *testing.T, status int) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+// fakeGitLab serves the project lookups, tag lists and raw files the tag fallback reads
+type fakeGitLab struct {
+	projects map[string]int    // Project path to ID
+	tags     []string          // Tags of every project
+	files    map[string]string // "<file path>@<ref>" to content
+}
+
+func (f *fakeGitLab) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.EscapedPath(), "/api/v4/projects/"), "/")
+	switch {
+	case len(parts) == 1:
+		path, _ := url.PathUnescape(parts[0])
+		if id, ok := f.projects[path]; ok {
+			json.NewEncoder(w).Encode(map[string]int{"id": id})
+			return
+		}
+	case len(parts) == 3 && parts[2] == "tags":
+		tags := make([]gitlab.Tag, len(f.tags))
+		for i, name := range f.tags {
+			tags[i].Name = name
+			tags[i].Commit.CommittedDate = time.Date(2024, 1, i+1, 0, 0, 0, 0, time.UTC)
+		}
+		json.NewEncoder(w).Encode(tags)
+		return
+	case len(parts) == 5 && parts[2] == "files":
+		path, _ := url.PathUnescape(parts[3])
+		if content, ok := f.files[path+"@"+r.URL.Query().Get("ref")]; ok {
+			w.Write([]byte(content))
+			return
+		}
+	}
+	http.NotFound(w, r)
+}
+
+// gitlabClient starts fake and returns a client for it with the module host of its projects
+func gitlabClient(t *testing.T, fake *fakeGitLab) (*gitlab.Client, string) {
+	t.Helper()
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	client := gitlab.NewClientWithOptions(srv.URL, "token", gitlab.Options{Timeout: 5 * time.Second})
+	return client, strings.Split(client.Host(), ":")[0]
+}
+
+func TestFileProxyLookups(t *testing.T) {
+	proxy := fileProxy(t, map[string]string{
+		"example.com/lib/@v/list":          "v1.0.0\nv1.2.0-rc.1\nv1.1.0\nv0.0.0-20200101000000-abcdefabcdef\n",
+		"example.com/lib/@v/v1.1.0.info":   `{"Version":"v1.1.0","Time":"2024-03-01T00:00:00Z"}`,
+		"example.com/lib/@v/v1.1.0.mod":    "module example.com/lib\n",
+		"example.com/!upper/@v/list":       "v0.1.0\n",
+		"example.com/!upper/@v/v0.1.0.mod": "module example.com/Upper\n",
+		"example.com/pseudo/@v/list":       "",
+		"example.com/pseudo/@latest":       `{"Version":"v0.0.0-20240101000000-abcdefabcdef"}`,
+	})
+	client := NewClient(Settings{Proxy: proxy}, nil)
+
+	versions, err := client.Versions("example.com/lib")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"v1.0.0", "v1.1.0", "v1.2.0-rc.1"}; !reflect.DeepEqual(versions, want) {
+		t.Errorf("Versions = %v, want %v", versions, want)
+	}
+
+	latest, err := client.Latest("example.com/lib")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.Version != "v1.1.0" || latest.Source != proxy || !latest.Time.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Latest = %+v, want v1.1.0 from %s at 2024-03-01", latest, proxy)
+	}
+
+	latest, err = client.Latest("example.com/pseudo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.Version != "v0.0.0-20240101000000-abcdefabcdef" {
+		t.Errorf("Latest without tags = %s, want the @latest pseudo-version", latest.Version)
+	}
+
+	mod, err := client.GoMod("example.com/lib", "v1.1.0")
+	if err != nil || string(mod) != "module example.com/lib\n" {
+		t.Errorf("GoMod = %q, %v", mod, err)
+	}
+
+	// Upper case letters are escaped as "!" and the lower case letter
+	mod, err = client.GoMod("example.com/Upper", "v0.1.0")
+	if err != nil || string(mod) != "module example.com/Upper\n" {
+		t.Errorf("GoMod of an escaped path = %q, %v", mod, err)
+	}
+
+	if _, err := client.Info("example.com/lib", "v9.9.9"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Info of a missing version = %v, want ErrNotFound", err)
+	}
+	if _, err := client.Versions("example.com/missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Versions of a missing module = %v, want ErrNotFound", err)
+	}
+}
+
+func TestProxyList(t *testing.T) {
+	good := fileProxy(t, map[string]string{"example.com/lib/@v/list": "v1.0.0\n"})
+	notFound := statusProxy(t, http.StatusNotFound)
+	gone := statusProxy(t, http.StatusGone)
+	broken := statusProxy(t, http.StatusInternalServerError)
+
+	tests := []struct {
+		name    string
+		proxy   string
+		want    []string
+		wantErr error // Checked with errors.Is when want is nil
+		errText string
+	}{
+		{name: "404 falls through a comma", proxy: notFound + "," + good, want: []string{"v1.0.0"}},
+		{name: "410 falls through a comma", proxy: gone + "," + good, want: []string{"v1.0.0"}},
+		{name: "500 stops at a comma", proxy: broken + "," + good, errText: "500"},
+		{name: "500 falls through a pipe", proxy: broken + "|" + good, want: []string{"v1.0.0"}},
+		{name: "not found anywhere", proxy: notFound + "," + notFound, wantErr: ErrNotFound},
+		{name: "off", proxy: "off", wantErr: ErrDisabled},
+		{name: "off after a miss", proxy: notFound + ",off", wantErr: ErrDisabled},
+		{name: "direct", proxy: "direct", wantErr: errDirect},
+		{name: "trailing slash", proxy: good + "/", want: []string{"v1.0.0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			versions, err := NewClient(Settings{Proxy: tt.proxy}, nil).Versions("example.com/lib")
+			switch {
+			case tt.want != nil:
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(versions, tt.want) {
+					t.Errorf("Versions = %v, want %v", versions, tt.want)
+				}
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Versions error = %v, want %v", err, tt.wantErr)
+				}
+			default:
+				if err == nil || errors.Is(err, ErrNotFound) || !strings.Contains(err.Error(), tt.errText) {
+					t.Errorf("Versions error = %v, want a proxy error with %q", err, tt.errText)
+				}
+			}
+		})
+	}
+}
+
+func TestPrivateRouting(t *testing.T) {
+	client, host := gitlabClient(t, &fakeGitLab{
+		projects: map[string]int{"group/lib": 1, "group/new": 2},
+		tags:     []string{"v1.0.0", "v1.1.0"},
+	})
+	// The public proxy has its own copy of group/lib but nothing of group/new
+	proxy := fileProxy(t, map[string]string{host + "/group/lib/@v/list": "v9.0.0\n"})
+
+	tests := []struct {
+		name     string
+		module   string
+		settings Settings
+		want     []string // Nil when the lookup fails
+	}{
+		{
+			name:     "proxy first without private settings",
+			module:   host + "/group/lib",
+			settings: Settings{},
+			want:     []string{"v9.0.0"},
+		},
+		{
+			name:     "GOPRIVATE skips the proxy",
+			module:   host + "/group/lib",
+			settings: Settings{Private: host + "/group"},
+			want:     []string{"v1.0.0", "v1.1.0"},
+		},
+		{
+			name:     "GONOPROXY skips the proxy",
+			module:   host + "/group/lib",
+			settings: Settings{NoProxy: host + "/group/*"},
+			want:     []string{"v1.0.0", "v1.1.0"},
+		},
+		{
+			name:     "GONOPROXY overrides GOPRIVATE",
+			module:   host + "/group/lib",
+			settings: Settings{Private: host + "/group", NoProxy: host + "/other"},
+			want:     []string{"v9.0.0"},
+		},
+		{
+			name:     "proxy miss falls back to GitLab without private settings",
+			module:   host + "/group/new",
+			settings: Settings{},
+			want:     []string{"v1.0.0", "v1.1.0"},
+		},
+		{
+			name:     "proxy miss of a public module",
+			module:   host + "/group/new",
+			settings: Settings{Private: host + "/other"},
+		},
+		{
+			name:     "GONOSUMDB makes a module private",
+			module:   host + "/group/new",
+			settings: Settings{Private: host + "/other", NoSumDB: host + "/group"},
+			want:     []string{"v1.0.0", "v1.1.0"},
+		},
+		{
+			name:     "other hosts never fall back",
+			module:   "example.com/group/new",
+			settings: Settings{Private: "example.com"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.settings.Proxy = proxy + ",direct"
+			versions, err := NewClient(tt.settings, client).Versions(tt.module)
+			if tt.want == nil {
+				if err == nil {
+					t.Fatalf("Versions = %v, want an error", versions)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(versions, tt.want) {
+				t.Errorf("Versions = %v, want %v", versions, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitLabTagFallback(t *testing.T) {
+	fake := &fakeGitLab{
+		projects: map[string]int{"group/repo": 7},
+		// Only the tags of sub with major version 2 belong to the module
+		tags: []string{"v1.0.0", "sub/v1.5.0", "sub/v2.0.0", "sub/v2.1.0", "sub/v2.2.0-beta.1", "sub/latest"},
+	}
+	client, host := gitlabClient(t, fake)
+	modPath := host + "/group/repo/sub/v2"
+	fake.files = map[string]string{"sub/go.mod@sub/v2.1.0": "module " + modPath + "\n"}
+	proxy := NewClient(Settings{Proxy: "direct"}, client)
+
+	versions, err := proxy.Versions(modPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"v2.0.0", "v2.1.0", "v2.2.0-beta.1"}; !reflect.DeepEqual(versions, want) {
+		t.Errorf("Versions = %v, want %v", versions, want)
+	}
+
+	latest, err := proxy.Latest(modPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.Version != "v2.1.0" || latest.Source != SourceGitLab || latest.Time.IsZero() {
+		t.Errorf("Latest = %+v, want v2.1.0 from GitLab with the commit time", latest)
+	}
+
+	tests := []struct {
+		version string
+		mod     string
+		wantErr error
+	}{
+		{version: "v2.1.0", mod: "module " + modPath + "\n"},
+		{version: "v2.0.0", mod: "module " + modPath + "\n"}, // No go.mod at the tag: synthesized
+		{version: "v2.3.0", wantErr: ErrNotFound},
+		{version: "v1.5.0", wantErr: ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			mod, err := proxy.GoMod(modPath, tt.version)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("GoMod error = %v, want %v", err, tt.wantErr)
+				}
+				if _, err := proxy.Info(modPath, tt.version); !errors.Is(err, tt.wantErr) {
+					t.Errorf("Info error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || string(mod) != tt.mod {
+				t.Errorf("GoMod = %q, %v, want %q", mod, err, tt.mod)
+			}
+			info, err := proxy.Info(modPath, tt.version)
+			if err != nil || info.Version != tt.version || info.Source != SourceGitLab {
+				t.Errorf("Info = %+v, %v", info, err)
+			}
+		})
+	}
+
+	if _, err := proxy.Versions(host + "/unknown/repo"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Versions of a module without project = %v, want ErrNotFound", err)
+	}
+	if _, err := NewClient(Settings{Proxy: "off"}, client).Versions(modPath); !errors.Is(err, ErrDisabled) {
+		t.Errorf("Versions with GOPROXY=off = %v, want ErrDisabled", err)
+	}
+}
+
+func TestLatestOf(t *testing.T) {
+	tests := []struct {
+		versions []string
+		want     string
+	}{
+		{[]string{"v1.0.0", "v1.10.0", "v1.9.0"}, "v1.10.0"},
+		{[]string{"v1.0.0", "v2.0.0-rc.1"}, "v1.0.0"},
+		{[]string{"v2.0.0-rc.1", "v2.0.0-beta.1"}, "v2.0.0-rc.1"},
+		{[]string{"latest", "1.0.0"}, ""},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := LatestOf(tt.versions); got != tt.want {
+			t.Errorf("LatestOf(%v) = %q, want %q", tt.versions, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+// internal/goproxy/gitlab.go
+package goproxy
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"gitlab-list/internal/gitlab"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// tagSet holds the module versions found among a GitLab project's tags
+type tagSet struct {
+	projectID int
+	dir       string          // Module directory inside the repository, "" for the root
+	versions  map[string]Info // Keyed by module version
+}
+
+// list returns the versions in semver order
+func (t *tagSet) list() []string {
+	versions := make([]string, 0, len(t.versions))
+	for v := range t.versions {
+		versions = append(versions, v)
+	}
+	semver.Sort(versions)
+	return versions
+}
+
+// tagName returns the git tag of a module version; modules in subdirectories use "<dir>/<version>"
+func (t *tagSet) tagName(version string) string {
+	if t.dir == "" {
+		return version
+	}
+	return t.dir + "/" + version
+}
+
+// goModPath returns the repository path of the module's go.mod
+func (t *tagSet) goModPath() string {
+	return path.Join(t.dir, "go.mod")
+}
+
+// Hosted reports whether modPath is hosted on the configured GitLab instance
+func (c *Client) Hosted(modPath string) bool {
+	if c.gitlab == nil {
+		return false
+	}
+	host := strings.Split(c.gitlab.Host(), ":")[0]
+	return host != "" && strings.HasPrefix(modPath, host+"/")
+}
+
+// private reports whether modPath is private per GONOPROXY, GONOSUMDB or GOPRIVATE.
+// Without any of them set, every module on the GitLab instance counts as private.
+func (c *Client) private(modPath string) bool {
+	s := c.settings
+	if s.Private == "" && s.NoProxy == "" && s.NoSumDB == "" {
+		return true
+	}
+	return s.matches(s.NoProxy, modPath) || s.matches(s.NoSumDB, modPath)
+}
+
+// useGitLab reports whether a failed proxy lookup should be answered from GitLab tags
+func (c *Client) useGitLab(modPath string, err error) bool {
+	if errors.Is(err, ErrDisabled) {
+		return false
+	}
+	return c.Hosted(modPath) && c.private(modPath)
+}
+
+// gitlabTags finds the GitLab project that hosts modPath and reads its version tags
+func (c *Client) gitlabTags(modPath string) (*tagSet, error) {
+	key := "tags:" + modPath
+	if v, err, ok := c.cache.get(key); ok {
+		tags, _ := v.(*tagSet)
+		return tags, err
+	}
+
+	tags, err := c.readGitLabTags(modPath)
+	c.cacheResult(key, tags, err)
+	return tags, err
+}
+
+func (c *Client) readGitLabTags(modPath string) (*tagSet, error) {
+	prefix, pathMajor, ok := module.SplitPathVersion(modPath)
+	if !ok {
+		return nil, fmt.Errorf("invalid module path %q", modPath)
+	}
+	host := strings.Split(c.gitlab.Host(), ":")[0]
+	elems := strings.Split(strings.TrimPrefix(prefix, host+"/"), "/")
+
+	// The project is the longest prefix GitLab knows; the remainder is the module directory.
+	// Projects always live in a namespace, so at least two elements are needed.
+	for n := len(elems); n >= 2; n-- {
+		projectPath := strings.Join(elems[:n], "/")
+		projectID, err := c.gitlab.GetProjectID(projectPath)
+		if gitlab.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve project %s: %w", projectPath, err)
+		}
+
+		tags, err := c.gitlab.ListTags(projectID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tags of %s: %w", projectPath, err)
+		}
+
+		set := &tagSet{projectID: projectID, dir: strings.Join(elems[n:], "/"), versions: make(map[string]Info)}
+		for _, tag := range tags {
+			version := tag.Name
+			if set.dir != "" {
+				if !strings.HasPrefix(version, set.dir+"/") {
+					continue
+				}
+				version = strings.TrimPrefix(version, set.dir+"/")
+			}
+			if semver.Canonical(version) != version || module.CheckPathMajor(version, pathMajor) != nil {
+				continue
+			}
+			set.versions[version] = Info{Version: version, Time: tag.Commit.CommittedDate, Source: SourceGitLab}
+		}
+		return set, nil
+	}
+
+	return nil, fmt.Errorf("%s: no GitLab project: %w", modPath, ErrNotFound)
+}
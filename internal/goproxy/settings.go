@@ -0,0 +1,83 @@
+// internal/goproxy/settings.go
+package goproxy
+
+import (
+	"strings"
+	"time"
+
+	"gitlab-list/internal/configuration"
+
+	"golang.org/x/mod/module"
+)
+
+// DefaultProxy is used when GOPROXY is empty, matching the go command
+const DefaultProxy = "https://proxy.golang.org,direct"
+
+// Settings mirrors the go command's module download environment
+type Settings struct {
+	Proxy    string        // GOPROXY: comma or pipe separated proxy URLs, "direct" or "off"
+	Private  string        // GOPRIVATE: default for NoProxy and NoSumDB
+	NoProxy  string        // GONOPROXY: module path globs fetched without a proxy
+	NoSumDB  string        // GONOSUMDB: module path globs treated as private
+	CacheTTL time.Duration // how long lookups are cached; 0 disables caching
+	Timeout  time.Duration // per-request timeout for proxy requests
+}
+
+// SettingsFromConfig builds settings from the configuration, falling back to the go command's defaults
+func SettingsFromConfig(cfg *configuration.Configuration) Settings {
+	s := Settings{
+		Proxy:    cfg.GoProxy,
+		Private:  cfg.GoPrivate,
+		NoProxy:  cfg.GoNoProxy,
+		NoSumDB:  cfg.GoNoSumDB,
+		CacheTTL: time.Hour,
+		Timeout:  15 * time.Second,
+	}
+	if d, err := time.ParseDuration(cfg.GoProxyCacheTTL); err == nil && d >= 0 {
+		s.CacheTTL = d
+	}
+	if d, err := time.ParseDuration(cfg.GoProxyTimeout); err == nil && d > 0 {
+		s.Timeout = d
+	}
+	return s
+}
+
+// proxyEntry is one element of the GOPROXY list
+type proxyEntry struct {
+	url string
+	// fallThrough is set for entries followed by "|": any error moves on to the next entry,
+	// not just a 404 or 410
+	fallThrough bool
+}
+
+// parseProxyList splits a GOPROXY value into its entries
+func parseProxyList(value string) []proxyEntry {
+	if strings.TrimSpace(value) == "" {
+		value = DefaultProxy
+	}
+
+	var entries []proxyEntry
+	for value != "" {
+		i := strings.IndexAny(value, ",|")
+		item, sep := value, byte(0)
+		if i >= 0 {
+			item, sep, value = value[:i], value[i], value[i+1:]
+		} else {
+			value = ""
+		}
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		entries = append(entries, proxyEntry{url: strings.TrimSuffix(item, "/"), fallThrough: sep == '|'})
+	}
+	return entries
+}
+
+// matches reports whether modPath matches the comma separated globs, or the GOPRIVATE globs when unset
+func (s Settings) matches(globs, modPath string) bool {
+	if globs == "" {
+		globs = s.Private
+	}
+	return globs != "" && module.MatchPrefixPatterns(globs, modPath)
+}
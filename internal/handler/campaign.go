@@ -0,0 +1,193 @@
+// internal/handler/campaign.go
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gitlab-list/internal/domain"
+	"gitlab-list/internal/repository"
+	"gitlab-list/internal/service"
+)
+
+// CampaignHandler handles HTTP requests for dependency bump campaigns
+type CampaignHandler struct {
+	campaigns *service.CampaignService
+}
+
+// NewCampaignHandler creates a new campaign handler
+func NewCampaignHandler(campaigns *service.CampaignService) *CampaignHandler {
+	return &CampaignHandler{
+		campaigns: campaigns,
+	}
+}
+
+// HandleCampaigns handles GET /api/campaigns and POST /api/campaigns
+func (h *CampaignHandler) HandleCampaigns(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		campaigns, err := h.campaigns.List()
+		if err != nil {
+			writeCampaignError(w, "Failed to list campaigns", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"campaigns": campaigns,
+			"count":     len(campaigns),
+		})
+	case http.MethodPost:
+		h.createCampaign(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// createCampaign starts a campaign with the caller's token, which the update jobs push with
+func (h *CampaignHandler) createCampaign(w http.ResponseWriter, r *http.Request) {
+	token, ok := bearerToken(w, r)
+	if !ok {
+		return
+	}
+
+	var request service.CampaignRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	campaign, err := h.campaigns.Create(request, token)
+	if err != nil {
+		writeCampaignError(w, "Failed to create campaign", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/campaigns/"+campaign.ID)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(campaign)
+}
+
+// HandleCampaign handles GET /api/campaigns/{id} (?refresh=true reads the merge requests first) and
+// POST /api/campaigns/{id}/refresh, /retry and /cancel. Retry and cancel act on the whole campaign,
+// or on one project with ?project_id= and optionally ?module_dir=.
+func (h *CampaignHandler) HandleCampaign(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/campaigns/"), "/"), "/")
+	id := parts[0]
+	if id == "" || len(parts) > 2 {
+		http.NotFound(w, r)
+		return
+	}
+
+	var campaign *domain.Campaign
+	var err error
+	if len(parts) == 1 {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if r.URL.Query().Get("refresh") == "true" {
+			campaign, err = h.campaigns.Refresh(id, optionalBearerToken(r))
+		} else {
+			campaign, err = h.campaigns.Get(id)
+		}
+		h.writeCampaign(w, campaign, err)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var projectID int
+	if projectIDStr := r.URL.Query().Get("project_id"); projectIDStr != "" {
+		projectID, err = strconv.Atoi(projectIDStr)
+		if err != nil {
+			http.Error(w, "Invalid project ID", http.StatusBadRequest)
+			return
+		}
+	}
+	moduleDir := r.URL.Query().Get("module_dir")
+
+	switch parts[1] {
+	case "refresh":
+		campaign, err = h.campaigns.Refresh(id, optionalBearerToken(r))
+	case "retry":
+		token, ok := bearerToken(w, r)
+		if !ok {
+			return
+		}
+		campaign, err = h.campaigns.Retry(id, projectID, moduleDir, token)
+	case "cancel":
+		campaign, err = h.campaigns.Cancel(id, projectID, moduleDir, optionalBearerToken(r))
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	h.writeCampaign(w, campaign, err)
+}
+
+// writeCampaign answers with the campaign; a refresh that failed for some projects still returns it
+// with the error alongside
+func (h *CampaignHandler) writeCampaign(w http.ResponseWriter, campaign *domain.Campaign, err error) {
+	if campaign == nil {
+		writeCampaignError(w, "Failed to update campaign", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"campaign": campaign,
+			"error":    err.Error(),
+		})
+		return
+	}
+	json.NewEncoder(w).Encode(campaign)
+}
+
+// bearerToken returns the token of the Authorization header, answering 401 or 400 when it is missing
+func bearerToken(w http.ResponseWriter, r *http.Request) (string, bool) {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		http.Error(w, "Authorization header required", http.StatusUnauthorized)
+		return "", false
+	}
+
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if token == authHeader {
+		http.Error(w, "Invalid authorization format. Use 'Bearer <token>'", http.StatusBadRequest)
+		return "", false
+	}
+	return token, true
+}
+
+// optionalBearerToken returns the Bearer token if any; without one the configured token is used
+func optionalBearerToken(r *http.Request) string {
+	return strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+}
+
+// writeCampaignError reports a missing store as 503, unknown campaigns as 404 and invalid ones as 400
+func writeCampaignError(w http.ResponseWriter, message string, err error) {
+	switch {
+	case errors.Is(err, repository.ErrNoStore):
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":   "Campaigns unavailable",
+			"message": "Campaigns are kept in the cache store. Please configure STORE.",
+			"details": err.Error(),
+		})
+	case errors.Is(err, repository.ErrCampaignNotFound), errors.Is(err, service.ErrCampaignProjectNotFound):
+		http.Error(w, fmt.Sprintf("%s: %v", message, err), http.StatusNotFound)
+	case strings.HasPrefix(err.Error(), "invalid campaign"):
+		http.Error(w, fmt.Sprintf("%s: %v", message, err), http.StatusBadRequest)
+	default:
+		http.Error(w, fmt.Sprintf("%s: %v", message, err), statusForError(err))
+	}
+}
@@ -9,26 +9,30 @@ import (
 	"strings"
 
 	"gitlab-list/internal/configuration"
+	"gitlab-list/internal/gitlab"
 )
 
 // ConfigHandler handles configuration-related HTTP requests
 type ConfigHandler struct {
 	configPath string
+	config     *configuration.Configuration
 }
 
 // NewConfigHandler creates a new configuration handler
-func NewConfigHandler() *ConfigHandler {
+func NewConfigHandler(cfg *configuration.Configuration) *ConfigHandler {
 	return &ConfigHandler{
 		configPath: ".env",
+		config:     cfg,
 	}
 }
 
 // ConfigRequest represents a configuration update request
 type ConfigRequest struct {
-	Token    string   `json:"token"`
-	Group    string   `json:"group"`
-	Tag      string   `json:"tag"`
-	Branches []string `json:"branches"`
+	Token     string   `json:"token"`
+	GitLabURL string   `json:"gitlab_url"`
+	Group     string   `json:"group"`
+	Tag       string   `json:"tag"`
+	Branches  []string `json:"branches"`
 }
 
 // SaveConfig handles POST /api/config
@@ -52,6 +56,9 @@ func (h *ConfigHandler) SaveConfig(w http.ResponseWriter, r *http.Request) {
 
 	// Create or update .env file
 	envContent := fmt.Sprintf("GITLAB_TOKEN=%s\n", req.Token)
+	if req.GitLabURL != "" {
+		envContent += fmt.Sprintf("GITLAB_URL=%s\n", strings.TrimSpace(req.GitLabURL))
+	}
 	if req.Group != "" {
 		envContent += fmt.Sprintf("GROUP=%s\n", req.Group)
 	}
@@ -89,7 +96,8 @@ func (h *ConfigHandler) TestConnection(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req struct {
-		Token string `json:"token"`
+		Token     string `json:"token"`
+		GitLabURL string `json:"gitlab_url"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid JSON", http.StatusBadRequest)
@@ -101,19 +109,15 @@ func (h *ConfigHandler) TestConnection(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Test the connection by making a simple GitLab API call
-	// This is a basic test - in a real implementation, you might want to test
-	// with a more specific endpoint or validate the token format
-	client := &http.Client{}
-	testURL := "https://git.prosoftke.sk/api/v4/user"
-	req2, err := http.NewRequest("GET", testURL, nil)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to create request: %v", err), http.StatusInternalServerError)
-		return
+	// Test against the instance from the request, falling back to the configured one
+	gitlabURL := strings.TrimSpace(req.GitLabURL)
+	if gitlabURL == "" {
+		gitlabURL = h.config.GitLabURL
 	}
 
-	req2.Header.Set("PRIVATE-TOKEN", req.Token)
-	resp, err := client.Do(req2)
+	// Test the connection by fetching the user the token belongs to
+	client := gitlab.NewClient(gitlabURL, req.Token)
+	resp, err := client.Do(http.MethodGet, "/user", nil)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Connection failed: %v", err), http.StatusBadRequest)
 		return
@@ -123,7 +127,8 @@ func (h *ConfigHandler) TestConnection(w http.ResponseWriter, r *http.Request) {
 	if resp.StatusCode == 200 {
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]string{
-			"message": "Connection successful",
+			"message":    "Connection successful",
+			"gitlab_url": client.BaseURL(),
 		})
 	} else {
 		http.Error(w, fmt.Sprintf("Authentication failed: %s", resp.Status), http.StatusUnauthorized)
@@ -142,9 +147,10 @@ func (h *ConfigHandler) GetConfig(w http.ResponseWriter, r *http.Request) {
 	// Return configuration (without sensitive data)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"group":    cfg.Group,
-		"tag":      cfg.Tag,
-		"branches": cfg.Branches,
-		"token":    cfg.Token,
+		"gitlab_url": cfg.GitLabURL,
+		"group":      cfg.Group,
+		"tag":        cfg.Tag,
+		"branches":   cfg.Branches,
+		"token":      cfg.Token,
 	})
 }
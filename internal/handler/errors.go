@@ -0,0 +1,41 @@
+// internal/handler/errors.go
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"gitlab-list/internal/gitlab"
+	"gitlab-list/internal/repository"
+)
+
+// statusForError maps typed GitLab API errors to the HTTP status returned to our clients
+func statusForError(err error) int {
+	switch {
+	case gitlab.IsNotFound(err):
+		return http.StatusNotFound
+	case gitlab.IsUnauthorized(err):
+		return http.StatusUnauthorized
+	case gitlab.IsRateLimited(err):
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeLeaseHeld answers 409 with the current holder when err says another replica holds a lease
+func writeLeaseHeld(w http.ResponseWriter, err error) bool {
+	var held *repository.LeaseHeldError
+	if !errors.As(err, &held) {
+		return false
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusConflict)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":   "Operation in progress",
+		"message": held.Error(),
+		"lock":    held.Lease,
+	})
+	return true
+}
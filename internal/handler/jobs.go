@@ -0,0 +1,94 @@
+// internal/handler/jobs.go
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"gitlab-list/internal/service"
+)
+
+// JobHandler handles HTTP requests for the queued jobs
+type JobHandler struct {
+	queue *service.JobQueue
+}
+
+// NewJobHandler creates a new job handler
+func NewJobHandler(queue *service.JobQueue) *JobHandler {
+	return &JobHandler{
+		queue: queue,
+	}
+}
+
+// ListJobs handles GET /api/jobs?kind=&subject=
+func (h *JobHandler) ListJobs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	jobs := h.queue.List(r.URL.Query().Get("kind"), r.URL.Query().Get("subject"))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"jobs":  jobs,
+		"count": len(jobs),
+	})
+}
+
+// HandleJob handles GET /api/jobs/{id} and POST /api/jobs/{id}/cancel (or DELETE /api/jobs/{id})
+func (h *JobHandler) HandleJob(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/jobs/"), "/"), "/")
+	id := parts[0]
+	if id == "" || len(parts) > 2 || (len(parts) == 2 && parts[1] != "cancel") {
+		http.NotFound(w, r)
+		return
+	}
+
+	var job service.QueuedJob
+	var err error
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		job, err = h.queue.Get(id)
+	case len(parts) == 1 && r.Method == http.MethodDelete, len(parts) == 2 && r.Method == http.MethodPost:
+		job, err = h.queue.Cancel(id)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		writeQueueError(w, "Failed to get job", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// writeQueued answers 202 with the queued job and where to follow it
+func writeQueued(w http.ResponseWriter, job service.QueuedJob) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/jobs/"+job.ID)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"job_id":     job.ID,
+		"status_url": "/api/jobs/" + job.ID,
+		"job":        job,
+	})
+}
+
+// writeQueueError reports a full queue as 503 and unknown jobs as 404
+func writeQueueError(w http.ResponseWriter, message string, err error) {
+	switch {
+	case errors.Is(err, service.ErrQueueFull):
+		w.Header().Set("Retry-After", "30")
+		http.Error(w, fmt.Sprintf("%s: %v", message, err), http.StatusServiceUnavailable)
+	case errors.Is(err, service.ErrJobNotQueued):
+		http.Error(w, fmt.Sprintf("%s: %v", message, err), http.StatusNotFound)
+	default:
+		http.Error(w, fmt.Sprintf("%s: %v", message, err), http.StatusInternalServerError)
+	}
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -12,11 +13,14 @@ import (
 
 type LibraryUpdaterHandler struct {
 	updater *service.LibraryUpdater
+	queue   *service.JobQueue
 }
 
-func NewLibraryUpdaterHandler(updater *service.LibraryUpdater) *LibraryUpdaterHandler {
+// NewLibraryUpdaterHandler creates a new library updater handler; project updates run on queue
+func NewLibraryUpdaterHandler(updater *service.LibraryUpdater, queue *service.JobQueue) *LibraryUpdaterHandler {
 	return &LibraryUpdaterHandler{
 		updater: updater,
+		queue:   queue,
 	}
 }
 
@@ -55,9 +59,9 @@ func (h *LibraryUpdaterHandler) GetOutdatedLibraries(w http.ResponseWriter, r *h
 	}
 
 	// Get outdated libraries
-	updates, err := h.updater.GetOutdatedLibraries(projectID)
+	updates, err := h.updater.GetOutdatedLibraries(projectID, r.URL.Query().Get("module_dir"), token)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to get outdated libraries: %v", err), http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("Failed to get outdated libraries: %v", err), statusForError(err))
 		return
 	}
 
@@ -94,6 +98,7 @@ func (h *LibraryUpdaterHandler) UpdateLibrary(w http.ResponseWriter, r *http.Req
 		ProjectID     int    `json:"project_id"`
 		LibraryName   string `json:"library_name"`
 		TargetVersion string `json:"target_version"`
+		ModuleDir     string `json:"module_dir,omitempty"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
@@ -107,15 +112,17 @@ func (h *LibraryUpdaterHandler) UpdateLibrary(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	// Update the library
-	result, err := h.updater.UpdateLibrary(request.ProjectID, request.LibraryName, request.TargetVersion, token)
+	// Update the library as a queued job; the result is the update result this endpoint answered with
+	// before it was queued
+	job, err := h.queue.Submit(service.JobKindProjectUpdate, strconv.Itoa(request.ProjectID), func(ctx context.Context, progress service.ProgressFunc) (interface{}, error) {
+		return h.updater.UpdateLibraryContext(ctx, request.ProjectID, request.ModuleDir, request.LibraryName, request.TargetVersion, token, progress)
+	})
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to update library: %v", err), http.StatusInternalServerError)
+		writeQueueError(w, "Failed to queue library update", err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(result)
+	writeQueued(w, job)
 }
 
 // BatchUpdateLibraries handles POST /api/library/batch-update
@@ -155,19 +162,25 @@ func (h *LibraryUpdaterHandler) BatchUpdateLibraries(w http.ResponseWriter, r *h
 		return
 	}
 
-	// Batch update libraries
-	results, err := h.updater.BatchUpdateLibraries(request.ProjectID, request.Updates, token)
+	// Batch update libraries as a queued job; the result has the shape this endpoint answered with
+	// before it was queued
+	job, err := h.queue.Submit(service.JobKindProjectUpdate, strconv.Itoa(request.ProjectID), func(ctx context.Context, progress service.ProgressFunc) (interface{}, error) {
+		results, err := h.updater.BatchUpdateLibrariesContext(ctx, request.ProjectID, request.Updates, token, progress)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{
+			"project_id": request.ProjectID,
+			"results":    results,
+			"count":      len(results),
+		}, nil
+	})
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to batch update libraries: %v", err), http.StatusInternalServerError)
+		writeQueueError(w, "Failed to queue batch update", err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"project_id": request.ProjectID,
-		"results":    results,
-		"count":      len(results),
-	})
+	writeQueued(w, job)
 }
 
 // GetUpdateStatus handles GET /api/library/status/{project_id}
@@ -204,13 +217,22 @@ func (h *LibraryUpdaterHandler) GetUpdateStatus(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	// Get update status (this would track ongoing updates)
-	// For now, return a simple status
+	// The project is busy while one of its update jobs waits or runs; finished ones are listed too
+	jobs := h.queue.List(service.JobKindProjectUpdate, strconv.Itoa(projectID))
+	status, message := "ready", "No update of this project is queued or running"
+	for _, job := range jobs {
+		if !job.Finished() {
+			status, message = job.Status, fmt.Sprintf("Update job %s is %s", job.ID, job.Status)
+			break
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"project_id": projectID,
-		"status":     "ready",
-		"message":    "Library updater is ready",
+		"status":     status,
+		"message":    message,
+		"jobs":       jobs,
 	})
 }
 
@@ -249,9 +271,9 @@ func (h *LibraryUpdaterHandler) GetProjectLibraries(w http.ResponseWriter, r *ht
 	}
 
 	// Get project libraries
-	libraries, err := h.updater.GetProjectLibraries(projectID, token)
+	libraries, err := h.updater.GetProjectLibraries(projectID, r.URL.Query().Get("module_dir"), token)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to get project libraries: %v", err), http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("Failed to get project libraries: %v", err), statusForError(err))
 		return
 	}
 
@@ -289,6 +311,13 @@ func (h *LibraryUpdaterHandler) UpdateProjectLibraries(w http.ResponseWriter, r
 		Updates    []service.ProjectLibraryUpdate `json:"updates"`
 		GoVersion  string                         `json:"go_version,omitempty"`
 		BranchName string                         `json:"branch_name,omitempty"`
+		ModuleDir  string                         `json:"module_dir,omitempty"`
+
+		// Verification; unset values default to VERIFY_UPDATES, VERIFY_TESTS and REQUIRE_GREEN_BUILD
+		DryRun       bool  `json:"dry_run,omitempty"`
+		Verify       *bool `json:"verify,omitempty"`
+		RunTests     *bool `json:"run_tests,omitempty"`
+		RequireGreen *bool `json:"require_green,omitempty"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
@@ -302,17 +331,35 @@ func (h *LibraryUpdaterHandler) UpdateProjectLibraries(w http.ResponseWriter, r
 		return
 	}
 
-	// Update project libraries
-	results, err := h.updater.UpdateProjectLibraries(request.ProjectID, request.Updates, request.GoVersion, request.BranchName, token)
+	opts := h.updater.DefaultUpdateOptions()
+	opts.DryRun = request.DryRun
+	if request.Verify != nil {
+		opts.Verify = *request.Verify
+	}
+	if request.RunTests != nil {
+		opts.Tests = *request.RunTests
+	}
+	if request.RequireGreen != nil {
+		opts.RequireGreen = *request.RequireGreen
+	}
+
+	// Update project libraries as a queued job; the result has the shape this endpoint answered with
+	// before it was queued
+	job, err := h.queue.Submit(service.JobKindProjectUpdate, strconv.Itoa(request.ProjectID), func(ctx context.Context, progress service.ProgressFunc) (interface{}, error) {
+		results, err := h.updater.UpdateProjectLibrariesContext(ctx, request.ProjectID, request.ModuleDir, request.Updates, request.GoVersion, request.BranchName, token, opts, progress)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{
+			"project_id": request.ProjectID,
+			"results":    results,
+			"count":      len(results),
+		}, nil
+	})
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to update project libraries: %v", err), http.StatusInternalServerError)
+		writeQueueError(w, "Failed to queue project update", err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"project_id": request.ProjectID,
-		"results":    results,
-		"count":      len(results),
-	})
+	writeQueued(w, job)
 }
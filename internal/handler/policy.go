@@ -0,0 +1,110 @@
+// internal/handler/policy.go
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gitlab-list/internal/policy"
+	"gitlab-list/internal/repository"
+	"gitlab-list/internal/service"
+)
+
+// PolicyHandler handles HTTP requests for dependency policy reports
+type PolicyHandler struct {
+	policies *service.PolicyService
+}
+
+// NewPolicyHandler creates a new policy handler
+func NewPolicyHandler(policies *service.PolicyService) *PolicyHandler {
+	return &PolicyHandler{
+		policies: policies,
+	}
+}
+
+// GetViolations handles GET /api/policy
+func (h *PolicyHandler) GetViolations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var projectID int
+	if projectIDStr := r.URL.Query().Get("project_id"); projectIDStr != "" {
+		id, err := strconv.Atoi(projectIDStr)
+		if err != nil {
+			http.Error(w, "Invalid project ID", http.StatusBadRequest)
+			return
+		}
+		projectID = id
+	}
+
+	report, err := h.policies.Evaluate(r.URL.Query().Get("ref"))
+	if err != nil {
+		h.writeError(w, "Failed to evaluate policy", err)
+		return
+	}
+
+	if projectID != 0 {
+		filterPolicyReport(report, projectID)
+	}
+
+	// view=projects or view=rules returns only one side of the report
+	switch r.URL.Query().Get("view") {
+	case "projects":
+		report.Rules = nil
+	case "rules":
+		report.Projects = nil
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// filterPolicyReport keeps only the violations of one project
+func filterPolicyReport(report *policy.Report, projectID int) {
+	projects := []policy.ProjectResult{}
+	report.Errors, report.Warnings = 0, 0
+	for _, result := range report.Projects {
+		if result.ProjectID == projectID {
+			projects = append(projects, result)
+			report.Errors += result.Errors
+			report.Warnings += result.Warnings
+		}
+	}
+	report.Projects = projects
+
+	for i := range report.Rules {
+		violations := []policy.Violation{}
+		for _, v := range report.Rules[i].Violations {
+			if v.ProjectID == projectID {
+				violations = append(violations, v)
+			}
+		}
+		report.Rules[i].Violations = violations
+		report.Rules[i].Projects = 0
+		if len(violations) > 0 {
+			report.Rules[i].Projects = 1
+		}
+	}
+}
+
+// writeError reports a missing cache store or policy file as 503, anything else by error type
+func (h *PolicyHandler) writeError(w http.ResponseWriter, message string, err error) {
+	if errors.Is(err, repository.ErrNoStore) ||
+		strings.Contains(err.Error(), "policy not configured") {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":   "Policy service unavailable",
+			"message": "Policy reports need the project cache (STORE) and a policy file (POLICY_FILE).",
+			"details": err.Error(),
+		})
+		return
+	}
+	http.Error(w, fmt.Sprintf("%s: %v", message, err), statusForError(err))
+}
@@ -2,26 +2,33 @@
 package handler
 
 import (
+	"context"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
 	"gitlab-list/internal/domain"
+	"gitlab-list/internal/repository"
 	"gitlab-list/internal/service"
 )
 
 // ProjectHandler handles HTTP requests for project operations
 type ProjectHandler struct {
 	projectService *service.ProjectService
+	queue          *service.JobQueue
 }
 
-// NewProjectHandler creates a new project handler
-func NewProjectHandler(projectService *service.ProjectService) *ProjectHandler {
+// NewProjectHandler creates a new project handler; cache loads run on queue
+func NewProjectHandler(projectService *service.ProjectService, queue *service.JobQueue) *ProjectHandler {
 	return &ProjectHandler{
 		projectService: projectService,
+		queue:          queue,
 	}
 }
 
@@ -41,6 +48,12 @@ func (h *ProjectHandler) SearchProjects(w http.ResponseWriter, r *http.Request)
 		VersionComparison:   r.URL.Query().Get("version_comparison"),
 		Group:               r.URL.Query().Get("group"),
 		Tag:                 r.URL.Query().Get("tag"),
+		Ref:                 r.URL.Query().Get("ref"),
+		Dependency:          r.URL.Query().Get("dependency"),
+		Replaced:            r.URL.Query().Get("replaced"),
+		Toolchain:           r.URL.Query().Get("toolchain"),
+		ToolchainComparison: r.URL.Query().Get("toolchain_comparison"),
+		Exclude:             r.URL.Query().Get("exclude"),
 	}
 
 	// Check if cache should be used
@@ -108,29 +121,35 @@ func (h *ProjectHandler) LoadInitialCache(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	// Load all projects into cache with the provided token
-	projectsCached, err := h.projectService.LoadInitialCacheWithToken(token)
-	if err != nil {
-		// Check if it's a MongoDB not available error
-		if strings.Contains(err.Error(), "MongoDB repository not available") {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusServiceUnavailable)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"error":   "Cache service unavailable",
-				"message": "MongoDB is not available. Please configure MongoDB to enable caching.",
-				"details": err.Error(),
-			})
-			return
-		}
-		http.Error(w, fmt.Sprintf("Failed to load initial cache: %v", err), http.StatusInternalServerError)
+	if !h.projectService.CacheEnabled() {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":   "Cache service unavailable",
+			"message": "No cache store is configured. Please set STORE to enable caching.",
+		})
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"message":         "Initial cache loaded successfully",
-		"projects_cached": projectsCached,
+	// Load all projects into cache with the provided token as a queued job; the result has the
+	// shape this endpoint answered with before it was queued
+	job, err := h.queue.Submit(service.JobKindCacheLoad, "", func(ctx context.Context, progress service.ProgressFunc) (interface{}, error) {
+		summary, err := h.projectService.LoadInitialCacheContext(ctx, token, progress)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{
+			"message":         "Initial cache loaded successfully",
+			"projects_cached": summary.Total,
+			"summary":         summary,
+		}, nil
 	})
+	if err != nil {
+		writeQueueError(w, "Failed to queue cache load", err)
+		return
+	}
+
+	writeQueued(w, job)
 }
 
 // RefreshCache handles POST /api/cache/refresh
@@ -140,28 +159,37 @@ func (h *ProjectHandler) RefreshCache(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Clear expired cache entries
-	err := h.projectService.ClearExpiredCache()
-	if err != nil {
-		// Check if it's a MongoDB not available error
-		if strings.Contains(err.Error(), "MongoDB repository not available") {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusServiceUnavailable)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"error":   "Cache service unavailable",
-				"message": "MongoDB is not available. Please configure MongoDB to enable caching.",
-				"details": err.Error(),
-			})
-			return
-		}
-		http.Error(w, fmt.Sprintf("Failed to refresh cache: %v", err), http.StatusInternalServerError)
+	// The token is optional; the configured one is used without it
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+	if !h.projectService.CacheEnabled() {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":   "Cache service unavailable",
+			"message": "No cache store is configured. Please set STORE to enable caching.",
+		})
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
-		"message": "Cache refreshed successfully",
+	// Re-read only the records whose branch head moved, as a queued job; the result has the shape this
+	// endpoint answered with before it was queued
+	job, err := h.queue.Submit(service.JobKindCacheRefresh, "", func(ctx context.Context, progress service.ProgressFunc) (interface{}, error) {
+		summary, err := h.projectService.RefreshCacheContext(ctx, token, progress)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{
+			"message": fmt.Sprintf("Cache refreshed: %d records read again, %d API calls saved", summary.Refetched, summary.APICallsSaved),
+			"summary": summary,
+		}, nil
 	})
+	if err != nil {
+		writeQueueError(w, "Failed to queue cache refresh", err)
+		return
+	}
+
+	writeQueued(w, job)
 }
 
 // ClearCache handles POST /api/cache/clear
@@ -174,13 +202,16 @@ func (h *ProjectHandler) ClearCache(w http.ResponseWriter, r *http.Request) {
 	// Clear all cache entries
 	err := h.projectService.ClearAllCache()
 	if err != nil {
-		// Check if it's a MongoDB not available error
-		if strings.Contains(err.Error(), "MongoDB repository not available") {
+		if writeLeaseHeld(w, err) {
+			return
+		}
+		// Check if no cache store is configured
+		if errors.Is(err, repository.ErrNoStore) {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusServiceUnavailable)
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"error":   "Cache service unavailable",
-				"message": "MongoDB is not available. Please configure MongoDB to enable caching.",
+				"message": "No cache store is configured. Please set STORE to enable caching.",
 				"details": err.Error(),
 			})
 			return
@@ -212,6 +243,36 @@ func (h *ProjectHandler) GetCacheStats(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(stats)
 }
 
+// GetLeases handles GET /api/locks
+func (h *ProjectHandler) GetLeases(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	leases, err := h.projectService.GetLeases()
+	if err != nil {
+		if errors.Is(err, repository.ErrNoStore) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error":   "Cache service unavailable",
+				"message": "Locks are kept in the cache store. Please configure STORE.",
+				"details": err.Error(),
+			})
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to get locks: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"locks": leases,
+		"count": len(leases),
+	})
+}
+
 // RefreshProjectCache handles POST /api/cache/refresh-project
 func (h *ProjectHandler) RefreshProjectCache(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -242,13 +303,13 @@ func (h *ProjectHandler) RefreshProjectCache(w http.ResponseWriter, r *http.Requ
 	// Refresh the specific project in cache
 	err := h.projectService.RefreshProjectInCache(req.ProjectID, req.Token)
 	if err != nil {
-		// Check if it's a MongoDB not available error
-		if strings.Contains(err.Error(), "MongoDB repository not available") {
+		// Check if no cache store is configured
+		if errors.Is(err, repository.ErrNoStore) {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusServiceUnavailable)
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"error":   "Cache service unavailable",
-				"message": "MongoDB is not available. Please configure MongoDB to enable caching.",
+				"message": "No cache store is configured. Please set STORE to enable caching.",
 				"details": err.Error(),
 			})
 			return
@@ -282,13 +343,13 @@ func (h *ProjectHandler) SearchLibraries(w http.ResponseWriter, r *http.Request)
 
 	libraries, err := h.projectService.SearchLibraries(query, limit)
 	if err != nil {
-		// Check if it's a MongoDB not available error
-		if strings.Contains(err.Error(), "MongoDB repository not available") {
+		// Check if no cache store is configured
+		if errors.Is(err, repository.ErrNoStore) {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusServiceUnavailable)
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"error":   "Cache service unavailable",
-				"message": "MongoDB is not available. Please configure MongoDB to enable caching.",
+				"message": "No cache store is configured. Please set STORE to enable caching.",
 				"details": err.Error(),
 			})
 			return
@@ -324,13 +385,13 @@ func (h *ProjectHandler) SearchGoVersions(w http.ResponseWriter, r *http.Request
 
 	versions, err := h.projectService.SearchGoVersions(query, limit)
 	if err != nil {
-		// Check if it's a MongoDB not available error
-		if strings.Contains(err.Error(), "MongoDB repository not available") {
+		// Check if no cache store is configured
+		if errors.Is(err, repository.ErrNoStore) {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusServiceUnavailable)
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"error":   "Cache service unavailable",
-				"message": "MongoDB is not available. Please configure MongoDB to enable caching.",
+				"message": "No cache store is configured. Please set STORE to enable caching.",
 				"details": err.Error(),
 			})
 			return
@@ -372,13 +433,13 @@ func (h *ProjectHandler) SearchLibraryVersions(w http.ResponseWriter, r *http.Re
 
 	versions, err := h.projectService.SearchLibraryVersions(libraryName, query, limit)
 	if err != nil {
-		// Check if it's a MongoDB not available error
-		if strings.Contains(err.Error(), "MongoDB repository not available") {
+		// Check if no cache store is configured
+		if errors.Is(err, repository.ErrNoStore) {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusServiceUnavailable)
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"error":   "Cache service unavailable",
-				"message": "MongoDB is not available. Please configure MongoDB to enable caching.",
+				"message": "No cache store is configured. Please set STORE to enable caching.",
 				"details": err.Error(),
 			})
 			return
@@ -415,13 +476,13 @@ func (h *ProjectHandler) SearchModules(w http.ResponseWriter, r *http.Request) {
 
 	modules, err := h.projectService.SearchModules(query, limit)
 	if err != nil {
-		// Check if it's a MongoDB not available error
-		if strings.Contains(err.Error(), "MongoDB repository not available") {
+		// Check if no cache store is configured
+		if errors.Is(err, repository.ErrNoStore) {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusServiceUnavailable)
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"error":   "Cache service unavailable",
-				"message": "MongoDB is not available. Please configure MongoDB to enable caching.",
+				"message": "No cache store is configured. Please set STORE to enable caching.",
 				"details": err.Error(),
 			})
 			return
@@ -462,9 +523,10 @@ func (h *ProjectHandler) GenerateFullArchitecture(w http.ResponseWriter, r *http
 
 	// Parse clients only option
 	clientsOnly := r.URL.Query().Get("clients_only") == "true"
+	ref := r.URL.Query().Get("ref")
 
 	// Generate full architecture with ignore patterns and clients only option
-	arch, err := h.projectService.GenerateFullArchitectureWithOptions(ignoreList, clientsOnly)
+	arch, err := h.projectService.GenerateFullArchitectureWithOptions(ref, ignoreList, clientsOnly)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to generate full architecture: %v", err), http.StatusInternalServerError)
 		return
@@ -530,13 +592,13 @@ func (h *ProjectHandler) GitLabWebhook(w http.ResponseWriter, r *http.Request) {
 	// Refresh the specific project in cache
 	err := h.projectService.RefreshProjectInCache(webhookPayload.Project.ID, token)
 	if err != nil {
-		// Check if it's a MongoDB not available error
-		if strings.Contains(err.Error(), "MongoDB repository not available") {
+		// Check if no cache store is configured
+		if errors.Is(err, repository.ErrNoStore) {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusServiceUnavailable)
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"error":   "Cache service unavailable",
-				"message": "MongoDB is not available. Please configure MongoDB to enable caching.",
+				"message": "No cache store is configured. Please set STORE to enable caching.",
 				"details": err.Error(),
 			})
 			return
@@ -580,13 +642,13 @@ func (h *ProjectHandler) GetChangedProjects(w http.ResponseWriter, r *http.Reque
 	// Get changed projects
 	changedProjects, err := h.projectService.GetChangedProjects(token)
 	if err != nil {
-		// Check if it's a MongoDB not available error
-		if strings.Contains(err.Error(), "MongoDB repository not available") {
+		// Check if no cache store is configured
+		if errors.Is(err, repository.ErrNoStore) {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusServiceUnavailable)
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"error":   "Cache service unavailable",
-				"message": "MongoDB is not available. Please configure MongoDB to enable caching.",
+				"message": "No cache store is configured. Please set STORE to enable caching.",
 				"details": err.Error(),
 			})
 			return
@@ -622,11 +684,11 @@ func (h *ProjectHandler) TestCache(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Try to cache a test project
-	projectHashes := map[int]string{
-		999: "test-hash-123",
+	projectHashes := map[string]string{
+		"999@": "test-hash-123",
 	}
 
-	err := h.projectService.TestCacheSave([]domain.Project{testProject}, "initial_load_all_projects", projectHashes)
+	err := h.projectService.TestCacheSave([]domain.Project{testProject}, projectHashes)
 	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
@@ -638,7 +700,9 @@ func (h *ProjectHandler) TestCache(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Try to retrieve from cache
-	cachedProjects, err := h.projectService.TestCacheGet("initial_load_all_projects")
+	cachedProjects, err := h.projectService.TestCacheGet(testProject.ID)
+	// The test document is not a real project; remove it either way
+	h.projectService.TestCacheDelete(testProject.ID)
 	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
@@ -675,11 +739,47 @@ func (h *ProjectHandler) GetProject(w http.ResponseWriter, r *http.Request) {
 	_ = ref
 }
 
-// GetLibraries handles GET /api/libraries
+// GetLibraries handles GET /api/libraries and reports library version drift across cached projects
 func (h *ProjectHandler) GetLibraries(w http.ResponseWriter, r *http.Request) {
-	// This would aggregate all libraries from all projects
-	// For now, return a placeholder
-	http.Error(w, "Not implemented", http.StatusNotImplemented)
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	opts := domain.DriftOptions{
+		Ref:          r.URL.Query().Get("ref"),
+		Prefix:       r.URL.Query().Get("prefix"),
+		InternalOnly: r.URL.Query().Get("internal") == "true",
+		DirectOnly:   r.URL.Query().Get("direct") == "true",
+	}
+
+	report, err := h.projectService.GetLibraryDrift(opts)
+	if err != nil {
+		if errors.Is(err, repository.ErrNoStore) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error":   "Cache service unavailable",
+				"message": "The library report is built from cached projects. Please configure STORE and load the cache.",
+				"details": err.Error(),
+			})
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to build library report: %v", err), statusForError(err))
+		return
+	}
+
+	if r.URL.Query().Get("format") == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", "attachment; filename=\"libraries.csv\"")
+		if err := writeDriftCSV(w, report); err != nil {
+			fmt.Printf("Warning: failed to write library report: %v\n", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
 }
 
 // GetArchitecture handles GET /api/architecture
@@ -768,6 +868,7 @@ func (h *ProjectHandler) GetArchitectureFile(w http.ResponseWriter, r *http.Requ
 }
 
 // GetProjectOpenAPI handles GET /api/projects/{id}/openapi
+// The first spec is returned unless ?path= selects another one; ?ref= picks the branch.
 func (h *ProjectHandler) GetProjectOpenAPI(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -789,16 +890,16 @@ func (h *ProjectHandler) GetProjectOpenAPI(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	// Get OpenAPI specification
-	openAPI, err := h.projectService.GetProjectOpenAPI(projectID)
+	// Get OpenAPI specifications
+	specs, err := h.projectService.GetProjectOpenAPISpecs(projectID, r.URL.Query().Get("ref"))
 	if err != nil {
-		// Check if it's a MongoDB not available error
-		if strings.Contains(err.Error(), "MongoDB repository not available") {
+		// Check if no cache store is configured
+		if errors.Is(err, repository.ErrNoStore) {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusServiceUnavailable)
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"error":   "Cache service unavailable",
-				"message": "MongoDB is not available. Please configure MongoDB to enable caching.",
+				"message": "No cache store is configured. Please set STORE to enable caching.",
 				"details": err.Error(),
 			})
 			return
@@ -807,18 +908,29 @@ func (h *ProjectHandler) GetProjectOpenAPI(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	if !openAPI.Found {
+	specPath := r.URL.Query().Get("path")
+	openAPI := findOpenAPISpec(specs, specPath)
+	if openAPI == nil {
+		message := fmt.Sprintf("No OpenAPI specification found for project %d", projectID)
+		if specPath != "" {
+			message = fmt.Sprintf("No OpenAPI specification at %s in project %d", specPath, projectID)
+		}
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusNotFound)
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"error":   "OpenAPI specification not found",
-			"message": fmt.Sprintf("No OpenAPI specification found for project %d", projectID),
+			"message": message,
+			"specs":   openAPISummaries(specs),
 		})
 		return
 	}
 
 	// Return the OpenAPI content
-	w.Header().Set("Content-Type", "application/yaml")
+	contentType := "application/yaml"
+	if openAPI.Format == "json" {
+		contentType = "application/json"
+	}
+	w.Header().Set("Content-Type", contentType)
 	w.Write([]byte(openAPI.Content))
 }
 
@@ -830,15 +942,15 @@ func (h *ProjectHandler) GetProjectsWithOpenAPI(w http.ResponseWriter, r *http.R
 	}
 
 	// Get all projects with OpenAPI specifications
-	projects, err := h.projectService.GetProjectsWithOpenAPI()
+	projects, err := h.projectService.GetProjectsWithOpenAPI(r.URL.Query().Get("ref"))
 	if err != nil {
-		// Check if it's a MongoDB not available error
-		if strings.Contains(err.Error(), "MongoDB repository not available") {
+		// Check if no cache store is configured
+		if errors.Is(err, repository.ErrNoStore) {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusServiceUnavailable)
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"error":   "Cache service unavailable",
-				"message": "MongoDB is not available. Please configure MongoDB to enable caching.",
+				"message": "No cache store is configured. Please set STORE to enable caching.",
 				"details": err.Error(),
 			})
 			return
@@ -865,6 +977,7 @@ func (h *ProjectHandler) SearchProjectsForOpenAPI(w http.ResponseWriter, r *http
 	// Get search parameters
 	query := r.URL.Query().Get("q")
 	hasOpenAPI := r.URL.Query().Get("has_openapi")
+	ref := r.URL.Query().Get("ref")
 	limitStr := r.URL.Query().Get("limit")
 
 	// Parse limit
@@ -876,15 +989,15 @@ func (h *ProjectHandler) SearchProjectsForOpenAPI(w http.ResponseWriter, r *http
 	}
 
 	// Get all cached projects
-	allProjects, err := h.projectService.GetCachedProjects("initial_load_all_projects")
+	allProjects, err := h.projectService.GetCachedProjectsOnRef(ref)
 	if err != nil {
-		// Check if it's a MongoDB not available error
-		if strings.Contains(err.Error(), "MongoDB repository not available") {
+		// Check if no cache store is configured
+		if errors.Is(err, repository.ErrNoStore) {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusServiceUnavailable)
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"error":   "Cache service unavailable",
-				"message": "MongoDB is not available. Please configure MongoDB to enable caching.",
+				"message": "No cache store is configured. Please set STORE to enable caching.",
 				"details": err.Error(),
 			})
 			return
@@ -897,7 +1010,7 @@ func (h *ProjectHandler) SearchProjectsForOpenAPI(w http.ResponseWriter, r *http
 	var filteredProjects []domain.Project
 	for _, project := range allProjects {
 		// Check if project has OpenAPI
-		hasOpenAPISpec := project.OpenAPI != nil && project.OpenAPI.Found
+		hasOpenAPISpec := len(project.OpenAPISpecs) > 0
 
 		// Apply OpenAPI filter
 		if hasOpenAPI == "true" && !hasOpenAPISpec {
@@ -915,8 +1028,8 @@ func (h *ProjectHandler) SearchProjectsForOpenAPI(w http.ResponseWriter, r *http
 				strings.Contains(strings.ToLower(project.Description), queryLower)
 
 			// Also search in OpenAPI content if available
-			if project.OpenAPI != nil && project.OpenAPI.Found {
-				matches = matches || strings.Contains(strings.ToLower(project.OpenAPI.Content), queryLower)
+			for _, spec := range project.OpenAPISpecs {
+				matches = matches || strings.Contains(strings.ToLower(spec.Content), queryLower)
 			}
 
 			if !matches {
@@ -943,18 +1056,9 @@ func (h *ProjectHandler) SearchProjectsForOpenAPI(w http.ResponseWriter, r *http
 			"web_url":     project.WebURL,
 		}
 
-		if project.OpenAPI != nil {
-			summary["openapi"] = map[string]interface{}{
-				"found":          project.OpenAPI.Found,
-				"path":           project.OpenAPI.Path,
-				"content_length": len(project.OpenAPI.Content),
-			}
-		} else {
-			summary["openapi"] = map[string]interface{}{
-				"found":          false,
-				"path":           "",
-				"content_length": 0,
-			}
+		summary["openapi"] = map[string]interface{}{
+			"found": len(project.OpenAPISpecs) > 0,
+			"specs": openAPISummaries(project.OpenAPISpecs),
 		}
 
 		projectSummaries = append(projectSummaries, summary)
@@ -967,6 +1071,7 @@ func (h *ProjectHandler) SearchProjectsForOpenAPI(w http.ResponseWriter, r *http
 		"total":       len(allProjects),
 		"query":       query,
 		"has_openapi": hasOpenAPI,
+		"ref":         ref,
 		"limit":       limit,
 	})
 }
@@ -979,7 +1084,7 @@ func (h *ProjectHandler) DebugOpenAPI(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get all cached projects to check OpenAPI data
-	allProjects, err := h.projectService.GetCachedProjects("initial_load_all_projects")
+	allProjects, err := h.projectService.GetCachedProjects()
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to get cached projects: %v", err), http.StatusInternalServerError)
 		return
@@ -995,19 +1100,10 @@ func (h *ProjectHandler) DebugOpenAPI(w http.ResponseWriter, r *http.Request) {
 			"path": project.Path,
 		}
 
-		if project.OpenAPI != nil {
-			projectInfo["openapi"] = map[string]interface{}{
-				"found":          project.OpenAPI.Found,
-				"path":           project.OpenAPI.Path,
-				"content_length": len(project.OpenAPI.Content),
-			}
-			if project.OpenAPI.Found {
-				openAPIProjects = append(openAPIProjects, projectInfo)
-			} else {
-				projectsWithoutOpenAPI = append(projectsWithoutOpenAPI, projectInfo)
-			}
+		projectInfo["openapi_specs"] = openAPISummaries(project.OpenAPISpecs)
+		if len(project.OpenAPISpecs) > 0 {
+			openAPIProjects = append(openAPIProjects, projectInfo)
 		} else {
-			projectInfo["openapi"] = "nil"
 			projectsWithoutOpenAPI = append(projectsWithoutOpenAPI, projectInfo)
 		}
 	}
@@ -1021,3 +1117,66 @@ func (h *ProjectHandler) DebugOpenAPI(w http.ResponseWriter, r *http.Request) {
 		"no_openapi_projects":      projectsWithoutOpenAPI,
 	})
 }
+
+// findOpenAPISpec returns the spec stored at specPath, or the first spec when specPath is empty
+func findOpenAPISpec(specs []domain.OpenAPI, specPath string) *domain.OpenAPI {
+	for i := range specs {
+		if specPath == "" || specs[i].Path == specPath {
+			return &specs[i]
+		}
+	}
+	return nil
+}
+
+// openAPISummaries describes specs without their content
+func openAPISummaries(specs []domain.OpenAPI) []map[string]interface{} {
+	summaries := make([]map[string]interface{}, 0, len(specs))
+	for _, spec := range specs {
+		summaries = append(summaries, map[string]interface{}{
+			"path":           spec.Path,
+			"format":         spec.Format,
+			"version":        spec.Version,
+			"title":          spec.Title,
+			"files":          spec.Files,
+			"content_length": len(spec.Content),
+		})
+	}
+	return summaries
+}
+
+// writeDriftCSV writes one row per library version in use
+func writeDriftCSV(w io.Writer, report *domain.DriftReport) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{
+		"module", "internal", "version", "projects", "project_paths",
+		"latest_in_use", "latest_available", "major", "minor", "patch", "days_behind", "score",
+	})
+	for _, library := range report.Libraries {
+		for _, usage := range library.Versions {
+			var paths []string
+			for _, consumer := range usage.Projects {
+				if consumer.ModuleDir != "" {
+					paths = append(paths, consumer.Path+"/"+consumer.ModuleDir)
+				} else {
+					paths = append(paths, consumer.Path)
+				}
+			}
+			cw.Write([]string{
+				library.Module,
+				strconv.FormatBool(library.Internal),
+				usage.Version,
+				strconv.Itoa(len(usage.Projects)),
+				strings.Join(paths, " "),
+				library.LatestInUse,
+				library.LatestAvailable,
+				strconv.Itoa(usage.Drift.Major),
+				strconv.Itoa(usage.Drift.Minor),
+				strconv.Itoa(usage.Drift.Patch),
+				strconv.Itoa(usage.Drift.DaysBehind),
+				strconv.Itoa(usage.Drift.Score),
+			})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
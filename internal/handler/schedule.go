@@ -0,0 +1,131 @@
+// internal/handler/schedule.go
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gitlab-list/internal/repository"
+	"gitlab-list/internal/service"
+)
+
+// ScheduleHandler handles HTTP requests for the scheduled jobs
+type ScheduleHandler struct {
+	schedule *service.ScheduleService
+}
+
+// NewScheduleHandler creates a new schedule handler
+func NewScheduleHandler(schedule *service.ScheduleService) *ScheduleHandler {
+	return &ScheduleHandler{
+		schedule: schedule,
+	}
+}
+
+// ListJobs handles GET /api/schedule
+func (h *ScheduleHandler) ListJobs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	jobs, err := h.schedule.ListJobs()
+	if err != nil {
+		writeScheduleError(w, "Failed to list jobs", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"jobs":  jobs,
+		"count": len(jobs),
+	})
+}
+
+// HandleJob handles GET /api/schedule/{name} and POST /api/schedule/{name}/run, /pause and /resume
+func (h *ScheduleHandler) HandleJob(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/schedule/"), "/"), "/")
+	name := parts[0]
+	if name == "" || len(parts) > 2 {
+		http.NotFound(w, r)
+		return
+	}
+
+	if len(parts) == 1 {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		limit := 20
+		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+			value, err := strconv.Atoi(limitStr)
+			if err != nil || value < 0 {
+				http.Error(w, "Invalid limit", http.StatusBadRequest)
+				return
+			}
+			limit = value
+		}
+		job, err := h.schedule.GetJob(name, limit)
+		if err != nil {
+			writeScheduleError(w, "Failed to get job", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(job)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var message string
+	var err error
+	var job interface{}
+	switch parts[1] {
+	case "run":
+		job, err = h.schedule.Trigger(name)
+		message = fmt.Sprintf("Job %s will start within the scheduler's poll interval", name)
+	case "pause":
+		job, err = h.schedule.SetPaused(name, true)
+		message = fmt.Sprintf("Scheduled runs of job %s are paused", name)
+	case "resume":
+		job, err = h.schedule.SetPaused(name, false)
+		message = fmt.Sprintf("Scheduled runs of job %s are resumed", name)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		writeScheduleError(w, "Failed to update job", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": message,
+		"job":     job,
+	})
+}
+
+// writeScheduleError reports a missing store as 503 and unknown jobs as 404
+func writeScheduleError(w http.ResponseWriter, message string, err error) {
+	switch {
+	case errors.Is(err, repository.ErrNoStore):
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":   "Schedule unavailable",
+			"message": "Scheduled jobs are shared through the cache store. Please configure STORE.",
+			"details": err.Error(),
+		})
+	case errors.Is(err, repository.ErrJobNotFound):
+		http.Error(w, fmt.Sprintf("%s: %v", message, err), http.StatusNotFound)
+	default:
+		http.Error(w, fmt.Sprintf("%s: %v", message, err), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,228 @@
+// internal/handler/snapshot.go
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitlab-list/internal/domain"
+	"gitlab-list/internal/repository"
+	"gitlab-list/internal/service"
+)
+
+// GetSnapshots handles GET /api/snapshots
+func (h *ProjectHandler) GetSnapshots(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	snapshots, err := h.projectService.GetSnapshots()
+	if err != nil {
+		writeSnapshotError(w, "Failed to list snapshots", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"snapshots": snapshots,
+		"count":     len(snapshots),
+	})
+}
+
+// CompactSnapshots handles POST /api/snapshots/compact
+func (h *ProjectHandler) CompactSnapshots(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	deleted, err := h.projectService.CompactSnapshots()
+	if err != nil {
+		writeSnapshotError(w, "Failed to compact snapshots", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Snapshots compacted",
+		"deleted": deleted,
+		"count":   len(deleted),
+	})
+}
+
+// GetGoVersionTrend handles GET /api/trends/go-versions
+func (h *ProjectHandler) GetGoVersionTrend(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	opts, err := parseTrendOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	trend, err := h.projectService.GetGoVersionTrend(opts)
+	if err != nil {
+		writeSnapshotError(w, "Failed to build Go version trend", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(trend)
+}
+
+// GetLibraryAdoption handles GET /api/trends/library?module=&version=
+func (h *ProjectHandler) GetLibraryAdoption(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	modPath := r.URL.Query().Get("module")
+	version := r.URL.Query().Get("version")
+	if modPath == "" || version == "" {
+		http.Error(w, "Missing required parameters: module and version", http.StatusBadRequest)
+		return
+	}
+
+	opts, err := parseTrendOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	adoption, err := h.projectService.GetLibraryAdoption(modPath, version, opts)
+	if err != nil {
+		writeSnapshotError(w, "Failed to build library adoption", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(adoption)
+}
+
+// GetSnapshotDiff handles GET /api/diff?from=&to=&ref=&format=json|markdown
+func (h *ProjectHandler) GetSnapshotDiff(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	diff, err := h.projectService.DiffSnapshots(query.Get("from"), query.Get("to"), query.Get("ref"))
+	if err != nil {
+		writeSnapshotError(w, "Failed to diff snapshots", err)
+		return
+	}
+	writeDiff(w, r, diff)
+}
+
+// GetProjectDiff handles GET /api/projects/{id}/diff?from=&to=&format=json|markdown.
+// The project is read live from GitLab on both refs, with the Bearer token when one is sent.
+func (h *ProjectHandler) GetProjectDiff(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract project ID from URL path
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) < 4 {
+		http.Error(w, "Invalid URL path", http.StatusBadRequest)
+		return
+	}
+	projectID, err := strconv.Atoi(parts[3])
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	to := r.URL.Query().Get("to")
+	if to == "" {
+		http.Error(w, "Missing required parameter: to", http.StatusBadRequest)
+		return
+	}
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	diff, err := h.projectService.DiffProjectRefs(projectID, r.URL.Query().Get("from"), to, token)
+	if err != nil {
+		writeSnapshotError(w, "Failed to diff refs", err)
+		return
+	}
+	writeDiff(w, r, diff)
+}
+
+// writeDiff writes a diff as JSON, or as a Markdown changelog for format=markdown
+func writeDiff(w http.ResponseWriter, r *http.Request, diff *domain.FleetDiff) {
+	if format := r.URL.Query().Get("format"); format == "markdown" || format == "md" {
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		w.Write([]byte(service.DiffMarkdown(diff)))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(diff)
+}
+
+// parseTrendOptions reads ref, interval, from and to (RFC 3339 or YYYY-MM-DD)
+func parseTrendOptions(r *http.Request) (domain.TrendOptions, error) {
+	opts := domain.TrendOptions{
+		Ref:      r.URL.Query().Get("ref"),
+		Interval: r.URL.Query().Get("interval"),
+	}
+	for _, param := range []struct {
+		name   string
+		target *time.Time
+		endDay bool
+	}{
+		{"from", &opts.From, false},
+		{"to", &opts.To, true},
+	} {
+		value := r.URL.Query().Get(param.name)
+		if value == "" {
+			continue
+		}
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			*param.target = t
+			continue
+		}
+		t, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return opts, fmt.Errorf("Invalid %s: use RFC 3339 or YYYY-MM-DD", param.name)
+		}
+		if param.endDay {
+			// A bare "to" date includes the whole day
+			t = t.Add(24*time.Hour - time.Nanosecond)
+		}
+		*param.target = t
+	}
+	return opts, nil
+}
+
+// writeSnapshotError reports a missing cache store as 503, unknown snapshots as 404 and invalid parameters as 400
+func writeSnapshotError(w http.ResponseWriter, message string, err error) {
+	switch {
+	case errors.Is(err, repository.ErrNoStore):
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":   "Snapshot service unavailable",
+			"message": "No cache store is configured. Please set STORE to keep snapshots.",
+			"details": err.Error(),
+		})
+	case strings.HasPrefix(err.Error(), "snapshot ") && strings.Contains(err.Error(), "not found"):
+		http.Error(w, fmt.Sprintf("%s: %v", message, err), http.StatusNotFound)
+	case strings.HasPrefix(err.Error(), "invalid "):
+		http.Error(w, fmt.Sprintf("%s: %v", message, err), http.StatusBadRequest)
+	default:
+		http.Error(w, fmt.Sprintf("%s: %v", message, err), statusForError(err))
+	}
+}
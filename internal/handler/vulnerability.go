@@ -0,0 +1,162 @@
+// internal/handler/vulnerability.go
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gitlab-list/internal/repository"
+	"gitlab-list/internal/service"
+)
+
+// VulnerabilityHandler handles HTTP requests for vulnerability reports
+type VulnerabilityHandler struct {
+	vulnerabilities *service.VulnerabilityService
+	queue           *service.JobQueue
+}
+
+// NewVulnerabilityHandler creates a new vulnerability handler; fixes run on queue
+func NewVulnerabilityHandler(vulnerabilities *service.VulnerabilityService, queue *service.JobQueue) *VulnerabilityHandler {
+	return &VulnerabilityHandler{
+		vulnerabilities: vulnerabilities,
+		queue:           queue,
+	}
+}
+
+// GetVulnerabilities handles GET /api/vulnerabilities
+func (h *VulnerabilityHandler) GetVulnerabilities(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	opts := service.VulnerabilityOptions{
+		Ref:        r.URL.Query().Get("ref"),
+		AdvisoryID: r.URL.Query().Get("advisory"),
+	}
+	if projectIDStr := r.URL.Query().Get("project_id"); projectIDStr != "" {
+		projectID, err := strconv.Atoi(projectIDStr)
+		if err != nil {
+			http.Error(w, "Invalid project ID", http.StatusBadRequest)
+			return
+		}
+		opts.ProjectID = projectID
+	}
+
+	report, err := h.vulnerabilities.GetReport(opts)
+	if err != nil {
+		h.writeError(w, "Failed to build vulnerability report", err)
+		return
+	}
+
+	// view=projects or view=advisories returns only one side of the report
+	switch r.URL.Query().Get("view") {
+	case "projects":
+		report.Advisories = nil
+	case "advisories":
+		report.Projects = nil
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// ReloadDatabase handles POST /api/vulnerabilities/reload
+func (h *VulnerabilityHandler) ReloadDatabase(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	database, err := h.vulnerabilities.Reload()
+	if err != nil {
+		h.writeError(w, "Failed to load vulnerability database", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":  "Vulnerability database loaded",
+		"database": database,
+	})
+}
+
+// FixProject handles POST /api/vulnerabilities/fix and opens a merge request with the recommended upgrades
+func (h *VulnerabilityHandler) FixProject(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get authorization token
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		http.Error(w, "Authorization header required", http.StatusUnauthorized)
+		return
+	}
+
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if token == authHeader {
+		http.Error(w, "Invalid authorization format. Use 'Bearer <token>'", http.StatusBadRequest)
+		return
+	}
+
+	// Parse request body
+	var request struct {
+		ProjectID  int    `json:"project_id"`
+		ModuleDir  string `json:"module_dir,omitempty"`
+		Ref        string `json:"ref,omitempty"`
+		BranchName string `json:"branch_name,omitempty"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if request.ProjectID == 0 {
+		http.Error(w, "Missing required field: project_id", http.StatusBadRequest)
+		return
+	}
+
+	// Fix the project as a queued job; the result has the shape this endpoint answered with before it
+	// was queued
+	job, err := h.queue.Submit(service.JobKindProjectUpdate, strconv.Itoa(request.ProjectID), func(ctx context.Context, progress service.ProgressFunc) (interface{}, error) {
+		results, err := h.vulnerabilities.FixProjectContext(ctx, request.ProjectID, request.ModuleDir, request.Ref, request.BranchName, token, progress)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{
+			"project_id": request.ProjectID,
+			"results":    results,
+			"count":      len(results),
+		}, nil
+	})
+	if err != nil {
+		writeQueueError(w, "Failed to queue vulnerability fix", err)
+		return
+	}
+
+	writeQueued(w, job)
+}
+
+// writeError reports a missing cache store or vulnerability database as 503, anything else by error type
+func (h *VulnerabilityHandler) writeError(w http.ResponseWriter, message string, err error) {
+	if errors.Is(err, repository.ErrNoStore) ||
+		strings.Contains(err.Error(), "vulnerability database not configured") {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":   "Vulnerability service unavailable",
+			"message": "Vulnerability reports need the project cache (STORE) and an OSV dump (OSV_DB).",
+			"details": err.Error(),
+		})
+		return
+	}
+	http.Error(w, fmt.Sprintf("%s: %v", message, err), statusForError(err))
+}
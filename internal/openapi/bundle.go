@@ -0,0 +1,412 @@
+// internal/openapi/bundle.go
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxBundleFiles caps how many referenced files are loaded for a single spec
+const maxBundleFiles = 100
+
+// Fetcher loads a repository file referenced by a spec
+type Fetcher func(filePath string) ([]byte, error)
+
+// Bundle resolves the external $refs of the spec at rootPath into a single document.
+// Referenced fragments are inlined in place and refs to URLs are kept as they are. A ref that recurses into
+// a fragment of another file, such as a tree schema, points to a copy of it hoisted into components/schemas
+// (definitions for Swagger 2.0); one that recurses into the root document points there.
+// It returns the bundled document, in the root's format, and the paths of the files that were inlined.
+// When the spec references no other files, root is returned unchanged.
+func Bundle(rootPath string, root []byte, fetch Fetcher) ([]byte, []string, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(root, &doc); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %s: %w", rootPath, err)
+	}
+	if len(doc.Content) == 0 {
+		return root, nil, nil
+	}
+
+	b := &bundler{
+		rootPath: rootPath,
+		fetch:    fetch,
+		docs:     map[string]*yaml.Node{rootPath: doc.Content[0]},
+		hoisted:  make(map[string]string),
+	}
+	if err := b.resolve(doc.Content[0], rootPath, nil); err != nil {
+		return nil, nil, err
+	}
+	if len(b.files) == 0 {
+		return root, nil, nil
+	}
+	if err := b.addSchemas(); err != nil {
+		return nil, nil, err
+	}
+
+	if formatOf(rootPath, root) == "json" {
+		var buf bytes.Buffer
+		if err := writeJSON(&buf, doc.Content[0]); err != nil {
+			return nil, nil, err
+		}
+		var out bytes.Buffer
+		if err := json.Indent(&out, buf.Bytes(), "", "  "); err != nil {
+			return nil, nil, fmt.Errorf("failed to encode bundled spec: %w", err)
+		}
+		return out.Bytes(), b.files, nil
+	}
+
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, nil, fmt.Errorf("failed to encode bundled spec: %w", err)
+	}
+	enc.Close()
+	return out.Bytes(), b.files, nil
+}
+
+// bundler holds the parsed documents of one Bundle call
+type bundler struct {
+	rootPath string
+	fetch    Fetcher
+	docs     map[string]*yaml.Node
+	files    []string
+
+	hoisted map[string]string // Schema names of the hoisted fragments by file#fragment
+	schemas []*yaml.Node      // Name and value pairs of the hoisted schemas
+}
+
+// resolve walks node, which belongs to the file base, and inlines every $ref that leaves the root document.
+// stack holds the refs currently being expanded and is used to detect cycles.
+func (b *bundler) resolve(node *yaml.Node, base string, stack []string) error {
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, child := range node.Content {
+			if err := b.resolve(child, base, stack); err != nil {
+				return err
+			}
+		}
+	case yaml.MappingNode:
+		if ref, ok := refValue(node); ok {
+			return b.inline(node, ref, base, stack)
+		}
+		for i := 1; i < len(node.Content); i += 2 {
+			if err := b.resolve(node.Content[i], base, stack); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// inline replaces a {$ref: ...} mapping with a copy of the fragment it points to
+func (b *bundler) inline(node *yaml.Node, ref, base string, stack []string) error {
+	if strings.Contains(ref, "://") {
+		return nil
+	}
+
+	file, fragment, _ := strings.Cut(ref, "#")
+	if file == "" {
+		// Local refs of the root document stay valid in the bundle
+		if base == b.rootPath {
+			return nil
+		}
+		file = base
+	} else {
+		file = path.Clean(path.Join(path.Dir(base), file))
+	}
+
+	key := file + "#" + fragment
+	for _, seen := range stack {
+		if seen == key {
+			return b.recurse(node, file, fragment, key)
+		}
+	}
+
+	doc, err := b.load(file)
+	if err != nil {
+		return err
+	}
+	target, err := lookup(doc, fragment)
+	if err != nil {
+		return fmt.Errorf("failed to resolve $ref %q in %s: %w", ref, base, err)
+	}
+
+	replacement := copyNode(target)
+	if err := b.resolve(replacement, file, append(stack, key)); err != nil {
+		return err
+	}
+	*node = *replacement
+	return nil
+}
+
+// recurse points a $ref back into a fragment that is being inlined: at the fragment itself in the root
+// document, and at a hoisted copy of it in the schemas of the bundle for any other file
+func (b *bundler) recurse(node *yaml.Node, file, fragment, key string) error {
+	if file == b.rootPath {
+		setRef(node, "#"+fragment)
+		return nil
+	}
+
+	name, ok := b.hoisted[key]
+	if !ok {
+		doc, err := b.load(file)
+		if err != nil {
+			return err
+		}
+		target, err := lookup(doc, fragment)
+		if err != nil {
+			return fmt.Errorf("failed to resolve $ref to %s: %w", key, err)
+		}
+
+		// Named before resolving so the refs of the copy to itself find it
+		name = b.schemaName(file, fragment)
+		b.hoisted[key] = name
+		schema := copyNode(target)
+		if err := b.resolve(schema, file, []string{key}); err != nil {
+			return err
+		}
+		b.schemas = append(b.schemas, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name}, schema)
+	}
+	setRef(node, b.schemasPointer()+"/"+name)
+	return nil
+}
+
+var schemaUnsafe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// schemaName names a hoisted fragment after its last pointer segment, or its file for a whole file,
+// with a number appended when the root or an earlier fragment already uses the name
+func (b *bundler) schemaName(file, fragment string) string {
+	base := path.Base(strings.TrimSuffix(fragment, "/"))
+	if strings.Trim(fragment, "/") == "" {
+		base = strings.TrimSuffix(path.Base(file), path.Ext(file))
+	}
+	base = schemaUnsafe.ReplaceAllString(base, "_")
+
+	taken := make(map[string]bool)
+	if schemas := b.schemasNode(false); schemas != nil {
+		for i := 0; i < len(schemas.Content); i += 2 {
+			taken[schemas.Content[i].Value] = true
+		}
+	}
+	for _, name := range b.hoisted {
+		taken[name] = true
+	}
+	name := base
+	for n := 2; taken[name]; n++ {
+		name = fmt.Sprintf("%s%d", base, n)
+	}
+	return name
+}
+
+// swagger reports whether the root document is a Swagger 2.0 spec
+func (b *bundler) swagger() bool {
+	return mappingValue(b.docs[b.rootPath], "swagger") != nil
+}
+
+// schemasPointer is the local ref of the schemas of the root document
+func (b *bundler) schemasPointer() string {
+	if b.swagger() {
+		return "#/definitions"
+	}
+	return "#/components/schemas"
+}
+
+// schemasNode returns the schemas mapping of the root document, creating it when create is set
+func (b *bundler) schemasNode(create bool) *yaml.Node {
+	node := b.docs[b.rootPath]
+	keys := []string{"components", "schemas"}
+	if b.swagger() {
+		keys = []string{"definitions"}
+	}
+	for _, key := range keys {
+		next := mappingValue(node, key)
+		if next == nil {
+			if !create || node.Kind != yaml.MappingNode {
+				return nil
+			}
+			next = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, next)
+		}
+		node = next
+	}
+	return node
+}
+
+// addSchemas appends the hoisted fragments to the schemas of the root document
+func (b *bundler) addSchemas() error {
+	if len(b.schemas) == 0 {
+		return nil
+	}
+	schemas := b.schemasNode(true)
+	if schemas == nil || schemas.Kind != yaml.MappingNode {
+		return fmt.Errorf("failed to hoist recursive $refs: %s of %s is not a mapping", b.schemasPointer(), b.rootPath)
+	}
+	schemas.Content = append(schemas.Content, b.schemas...)
+	return nil
+}
+
+// load returns the parsed document at filePath, fetching it on first use
+func (b *bundler) load(filePath string) (*yaml.Node, error) {
+	if doc, ok := b.docs[filePath]; ok {
+		return doc, nil
+	}
+	if len(b.files) >= maxBundleFiles {
+		return nil, fmt.Errorf("spec references more than %d files", maxBundleFiles)
+	}
+
+	data, err := b.fetch(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", filePath, err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filePath, err)
+	}
+	root := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	if len(doc.Content) > 0 {
+		root = doc.Content[0]
+	}
+
+	b.docs[filePath] = root
+	b.files = append(b.files, filePath)
+	return root, nil
+}
+
+// refValue returns the $ref of a mapping node, if it has one
+func refValue(node *yaml.Node) (string, bool) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == "$ref" && node.Content[i+1].Kind == yaml.ScalarNode {
+			return node.Content[i+1].Value, true
+		}
+	}
+	return "", false
+}
+
+// setRef changes the $ref of a mapping node
+func setRef(node *yaml.Node, ref string) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == "$ref" {
+			node.Content[i+1].Value = ref
+			return
+		}
+	}
+}
+
+// mappingValue returns the value of key in a mapping node, or nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// lookup resolves a JSON pointer fragment such as "/components/schemas/Pet" within doc
+func lookup(doc *yaml.Node, fragment string) (*yaml.Node, error) {
+	fragment = strings.TrimPrefix(fragment, "/")
+	if fragment == "" {
+		return doc, nil
+	}
+
+	node := doc
+	for _, token := range strings.Split(fragment, "/") {
+		if unescaped, err := url.PathUnescape(token); err == nil {
+			token = unescaped
+		}
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+
+		for node.Kind == yaml.AliasNode {
+			node = node.Alias
+		}
+
+		var next *yaml.Node
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == token {
+					next = node.Content[i+1]
+					break
+				}
+			}
+		case yaml.SequenceNode:
+			if idx, err := strconv.Atoi(token); err == nil && idx >= 0 && idx < len(node.Content) {
+				next = node.Content[idx]
+			}
+		}
+		if next == nil {
+			return nil, fmt.Errorf("pointer segment %q not found", token)
+		}
+		node = next
+	}
+	return node, nil
+}
+
+// copyNode deep-copies a node so an inlined fragment can be modified independently
+func copyNode(node *yaml.Node) *yaml.Node {
+	clone := *node
+	if node.Content != nil {
+		clone.Content = make([]*yaml.Node, len(node.Content))
+		for i, child := range node.Content {
+			clone.Content[i] = copyNode(child)
+		}
+	}
+	return &clone
+}
+
+// writeJSON encodes node as compact JSON, keeping the key order of the source document
+func writeJSON(buf *bytes.Buffer, node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.AliasNode:
+		return writeJSON(buf, node.Alias)
+	case yaml.MappingNode:
+		buf.WriteByte('{')
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, _ := json.Marshal(node.Content[i].Value)
+			buf.Write(key)
+			buf.WriteByte(':')
+			if err := writeJSON(buf, node.Content[i+1]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case yaml.SequenceNode:
+		buf.WriteByte('[')
+		for i, child := range node.Content {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSON(buf, child); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	default:
+		var v interface{}
+		if err := node.Decode(&v); err != nil {
+			return fmt.Errorf("failed to decode value %q: %w", node.Value, err)
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("failed to encode value %q: %w", node.Value, err)
+		}
+		buf.Write(data)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+// internal/openapi/detect.go
+package openapi
+
+import (
+	"bytes"
+	"path"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MaxCandidates caps how many files of a repository are downloaded for content sniffing
+const MaxCandidates = 200
+
+// maxSpecSize is the largest file that is still parsed as a potential spec
+const maxSpecSize = 10 << 20
+
+// skippedDirs never contain a service's own API specification
+var skippedDirs = map[string]bool{
+	"vendor":       true,
+	"node_modules": true,
+	".git":         true,
+	"testdata":     true,
+	"third_party":  true,
+}
+
+// skippedFiles are well-known YAML/JSON files that are never specs
+var skippedFiles = map[string]bool{
+	"package.json":        true,
+	"package-lock.json":   true,
+	"composer.json":       true,
+	"tsconfig.json":       true,
+	".gitlab-ci.yml":      true,
+	".golangci.yml":       true,
+	".golangci.yaml":      true,
+	"docker-compose.yml":  true,
+	"docker-compose.yaml": true,
+	"renovate.json":       true,
+}
+
+// Info describes a document recognised as OpenAPI 3.x or Swagger 2.0
+type Info struct {
+	Version string // value of the "openapi" or "swagger" field
+	Title   string // info.title
+	Format  string // "yaml" or "json"
+}
+
+// Candidates filters repository paths down to YAML/JSON files that may hold a spec.
+// Paths whose name hints at an API spec come first so the MaxCandidates cap never drops them.
+func Candidates(paths []string) []string {
+	var out []string
+	for _, p := range paths {
+		if isCandidate(p) {
+			out = append(out, p)
+		}
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		return nameScore(out[i]) > nameScore(out[j])
+	})
+
+	if len(out) > MaxCandidates {
+		out = out[:MaxCandidates]
+	}
+	return out
+}
+
+// isCandidate reports whether a path has a spec extension and lives outside skipped directories
+func isCandidate(p string) bool {
+	switch strings.ToLower(path.Ext(p)) {
+	case ".yaml", ".yml", ".json":
+	default:
+		return false
+	}
+
+	if skippedFiles[strings.ToLower(path.Base(p))] {
+		return false
+	}
+	for _, dir := range strings.Split(path.Dir(p), "/") {
+		if skippedDirs[dir] {
+			return false
+		}
+	}
+	return true
+}
+
+// nameScore ranks file names that usually hold API specs
+func nameScore(p string) int {
+	name := strings.ToLower(path.Base(p))
+	switch {
+	case strings.Contains(name, "openapi"), strings.Contains(name, "swagger"):
+		return 2
+	case strings.Contains(name, "api"), strings.Contains(strings.ToLower(p), "spec"):
+		return 1
+	}
+	return 0
+}
+
+// Detect reports whether data is an OpenAPI 3.x or Swagger 2.0 document, judging by content only
+func Detect(filePath string, data []byte) (Info, bool) {
+	if len(data) == 0 || len(data) > maxSpecSize {
+		return Info{}, false
+	}
+	// Cheap pre-check before a full parse
+	if !bytes.Contains(data, []byte("openapi")) && !bytes.Contains(data, []byte("swagger")) {
+		return Info{}, false
+	}
+
+	var doc struct {
+		OpenAPI string `yaml:"openapi"`
+		Swagger string `yaml:"swagger"`
+		Info    struct {
+			Title string `yaml:"title"`
+		} `yaml:"info"`
+	}
+	// JSON is a subset of YAML, so one decoder covers both formats
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return Info{}, false
+	}
+
+	info := Info{Title: doc.Info.Title, Format: formatOf(filePath, data)}
+	switch {
+	case strings.HasPrefix(doc.OpenAPI, "3."):
+		info.Version = doc.OpenAPI
+	case doc.Swagger == "2.0":
+		info.Version = doc.Swagger
+	default:
+		return Info{}, false
+	}
+	return info, true
+}
+
+// formatOf returns "json" for JSON documents and "yaml" otherwise
+func formatOf(filePath string, data []byte) string {
+	if strings.EqualFold(path.Ext(filePath), ".json") {
+		return "json"
+	}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		return "json"
+	}
+	return "yaml"
+}
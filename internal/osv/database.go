@@ -0,0 +1,124 @@
+// internal/osv/database.go
+package osv
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Database is an in-memory set of OSV entries indexed by Go module path
+type Database struct {
+	Path     string
+	LoadedAt time.Time
+	byID     map[string]*Entry
+	byModule map[string][]*Entry
+}
+
+// Load reads an OSV dump from disk: a zip archive such as the Go vulndb export, or a directory
+// of JSON files. Files that are not OSV entries (e.g. index files) and withdrawn entries are skipped.
+func Load(path string) (*Database, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open vulnerability database: %w", err)
+	}
+
+	db := &Database{
+		Path:     path,
+		LoadedAt: time.Now(),
+		byID:     make(map[string]*Entry),
+		byModule: make(map[string][]*Entry),
+	}
+	if info.IsDir() {
+		err = db.loadDir(path)
+	} else {
+		err = db.loadZip(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+func (db *Database) loadDir(dir string) error {
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(d.Name(), ".json") {
+			return nil
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", p, err)
+		}
+		db.add(data)
+		return nil
+	})
+}
+
+func (db *Database) loadZip(path string) error {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return fmt.Errorf("failed to open vulnerability archive: %w", err)
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() || !strings.HasSuffix(f.Name, ".json") {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", f.Name, err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", f.Name, err)
+		}
+		db.add(data)
+	}
+	return nil
+}
+
+// add indexes data if it is a current OSV entry affecting Go modules
+func (db *Database) add(data []byte) {
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.ID == "" || entry.Withdrawn != nil {
+		return
+	}
+	if db.byID[entry.ID] != nil {
+		// Dumps may carry an entry twice, e.g. under ID/ and at the root
+		return
+	}
+
+	seen := make(map[string]bool)
+	for _, affected := range entry.Affected {
+		name := affected.Package.Name
+		if affected.Package.Ecosystem != EcosystemGo || seen[name] {
+			continue
+		}
+		seen[name] = true
+		db.byModule[name] = append(db.byModule[name], &entry)
+	}
+	if len(seen) > 0 {
+		db.byID[entry.ID] = &entry
+	}
+}
+
+// Len returns the number of entries affecting Go modules
+func (db *Database) Len() int {
+	return len(db.byID)
+}
+
+// Entry returns the entry with the given ID, or nil
+func (db *Database) Entry(id string) *Entry {
+	return db.byID[id]
+}
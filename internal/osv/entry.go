@@ -0,0 +1,68 @@
+// internal/osv/entry.go
+package osv
+
+import "time"
+
+// Ecosystem of Go modules in OSV entries
+const EcosystemGo = "Go"
+
+// Packages the Go vulnerability database uses for Go itself
+const (
+	ModuleStdlib    = "stdlib"
+	ModuleToolchain = "toolchain"
+)
+
+// Entry is an OSV advisory (https://ossf.github.io/osv-schema/), reduced to the fields we match on
+type Entry struct {
+	ID        string      `json:"id"`
+	Summary   string      `json:"summary,omitempty"`
+	Details   string      `json:"details,omitempty"`
+	Aliases   []string    `json:"aliases,omitempty"`
+	Published time.Time   `json:"published,omitempty"`
+	Modified  time.Time   `json:"modified"`
+	Withdrawn *time.Time  `json:"withdrawn,omitempty"`
+	Affected  []Affected  `json:"affected"`
+	Refs      []Reference `json:"references,omitempty"`
+}
+
+// Affected lists the vulnerable versions of one package
+type Affected struct {
+	Package  Package  `json:"package"`
+	Ranges   []Range  `json:"ranges,omitempty"`
+	Versions []string `json:"versions,omitempty"`
+}
+
+// Package identifies a package within an ecosystem; for Go the name is the module path
+type Package struct {
+	Name      string `json:"name"`
+	Ecosystem string `json:"ecosystem"`
+}
+
+// Range is a sequence of introduced/fixed events
+type Range struct {
+	Type   string  `json:"type"` // "SEMVER" for Go
+	Events []Event `json:"events"`
+}
+
+// Event is a single boundary of a range; exactly one field is set
+type Event struct {
+	Introduced   string `json:"introduced,omitempty"`
+	Fixed        string `json:"fixed,omitempty"`
+	LastAffected string `json:"last_affected,omitempty"`
+}
+
+// Reference is a link with more information
+type Reference struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+// URL returns the advisory page of the entry
+func (e *Entry) URL() string {
+	for _, ref := range e.Refs {
+		if ref.Type == "ADVISORY" || ref.Type == "WEB" {
+			return ref.URL
+		}
+	}
+	return "https://osv.dev/vulnerability/" + e.ID
+}
@@ -0,0 +1,138 @@
+// internal/osv/match.go
+package osv
+
+import (
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// Match is an entry affecting a queried version
+type Match struct {
+	Entry   *Entry
+	Module  string // Matched module path, "stdlib" or "toolchain"
+	FixedIn string // Lowest fixed version above the queried one, in the queried form; empty when unfixed
+}
+
+// Query returns the entries affecting module modPath at version (e.g. "v1.2.3")
+func (db *Database) Query(modPath, version string) []Match {
+	if !semver.IsValid(version) {
+		return nil
+	}
+	var matches []Match
+	for _, entry := range db.byModule[modPath] {
+		if affected, fixed := entryAffects(entry, modPath, version); affected {
+			if fixed != "" {
+				fixed = "v" + fixed
+			}
+			matches = append(matches, Match{Entry: entry, Module: modPath, FixedIn: fixed})
+		}
+	}
+	return matches
+}
+
+// QueryGo returns the standard library and toolchain entries affecting a Go version
+// such as "1.21", "1.22.3" or "go1.23rc1". FixedIn is returned as a Go version, e.g. "1.22.5".
+func (db *Database) QueryGo(goVersion string) []Match {
+	version := GoSemver(goVersion)
+	if version == "" {
+		return nil
+	}
+
+	var matches []Match
+	for _, modPath := range []string{ModuleStdlib, ModuleToolchain} {
+		for _, entry := range db.byModule[modPath] {
+			if affected, fixed := entryAffects(entry, modPath, version); affected {
+				matches = append(matches, Match{Entry: entry, Module: modPath, FixedIn: fixed})
+			}
+		}
+	}
+	return matches
+}
+
+// GoSemver converts a Go version ("1.21", "go1.22.3", "1.23rc1") to semver ("v1.21.0", "v1.22.3", "v1.23.0-rc1").
+// It returns "" for versions it cannot convert.
+func GoSemver(goVersion string) string {
+	v := strings.TrimPrefix(strings.TrimSpace(goVersion), "go")
+	pre := ""
+	if i := strings.IndexAny(v, "abcdefghijklmnopqrstuvwxyz"); i >= 0 {
+		v, pre = v[:i], "-"+v[i:]
+	}
+	parts := strings.Split(v, ".")
+	for len(parts) < 3 {
+		parts = append(parts, "0")
+	}
+	version := "v" + strings.Join(parts, ".") + pre
+	if !semver.IsValid(version) {
+		return ""
+	}
+	return version
+}
+
+// entryAffects reports whether any affected block of entry for modPath includes version,
+// and the fixed version (without "v") that closes the matching range
+func entryAffects(entry *Entry, modPath, version string) (bool, string) {
+	for _, affected := range entry.Affected {
+		if affected.Package.Ecosystem != EcosystemGo || affected.Package.Name != modPath {
+			continue
+		}
+		for _, v := range affected.Versions {
+			if semver.Compare("v"+v, version) == 0 {
+				return true, ""
+			}
+		}
+		for _, r := range affected.Ranges {
+			if r.Type != "SEMVER" {
+				continue
+			}
+			if ok, fixed := rangeAffects(r, version); ok {
+				return true, fixed
+			}
+		}
+	}
+	return false, ""
+}
+
+// rangeAffects evaluates the events of a SEMVER range in version order
+func rangeAffects(r Range, version string) (bool, string) {
+	events := append([]Event(nil), r.Events...)
+	sort.SliceStable(events, func(i, j int) bool {
+		return semver.Compare(eventVersion(events[i]), eventVersion(events[j])) < 0
+	})
+
+	affected := false
+	for _, e := range events {
+		switch {
+		case e.Introduced != "":
+			if e.Introduced == "0" || semver.Compare(version, "v"+e.Introduced) >= 0 {
+				affected = true
+			}
+		case e.Fixed != "":
+			if semver.Compare(version, "v"+e.Fixed) >= 0 {
+				affected = false
+			} else if affected {
+				return true, e.Fixed
+			}
+		case e.LastAffected != "":
+			if semver.Compare(version, "v"+e.LastAffected) > 0 {
+				affected = false
+			}
+		}
+	}
+	return affected, ""
+}
+
+// eventVersion returns the semver of an event for sorting; "introduced: 0" sorts first
+func eventVersion(e Event) string {
+	switch {
+	case e.Introduced == "0":
+		return "v0.0.0-0"
+	case e.Introduced != "":
+		return "v" + e.Introduced
+	case e.Fixed != "":
+		return "v" + e.Fixed
+	default:
+		return "v" + e.LastAffected
+	}
+}
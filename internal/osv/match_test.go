@@ -0,0 +1,156 @@
+// internal/osv/match_test.go
+package osv
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// testDatabase indexes entries as Load would
+func testDatabase(t *testing.T, entries ...Entry) *Database {
+	t.Helper()
+	db := &Database{byID: make(map[string]*Entry), byModule: make(map[string][]*Entry)}
+	for _, entry := range entries {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		db.add(data)
+	}
+	return db
+}
+
+func goEntry(id, module string, ranges ...[]Event) Entry {
+	affected := Affected{Package: Package{Name: module, Ecosystem: EcosystemGo}}
+	for _, events := range ranges {
+		affected.Ranges = append(affected.Ranges, Range{Type: "SEMVER", Events: events})
+	}
+	return Entry{ID: id, Affected: []Affected{affected}}
+}
+
+func TestQuery(t *testing.T) {
+	db := testDatabase(t,
+		// Two ranges of one package
+		goEntry("MULTI", "example.com/multi",
+			[]Event{{Introduced: "0"}, {Fixed: "1.2.0"}},
+			[]Event{{Introduced: "1.5.0"}, {Fixed: "1.5.3"}},
+		),
+		// Two ranges in one, listed out of order
+		goEntry("UNSORTED", "example.com/unsorted",
+			[]Event{{Introduced: "1.5.0"}, {Fixed: "1.5.3"}, {Introduced: "0"}, {Fixed: "1.2.0"}},
+		),
+		goEntry("LAST", "example.com/last",
+			[]Event{{Introduced: "2.0.0"}, {LastAffected: "2.3.1"}},
+		),
+		goEntry("UNFIXED", "example.com/unfixed",
+			[]Event{{Introduced: "0"}},
+		),
+	)
+
+	tests := []struct {
+		name     string
+		module   string
+		version  string
+		affected bool
+		fixedIn  string
+	}{
+		{"first range", "example.com/multi", "v1.1.0", true, "v1.2.0"},
+		{"first fix", "example.com/multi", "v1.2.0", false, ""},
+		{"between ranges", "example.com/multi", "v1.4.9", false, ""},
+		{"second range", "example.com/multi", "v1.5.0", true, "v1.5.3"},
+		{"second fix", "example.com/multi", "v1.5.3", false, ""},
+		{"pseudo-version in first range", "example.com/multi", "v0.0.0-20200101000000-abcdefabcdef", true, "v1.2.0"},
+		{"unsorted first range", "example.com/unsorted", "v1.0.0", true, "v1.2.0"},
+		{"unsorted between ranges", "example.com/unsorted", "v1.3.0", false, ""},
+		{"unsorted second range", "example.com/unsorted", "v1.5.2", true, "v1.5.3"},
+		{"before introduced", "example.com/last", "v1.9.0", false, ""},
+		{"introduced", "example.com/last", "v2.0.0", true, ""},
+		{"last affected", "example.com/last", "v2.3.1", true, ""},
+		{"after last affected", "example.com/last", "v2.3.2", false, ""},
+		{"introduced 0 without fix", "example.com/unfixed", "v0.1.0", true, ""},
+		{"introduced 0 without fix, latest", "example.com/unfixed", "v9.9.9", true, ""},
+		{"other module", "example.com/other", "v1.0.0", false, ""},
+		{"invalid version", "example.com/unfixed", "1.0.0", false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches := db.Query(tt.module, tt.version)
+			if !tt.affected {
+				if len(matches) != 0 {
+					t.Fatalf("Query(%s, %s) = %d matches, want none", tt.module, tt.version, len(matches))
+				}
+				return
+			}
+			if len(matches) != 1 {
+				t.Fatalf("Query(%s, %s) = %d matches, want 1", tt.module, tt.version, len(matches))
+			}
+			if matches[0].FixedIn != tt.fixedIn {
+				t.Errorf("FixedIn = %q, want %q", matches[0].FixedIn, tt.fixedIn)
+			}
+			if matches[0].Module != tt.module {
+				t.Errorf("Module = %q, want %q", matches[0].Module, tt.module)
+			}
+		})
+	}
+}
+
+func TestQueryGo(t *testing.T) {
+	db := testDatabase(t,
+		goEntry("STDLIB", ModuleStdlib,
+			[]Event{{Introduced: "0"}, {Fixed: "1.21.12"}, {Introduced: "1.22.0-0"}, {Fixed: "1.22.5"}},
+		),
+		goEntry("TOOLCHAIN", ModuleToolchain,
+			[]Event{{Introduced: "1.22.0-0"}, {LastAffected: "1.22.1"}},
+		),
+	)
+
+	tests := []struct {
+		version string
+		want    map[string]string // Fixed version per matched module
+	}{
+		{"1.21", map[string]string{ModuleStdlib: "1.21.12"}},
+		{"1.21.12", map[string]string{}},
+		{"go1.22.1", map[string]string{ModuleStdlib: "1.22.5", ModuleToolchain: ""}},
+		{"1.22rc1", map[string]string{ModuleStdlib: "1.22.5", ModuleToolchain: ""}},
+		{"1.22.3", map[string]string{ModuleStdlib: "1.22.5"}},
+		{"1.22.5", map[string]string{}},
+		{"1.23rc1", map[string]string{}},
+		{"not-a-version", map[string]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			got := make(map[string]string)
+			for _, match := range db.QueryGo(tt.version) {
+				got[match.Module] = match.FixedIn
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("QueryGo(%s) matched %v, want %v", tt.version, got, tt.want)
+			}
+			for module, fixed := range tt.want {
+				if gotFixed, ok := got[module]; !ok || gotFixed != fixed {
+					t.Errorf("QueryGo(%s) %s fixed in %q (matched %t), want %q", tt.version, module, gotFixed, ok, fixed)
+				}
+			}
+		})
+	}
+}
+
+func TestGoSemver(t *testing.T) {
+	tests := []struct {
+		version string
+		want    string
+	}{
+		{"1.21", "v1.21.0"},
+		{"go1.22.3", "v1.22.3"},
+		{"1.23rc1", "v1.23.0-rc1"},
+		{" 1.20 ", "v1.20.0"},
+		{"1", "v1.0.0"},
+		{"", ""},
+		{"1.x", ""},
+	}
+	for _, tt := range tests {
+		if got := GoSemver(tt.version); got != tt.want {
+			t.Errorf("GoSemver(%q) = %q, want %q", tt.version, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+// internal/policy/evaluate.go
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"gitlab-list/internal/domain"
+	"gitlab-list/internal/gomod"
+	"gitlab-list/internal/osv"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// Violation is a single rule broken by a project module
+type Violation struct {
+	Rule      string `json:"rule"`
+	Type      string `json:"type"`
+	Severity  string `json:"severity"`
+	Message   string `json:"message"`
+	ProjectID int    `json:"project_id"`
+	Project   string `json:"project"`              // Project path with namespace
+	ModuleDir string `json:"module_dir,omitempty"` // Empty for the root module
+	Module    string `json:"module,omitempty"`     // Offending requirement, if the rule is about one
+	Version   string `json:"version,omitempty"`    // Offending version (library or Go version)
+}
+
+// ProjectResult lists the violations of one project
+type ProjectResult struct {
+	ProjectID  int         `json:"project_id"`
+	Name       string      `json:"name"`
+	Path       string      `json:"path"`
+	Errors     int         `json:"errors"`
+	Warnings   int         `json:"warnings"`
+	Violations []Violation `json:"violations"`
+}
+
+// RuleResult lists the violations of one rule across the fleet
+type RuleResult struct {
+	Rule       Rule        `json:"rule"`
+	Projects   int         `json:"projects"` // Number of projects breaking the rule
+	Violations []Violation `json:"violations"`
+}
+
+// Report is the outcome of evaluating a policy against a set of projects
+type Report struct {
+	GeneratedAt time.Time       `json:"generated_at"`
+	Ref         string          `json:"ref,omitempty"`
+	Evaluated   int             `json:"evaluated"` // Number of projects checked
+	Errors      int             `json:"errors"`
+	Warnings    int             `json:"warnings"`
+	Projects    []ProjectResult `json:"projects"` // Only projects with violations
+	Rules       []RuleResult    `json:"rules"`    // Every rule, in policy order
+}
+
+// Evaluate checks every module of every project against the policy rules
+func Evaluate(p *Policy, projects []domain.Project) *Report {
+	report := &Report{
+		GeneratedAt: time.Now(),
+		Evaluated:   len(projects),
+		Projects:    []ProjectResult{},
+		Rules:       make([]RuleResult, len(p.Rules)),
+	}
+	for i, rule := range p.Rules {
+		report.Rules[i] = RuleResult{Rule: rule, Violations: []Violation{}}
+	}
+
+	for _, project := range projects {
+		result := ProjectResult{ProjectID: project.ID, Name: project.Name, Path: project.Path}
+		for i, rule := range p.Rules {
+			if !rule.appliesTo(project.Path) {
+				continue
+			}
+			var found []Violation
+			for _, mod := range project.GoModules() {
+				found = append(found, rule.check(mod)...)
+			}
+			if len(found) == 0 {
+				continue
+			}
+			for j := range found {
+				found[j].ProjectID = project.ID
+				found[j].Project = project.Path
+			}
+			report.Rules[i].Projects++
+			report.Rules[i].Violations = append(report.Rules[i].Violations, found...)
+			result.Violations = append(result.Violations, found...)
+		}
+		if len(result.Violations) == 0 {
+			continue
+		}
+		for _, v := range result.Violations {
+			if v.Severity == SeverityError {
+				result.Errors++
+			} else {
+				result.Warnings++
+			}
+		}
+		report.Errors += result.Errors
+		report.Warnings += result.Warnings
+		report.Projects = append(report.Projects, result)
+	}
+
+	// Most errors first, then by path
+	sort.Slice(report.Projects, func(i, j int) bool {
+		a, b := report.Projects[i], report.Projects[j]
+		if a.Errors != b.Errors {
+			return a.Errors > b.Errors
+		}
+		if a.Warnings != b.Warnings {
+			return a.Warnings > b.Warnings
+		}
+		return a.Path < b.Path
+	})
+	return report
+}
+
+// appliesTo reports whether the rule is in scope for a project path
+func (r Rule) appliesTo(projectPath string) bool {
+	if len(r.Projects) > 0 && !matchAny(r.Projects, projectPath) {
+		return false
+	}
+	return !matchAny(r.SkipProject, projectPath)
+}
+
+// check returns the violations of the rule in one module
+func (r Rule) check(mod domain.Module) []Violation {
+	var out []Violation
+	add := func(lib, version, format string, args ...interface{}) {
+		v := Violation{
+			Rule:     r.Name,
+			Type:     r.Type,
+			Severity: r.Severity,
+			Message:  fmt.Sprintf(format, args...),
+			Module:   lib,
+			Version:  version,
+		}
+		if mod.Dir != gomod.RootDir {
+			v.ModuleDir = mod.Dir
+		}
+		out = append(out, v)
+	}
+
+	if r.Type == RuleGoVersion {
+		if mod.GoVersion == "" {
+			add("", "", "go.mod has no go directive")
+			return out
+		}
+		current := osv.GoSemver(mod.GoVersion)
+		if current == "" {
+			add("", mod.GoVersion, "go %s is not a valid Go version", mod.GoVersion)
+			return out
+		}
+		if r.Min != "" && semver.Compare(current, osv.GoSemver(r.Min)) < 0 {
+			add("", mod.GoVersion, "go %s is below the required minimum %s", mod.GoVersion, r.Min)
+		}
+		if r.Max != "" && semver.Compare(current, osv.GoSemver(r.Max)) > 0 {
+			add("", mod.GoVersion, "go %s is above the allowed maximum %s", mod.GoVersion, r.Max)
+		}
+		return out
+	}
+
+	for _, lib := range mod.Libraries {
+		if lib.Indirect && !r.Indirect {
+			continue
+		}
+		switch r.Type {
+		case RuleBannedModule:
+			if matchAny(r.Modules, lib.Name) {
+				add(lib.Name, lib.Version, "%s is banned", lib.Name)
+			}
+		case RuleMinVersion:
+			if sameModule(lib.Name, r.Module) && semver.Compare(lib.Version, r.Version) < 0 {
+				add(lib.Name, lib.Version, "%s %s is below the required minimum %s", lib.Name, lib.Version, r.Version)
+			}
+		case RuleMaxVersion:
+			if sameModule(lib.Name, r.Module) && semver.Compare(lib.Version, r.Version) > 0 {
+				add(lib.Name, lib.Version, "%s %s is above the allowed maximum %s", lib.Name, lib.Version, r.Version)
+			}
+		case RuleAllowedMajors:
+			if sameModule(lib.Name, r.Module) && !containsString(r.Majors, semver.Major(lib.Version)) {
+				add(lib.Name, lib.Version, "%s %s is not an allowed major version (allowed: %v)", lib.Name, lib.Version, r.Majors)
+			}
+		case RuleForbiddenReplace:
+			if lib.Replace == nil {
+				continue
+			}
+			if r.Local && lib.Replace.Local {
+				add(lib.Name, lib.Version, "%s is replaced by local directory %s", lib.Name, lib.Replace.Path)
+			} else if matchAny(r.Targets, lib.Replace.Path) {
+				add(lib.Name, lib.Version, "%s is replaced by forbidden target %s", lib.Name, lib.Replace.Path)
+			}
+		}
+	}
+	return out
+}
+
+// sameModule reports whether modPath is rulePath, ignoring any /vN major version suffix,
+// so a rule on "example.com/client" also covers "example.com/client/v2"
+func sameModule(modPath, rulePath string) bool {
+	return stripMajor(modPath) == stripMajor(rulePath)
+}
+
+func stripMajor(modPath string) string {
+	if prefix, _, ok := module.SplitPathVersion(modPath); ok {
+		return prefix
+	}
+	return modPath
+}
+
+// matchAny reports whether target matches any glob, either exactly or as a path prefix
+func matchAny(globs []string, target string) bool {
+	for _, glob := range globs {
+		if module.MatchPrefixPatterns(glob, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsString reports whether values contains value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,339 @@
+// internal/policy/evaluate_test.go
+package policy
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"gitlab-list/internal/domain"
+)
+
+// lib returns a direct requirement
+func lib(name, version string) domain.Library {
+	return domain.Library{Name: name, Version: version}
+}
+
+// indirect returns a requirement marked "// indirect"
+func indirect(name, version string) domain.Library {
+	return domain.Library{Name: name, Version: version, Indirect: true}
+}
+
+// replaced returns a direct requirement replaced by target, a directory when local
+func replaced(name, version, target string, local bool) domain.Library {
+	l := lib(name, version)
+	l.Replace = &domain.Replacement{Path: target, Local: local}
+	if !local {
+		l.Replace.Version = version
+	}
+	return l
+}
+
+// found lists violations as "<module dir>:<module>@<version>" for comparison
+func found(violations []Violation) []string {
+	out := []string{}
+	for _, v := range violations {
+		out = append(out, fmt.Sprintf("%s:%s@%s", v.ModuleDir, v.Module, v.Version))
+	}
+	return out
+}
+
+func TestEvaluateRules(t *testing.T) {
+	tests := []struct {
+		name      string
+		rule      Rule
+		goVersion string
+		libs      []domain.Library
+		want      []string
+	}{
+		{
+			name: "banned glob",
+			rule: Rule{Type: RuleBannedModule, Modules: []string{"github.com/pkg/*"}},
+			libs: []domain.Library{lib("github.com/pkg/errors", "v0.9.1"), lib("github.com/pkgx/errors", "v1.0.0")},
+			want: []string{":github.com/pkg/errors@v0.9.1"},
+		},
+		{
+			name: "banned prefix covers subpackages",
+			rule: Rule{Type: RuleBannedModule, Modules: []string{"github.com/legacy"}},
+			libs: []domain.Library{lib("github.com/legacy/client", "v1.0.0")},
+			want: []string{":github.com/legacy/client@v1.0.0"},
+		},
+		{
+			name: "indirect requirements are skipped",
+			rule: Rule{Type: RuleBannedModule, Modules: []string{"github.com/pkg/errors"}},
+			libs: []domain.Library{indirect("github.com/pkg/errors", "v0.9.1")},
+			want: []string{},
+		},
+		{
+			name: "include_indirect checks them",
+			rule: Rule{Type: RuleBannedModule, Modules: []string{"github.com/pkg/errors"}, Indirect: true},
+			libs: []domain.Library{indirect("github.com/pkg/errors", "v0.9.1")},
+			want: []string{":github.com/pkg/errors@v0.9.1"},
+		},
+		{
+			name: "min version",
+			rule: Rule{Type: RuleMinVersion, Module: "example.com/lib", Version: "v1.2.0"},
+			libs: []domain.Library{lib("example.com/lib", "v1.1.9"), lib("example.com/other", "v0.1.0")},
+			want: []string{":example.com/lib@v1.1.9"},
+		},
+		{
+			name: "min version met",
+			rule: Rule{Type: RuleMinVersion, Module: "example.com/lib", Version: "v1.2.0"},
+			libs: []domain.Library{lib("example.com/lib", "v1.2.0")},
+			want: []string{},
+		},
+		{
+			name: "min version pseudo-version",
+			rule: Rule{Type: RuleMinVersion, Module: "example.com/lib", Version: "v1.2.0"},
+			libs: []domain.Library{lib("example.com/lib", "v1.1.1-0.20240101000000-abcdefabcdef")},
+			want: []string{":example.com/lib@v1.1.1-0.20240101000000-abcdefabcdef"},
+		},
+		{
+			name: "min version covers the major suffix",
+			rule: Rule{Type: RuleMinVersion, Module: "example.com/client", Version: "v2.1.0"},
+			libs: []domain.Library{lib("example.com/client/v2", "v2.0.3"), lib("example.com/client/v3", "v3.0.0")},
+			want: []string{":example.com/client/v2@v2.0.3"},
+		},
+		{
+			name: "max version",
+			rule: Rule{Type: RuleMaxVersion, Module: "example.com/lib/v2", Version: "v1.9.0"},
+			libs: []domain.Library{lib("example.com/lib", "v1.9.0"), lib("example.com/lib/v2", "v2.0.0")},
+			want: []string{":example.com/lib/v2@v2.0.0"},
+		},
+		{
+			name: "allowed majors",
+			rule: Rule{Type: RuleAllowedMajors, Module: "example.com/client", Majors: []string{"v2"}},
+			libs: []domain.Library{
+				lib("example.com/client", "v1.4.0"),
+				lib("example.com/client/v2", "v2.3.0"),
+				lib("example.com/client/v3", "v3.0.0"),
+				lib("example.com/clientx", "v1.0.0"),
+			},
+			want: []string{":example.com/client@v1.4.0", ":example.com/client/v3@v3.0.0"},
+		},
+		{
+			name: "allowed majors of gopkg.in paths",
+			rule: Rule{Type: RuleAllowedMajors, Module: "gopkg.in/yaml.v3", Majors: []string{"v3"}},
+			libs: []domain.Library{lib("gopkg.in/yaml.v2", "v2.4.0"), lib("gopkg.in/yaml.v3", "v3.0.1")},
+			want: []string{":gopkg.in/yaml.v2@v2.4.0"},
+		},
+		{
+			name: "forbidden local replace",
+			rule: Rule{Type: RuleForbiddenReplace, Local: true},
+			libs: []domain.Library{
+				replaced("example.com/local", "v1.0.0", "../local", true),
+				replaced("example.com/fork", "v1.0.0", "github.com/fork/lib", false),
+				lib("example.com/plain", "v1.0.0"),
+			},
+			want: []string{":example.com/local@v1.0.0"},
+		},
+		{
+			name: "forbidden replace target",
+			rule: Rule{Type: RuleForbiddenReplace, Targets: []string{"github.com/fork/*"}},
+			libs: []domain.Library{
+				replaced("example.com/local", "v1.0.0", "../local", true),
+				replaced("example.com/fork", "v1.0.0", "github.com/fork/lib", false),
+			},
+			want: []string{":example.com/fork@v1.0.0"},
+		},
+		{
+			name:      "go version below min",
+			rule:      Rule{Type: RuleGoVersion, Min: "1.22"},
+			goVersion: "1.21.5",
+			want:      []string{":@1.21.5"},
+		},
+		{
+			name:      "go version at min",
+			rule:      Rule{Type: RuleGoVersion, Min: "1.22"},
+			goVersion: "1.22",
+			want:      []string{},
+		},
+		{
+			name:      "go release candidate below min",
+			rule:      Rule{Type: RuleGoVersion, Min: "1.22"},
+			goVersion: "1.22rc1",
+			want:      []string{":@1.22rc1"},
+		},
+		{
+			name:      "go version above max",
+			rule:      Rule{Type: RuleGoVersion, Min: "1.21", Max: "1.23"},
+			goVersion: "1.24.1",
+			want:      []string{":@1.24.1"},
+		},
+		{
+			name:      "go patch within max",
+			rule:      Rule{Type: RuleGoVersion, Max: "1.23.4"},
+			goVersion: "1.23.4",
+			want:      []string{},
+		},
+		{
+			name: "no go directive",
+			rule: Rule{Type: RuleGoVersion, Min: "1.22"},
+			want: []string{":@"},
+		},
+		{
+			name:      "invalid go directive",
+			rule:      Rule{Type: RuleGoVersion, Min: "1.22"},
+			goVersion: "1.x",
+			want:      []string{":@1.x"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := New(tt.rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			project := domain.Project{ID: 1, Path: "group/app", GoVersion: tt.goVersion, Libraries: tt.libs}
+			report := Evaluate(p, []domain.Project{project})
+			if got := found(report.Rules[0].Violations); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("violations = %v, want %v", got, tt.want)
+			}
+			if want := len(tt.want); report.Errors != want {
+				t.Errorf("Errors = %d, want %d", report.Errors, want)
+			}
+		})
+	}
+}
+
+func TestEvaluateModules(t *testing.T) {
+	p, err := New(Rule{Type: RuleGoVersion, Min: "1.22"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	project := domain.Project{
+		ID:   1,
+		Path: "group/mono",
+		// The root fields are ignored once the modules are known
+		GoVersion: "1.10",
+		Modules: []domain.Module{
+			{Path: "example.com/mono", Dir: ".", GoVersion: "1.21"},
+			{Path: "example.com/mono/tools", Dir: "tools", GoVersion: "1.20"},
+			{Path: "example.com/mono/api", Dir: "api", GoVersion: "1.22"},
+		},
+	}
+	report := Evaluate(p, []domain.Project{project})
+	if got, want := found(report.Rules[0].Violations), []string{":@1.21", "tools:@1.20"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("violations = %v, want %v", got, want)
+	}
+	// Both modules break the rule in one project
+	if report.Rules[0].Projects != 1 || len(report.Projects) != 1 || report.Projects[0].Errors != 2 {
+		t.Errorf("rule projects = %d, projects = %+v", report.Rules[0].Projects, report.Projects)
+	}
+}
+
+func TestEvaluateProjectScope(t *testing.T) {
+	tests := []struct {
+		name     string
+		projects []string
+		skip     []string
+		want     []string // Project paths with violations
+	}{
+		{name: "all projects", want: []string{"platform/api", "platform/legacy/billing", "tools/lint"}},
+		{name: "prefix", projects: []string{"platform"}, want: []string{"platform/api", "platform/legacy/billing"}},
+		{name: "glob", projects: []string{"*/lint"}, want: []string{"tools/lint"}},
+		{name: "skipped prefix", skip: []string{"platform/legacy"}, want: []string{"platform/api", "tools/lint"}},
+		{name: "skip wins", projects: []string{"platform"}, skip: []string{"platform/*"}, want: []string{}},
+		{name: "partial element does not match", projects: []string{"platform/ap"}, want: []string{}},
+	}
+	var fleet []domain.Project
+	for i, path := range []string{"platform/api", "platform/legacy/billing", "tools/lint"} {
+		fleet = append(fleet, domain.Project{ID: i + 1, Path: path, GoVersion: "1.20"})
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := New(Rule{Type: RuleGoVersion, Min: "1.22", Projects: tt.projects, SkipProject: tt.skip})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, result := range Evaluate(p, fleet).Projects {
+				got = append(got, result.Path)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("projects = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSameModule(t *testing.T) {
+	tests := []struct {
+		modPath  string
+		rulePath string
+		want     bool
+	}{
+		{"example.com/client", "example.com/client", true},
+		{"example.com/client/v2", "example.com/client", true},
+		{"example.com/client", "example.com/client/v3", true},
+		{"example.com/client/v2", "example.com/client/v3", true},
+		{"example.com/clientx", "example.com/client", false},
+		{"example.com/client/sub", "example.com/client", false},
+		{"example.com/client/v1", "example.com/client", false}, // v1 is not a major suffix
+		{"gopkg.in/yaml.v2", "gopkg.in/yaml.v3", true},
+	}
+	for _, tt := range tests {
+		if got := sameModule(tt.modPath, tt.rulePath); got != tt.want {
+			t.Errorf("sameModule(%s, %s) = %t, want %t", tt.modPath, tt.rulePath, got, tt.want)
+		}
+	}
+}
+
+// The example file holds the rules from the wiki: Go 1.22, no pkg/errors,
+// the organization client at v2 or newer and no local replaces
+func TestExamplePolicy(t *testing.T) {
+	p, err := Load("../../policy.example.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const client = "git.prosoftke.sk/nghis/openapi/clients/go/nghisorganizationgoclient"
+	fleet := []domain.Project{
+		{
+			ID: 1, Path: "nghis/compliant", GoVersion: "1.22.3",
+			Libraries: []domain.Library{lib(client+"/v2", "v2.1.0"), replaced("example.com/fork", "v1.0.0", "example.com/myfork", false)},
+		},
+		{
+			ID: 2, Path: "nghis/legacy", GoVersion: "1.21",
+			Libraries: []domain.Library{
+				lib("github.com/pkg/errors", "v0.9.1"),
+				lib(client, "v1.8.0"),
+				replaced("example.com/shared", "v1.0.0", "../shared", true),
+			},
+		},
+		{
+			ID: 3, Path: "nghis/indirect", GoVersion: "1.23",
+			Libraries: []domain.Library{indirect("github.com/pkg/errors", "v0.9.1"), indirect(client, "v1.8.0")},
+		},
+	}
+	report := Evaluate(p, fleet)
+
+	if report.Evaluated != 3 || report.Errors != 4 || report.Warnings != 1 {
+		t.Errorf("Evaluated, Errors, Warnings = %d, %d, %d, want 3, 4, 1", report.Evaluated, report.Errors, report.Warnings)
+	}
+	if len(report.Projects) != 1 || report.Projects[0].Path != "nghis/legacy" {
+		t.Fatalf("projects with violations = %+v, want only nghis/legacy", report.Projects)
+	}
+
+	want := map[string][]string{
+		"minimum-go":                  {":@1.21"},
+		"no-pkg-errors":               {":github.com/pkg/errors@v0.9.1"},
+		"organization-client-v2":      {":" + client + "@v1.8.0"},
+		"organization-client-minimum": {":" + client + "@v1.8.0"},
+		"no-local-replace":            {":example.com/shared@v1.0.0"},
+	}
+	if len(report.Rules) != len(want) {
+		t.Fatalf("rules = %d, want %d", len(report.Rules), len(want))
+	}
+	for _, rule := range report.Rules {
+		if got := found(rule.Violations); !reflect.DeepEqual(got, want[rule.Rule.Name]) {
+			t.Errorf("rule %s: violations = %v, want %v", rule.Rule.Name, got, want[rule.Rule.Name])
+		}
+		if rule.Projects != 1 {
+			t.Errorf("rule %s: projects = %d, want 1", rule.Rule.Name, rule.Projects)
+		}
+	}
+	if severity := report.Rules[3].Violations[0].Severity; severity != SeverityWarning {
+		t.Errorf("organization-client-minimum severity = %s, want warning", severity)
+	}
+}
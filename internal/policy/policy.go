@@ -0,0 +1,135 @@
+// internal/policy/policy.go
+package policy
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gitlab-list/internal/osv"
+
+	"golang.org/x/mod/semver"
+	"gopkg.in/yaml.v3"
+)
+
+// Rule types
+const (
+	RuleBannedModule     = "banned_module"     // modules: globs that must not be required
+	RuleMinVersion       = "min_version"       // module, version: lowest allowed version
+	RuleMaxVersion       = "max_version"       // module, version: highest allowed version
+	RuleAllowedMajors    = "allowed_majors"    // module, majors: e.g. [v2, v3]
+	RuleGoVersion        = "go_version"        // min and/or max of the go directive
+	RuleForbiddenReplace = "forbidden_replace" // local: no directory replaces; targets: globs of forbidden replacements
+)
+
+// Severities
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Policy is a set of dependency rules, usually loaded from a YAML file
+type Policy struct {
+	Rules []Rule `yaml:"rules" json:"rules"`
+}
+
+// Rule is a single declarative check. Which fields apply depends on Type.
+type Rule struct {
+	Name        string   `yaml:"name" json:"name"`
+	Type        string   `yaml:"type" json:"type"`
+	Severity    string   `yaml:"severity,omitempty" json:"severity"`
+	Description string   `yaml:"description,omitempty" json:"description,omitempty"`
+	Projects    []string `yaml:"projects,omitempty" json:"projects,omitempty"`                 // Project path globs the rule applies to; all when empty
+	SkipProject []string `yaml:"skip_projects,omitempty" json:"skip_projects,omitempty"`       // Project path globs exempt from the rule
+	Modules     []string `yaml:"modules,omitempty" json:"modules,omitempty"`                   // banned_module
+	Module      string   `yaml:"module,omitempty" json:"module,omitempty"`                     // min_version, max_version, allowed_majors
+	Version     string   `yaml:"version,omitempty" json:"version,omitempty"`                   // min_version, max_version
+	Majors      []string `yaml:"majors,omitempty" json:"majors,omitempty"`                     // allowed_majors
+	Min         string   `yaml:"min,omitempty" json:"min,omitempty"`                           // go_version
+	Max         string   `yaml:"max,omitempty" json:"max,omitempty"`                           // go_version
+	Local       bool     `yaml:"local,omitempty" json:"local,omitempty"`                       // forbidden_replace
+	Targets     []string `yaml:"targets,omitempty" json:"targets,omitempty"`                   // forbidden_replace
+	Indirect    bool     `yaml:"include_indirect,omitempty" json:"include_indirect,omitempty"` // also check indirect requirements
+}
+
+// New builds a policy from rules, filling defaults and validating them like Parse
+func New(rules ...Rule) (*Policy, error) {
+	p := &Policy{Rules: append([]Rule{}, rules...)}
+	for i := range p.Rules {
+		if err := p.Rules[i].normalize(i); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// Load reads and validates a policy file
+func Load(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse parses and validates a YAML policy
+func Parse(data []byte) (*Policy, error) {
+	var p Policy
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse policy: %w", err)
+	}
+	return New(p.Rules...)
+}
+
+// normalize fills defaults and checks that the fields the rule type needs are present
+func (r *Rule) normalize(index int) error {
+	if r.Name == "" {
+		r.Name = fmt.Sprintf("%s-%d", r.Type, index+1)
+	}
+	switch r.Severity {
+	case "":
+		r.Severity = SeverityError
+	case SeverityError, SeverityWarning:
+	default:
+		return fmt.Errorf("rule %s: unknown severity %q", r.Name, r.Severity)
+	}
+
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("rule %s: %s", r.Name, fmt.Sprintf(format, args...))
+	}
+	switch r.Type {
+	case RuleBannedModule:
+		if len(r.Modules) == 0 {
+			return invalid("modules is required")
+		}
+	case RuleMinVersion, RuleMaxVersion:
+		if r.Module == "" || !semver.IsValid(r.Version) {
+			return invalid("module and a valid version (e.g. v1.2.3) are required")
+		}
+	case RuleAllowedMajors:
+		if r.Module == "" || len(r.Majors) == 0 {
+			return invalid("module and majors are required")
+		}
+		for i, major := range r.Majors {
+			if !strings.HasPrefix(major, "v") {
+				r.Majors[i] = "v" + major
+			}
+		}
+	case RuleGoVersion:
+		if r.Min == "" && r.Max == "" {
+			return invalid("min or max is required")
+		}
+		for _, v := range []string{r.Min, r.Max} {
+			if v != "" && osv.GoSemver(v) == "" {
+				return invalid("invalid Go version %q", v)
+			}
+		}
+	case RuleForbiddenReplace:
+		if !r.Local && len(r.Targets) == 0 {
+			return invalid("local or targets is required")
+		}
+	default:
+		return invalid("unknown type %q", r.Type)
+	}
+	return nil
+}
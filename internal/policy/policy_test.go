@@ -0,0 +1,98 @@
+// internal/policy/policy_test.go
+package policy
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    Rule
+		wantErr string // Empty when the rule is valid
+	}{
+		{name: "banned module", rule: Rule{Type: RuleBannedModule, Modules: []string{"github.com/pkg/errors"}}},
+		{name: "banned module without modules", rule: Rule{Type: RuleBannedModule}, wantErr: "modules is required"},
+		{name: "min version", rule: Rule{Type: RuleMinVersion, Module: "example.com/lib", Version: "v1.2.0"}},
+		{name: "min version without v", rule: Rule{Type: RuleMinVersion, Module: "example.com/lib", Version: "1.2.0"}, wantErr: "valid version"},
+		{name: "max version without module", rule: Rule{Type: RuleMaxVersion, Version: "v1.2.0"}, wantErr: "module and a valid version"},
+		{name: "allowed majors", rule: Rule{Type: RuleAllowedMajors, Module: "example.com/lib", Majors: []string{"v2"}}},
+		{name: "allowed majors without majors", rule: Rule{Type: RuleAllowedMajors, Module: "example.com/lib"}, wantErr: "majors are required"},
+		{name: "go version min", rule: Rule{Type: RuleGoVersion, Min: "1.22"}},
+		{name: "go version max with go prefix", rule: Rule{Type: RuleGoVersion, Max: "go1.24.1"}},
+		{name: "go version without bounds", rule: Rule{Type: RuleGoVersion}, wantErr: "min or max is required"},
+		{name: "go version invalid", rule: Rule{Type: RuleGoVersion, Min: "latest"}, wantErr: `invalid Go version "latest"`},
+		{name: "forbidden local replace", rule: Rule{Type: RuleForbiddenReplace, Local: true}},
+		{name: "forbidden replace targets", rule: Rule{Type: RuleForbiddenReplace, Targets: []string{"github.com/fork/*"}}},
+		{name: "forbidden replace without either", rule: Rule{Type: RuleForbiddenReplace}, wantErr: "local or targets is required"},
+		{name: "unknown type", rule: Rule{Type: "max_age"}, wantErr: `unknown type "max_age"`},
+		{name: "unknown severity", rule: Rule{Type: RuleGoVersion, Min: "1.22", Severity: "fatal"}, wantErr: `unknown severity "fatal"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.rule)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("New = %v, want a valid rule", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("New = %v, want an error with %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewDefaults(t *testing.T) {
+	p, err := New(
+		Rule{Type: RuleGoVersion, Min: "1.22"},
+		Rule{Name: "client", Type: RuleAllowedMajors, Module: "example.com/client", Majors: []string{"2", "v3"}, Severity: SeverityWarning},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Unnamed rules are named after their type and position
+	if got := p.Rules[0]; got.Name != "go_version-1" || got.Severity != SeverityError {
+		t.Errorf("first rule = %s with severity %s, want go_version-1 with error", got.Name, got.Severity)
+	}
+	if got := p.Rules[1]; got.Name != "client" || got.Severity != SeverityWarning || !reflect.DeepEqual(got.Majors, []string{"v2", "v3"}) {
+		t.Errorf("second rule = %s with severity %s and majors %v", got.Name, got.Severity, got.Majors)
+	}
+}
+
+func TestParse(t *testing.T) {
+	p, err := Parse([]byte(`rules:
+  - name: no-fork
+    type: forbidden_replace
+    targets: [github.com/fork/*]
+    projects: [platform]
+    skip_projects: [platform/legacy]
+    include_indirect: true
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Rule{
+		Name:        "no-fork",
+		Type:        RuleForbiddenReplace,
+		Severity:    SeverityError,
+		Targets:     []string{"github.com/fork/*"},
+		Projects:    []string{"platform"},
+		SkipProject: []string{"platform/legacy"},
+		Indirect:    true,
+	}
+	if len(p.Rules) != 1 || !reflect.DeepEqual(p.Rules[0], want) {
+		t.Errorf("Parse = %+v, want %+v", p.Rules, want)
+	}
+
+	if _, err := Parse([]byte("rules:\n  - type: banned_module\n")); err == nil {
+		t.Error("Parse of an invalid rule succeeded")
+	}
+	if _, err := Parse([]byte("rules: [")); err == nil {
+		t.Error("Parse of invalid YAML succeeded")
+	}
+}
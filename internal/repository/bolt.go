@@ -0,0 +1,661 @@
+// internal/repository/bolt.go
+package repository
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"gitlab-list/internal/domain"
+)
+
+var (
+	projectBucket     = []byte("projects")  // One document per ProjectKey
+	snapshotBucket    = []byte("snapshots") // Nested bucket per snapshot ID, records keyed by sequence
+	metaBucket        = []byte("meta")      // Cache schema version
+	jobBucket         = []byte("jobs")      // Scheduled job state by name
+	jobRunBucket      = []byte("job_runs")  // Job runs by run ID
+	leaseBucket       = []byte("leases")    // Leases by name
+	campaignBucket    = []byte("campaigns") // Campaigns with their projects by ID
+	legacyCacheBucket = []byte("cache")     // Version 1: nested bucket per search hash
+)
+
+// BoltStore implements the cache in an embedded bbolt file, so the full API runs without a database
+// server. Only one process can open the file at a time.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// NewBoltStore opens (or creates) the store file at path
+func NewBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open store %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{projectBucket, snapshotBucket, metaBucket, jobBucket, jobRunBucket, leaseBucket, campaignBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return migrateBolt(tx)
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create store buckets: %w", err)
+	}
+
+	return &BoltStore{db: db}, nil
+}
+
+// UpsertProjects writes the document of every project record in one transaction, unless fence is lost.
+// projectHashes is keyed by ProjectKey. A document fetched after fetchedAt is left untouched.
+func (s *BoltStore) UpsertProjects(projects []domain.Project, projectHashes map[string]string, fetchedAt time.Time, fence Fence) error {
+	now := time.Now()
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		if err := checkFence(tx, fence, now); err != nil {
+			return err
+		}
+		bucket := tx.Bucket(projectBucket)
+		for _, project := range projects {
+			var existing *CachedProject
+			if value := bucket.Get([]byte(ProjectKey(project))); value != nil {
+				existing = &CachedProject{}
+				if err := json.Unmarshal(value, existing); err != nil {
+					return err
+				}
+			}
+			entry, ok := upsertCached(existing, project, projectHashes[ProjectKey(project)], fetchedAt, now)
+			if !ok {
+				continue
+			}
+			if err := putCached(bucket, entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upsert projects: %w", err)
+	}
+	return nil
+}
+
+// PruneProjects removes the documents fetched before before, of one project or of all when projectID is 0,
+// unless fence is lost
+func (s *BoltStore) PruneProjects(projectID int, before time.Time, fence Fence) (int64, error) {
+	var deleted int64
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		if err := checkFence(tx, fence, time.Now()); err != nil {
+			return err
+		}
+		bucket := tx.Bucket(projectBucket)
+		var keys [][]byte
+		err := bucket.ForEach(func(key, value []byte) error {
+			var entry CachedProject
+			if err := json.Unmarshal(value, &entry); err != nil {
+				return err
+			}
+			if prunable(entry, projectID, before) {
+				keys = append(keys, append([]byte(nil), key...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		// Keys are deleted after the walk; bbolt does not allow changes during ForEach
+		for _, key := range keys {
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+			deleted++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune projects: %w", err)
+	}
+	return deleted, nil
+}
+
+// checkFence checks fence against the lease stored in tx
+func checkFence(tx *bolt.Tx, fence Fence, now time.Time) error {
+	if fence.Lease == "" {
+		return nil
+	}
+	var current *domain.Lease
+	if value := tx.Bucket(leaseBucket).Get([]byte(fence.Lease)); value != nil {
+		current = &domain.Lease{}
+		if err := json.Unmarshal(value, current); err != nil {
+			return err
+		}
+	}
+	return fence.check(current, now)
+}
+
+// GetCachedProjects retrieves the cached projects matching filter
+func (s *BoltStore) GetCachedProjects(filter ProjectFilter) ([]domain.Project, error) {
+	cached, err := s.GetCachedProjectsWithHashes(filter)
+	if err != nil {
+		return nil, err
+	}
+	return cachedProjects(cached), nil
+}
+
+// GetCachedProjectsWithHashes retrieves the cached documents matching filter
+func (s *BoltStore) GetCachedProjectsWithHashes(filter ProjectFilter) ([]CachedProject, error) {
+	var cached []CachedProject
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(projectBucket).ForEach(func(_, value []byte) error {
+			var entry CachedProject
+			if err := json.Unmarshal(value, &entry); err != nil {
+				return err
+			}
+			if filter.Matches(entry.Project) {
+				cached = append(cached, entry)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode cached projects: %w", err)
+	}
+	sortCached(cached)
+	return cached, nil
+}
+
+// IsCacheValid checks if any project is cached
+func (s *BoltStore) IsCacheValid() (bool, error) {
+	valid := false
+	err := s.db.View(func(tx *bolt.Tx) error {
+		key, _ := tx.Bucket(projectBucket).Cursor().First()
+		valid = key != nil
+		return nil
+	})
+	return valid, err
+}
+
+// ClearAllCache removes all cache entries; snapshots are kept
+func (s *BoltStore) ClearAllCache() error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket(projectBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucket(projectBucket)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to clear all cache: %w", err)
+	}
+	return nil
+}
+
+// GetCacheStats returns cache statistics
+func (s *BoltStore) GetCacheStats() (map[string]interface{}, error) {
+	cached, err := s.GetCachedProjectsWithHashes(ProjectFilter{})
+	if err != nil {
+		return nil, err
+	}
+	return cacheStats(cached, "bolt"), nil
+}
+
+// SaveSnapshot stores the project records of one sync
+func (s *BoltStore) SaveSnapshot(projects []domain.ProjectSnapshot) error {
+	if len(projects) == 0 {
+		return nil
+	}
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		root := tx.Bucket(snapshotBucket)
+		for _, project := range projects {
+			bucket, err := root.CreateBucketIfNotExists([]byte(project.SnapshotID))
+			if err != nil {
+				return err
+			}
+			if err := putSequenced(bucket, project); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save snapshot: %w", err)
+	}
+	return nil
+}
+
+// ListSnapshots returns every stored snapshot, oldest first
+func (s *BoltStore) ListSnapshots() ([]domain.Snapshot, error) {
+	var snapshots []domain.Snapshot
+	err := s.db.View(func(tx *bolt.Tx) error {
+		root := tx.Bucket(snapshotBucket)
+		return root.ForEachBucket(func(id []byte) error {
+			bucket := root.Bucket(id)
+			_, first := bucket.Cursor().First()
+			if first == nil {
+				return nil
+			}
+			var record domain.ProjectSnapshot
+			if err := json.Unmarshal(first, &record); err != nil {
+				return err
+			}
+			snapshots = append(snapshots, domain.Snapshot{
+				ID:       string(id),
+				TakenAt:  record.TakenAt,
+				Projects: bucket.Stats().KeyN,
+			})
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+
+	sort.SliceStable(snapshots, func(i, j int) bool { return snapshots[i].TakenAt.Before(snapshots[j].TakenAt) })
+	return snapshots, nil
+}
+
+// GetSnapshotProjects returns the project records of the given snapshots, oldest first
+func (s *BoltStore) GetSnapshotProjects(snapshotIDs []string, withLibraries bool) ([]domain.ProjectSnapshot, error) {
+	var projects []domain.ProjectSnapshot
+	err := s.db.View(func(tx *bolt.Tx) error {
+		root := tx.Bucket(snapshotBucket)
+		for _, id := range snapshotIDs {
+			bucket := root.Bucket([]byte(id))
+			if bucket == nil {
+				continue
+			}
+			err := bucket.ForEach(func(_, value []byte) error {
+				var record domain.ProjectSnapshot
+				if err := json.Unmarshal(value, &record); err != nil {
+					return err
+				}
+				if !withLibraries {
+					record = withoutLibraries(record)
+				}
+				projects = append(projects, record)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot projects: %w", err)
+	}
+
+	sort.SliceStable(projects, func(i, j int) bool { return projects[i].TakenAt.Before(projects[j].TakenAt) })
+	return projects, nil
+}
+
+// DeleteSnapshots removes whole snapshots
+func (s *BoltStore) DeleteSnapshots(snapshotIDs []string) (int64, error) {
+	var deleted int64
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		root := tx.Bucket(snapshotBucket)
+		for _, id := range snapshotIDs {
+			bucket := root.Bucket([]byte(id))
+			if bucket == nil {
+				continue
+			}
+			deleted += int64(bucket.Stats().KeyN)
+			if err := root.DeleteBucket([]byte(id)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete snapshots: %w", err)
+	}
+	return deleted, nil
+}
+
+// RegisterJob stores the configuration and next run of a scheduled job, keeping its pause flag
+// and pending run request
+func (s *BoltStore) RegisterJob(job domain.ScheduledJob) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(jobBucket)
+		var existing *domain.ScheduledJob
+		if value := bucket.Get([]byte(job.Name)); value != nil {
+			existing = &domain.ScheduledJob{}
+			if err := json.Unmarshal(value, existing); err != nil {
+				return err
+			}
+		}
+		return putJSON(bucket, job.Name, registeredJob(existing, job))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to register job %s: %w", job.Name, err)
+	}
+	return nil
+}
+
+// ListJobs returns every registered job by name
+func (s *BoltStore) ListJobs() ([]domain.ScheduledJob, error) {
+	var jobs []domain.ScheduledJob
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(jobBucket).ForEach(func(_, value []byte) error {
+			var job domain.ScheduledJob
+			if err := json.Unmarshal(value, &job); err != nil {
+				return err
+			}
+			jobs = append(jobs, job)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %w", err)
+	}
+	sortJobs(jobs)
+	return jobs, nil
+}
+
+// SetJobPaused pauses or resumes a job
+func (s *BoltStore) SetJobPaused(name string, paused bool) error {
+	return s.updateJob(name, func(job *domain.ScheduledJob) { job.Paused = paused })
+}
+
+// RequestJobRun asks the scheduler to run a job as soon as it polls
+func (s *BoltStore) RequestJobRun(name string, at time.Time) error {
+	return s.updateJob(name, func(job *domain.ScheduledJob) { job.TriggeredAt = &at })
+}
+
+func (s *BoltStore) updateJob(name string, update func(job *domain.ScheduledJob)) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(jobBucket)
+		value := bucket.Get([]byte(name))
+		if value == nil {
+			return fmt.Errorf("%w: %s", ErrJobNotFound, name)
+		}
+		var job domain.ScheduledJob
+		if err := json.Unmarshal(value, &job); err != nil {
+			return fmt.Errorf("failed to decode job %s: %w", name, err)
+		}
+		update(&job)
+		return putJSON(bucket, name, job)
+	})
+}
+
+// TakeJobRequest clears a pending run request and reports whether there was one
+func (s *BoltStore) TakeJobRequest(name string) (bool, error) {
+	taken := false
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(jobBucket)
+		value := bucket.Get([]byte(name))
+		if value == nil {
+			return nil
+		}
+		var job domain.ScheduledJob
+		if err := json.Unmarshal(value, &job); err != nil {
+			return err
+		}
+		if job.TriggeredAt == nil {
+			return nil
+		}
+		taken = true
+		job.TriggeredAt = nil
+		return putJSON(bucket, name, job)
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to take run request of job %s: %w", name, err)
+	}
+	return taken, nil
+}
+
+// SaveJobRun inserts or replaces a run record
+func (s *BoltStore) SaveJobRun(run domain.JobRun) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return putJSON(tx.Bucket(jobRunBucket), run.ID, run)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save job run: %w", err)
+	}
+	return nil
+}
+
+// ListJobRuns returns the runs of a job (of all jobs when job is empty), newest first, at most limit (0 = all)
+func (s *BoltStore) ListJobRuns(job string, limit int) ([]domain.JobRun, error) {
+	var runs []domain.JobRun
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(jobRunBucket).ForEach(func(_, value []byte) error {
+			var run domain.JobRun
+			if err := json.Unmarshal(value, &run); err != nil {
+				return err
+			}
+			if job == "" || run.Job == job {
+				runs = append(runs, run)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list job runs: %w", err)
+	}
+	return latestRuns(runs, limit), nil
+}
+
+// AcquireLease takes the lease for holder when it is free or expired, with the next fencing token.
+// A held lease returns a *LeaseHeldError describing the current holder.
+func (s *BoltStore) AcquireLease(name, holder string, ttl time.Duration) (*domain.Lease, error) {
+	var lease domain.Lease
+	err := s.updateLease(name, func(existing *domain.Lease) (*domain.Lease, error) {
+		var err error
+		lease, err = acquiredLease(existing, name, holder, ttl, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		return &lease, nil
+	})
+	if err != nil {
+		var held *LeaseHeldError
+		if errors.As(err, &held) {
+			return nil, held
+		}
+		return nil, fmt.Errorf("failed to acquire lease %s: %w", name, err)
+	}
+	return &lease, nil
+}
+
+// RenewLease extends the lease while it still carries token
+func (s *BoltStore) RenewLease(name string, token int64, ttl time.Duration) (*domain.Lease, error) {
+	var lease domain.Lease
+	err := s.updateLease(name, func(existing *domain.Lease) (*domain.Lease, error) {
+		var err error
+		lease, err = renewedLease(existing, token, ttl, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		return &lease, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to renew lease %s: %w", name, err)
+	}
+	return &lease, nil
+}
+
+// ReleaseLease frees the lease if it still carries token
+func (s *BoltStore) ReleaseLease(name string, token int64) error {
+	err := s.updateLease(name, func(existing *domain.Lease) (*domain.Lease, error) {
+		if lease, ok := releasedLease(existing, token, time.Now()); ok {
+			return &lease, nil
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to release lease %s: %w", name, err)
+	}
+	return nil
+}
+
+// updateLease replaces the lease by what update returns for it (nil: no change) in one transaction
+func (s *BoltStore) updateLease(name string, update func(existing *domain.Lease) (*domain.Lease, error)) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(leaseBucket)
+		var existing *domain.Lease
+		if value := bucket.Get([]byte(name)); value != nil {
+			existing = &domain.Lease{}
+			if err := json.Unmarshal(value, existing); err != nil {
+				return err
+			}
+		}
+		lease, err := update(existing)
+		if err != nil || lease == nil {
+			return err
+		}
+		return putJSON(bucket, name, lease)
+	})
+}
+
+// ListLeases returns every lease ever taken, by name
+func (s *BoltStore) ListLeases() ([]domain.Lease, error) {
+	var leases []domain.Lease
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(leaseBucket).ForEach(func(_, value []byte) error {
+			var lease domain.Lease
+			if err := json.Unmarshal(value, &lease); err != nil {
+				return err
+			}
+			leases = append(leases, lease)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list leases: %w", err)
+	}
+	sortLeases(leases)
+	return leases, nil
+}
+
+// SaveCampaign inserts or replaces a campaign with all its projects
+func (s *BoltStore) SaveCampaign(campaign domain.Campaign) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return putJSON(tx.Bucket(campaignBucket), campaign.ID, campaign)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save campaign: %w", err)
+	}
+	return nil
+}
+
+// GetCampaign returns a campaign with its projects
+func (s *BoltStore) GetCampaign(id string) (*domain.Campaign, error) {
+	var campaign *domain.Campaign
+	err := s.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(campaignBucket).Get([]byte(id))
+		if value == nil {
+			return nil
+		}
+		campaign = &domain.Campaign{}
+		return json.Unmarshal(value, campaign)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get campaign %s: %w", id, err)
+	}
+	if campaign == nil {
+		return nil, fmt.Errorf("%w: %s", ErrCampaignNotFound, id)
+	}
+	return campaign, nil
+}
+
+// ListCampaigns returns every campaign, newest first
+func (s *BoltStore) ListCampaigns() ([]domain.Campaign, error) {
+	var campaigns []domain.Campaign
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(campaignBucket).ForEach(func(_, value []byte) error {
+			var campaign domain.Campaign
+			if err := json.Unmarshal(value, &campaign); err != nil {
+				return err
+			}
+			campaigns = append(campaigns, campaign)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list campaigns: %w", err)
+	}
+	sortCampaigns(campaigns)
+	return campaigns, nil
+}
+
+// UpdateCampaignProject replaces one project of a campaign, matched by project ID and module directory
+func (s *BoltStore) UpdateCampaignProject(id string, project domain.CampaignProject) error {
+	return s.updateCampaign(id, func(campaign *domain.Campaign) error {
+		return replaceCampaignProject(campaign, project, time.Now())
+	})
+}
+
+// SetCampaignStatus sets the status of a campaign
+func (s *BoltStore) SetCampaignStatus(id, status string) error {
+	return s.updateCampaign(id, func(campaign *domain.Campaign) error {
+		campaign.Status = status
+		campaign.UpdatedAt = time.Now()
+		return nil
+	})
+}
+
+// updateCampaign applies update to a campaign in one transaction
+func (s *BoltStore) updateCampaign(id string, update func(campaign *domain.Campaign) error) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(campaignBucket)
+		value := bucket.Get([]byte(id))
+		if value == nil {
+			return fmt.Errorf("%w: %s", ErrCampaignNotFound, id)
+		}
+		var campaign domain.Campaign
+		if err := json.Unmarshal(value, &campaign); err != nil {
+			return err
+		}
+		if err := update(&campaign); err != nil {
+			return err
+		}
+		return putJSON(bucket, id, campaign)
+	})
+}
+
+// Close closes the store file
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+// putCached stores a project document as JSON under its key
+func putCached(bucket *bolt.Bucket, entry CachedProject) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return bucket.Put([]byte(entry.Key), data)
+}
+
+// putJSON stores value as JSON under key
+func putJSON(bucket *bolt.Bucket, key string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return bucket.Put([]byte(key), data)
+}
+
+// putSequenced stores value as JSON under the bucket's next sequence number, preserving insertion order
+func putSequenced(bucket *bolt.Bucket, value interface{}) error {
+	seq, err := bucket.NextSequence()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, seq)
+	return bucket.Put(key, data)
+}
@@ -0,0 +1,94 @@
+// internal/repository/campaign.go
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"gitlab-list/internal/domain"
+)
+
+// SaveCampaign inserts or replaces a campaign with all its projects
+func (r *MongoDBRepository) SaveCampaign(campaign domain.Campaign) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.campaigns.ReplaceOne(ctx, bson.M{"_id": campaign.ID}, campaign, options.Replace().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("failed to save campaign: %w", err)
+	}
+	return nil
+}
+
+// GetCampaign returns a campaign with its projects
+func (r *MongoDBRepository) GetCampaign(id string) (*domain.Campaign, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var campaign domain.Campaign
+	err := r.campaigns.FindOne(ctx, bson.M{"_id": id}).Decode(&campaign)
+	if err == mongo.ErrNoDocuments {
+		return nil, fmt.Errorf("%w: %s", ErrCampaignNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get campaign %s: %w", id, err)
+	}
+	return &campaign, nil
+}
+
+// ListCampaigns returns every campaign, newest first
+func (r *MongoDBRepository) ListCampaigns() ([]domain.Campaign, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := r.campaigns.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list campaigns: %w", err)
+	}
+	var campaigns []domain.Campaign
+	if err := cursor.All(ctx, &campaigns); err != nil {
+		return nil, fmt.Errorf("failed to decode campaigns: %w", err)
+	}
+	return campaigns, nil
+}
+
+// UpdateCampaignProject replaces one project of a campaign, matched by project ID and module
+// directory, without touching the others, so concurrent updates of different projects never collide
+func (r *MongoDBRepository) UpdateCampaignProject(id string, project domain.CampaignProject) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := r.campaigns.UpdateOne(ctx,
+		bson.M{
+			"_id":      id,
+			"projects": bson.M{"$elemMatch": bson.M{"project_id": project.ProjectID, "module_dir": project.ModuleDir}},
+		},
+		bson.M{"$set": bson.M{"projects.$": project, "updated_at": time.Now()}})
+	if err != nil {
+		return fmt.Errorf("failed to update campaign %s: %w", id, err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%w: project %d (%s) is not part of campaign %s", ErrCampaignNotFound, project.ProjectID, project.ModuleDir, id)
+	}
+	return nil
+}
+
+// SetCampaignStatus sets the status of a campaign
+func (r *MongoDBRepository) SetCampaignStatus(id, status string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := r.campaigns.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"status": status, "updated_at": time.Now()}})
+	if err != nil {
+		return fmt.Errorf("failed to update campaign %s: %w", id, err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%w: %s", ErrCampaignNotFound, id)
+	}
+	return nil
+}
@@ -4,41 +4,44 @@ package repository
 import (
 	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
 	"net/url"
-	"strings"
 
 	"gitlab-list/internal/configuration"
 	"gitlab-list/internal/domain"
+	"gitlab-list/internal/gitlab"
+	"gitlab-list/internal/gomod"
+	"gitlab-list/internal/openapi"
 )
 
-const gitlabAPI = "https://git.prosoftke.sk/api/v4"
-
 // GitLabRepository implements project repository using GitLab API
 type GitLabRepository struct {
 	config *configuration.Configuration
-	client *http.Client
+	client *gitlab.Client
 }
 
-// NewGitLabRepository creates a new GitLab repository instance
-func NewGitLabRepository(cfg *configuration.Configuration) *GitLabRepository {
+// NewGitLabRepository creates a GitLab repository for the configured group and tag that sends its requests through client
+func NewGitLabRepository(cfg *configuration.Configuration, client *gitlab.Client) *GitLabRepository {
 	return &GitLabRepository{
 		config: cfg,
-		client: http.DefaultClient,
+		client: client,
 	}
 }
 
-// NewGitLabRepositoryWithToken creates a new GitLab repository instance with a specific token
-func NewGitLabRepositoryWithToken(token, group, tag string) *GitLabRepository {
-	cfg := &configuration.Configuration{
-		Token: token,
-		Group: group,
-		Tag:   tag,
+// WithToken returns a repository for the same GitLab instance, group and tag that authenticates with token
+func (r *GitLabRepository) WithToken(token string) ProjectRepository {
+	cfg := *r.config
+	cfg.Token = token
+	return &GitLabRepository{
+		config: &cfg,
+		client: r.client.WithToken(token),
 	}
+}
+
+// WithCallCounter returns a repository that counts its GitLab API requests in counter
+func (r *GitLabRepository) WithCallCounter(counter *gitlab.CallCounter) ProjectRepository {
 	return &GitLabRepository{
-		config: cfg,
-		client: http.DefaultClient,
+		config: r.config,
+		client: r.client.WithCallCounter(counter),
 	}
 }
 
@@ -61,10 +64,9 @@ func (r *GitLabRepository) GetProjects() ([]domain.Project, error) {
 		groupPath := r.config.Group
 		escaped := url.PathEscape(groupPath)
 
-		url := fmt.Sprintf("%s/groups/%s/projects?include_subgroups=true&per_page=100&page=%d",
-			gitlabAPI, escaped, page)
+		path := fmt.Sprintf("/groups/%s/projects?include_subgroups=true&per_page=100&page=%d", escaped, page)
 
-		resp, err := r.makeRequest(url)
+		resp, err := r.client.Get(path)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get projects: %w", err)
 		}
@@ -92,8 +94,7 @@ func (r *GitLabRepository) GetProjects() ([]domain.Project, error) {
 // GetProjectDetails retrieves detailed information about a project including Go version and dependencies
 func (r *GitLabRepository) GetProjectDetails(projectID int, ref string) (*domain.Project, error) {
 	// Get basic project info
-	url := fmt.Sprintf("%s/projects/%d", gitlabAPI, projectID)
-	resp, err := r.makeRequest(url)
+	resp, err := r.client.Get(fmt.Sprintf("/projects/%d", projectID))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get project details: %w", err)
 	}
@@ -103,242 +104,238 @@ func (r *GitLabRepository) GetProjectDetails(projectID int, ref string) (*domain
 	if err = json.NewDecoder(resp.Body).Decode(&project); err != nil {
 		return nil, fmt.Errorf("failed to decode project: %w", err)
 	}
-
-	// Get Go version from go.mod
-	goVersion, err := r.getGoVersion(projectID, ref)
-	if err == nil {
-		project.GoVersion = goVersion
+	project.Ref = ref
+	if project.Ref == "" {
+		project.Ref = project.DefaultBranch
 	}
 
-	// Get dependencies
-	libraries, err := r.getDependencies(projectID, ref)
-	if err == nil {
-		project.Libraries = libraries
+	// List the repository tree once; go.mod, go.work and OpenAPI discovery all work from it
+	paths, err := r.listFiles(projectID, ref)
+	if err != nil {
+		return nil, err
 	}
 
-	// Get OpenAPI specification
-	openAPI, err := r.getOpenAPI(projectID, ref)
+	// Get every module with its Go version and dependencies (no go.mod just means this is not a Go project)
+	modules, workspace, err := r.getModules(projectID, ref, paths)
 	if err != nil {
-		// Log the error but don't fail the entire operation
-		fmt.Printf("Warning: Failed to get OpenAPI for project %d (%s): %v\n", projectID, project.Name, err)
-		project.OpenAPI = &domain.OpenAPI{Found: false}
-	} else {
-		project.OpenAPI = openAPI
-		if openAPI.Found {
-			fmt.Printf("Found OpenAPI for project %d (%s): %s\n", projectID, project.Name, openAPI.Path)
+		return nil, err
+	}
+	project.Modules = modules
+	project.Workspace = workspace
+	for _, module := range modules {
+		if module.Dir == gomod.RootDir {
+			project.GoVersion = module.GoVersion
+			project.Toolchain = module.Toolchain
+			project.Libraries = module.Libraries
+			project.Excludes = module.Excludes
 		}
 	}
 
+	// Get OpenAPI specifications
+	specs, err := r.getOpenAPISpecs(projectID, ref, paths)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get OpenAPI: %w", err)
+	}
+	project.OpenAPISpecs = specs
+	for _, spec := range specs {
+		fmt.Printf("Found OpenAPI for project %d (%s): %s\n", projectID, project.Name, spec.Path)
+	}
+
 	return &project, nil
 }
 
-// getGoVersion retrieves the Go version from go.mod file
-func (r *GitLabRepository) getGoVersion(projectID int, ref string) (string, error) {
-	filePath := "go.mod"
-	escaped := url.PathEscape(filePath)
-	requestURL := fmt.Sprintf("%s/projects/%d/repository/files/%s/raw", gitlabAPI, projectID, escaped)
-
-	if strings.TrimSpace(ref) != "" {
-		requestURL += "?ref=" + url.QueryEscape(ref)
+// GetBranch retrieves a single branch; a missing branch yields an error matching gitlab.ErrNotFound
+func (r *GitLabRepository) GetBranch(projectID int, branch string) (*domain.Branch, error) {
+	var payload struct {
+		Name    string `json:"name"`
+		Default bool   `json:"default"`
+		Commit  struct {
+			ID string `json:"id"`
+		} `json:"commit"`
 	}
 
-	resp, err := r.makeRequest(requestURL)
-	if err != nil {
-		return "", err
+	path := fmt.Sprintf("/projects/%d/repository/branches/%s", projectID, url.PathEscape(branch))
+	if err := r.client.GetJSON(path, &payload); err != nil {
+		return nil, fmt.Errorf("failed to get branch %s: %w", branch, err)
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
-	}
-
-	// Parse go.mod to extract Go version
-	return r.parseGoVersion(body), nil
+	return &domain.Branch{
+		Name:      payload.Name,
+		CommitSHA: payload.Commit.ID,
+		Default:   payload.Default,
+	}, nil
 }
 
-// getDependencies retrieves dependencies from go.mod file
-func (r *GitLabRepository) getDependencies(projectID int, ref string) ([]domain.Library, error) {
-	filePath := "go.mod"
-	escaped := url.PathEscape(filePath)
-	requestURL := fmt.Sprintf("%s/projects/%d/repository/files/%s/raw", gitlabAPI, projectID, escaped)
-
-	if strings.TrimSpace(ref) != "" {
-		requestURL += "?ref=" + url.QueryEscape(ref)
+// listFiles returns the paths of all files in the repository at ref
+func (r *GitLabRepository) listFiles(projectID int, ref string) ([]string, error) {
+	tree, err := r.client.ListTree(projectID, ref)
+	if gitlab.IsNotFound(err) {
+		// Empty repositories have no tree
+		return nil, nil
 	}
-
-	resp, err := r.makeRequest(requestURL)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to list repository tree: %w", err)
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+	var paths []string
+	for _, entry := range tree {
+		if entry.Type == "blob" {
+			paths = append(paths, entry.Path)
+		}
 	}
-
-	// Parse go.mod to extract dependencies
-	return r.parseDependencies(body), nil
+	return paths, nil
 }
 
-// getOpenAPI retrieves OpenAPI specification from common file locations
-func (r *GitLabRepository) getOpenAPI(projectID int, ref string) (*domain.OpenAPI, error) {
-	// Common OpenAPI file locations to check
-	openAPIPaths := []string{
-		"openapi.yaml",
-		"openapi.yml",
-		"swagger.yaml",
-		"swagger.yml",
-		"api.yaml",
-		"api.yml",
-		"docs/openapi.yaml",
-		"docs/openapi.yml",
-		"docs/swagger.yaml",
-		"docs/swagger.yml",
-		"api/openapi.yaml",
-		"api/openapi.yml",
+// getModules reads every go.mod in the tree and the root go.work.
+// Directories named by go.work use directives are included even where the go command would skip them.
+func (r *GitLabRepository) getModules(projectID int, ref string, paths []string) ([]domain.Module, *domain.Workspace, error) {
+	files := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		files[p] = true
 	}
 
-	fmt.Printf("Searching for OpenAPI files in project %d...\n", projectID)
-	for _, filePath := range openAPIPaths {
-		openAPI, err := r.getOpenAPIFile(projectID, ref, filePath)
-		if err == nil && openAPI.Found {
-			fmt.Printf("Found OpenAPI file: %s\n", filePath)
-			return openAPI, nil
+	var workspace *domain.Workspace
+	inWorkspace := make(map[string]bool)
+	dirs := gomod.ModuleDirs(paths)
+	if files["go.work"] {
+		data, err := r.client.GetRawFile(projectID, "go.work", ref)
+		if err != nil && !gitlab.IsNotFound(err) {
+			return nil, nil, fmt.Errorf("failed to get go.work: %w", err)
 		}
-		if err != nil {
-			fmt.Printf("Error checking %s: %v\n", filePath, err)
+		if err == nil {
+			ws, err := gomod.ParseWork(gomod.RootDir, data)
+			if err != nil {
+				fmt.Printf("Warning: project %d: %v\n", projectID, err)
+			} else {
+				workspace = &domain.Workspace{GoVersion: ws.GoVersion, Use: ws.Use}
+				for _, dir := range ws.Use {
+					if !inWorkspace[dir] && files[gomod.GoModPath(dir)] && !containsString(dirs, dir) {
+						dirs = append(dirs, dir)
+					}
+					inWorkspace[dir] = true
+				}
+			}
 		}
 	}
 
-	fmt.Printf("No OpenAPI files found for project %d\n", projectID)
-	// Return empty OpenAPI if no file found
-	return &domain.OpenAPI{
-		Content: "",
-		Path:    "",
-		Found:   false,
-	}, nil
-}
-
-// getOpenAPIFile retrieves a specific OpenAPI file
-func (r *GitLabRepository) getOpenAPIFile(projectID int, ref, filePath string) (*domain.OpenAPI, error) {
-	escaped := url.PathEscape(filePath)
-	requestURL := fmt.Sprintf("%s/projects/%d/repository/files/%s/raw", gitlabAPI, projectID, escaped)
+	var modules []domain.Module
+	for _, dir := range dirs {
+		goModPath := gomod.GoModPath(dir)
+		data, err := r.client.GetRawFile(projectID, goModPath, ref)
+		if gitlab.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get %s: %w", goModPath, err)
+		}
 
-	if strings.TrimSpace(ref) != "" {
-		requestURL += "?ref=" + url.QueryEscape(ref)
+		module, err := ParseModule(goModPath, data)
+		if err != nil {
+			// A broken go.mod should not hide the rest of the project
+			fmt.Printf("Warning: project %d: %v\n", projectID, err)
+			module = domain.Module{Path: gomod.ModulePath(data)}
+		}
+		module.Dir = dir
+		module.InWorkspace = inWorkspace[dir]
+		modules = append(modules, module)
 	}
 
-	fmt.Printf("Trying to fetch OpenAPI file: %s (URL: %s)\n", filePath, requestURL)
-	resp, err := r.makeRequest(requestURL)
-	if err != nil {
-		fmt.Printf("Failed to fetch %s: %v\n", filePath, err)
-		return &domain.OpenAPI{Found: false}, err
-	}
-	defer resp.Body.Close()
+	return modules, workspace, nil
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		fmt.Printf("Failed to read body for %s: %v\n", filePath, err)
-		return &domain.OpenAPI{Found: false}, err
+// getOpenAPISpecs finds every OpenAPI 3.x or Swagger 2.0 document among the repository files.
+// Files are recognised by content rather than name, and specs split over several files are bundled.
+func (r *GitLabRepository) getOpenAPISpecs(projectID int, ref string, paths []string) ([]domain.OpenAPI, error) {
+	fetch := func(filePath string) ([]byte, error) {
+		return r.client.GetRawFile(projectID, filePath, ref)
 	}
 
-	fmt.Printf("Successfully fetched %s (%d bytes)\n", filePath, len(body))
-	return &domain.OpenAPI{
-		Content: string(body),
-		Path:    filePath,
-		Found:   true,
-	}, nil
-}
+	var specs []domain.OpenAPI
+	for _, filePath := range openapi.Candidates(paths) {
+		body, err := fetch(filePath)
+		if gitlab.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error checking %s: %w", filePath, err)
+		}
 
-// makeRequest makes an authenticated request to GitLab API
-func (r *GitLabRepository) makeRequest(url string) (*http.Response, error) {
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
+		info, ok := openapi.Detect(filePath, body)
+		if !ok {
+			continue
+		}
 
-	req.Header.Set("PRIVATE-TOKEN", r.config.Token)
+		spec := domain.OpenAPI{
+			Content: string(body),
+			Path:    filePath,
+			Found:   true,
+			Format:  info.Format,
+			Version: info.Version,
+			Title:   info.Title,
+		}
 
-	resp, err := r.client.Do(req)
-	if err != nil {
-		return nil, err
-	}
+		bundled, files, err := openapi.Bundle(filePath, body, fetch)
+		if err != nil {
+			// Keep the unbundled spec rather than dropping it
+			fmt.Printf("Warning: failed to bundle OpenAPI %s in project %d: %v\n", filePath, projectID, err)
+		} else {
+			spec.Content = string(bundled)
+			spec.Files = files
+		}
 
-	if resp.StatusCode >= 400 {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("GitLab API error: %s\nResponse: %s", resp.Status, string(body))
+		specs = append(specs, spec)
 	}
 
-	return resp, nil
+	return specs, nil
 }
 
-// parseGoVersion extracts Go version from go.mod content
-func (r *GitLabRepository) parseGoVersion(data []byte) string {
-	// Simple parsing - look for "go 1.x" line
-	lines := strings.Split(string(data), "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "go ") {
-			return strings.TrimPrefix(line, "go ")
-		}
+// ParseModule converts a go.mod into a module with its requirements, effective replacements,
+// excludes, retractions and toolchain. Dir and InWorkspace are left to the caller.
+func ParseModule(goModPath string, data []byte) (domain.Module, error) {
+	file, err := gomod.Parse(goModPath, data)
+	if err != nil {
+		return domain.Module{}, err
 	}
-	return ""
-}
-
-// parseDependencies extracts dependencies from go.mod content
-func (r *GitLabRepository) parseDependencies(data []byte) []domain.Library {
-	var libraries []domain.Library
-	lines := strings.Split(string(data), "\n")
 
-	// Debug: log the go.mod content for troubleshooting (uncomment for debugging)
-	// fmt.Printf("DEBUG: Parsing go.mod content:\n%s\n", string(data))
-
-	inRequireBlock := false
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-
-		// Check if we're entering the require block
-		if line == "require (" {
-			inRequireBlock = true
-			continue
-		}
-
-		// Check if we're exiting the require block
-		if line == ")" && inRequireBlock {
-			inRequireBlock = false
-			continue
+	module := domain.Module{
+		Path:      file.Module,
+		GoVersion: file.GoVersion,
+		Toolchain: file.Toolchain,
+	}
+	for _, req := range file.Requires {
+		lib := domain.Library{
+			Name:     req.Path,
+			Version:  req.Version,
+			Indirect: req.Indirect,
 		}
-
-		// Check for single-line require statements
-		if strings.HasPrefix(line, "require ") {
-			// Single line require: "require module version"
-			parts := strings.Fields(line)
-			if len(parts) >= 3 {
-				lib := domain.Library{
-					Name:    parts[1],
-					Version: parts[2],
-				}
-				libraries = append(libraries, lib)
+		if rep := file.Replacement(req.Path, req.Version); rep != nil {
+			lib.Replace = &domain.Replacement{
+				Path:    rep.NewPath,
+				Version: rep.NewVersion,
+				Local:   rep.Local(),
 			}
-			continue
 		}
-
-		// Check for multi-line require block entries
-		if inRequireBlock && line != "" && !strings.HasPrefix(line, "//") {
-			// Multi-line require block: "module version"
-			parts := strings.Fields(line)
-			if len(parts) >= 2 {
-				lib := domain.Library{
-					Name:    parts[0],
-					Version: parts[1],
-				}
-				libraries = append(libraries, lib)
-			}
+		module.Libraries = append(module.Libraries, lib)
+	}
+	for _, exc := range file.Excludes {
+		module.Excludes = append(module.Excludes, domain.ModuleVersion{Path: exc.Path, Version: exc.Version})
+	}
+	for _, ret := range file.Retracts {
+		if ret.Low == ret.High {
+			module.Retracts = append(module.Retracts, ret.Low)
+		} else {
+			module.Retracts = append(module.Retracts, fmt.Sprintf("[%s, %s]", ret.Low, ret.High))
 		}
 	}
+	return module, nil
+}
 
-	// fmt.Printf("DEBUG: Parsed %d libraries\n", len(libraries))
-	return libraries
+// containsString reports whether values contains value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
 }
@@ -1,12 +1,60 @@
 // internal/repository/interfaces.go
 package repository
 
-import "gitlab-list/internal/domain"
+import (
+	"time"
+
+	"gitlab-list/internal/domain"
+	"gitlab-list/internal/gitlab"
+)
 
 // ProjectRepository defines the interface for project data access
 type ProjectRepository interface {
 	GetProjects() ([]domain.Project, error)
 	GetProjectDetails(projectID int, ref string) (*domain.Project, error)
+	GetBranch(projectID int, branch string) (*domain.Branch, error)
 	GetGroup() string
 	GetTag() string
+	WithToken(token string) ProjectRepository
+	WithCallCounter(counter *gitlab.CallCounter) ProjectRepository
+}
+
+// CacheStore defines the interface for the project cache and sync snapshots.
+// The cache holds one document per (project, ref), keyed by ProjectKey; searches query those documents.
+// Scheduled job state, run history, leases and campaigns live next to the cache so the API and the scheduler share them.
+// It is implemented by MongoDB, an embedded bbolt file and an in-memory store.
+type CacheStore interface {
+	UpsertProjects(projects []domain.Project, projectHashes map[string]string, fetchedAt time.Time, fence Fence) error
+	PruneProjects(projectID int, before time.Time, fence Fence) (int64, error)
+	GetCachedProjects(filter ProjectFilter) ([]domain.Project, error)
+	GetCachedProjectsWithHashes(filter ProjectFilter) ([]CachedProject, error)
+	IsCacheValid() (bool, error)
+	ClearAllCache() error
+	GetCacheStats() (map[string]interface{}, error)
+
+	SaveSnapshot(projects []domain.ProjectSnapshot) error
+	ListSnapshots() ([]domain.Snapshot, error)
+	GetSnapshotProjects(snapshotIDs []string, withLibraries bool) ([]domain.ProjectSnapshot, error)
+	DeleteSnapshots(snapshotIDs []string) (int64, error)
+
+	RegisterJob(job domain.ScheduledJob) error
+	ListJobs() ([]domain.ScheduledJob, error)
+	SetJobPaused(name string, paused bool) error
+	RequestJobRun(name string, at time.Time) error
+	TakeJobRequest(name string) (bool, error)
+	SaveJobRun(run domain.JobRun) error
+	ListJobRuns(job string, limit int) ([]domain.JobRun, error)
+
+	AcquireLease(name, holder string, ttl time.Duration) (*domain.Lease, error)
+	RenewLease(name string, token int64, ttl time.Duration) (*domain.Lease, error)
+	ReleaseLease(name string, token int64) error
+	ListLeases() ([]domain.Lease, error)
+
+	SaveCampaign(campaign domain.Campaign) error
+	GetCampaign(id string) (*domain.Campaign, error)
+	ListCampaigns() ([]domain.Campaign, error)
+	UpdateCampaignProject(id string, project domain.CampaignProject) error
+	SetCampaignStatus(id, status string) error
+
+	Close() error
 }
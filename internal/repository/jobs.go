@@ -0,0 +1,134 @@
+// internal/repository/jobs.go
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"gitlab-list/internal/domain"
+)
+
+// RegisterJob stores the configuration and next run of a scheduled job, keeping its pause flag
+// and pending run request
+func (r *MongoDBRepository) RegisterJob(job domain.ScheduledJob) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.jobs.UpdateOne(ctx,
+		bson.M{"_id": job.Name},
+		bson.M{
+			"$set": bson.M{
+				"description": job.Description,
+				"schedule":    job.Schedule,
+				"enabled":     job.Enabled,
+				"timeout":     job.Timeout,
+				"next_run":    job.NextRun,
+				"updated_at":  job.UpdatedAt,
+			},
+			"$setOnInsert": bson.M{"paused": false},
+		},
+		options.Update().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("failed to register job %s: %w", job.Name, err)
+	}
+	return nil
+}
+
+// ListJobs returns every registered job by name
+func (r *MongoDBRepository) ListJobs() ([]domain.ScheduledJob, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := r.jobs.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %w", err)
+	}
+	var jobs []domain.ScheduledJob
+	if err := cursor.All(ctx, &jobs); err != nil {
+		return nil, fmt.Errorf("failed to decode jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// SetJobPaused pauses or resumes a job
+func (r *MongoDBRepository) SetJobPaused(name string, paused bool) error {
+	return r.updateJob(name, bson.M{"$set": bson.M{"paused": paused}})
+}
+
+// RequestJobRun asks the scheduler to run a job as soon as it polls
+func (r *MongoDBRepository) RequestJobRun(name string, at time.Time) error {
+	return r.updateJob(name, bson.M{"$set": bson.M{"triggered_at": at}})
+}
+
+func (r *MongoDBRepository) updateJob(name string, update bson.M) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := r.jobs.UpdateOne(ctx, bson.M{"_id": name}, update)
+	if err != nil {
+		return fmt.Errorf("failed to update job %s: %w", name, err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+	return nil
+}
+
+// TakeJobRequest clears a pending run request and reports whether there was one.
+// Only one caller sees true for each request.
+func (r *MongoDBRepository) TakeJobRequest(name string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err := r.jobs.FindOneAndUpdate(ctx,
+		bson.M{"_id": name, "triggered_at": bson.M{"$exists": true}},
+		bson.M{"$unset": bson.M{"triggered_at": ""}}).Err()
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to take run request of job %s: %w", name, err)
+	}
+	return true, nil
+}
+
+// SaveJobRun inserts or replaces a run record
+func (r *MongoDBRepository) SaveJobRun(run domain.JobRun) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.jobRuns.ReplaceOne(ctx, bson.M{"_id": run.ID}, run, options.Replace().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("failed to save job run: %w", err)
+	}
+	return nil
+}
+
+// ListJobRuns returns the runs of a job (of all jobs when job is empty), newest first, at most limit (0 = all)
+func (r *MongoDBRepository) ListJobRuns(job string, limit int) ([]domain.JobRun, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{}
+	if job != "" {
+		filter["job"] = job
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "started_at", Value: -1}})
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+	cursor, err := r.jobRuns.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list job runs: %w", err)
+	}
+	var runs []domain.JobRun
+	if err := cursor.All(ctx, &runs); err != nil {
+		return nil, fmt.Errorf("failed to decode job runs: %w", err)
+	}
+	return runs, nil
+}
@@ -0,0 +1,147 @@
+// internal/repository/lease.go
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"gitlab-list/internal/domain"
+)
+
+// AcquireLease takes the lease for holder when it is free or expired, with the next fencing token.
+// A held lease returns a *LeaseHeldError describing the current holder.
+func (r *MongoDBRepository) AcquireLease(name, holder string, ttl time.Duration) (*domain.Lease, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// The clock of the acquiring process decides expiry; replicas are expected to run NTP
+	now := time.Now()
+	filter := bson.M{
+		"_id": name,
+		"$or": []bson.M{
+			{"holder": ""},
+			{"expires_at": bson.M{"$lte": now}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"holder":      holder,
+			"acquired_at": now,
+			"renewed_at":  now,
+			"expires_at":  now.Add(ttl),
+		},
+		"$inc": bson.M{"token": 1},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var lease domain.Lease
+	err := r.leases.FindOneAndUpdate(ctx, filter, update, opts).Decode(&lease)
+	if mongo.IsDuplicateKeyError(err) {
+		// The lease exists and is held, so the filter missed and the upsert collided with its _id
+		var current domain.Lease
+		if err := r.leases.FindOne(ctx, bson.M{"_id": name}).Decode(&current); err != nil {
+			return nil, fmt.Errorf("failed to read lease %s: %w", name, err)
+		}
+		return nil, &LeaseHeldError{Lease: current}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire lease %s: %w", name, err)
+	}
+	return &lease, nil
+}
+
+// RenewLease extends the lease while it still carries token
+func (r *MongoDBRepository) RenewLease(name string, token int64, ttl time.Duration) (*domain.Lease, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	var lease domain.Lease
+	err := r.leases.FindOneAndUpdate(ctx,
+		bson.M{"_id": name, "token": token, "holder": bson.M{"$ne": ""}},
+		bson.M{"$set": bson.M{"renewed_at": now, "expires_at": now.Add(ttl)}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&lease)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("%w: %s", ErrLeaseLost, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to renew lease %s: %w", name, err)
+	}
+	return &lease, nil
+}
+
+// ReleaseLease frees the lease if it still carries token
+func (r *MongoDBRepository) ReleaseLease(name string, token int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.leases.UpdateOne(ctx,
+		bson.M{"_id": name, "token": token},
+		bson.M{"$set": bson.M{"holder": "", "expires_at": time.Now()}})
+	if err != nil {
+		return fmt.Errorf("failed to release lease %s: %w", name, err)
+	}
+	return nil
+}
+
+// fenced runs write unless fence is lost. The lease document is touched first, in the same transaction as
+// write, so a takeover of the lease conflicts with the write instead of slipping in between. A standalone
+// server has no transactions and only gets the check before write.
+func (r *MongoDBRepository) fenced(ctx context.Context, fence Fence, write func(ctx context.Context) error) error {
+	if fence.Lease == "" {
+		return write(ctx)
+	}
+	if !r.transactions {
+		if err := r.touchFence(ctx, fence); err != nil {
+			return err
+		}
+		return write(ctx)
+	}
+
+	return r.client.UseSession(ctx, func(sc mongo.SessionContext) error {
+		_, err := sc.WithTransaction(sc, func(sc mongo.SessionContext) (interface{}, error) {
+			if err := r.touchFence(sc, fence); err != nil {
+				return nil, err
+			}
+			return nil, write(sc)
+		})
+		return err
+	})
+}
+
+// touchFence marks the lease of fence as written under, or returns ErrLeaseLost when it moved on
+func (r *MongoDBRepository) touchFence(ctx context.Context, fence Fence) error {
+	now := time.Now()
+	result, err := r.leases.UpdateOne(ctx,
+		bson.M{"_id": fence.Lease, "token": fence.Token, "holder": bson.M{"$ne": ""}, "expires_at": bson.M{"$gt": now}},
+		bson.M{"$set": bson.M{"fenced_at": now}})
+	if err != nil {
+		return fmt.Errorf("failed to check lease %s: %w", fence.Lease, err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%w: %s is no longer held with token %d", ErrLeaseLost, fence.Lease, fence.Token)
+	}
+	return nil
+}
+
+// ListLeases returns every lease ever taken, by name
+func (r *MongoDBRepository) ListLeases() ([]domain.Lease, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := r.leases.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list leases: %w", err)
+	}
+	var leases []domain.Lease
+	if err := cursor.All(ctx, &leases); err != nil {
+		return nil, fmt.Errorf("failed to decode leases: %w", err)
+	}
+	return leases, nil
+}
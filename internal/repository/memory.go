@@ -0,0 +1,411 @@
+// internal/repository/memory.go
+package repository
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"gitlab-list/internal/domain"
+)
+
+// MemoryStore implements the cache in process memory. It needs no setup, which suits
+// laptops, CI jobs and tests; its contents are lost on restart.
+type MemoryStore struct {
+	mu        sync.RWMutex
+	projects  map[string]CachedProject // By ProjectKey
+	snapshots []domain.ProjectSnapshot
+	jobs      map[string]domain.ScheduledJob
+	jobRuns   map[string]domain.JobRun // By run ID
+	leases    map[string]domain.Lease
+	campaigns map[string]domain.Campaign
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		projects:  make(map[string]CachedProject),
+		jobs:      make(map[string]domain.ScheduledJob),
+		jobRuns:   make(map[string]domain.JobRun),
+		leases:    make(map[string]domain.Lease),
+		campaigns: make(map[string]domain.Campaign),
+	}
+}
+
+// UpsertProjects writes the document of every project record under one lock, unless fence is lost.
+// projectHashes is keyed by ProjectKey. A document fetched after fetchedAt is left untouched.
+func (s *MemoryStore) UpsertProjects(projects []domain.Project, projectHashes map[string]string, fetchedAt time.Time, fence Fence) error {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkFence(fence, now); err != nil {
+		return err
+	}
+	for _, project := range projects {
+		key := ProjectKey(project)
+		var existing *CachedProject
+		if entry, ok := s.projects[key]; ok {
+			existing = &entry
+		}
+		if entry, ok := upsertCached(existing, project, projectHashes[key], fetchedAt, now); ok {
+			s.projects[key] = entry
+		}
+	}
+	return nil
+}
+
+// PruneProjects removes the documents fetched before before, of one project or of all when projectID is 0,
+// unless fence is lost
+func (s *MemoryStore) PruneProjects(projectID int, before time.Time, fence Fence) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkFence(fence, time.Now()); err != nil {
+		return 0, err
+	}
+
+	var deleted int64
+	for key, entry := range s.projects {
+		if prunable(entry, projectID, before) {
+			delete(s.projects, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// checkFence checks fence against the stored lease; the caller holds the lock
+func (s *MemoryStore) checkFence(fence Fence, now time.Time) error {
+	var current *domain.Lease
+	if lease, ok := s.leases[fence.Lease]; ok {
+		current = &lease
+	}
+	return fence.check(current, now)
+}
+
+// GetCachedProjects retrieves the cached projects matching filter
+func (s *MemoryStore) GetCachedProjects(filter ProjectFilter) ([]domain.Project, error) {
+	cached, _ := s.GetCachedProjectsWithHashes(filter)
+	return cachedProjects(cached), nil
+}
+
+// GetCachedProjectsWithHashes retrieves the cached documents matching filter
+func (s *MemoryStore) GetCachedProjectsWithHashes(filter ProjectFilter) ([]CachedProject, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var cached []CachedProject
+	for _, entry := range s.projects {
+		if filter.Matches(entry.Project) {
+			cached = append(cached, entry)
+		}
+	}
+	sortCached(cached)
+	return cached, nil
+}
+
+// IsCacheValid checks if any project is cached
+func (s *MemoryStore) IsCacheValid() (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.projects) > 0, nil
+}
+
+// ClearAllCache removes all cache entries; snapshots are kept
+func (s *MemoryStore) ClearAllCache() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.projects = make(map[string]CachedProject)
+	return nil
+}
+
+// GetCacheStats returns cache statistics
+func (s *MemoryStore) GetCacheStats() (map[string]interface{}, error) {
+	cached, _ := s.GetCachedProjectsWithHashes(ProjectFilter{})
+	return cacheStats(cached, "memory"), nil
+}
+
+// SaveSnapshot stores the project records of one sync
+func (s *MemoryStore) SaveSnapshot(projects []domain.ProjectSnapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snapshots = append(s.snapshots, projects...)
+	return nil
+}
+
+// ListSnapshots returns every stored snapshot, oldest first
+func (s *MemoryStore) ListSnapshots() ([]domain.Snapshot, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return summarizeSnapshots(s.snapshots), nil
+}
+
+// GetSnapshotProjects returns the project records of the given snapshots, oldest first
+func (s *MemoryStore) GetSnapshotProjects(snapshotIDs []string, withLibraries bool) ([]domain.ProjectSnapshot, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var projects []domain.ProjectSnapshot
+	for _, record := range s.snapshots {
+		if !containsID(snapshotIDs, record.SnapshotID) {
+			continue
+		}
+		if !withLibraries {
+			record = withoutLibraries(record)
+		}
+		projects = append(projects, record)
+	}
+	sort.SliceStable(projects, func(i, j int) bool { return projects[i].TakenAt.Before(projects[j].TakenAt) })
+	return projects, nil
+}
+
+// DeleteSnapshots removes whole snapshots
+func (s *MemoryStore) DeleteSnapshots(snapshotIDs []string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.snapshots[:0]
+	var deleted int64
+	for _, record := range s.snapshots {
+		if containsID(snapshotIDs, record.SnapshotID) {
+			deleted++
+			continue
+		}
+		kept = append(kept, record)
+	}
+	s.snapshots = kept
+	return deleted, nil
+}
+
+// RegisterJob stores the configuration and next run of a scheduled job, keeping its pause flag
+// and pending run request
+func (s *MemoryStore) RegisterJob(job domain.ScheduledJob) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var existing *domain.ScheduledJob
+	if state, ok := s.jobs[job.Name]; ok {
+		existing = &state
+	}
+	s.jobs[job.Name] = registeredJob(existing, job)
+	return nil
+}
+
+// ListJobs returns every registered job by name
+func (s *MemoryStore) ListJobs() ([]domain.ScheduledJob, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	jobs := make([]domain.ScheduledJob, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, job)
+	}
+	sortJobs(jobs)
+	return jobs, nil
+}
+
+// SetJobPaused pauses or resumes a job
+func (s *MemoryStore) SetJobPaused(name string, paused bool) error {
+	return s.updateJob(name, func(job *domain.ScheduledJob) { job.Paused = paused })
+}
+
+// RequestJobRun asks the scheduler to run a job as soon as it polls
+func (s *MemoryStore) RequestJobRun(name string, at time.Time) error {
+	return s.updateJob(name, func(job *domain.ScheduledJob) { job.TriggeredAt = &at })
+}
+
+func (s *MemoryStore) updateJob(name string, update func(job *domain.ScheduledJob)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+	update(&job)
+	s.jobs[name] = job
+	return nil
+}
+
+// TakeJobRequest clears a pending run request and reports whether there was one
+func (s *MemoryStore) TakeJobRequest(name string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[name]
+	if !ok || job.TriggeredAt == nil {
+		return false, nil
+	}
+	job.TriggeredAt = nil
+	s.jobs[name] = job
+	return true, nil
+}
+
+// SaveJobRun inserts or replaces a run record
+func (s *MemoryStore) SaveJobRun(run domain.JobRun) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobRuns[run.ID] = run
+	return nil
+}
+
+// ListJobRuns returns the runs of a job (of all jobs when job is empty), newest first, at most limit (0 = all)
+func (s *MemoryStore) ListJobRuns(job string, limit int) ([]domain.JobRun, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var runs []domain.JobRun
+	for _, run := range s.jobRuns {
+		if job == "" || run.Job == job {
+			runs = append(runs, run)
+		}
+	}
+	return latestRuns(runs, limit), nil
+}
+
+// AcquireLease takes the lease for holder when it is free or expired, with the next fencing token.
+// A held lease returns a *LeaseHeldError describing the current holder.
+func (s *MemoryStore) AcquireLease(name, holder string, ttl time.Duration) (*domain.Lease, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var existing *domain.Lease
+	if current, ok := s.leases[name]; ok {
+		existing = &current
+	}
+	lease, err := acquiredLease(existing, name, holder, ttl, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	s.leases[name] = lease
+	return &lease, nil
+}
+
+// RenewLease extends the lease while it still carries token
+func (s *MemoryStore) RenewLease(name string, token int64, ttl time.Duration) (*domain.Lease, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var existing *domain.Lease
+	if current, ok := s.leases[name]; ok {
+		existing = &current
+	}
+	lease, err := renewedLease(existing, token, ttl, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, name)
+	}
+	s.leases[name] = lease
+	return &lease, nil
+}
+
+// ReleaseLease frees the lease if it still carries token
+func (s *MemoryStore) ReleaseLease(name string, token int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var existing *domain.Lease
+	if current, ok := s.leases[name]; ok {
+		existing = &current
+	}
+	if lease, ok := releasedLease(existing, token, time.Now()); ok {
+		s.leases[name] = lease
+	}
+	return nil
+}
+
+// ListLeases returns every lease ever taken, by name
+func (s *MemoryStore) ListLeases() ([]domain.Lease, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	leases := make([]domain.Lease, 0, len(s.leases))
+	for _, lease := range s.leases {
+		leases = append(leases, lease)
+	}
+	sortLeases(leases)
+	return leases, nil
+}
+
+// SaveCampaign inserts or replaces a campaign with all its projects
+func (s *MemoryStore) SaveCampaign(campaign domain.Campaign) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.campaigns[campaign.ID] = copyCampaign(campaign)
+	return nil
+}
+
+// GetCampaign returns a campaign with its projects
+func (s *MemoryStore) GetCampaign(id string) (*domain.Campaign, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	campaign, ok := s.campaigns[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrCampaignNotFound, id)
+	}
+	campaign = copyCampaign(campaign)
+	return &campaign, nil
+}
+
+// ListCampaigns returns every campaign, newest first
+func (s *MemoryStore) ListCampaigns() ([]domain.Campaign, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	campaigns := make([]domain.Campaign, 0, len(s.campaigns))
+	for _, campaign := range s.campaigns {
+		campaigns = append(campaigns, copyCampaign(campaign))
+	}
+	sortCampaigns(campaigns)
+	return campaigns, nil
+}
+
+// UpdateCampaignProject replaces one project of a campaign, matched by project ID and module directory
+func (s *MemoryStore) UpdateCampaignProject(id string, project domain.CampaignProject) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	campaign, ok := s.campaigns[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrCampaignNotFound, id)
+	}
+	campaign = copyCampaign(campaign)
+	if err := replaceCampaignProject(&campaign, project, time.Now()); err != nil {
+		return err
+	}
+	s.campaigns[id] = copyCampaign(campaign)
+	return nil
+}
+
+// SetCampaignStatus sets the status of a campaign
+func (s *MemoryStore) SetCampaignStatus(id, status string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	campaign, ok := s.campaigns[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrCampaignNotFound, id)
+	}
+	campaign.Status = status
+	campaign.UpdatedAt = time.Now()
+	s.campaigns[id] = campaign
+	return nil
+}
+
+// copyCampaign copies the projects of a campaign, so callers never share them with the store
+func copyCampaign(campaign domain.Campaign) domain.Campaign {
+	campaign.Projects = append([]domain.CampaignProject(nil), campaign.Projects...)
+	for i, project := range campaign.Projects {
+		if project.MergeRequest != nil {
+			mr := *project.MergeRequest
+			campaign.Projects[i].MergeRequest = &mr
+		}
+	}
+	return campaign
+}
+
+// Close releases nothing; it exists to satisfy CacheStore
+func (s *MemoryStore) Close() error {
+	return nil
+}
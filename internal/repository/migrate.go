@@ -0,0 +1,114 @@
+// internal/repository/migrate.go
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+
+	bolt "go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// cacheSchemaKey names the stored cache schema version
+const cacheSchemaKey = "cache_schema"
+
+// migrate brings the MongoDB cache to CacheSchemaVersion. Version 1 documents of the full sync become
+// the per-project documents; copies kept for other search hashes are dropped. Rerunning is safe.
+func (r *MongoDBRepository) migrate(ctx context.Context) error {
+	var meta struct {
+		Version int `bson:"version"`
+	}
+	err := r.meta.FindOne(ctx, bson.M{"_id": cacheSchemaKey}).Decode(&meta)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return fmt.Errorf("failed to read cache schema version: %w", err)
+	}
+	if meta.Version >= CacheSchemaVersion {
+		return nil
+	}
+
+	cursor, err := r.collection.Find(ctx, bson.M{"search_hash": legacyInitialLoadHash})
+	if err != nil {
+		return fmt.Errorf("failed to find version 1 cache: %w", err)
+	}
+	var legacy []legacyCachedProject
+	if err := cursor.All(ctx, &legacy); err != nil {
+		return fmt.Errorf("failed to decode version 1 cache: %w", err)
+	}
+
+	if len(legacy) > 0 {
+		models := make([]mongo.WriteModel, 0, len(legacy))
+		for _, entry := range legacy {
+			models = append(models, upsertModel(entry.Project, entry.ProjectHash, entry.UpdatedAt, entry.UpdatedAt))
+		}
+		if err := r.bulkUpsert(ctx, models); err != nil {
+			return fmt.Errorf("failed to migrate cache: %w", err)
+		}
+	}
+	if _, err := r.collection.DeleteMany(ctx, bson.M{"search_hash": bson.M{"$exists": true}}); err != nil {
+		return fmt.Errorf("failed to remove version 1 cache: %w", err)
+	}
+	// The version 1 index; missing on fresh databases
+	r.collection.Indexes().DropOne(ctx, "search_hash_1")
+
+	_, err = r.meta.UpdateOne(ctx,
+		bson.M{"_id": cacheSchemaKey},
+		bson.M{"$set": bson.M{"version": CacheSchemaVersion}},
+		options.Update().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("failed to store cache schema version: %w", err)
+	}
+
+	if len(legacy) > 0 {
+		log.Printf("Migrated the MongoDB cache to schema version %d (%d project documents)", CacheSchemaVersion, len(legacy))
+	}
+	return nil
+}
+
+// migrateBolt brings a bolt file to CacheSchemaVersion inside tx. Version 1 files kept a nested
+// bucket per search hash under "cache".
+func migrateBolt(tx *bolt.Tx) error {
+	meta := tx.Bucket(metaBucket)
+	version, _ := strconv.Atoi(string(meta.Get([]byte(cacheSchemaKey))))
+	if version >= CacheSchemaVersion {
+		return nil
+	}
+
+	migrated := 0
+	if legacyRoot := tx.Bucket(legacyCacheBucket); legacyRoot != nil {
+		projects := tx.Bucket(projectBucket)
+		if legacy := legacyRoot.Bucket([]byte(legacyInitialLoadHash)); legacy != nil {
+			err := legacy.ForEach(func(_, value []byte) error {
+				var entry legacyCachedProject
+				if err := json.Unmarshal(value, &entry); err != nil {
+					return err
+				}
+				migrated++
+				return putCached(projects, CachedProject{
+					Key:         ProjectKey(entry.Project),
+					Project:     entry.Project,
+					ProjectHash: entry.ProjectHash,
+					CreatedAt:   entry.CreatedAt,
+					UpdatedAt:   entry.UpdatedAt,
+					FetchedAt:   entry.UpdatedAt,
+					Source:      "gitlab",
+				})
+			})
+			if err != nil {
+				return fmt.Errorf("failed to migrate cache: %w", err)
+			}
+		}
+		if err := tx.DeleteBucket(legacyCacheBucket); err != nil {
+			return err
+		}
+	}
+
+	if migrated > 0 {
+		log.Printf("Migrated the bolt cache to schema version %d (%d project documents)", CacheSchemaVersion, migrated)
+	}
+	return meta.Put([]byte(cacheSchemaKey), []byte(strconv.Itoa(CacheSchemaVersion)))
+}
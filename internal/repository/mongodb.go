@@ -3,11 +3,11 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
@@ -19,17 +19,25 @@ type MongoDBRepository struct {
 	client     *mongo.Client
 	database   *mongo.Database
 	collection *mongo.Collection
+	snapshots  *mongo.Collection
+	meta       *mongo.Collection // Cache schema version
+	jobs       *mongo.Collection // Scheduled job state, one document per job
+	jobRuns    *mongo.Collection
+	leases     *mongo.Collection
+	campaigns  *mongo.Collection
+
+	transactions bool // Replica set or sharded cluster; a standalone server has no transactions
 }
 
-// CachedProject represents a cached project with metadata
+// CachedProject is the cache document of one project on one ref
 type CachedProject struct {
-	ID          primitive.ObjectID `bson:"_id,omitempty"`
-	Project     domain.Project     `bson:"project"`
-	SearchHash  string             `bson:"search_hash"`
-	ProjectHash string             `bson:"project_hash"` // Hash of project content for change detection
-	CreatedAt   time.Time          `bson:"created_at"`
-	UpdatedAt   time.Time          `bson:"updated_at"`
-	Source      string             `bson:"source"` // "gitlab" or "cache"
+	Key         string         `bson:"_id"` // ProjectKey
+	Project     domain.Project `bson:"project"`
+	ProjectHash string         `bson:"project_hash"` // Hash of project content for change detection
+	CreatedAt   time.Time      `bson:"created_at"`
+	UpdatedAt   time.Time      `bson:"updated_at"`
+	FetchedAt   time.Time      `bson:"fetched_at"` // When the details were read; older reads never replace newer ones
+	Source      string         `bson:"source"`     // "gitlab" or "cache"
 }
 
 // NewMongoDBRepository creates a new MongoDB repository
@@ -53,102 +61,217 @@ func NewMongoDBRepository(connectionString, databaseName string) (*MongoDBReposi
 	// Create indexes
 	_, err = collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
 		{
-			Keys: bson.D{{Key: "search_hash", Value: 1}},
+			Keys: bson.D{{Key: "project_hash", Value: 1}},
 		},
 		{
-			Keys: bson.D{{Key: "project_hash", Value: 1}},
+			Keys: bson.D{{Key: "fetched_at", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "project.path", Value: 1}, {Key: "project.ref", Value: 1}},
 		},
 		{
-			Keys: bson.D{{Key: "created_at", Value: 1}},
+			Keys: bson.D{{Key: "project.id", Value: 1}, {Key: "project.ref", Value: 1}},
 		},
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create indexes: %w", err)
 	}
 
-	return &MongoDBRepository{
+	snapshots := database.Collection("snapshots")
+	_, err = snapshots.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "snapshot_id", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "taken_at", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "project_id", Value: 1}, {Key: "taken_at", Value: 1}},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create snapshot indexes: %w", err)
+	}
+
+	jobRuns := database.Collection("job_runs")
+	_, err = jobRuns.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "job", Value: 1}, {Key: "started_at", Value: -1}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create job run indexes: %w", err)
+	}
+
+	// Fenced writes run in a transaction where the deployment supports them
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	if err := database.RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+		return nil, fmt.Errorf("failed to read MongoDB topology: %w", err)
+	}
+
+	r := &MongoDBRepository{
 		client:     client,
 		database:   database,
 		collection: collection,
-	}, nil
+		snapshots:  snapshots,
+		meta:       database.Collection("meta"),
+		jobs:       database.Collection("jobs"),
+		jobRuns:    jobRuns,
+		leases:     database.Collection("leases"),
+		campaigns:  database.Collection("campaigns"),
+
+		transactions: hello.SetName != "" || hello.Msg == "isdbgrid",
+	}
+	if err := r.migrate(ctx); err != nil {
+		return nil, err
+	}
+	return r, nil
 }
 
-// CacheProjects caches a list of projects with search criteria
-func (r *MongoDBRepository) CacheProjects(projects []domain.Project, searchHash string, projectHashes map[int]string) error {
+// ProjectKey identifies a cached project record, one per (project, ref)
+func ProjectKey(project domain.Project) string {
+	return fmt.Sprintf("%d@%s", project.ID, project.Ref)
+}
+
+// UpsertProjects writes the document of every project record atomically, one per (project, ref),
+// unless fence is lost. projectHashes is keyed by ProjectKey. A document fetched after fetchedAt is left untouched.
+func (r *MongoDBRepository) UpsertProjects(projects []domain.Project, projectHashes map[string]string, fetchedAt time.Time, fence Fence) error {
+	if len(projects) == 0 {
+		return nil
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	// Remove existing cache for this search
-	_, err := r.collection.DeleteMany(ctx, bson.M{"search_hash": searchHash})
+	return r.fenced(ctx, fence, func(ctx context.Context) error {
+		// Newer documents are skipped up front: inside a transaction the upsert collision
+		// bulkUpsert tolerates would abort the whole transaction
+		newer, err := r.newerProjects(ctx, projects, fetchedAt)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		models := make([]mongo.WriteModel, 0, len(projects))
+		for _, project := range projects {
+			if !newer[ProjectKey(project)] {
+				models = append(models, upsertModel(project, projectHashes[ProjectKey(project)], fetchedAt, now))
+			}
+		}
+		if len(models) == 0 {
+			return nil
+		}
+		return r.bulkUpsert(ctx, models)
+	})
+}
+
+// newerProjects returns the keys of the documents of projects fetched after fetchedAt
+func (r *MongoDBRepository) newerProjects(ctx context.Context, projects []domain.Project, fetchedAt time.Time) (map[string]bool, error) {
+	keys := make([]string, 0, len(projects))
+	for _, project := range projects {
+		keys = append(keys, ProjectKey(project))
+	}
+	cursor, err := r.collection.Find(ctx,
+		bson.M{"_id": bson.M{"$in": keys}, "fetched_at": bson.M{"$gt": fetchedAt}},
+		options.Find().SetProjection(bson.M{"_id": 1}))
 	if err != nil {
-		return fmt.Errorf("failed to clear existing cache: %w", err)
+		return nil, fmt.Errorf("failed to read cached projects: %w", err)
 	}
+	var docs []struct {
+		Key string `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("failed to decode cached projects: %w", err)
+	}
+	newer := make(map[string]bool, len(docs))
+	for _, doc := range docs {
+		newer[doc.Key] = true
+	}
+	return newer, nil
+}
 
-	// Insert new cache entries
-	var cacheEntries []interface{}
-	now := time.Now()
-
-	for _, project := range projects {
-		projectHash := projectHashes[project.ID]
-		cacheEntry := CachedProject{
-			Project:     project,
-			SearchHash:  searchHash,
-			ProjectHash: projectHash,
-			CreatedAt:   now,
-			UpdatedAt:   now,
-			Source:      "gitlab",
-		}
-		cacheEntries = append(cacheEntries, cacheEntry)
+// upsertModel replaces a project document unless it holds a newer read
+func upsertModel(project domain.Project, projectHash string, fetchedAt, now time.Time) mongo.WriteModel {
+	filter := bson.M{
+		"_id": ProjectKey(project),
+		"$or": []bson.M{
+			{"fetched_at": bson.M{"$lte": fetchedAt}},
+			{"fetched_at": bson.M{"$exists": false}},
+		},
 	}
+	update := bson.M{
+		"$set": bson.M{
+			"project":      project,
+			"project_hash": projectHash,
+			"updated_at":   now,
+			"fetched_at":   fetchedAt,
+			"source":       "gitlab",
+		},
+		"$setOnInsert": bson.M{"created_at": now},
+	}
+	return mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(update).SetUpsert(true)
+}
 
-	if len(cacheEntries) > 0 {
-		_, err = r.collection.InsertMany(ctx, cacheEntries)
-		if err != nil {
-			return fmt.Errorf("failed to cache projects: %w", err)
+// bulkUpsert runs upsertModel writes. A newer document makes the filter miss and the upsert collide
+// with its _id; those duplicate key errors mean the write was stale and are ignored.
+func (r *MongoDBRepository) bulkUpsert(ctx context.Context, models []mongo.WriteModel) error {
+	_, err := r.collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	var bulkErr mongo.BulkWriteException
+	if errors.As(err, &bulkErr) && bulkErr.WriteConcernError == nil {
+		for _, writeErr := range bulkErr.WriteErrors {
+			if writeErr.Code != 11000 {
+				return fmt.Errorf("failed to upsert projects: %w", err)
+			}
 		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to upsert projects: %w", err)
 	}
-
 	return nil
 }
 
-// GetCachedProjects retrieves cached projects by search hash
-func (r *MongoDBRepository) GetCachedProjects(searchHash string) ([]domain.Project, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// PruneProjects removes the documents fetched before before, of one project or of all when projectID is 0,
+// unless fence is lost
+func (r *MongoDBRepository) PruneProjects(projectID int, before time.Time, fence Fence) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	filter := bson.M{
-		"search_hash": searchHash,
+	filter := bson.M{"fetched_at": bson.M{"$lt": before}}
+	if projectID != 0 {
+		filter["project.id"] = projectID
 	}
-
-	cursor, err := r.collection.Find(ctx, filter)
+	var deleted int64
+	err := r.fenced(ctx, fence, func(ctx context.Context) error {
+		result, err := r.collection.DeleteMany(ctx, filter)
+		if err != nil {
+			return fmt.Errorf("failed to prune projects: %w", err)
+		}
+		deleted = result.DeletedCount
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to find cached projects: %w", err)
-	}
-	defer cursor.Close(ctx)
-
-	var cachedProjects []CachedProject
-	if err = cursor.All(ctx, &cachedProjects); err != nil {
-		return nil, fmt.Errorf("failed to decode cached projects: %w", err)
+		return 0, err
 	}
+	return deleted, nil
+}
 
-	var projects []domain.Project
-	for _, cached := range cachedProjects {
-		projects = append(projects, cached.Project)
+// GetCachedProjects retrieves the cached projects matching filter
+func (r *MongoDBRepository) GetCachedProjects(filter ProjectFilter) ([]domain.Project, error) {
+	cached, err := r.GetCachedProjectsWithHashes(filter)
+	if err != nil {
+		return nil, err
 	}
-
-	return projects, nil
+	return cachedProjects(cached), nil
 }
 
-// GetCachedProjectsWithHashes retrieves cached projects with their hashes
-func (r *MongoDBRepository) GetCachedProjectsWithHashes(searchHash string) ([]CachedProject, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// GetCachedProjectsWithHashes retrieves the cached documents matching filter
+func (r *MongoDBRepository) GetCachedProjectsWithHashes(filter ProjectFilter) ([]CachedProject, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	filter := bson.M{
-		"search_hash": searchHash,
-	}
-
-	cursor, err := r.collection.Find(ctx, filter)
+	opts := options.Find().SetSort(bson.D{{Key: "project.path", Value: 1}, {Key: "project.ref", Value: 1}})
+	cursor, err := r.collection.Find(ctx, mongoFilter(filter), opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find cached projects: %w", err)
 	}
@@ -162,16 +285,34 @@ func (r *MongoDBRepository) GetCachedProjectsWithHashes(searchHash string) ([]Ca
 	return cachedProjects, nil
 }
 
-// IsCacheValid checks if cache exists (no TTL, cache is always valid until manually cleared)
-func (r *MongoDBRepository) IsCacheValid(searchHash string) (bool, error) {
+// mongoFilter translates a ProjectFilter into a query
+func mongoFilter(filter ProjectFilter) bson.M {
+	query := bson.M{}
+	if filter.ProjectID != 0 {
+		query["project.id"] = filter.ProjectID
+	}
+	if filter.Ref != "" {
+		query["project.ref"] = filter.Ref
+	}
+	if filter.DefaultRef {
+		// Same rule as domain.Project.OnRef("")
+		query["$or"] = []bson.M{
+			{"project.ref": ""},
+			{"$expr": bson.M{"$eq": bson.A{"$project.ref", "$project.defaultbranch"}}},
+		}
+	}
+	if filter.WithOpenAPI {
+		query["project.openapispecs.0"] = bson.M{"$exists": true}
+	}
+	return query
+}
+
+// IsCacheValid checks if any project is cached (no TTL, cache is always valid until manually cleared)
+func (r *MongoDBRepository) IsCacheValid() (bool, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	filter := bson.M{
-		"search_hash": searchHash,
-	}
-
-	count, err := r.collection.CountDocuments(ctx, filter)
+	count, err := r.collection.CountDocuments(ctx, bson.M{}, options.Count().SetLimit(1))
 	if err != nil {
 		return false, fmt.Errorf("failed to check cache validity: %w", err)
 	}
@@ -199,21 +340,30 @@ func (r *MongoDBRepository) GetCacheStats() (map[string]interface{}, error) {
 
 	stats := make(map[string]interface{})
 
-	// Total cached projects
+	// Total cached documents, one per (project, ref)
 	totalCount, err := r.collection.CountDocuments(ctx, bson.M{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to count total cached projects: %w", err)
 	}
 	stats["total_cached_projects"] = totalCount
 
-	// Cache entries by search hash
+	projectIDs, err := r.collection.Distinct(ctx, "project.id", bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count distinct projects: %w", err)
+	}
+	stats["distinct_projects"] = len(projectIDs)
+
+	// Cache entries by ref
 	pipeline := []bson.M{
 		{
 			"$group": bson.M{
-				"_id":   "$search_hash",
+				"_id":   "$project.ref",
 				"count": bson.M{"$sum": 1},
 			},
 		},
+		{
+			"$sort": bson.M{"_id": 1},
+		},
 	}
 
 	cursor, err := r.collection.Aggregate(ctx, pipeline)
@@ -222,75 +372,18 @@ func (r *MongoDBRepository) GetCacheStats() (map[string]interface{}, error) {
 	}
 	defer cursor.Close(ctx)
 
-	var searchHashStats []bson.M
-	if err = cursor.All(ctx, &searchHashStats); err != nil {
-		return nil, fmt.Errorf("failed to decode search hash stats: %w", err)
+	var refStats []bson.M
+	if err = cursor.All(ctx, &refStats); err != nil {
+		return nil, fmt.Errorf("failed to decode ref stats: %w", err)
 	}
 
-	stats["search_hashes"] = searchHashStats
-	stats["cache_type"] = "hash_based" // Indicate this is hash-based cache
+	stats["refs"] = refStats
+	stats["cache_type"] = "mongodb"
+	stats["schema_version"] = CacheSchemaVersion
 
 	return stats, nil
 }
 
-// GetProjectOpenAPI retrieves OpenAPI data for a specific project
-func (r *MongoDBRepository) GetProjectOpenAPI(projectID int) (*domain.OpenAPI, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	filter := bson.M{
-		"project.id": projectID,
-	}
-
-	var cachedProject CachedProject
-	err := r.collection.FindOne(ctx, filter).Decode(&cachedProject)
-	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, fmt.Errorf("project %d not found in cache", projectID)
-		}
-		return nil, fmt.Errorf("failed to find project: %w", err)
-	}
-
-	if cachedProject.Project.OpenAPI == nil {
-		return &domain.OpenAPI{Found: false}, nil
-	}
-
-	return cachedProject.Project.OpenAPI, nil
-}
-
-// GetProjectsWithOpenAPI retrieves all projects that have OpenAPI specifications
-func (r *MongoDBRepository) GetProjectsWithOpenAPI() ([]domain.Project, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	// Filter for projects that have OpenAPI data
-	filter := bson.M{
-		"project.openapi": bson.M{
-			"$exists": true,
-			"$ne":     nil,
-		},
-		"project.openapi.found": true,
-	}
-
-	cursor, err := r.collection.Find(ctx, filter)
-	if err != nil {
-		return nil, fmt.Errorf("failed to find projects with OpenAPI: %w", err)
-	}
-	defer cursor.Close(ctx)
-
-	var cachedProjects []CachedProject
-	if err = cursor.All(ctx, &cachedProjects); err != nil {
-		return nil, fmt.Errorf("failed to decode projects with OpenAPI: %w", err)
-	}
-
-	var projects []domain.Project
-	for _, cached := range cachedProjects {
-		projects = append(projects, cached.Project)
-	}
-
-	return projects, nil
-}
-
 // Close closes the MongoDB connection
 func (r *MongoDBRepository) Close() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
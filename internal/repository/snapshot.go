@@ -0,0 +1,116 @@
+// internal/repository/snapshot.go
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"gitlab-list/internal/domain"
+)
+
+// SaveSnapshot stores the project records of one sync. Snapshots are never updated, only compacted.
+func (r *MongoDBRepository) SaveSnapshot(projects []domain.ProjectSnapshot) error {
+	if len(projects) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	docs := make([]interface{}, 0, len(projects))
+	for _, project := range projects {
+		docs = append(docs, project)
+	}
+	if _, err := r.snapshots.InsertMany(ctx, docs); err != nil {
+		return fmt.Errorf("failed to save snapshot: %w", err)
+	}
+	return nil
+}
+
+// ListSnapshots returns every stored snapshot, oldest first
+func (r *MongoDBRepository) ListSnapshots() ([]domain.Snapshot, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	pipeline := []bson.M{
+		{
+			"$group": bson.M{
+				"_id":      "$snapshot_id",
+				"taken_at": bson.M{"$min": "$taken_at"},
+				"projects": bson.M{"$sum": 1},
+			},
+		},
+		{
+			"$sort": bson.M{"taken_at": 1},
+		},
+	}
+
+	cursor, err := r.snapshots.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		ID       string    `bson:"_id"`
+		TakenAt  time.Time `bson:"taken_at"`
+		Projects int       `bson:"projects"`
+	}
+	if err = cursor.All(ctx, &rows); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshots: %w", err)
+	}
+
+	snapshots := make([]domain.Snapshot, 0, len(rows))
+	for _, row := range rows {
+		snapshots = append(snapshots, domain.Snapshot{ID: row.ID, TakenAt: row.TakenAt, Projects: row.Projects})
+	}
+	return snapshots, nil
+}
+
+// GetSnapshotProjects returns the project records of the given snapshots, oldest first.
+// Libraries are left out unless withLibraries is set, which keeps Go version trends cheap.
+func (r *MongoDBRepository) GetSnapshotProjects(snapshotIDs []string, withLibraries bool) ([]domain.ProjectSnapshot, error) {
+	if len(snapshotIDs) == 0 {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "taken_at", Value: 1}})
+	if !withLibraries {
+		opts.SetProjection(bson.M{"modules.libraries": 0})
+	}
+
+	cursor, err := r.snapshots.Find(ctx, bson.M{"snapshot_id": bson.M{"$in": snapshotIDs}}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find snapshot projects: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var projects []domain.ProjectSnapshot
+	if err = cursor.All(ctx, &projects); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot projects: %w", err)
+	}
+	return projects, nil
+}
+
+// DeleteSnapshots removes whole snapshots
+func (r *MongoDBRepository) DeleteSnapshots(snapshotIDs []string) (int64, error) {
+	if len(snapshotIDs) == 0 {
+		return 0, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	result, err := r.snapshots.DeleteMany(ctx, bson.M{"snapshot_id": bson.M{"$in": snapshotIDs}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete snapshots: %w", err)
+	}
+	return result.DeletedCount, nil
+}
@@ -0,0 +1,340 @@
+// internal/repository/store.go
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"gitlab-list/internal/configuration"
+	"gitlab-list/internal/domain"
+)
+
+// ErrNoStore is returned by operations that need the cache store when none is configured (STORE=none)
+var ErrNoStore = errors.New("cache store not configured")
+
+// Store backends selectable with STORE
+const (
+	StoreMongoDB = "mongodb"
+	StoreBolt    = "bolt"
+	StoreMemory  = "memory"
+	StoreNone    = "none"
+)
+
+// OpenStore opens the cache store selected by the configuration; StoreNone returns a nil store. A
+// MongoDB store that cannot be reached is an error: a private in-memory store per process would
+// silently lose leases, job history and campaigns, so memory is only used with STORE=memory.
+func OpenStore(cfg *configuration.Configuration) (CacheStore, error) {
+	switch strings.ToLower(strings.TrimSpace(cfg.Store)) {
+	case "", StoreMongoDB:
+		mongoRepo, err := NewMongoDBRepository(cfg.GetMongoDBURI(), cfg.MongoDBDatabase)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to MongoDB: %w", err)
+		}
+		log.Println("MongoDB cache initialized successfully")
+		return mongoRepo, nil
+	case StoreBolt:
+		store, err := NewBoltStore(cfg.StorePath)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("Embedded cache initialized at %s", cfg.StorePath)
+		return store, nil
+	case StoreMemory:
+		log.Println("In-memory cache initialized (contents are lost on restart)")
+		return NewMemoryStore(), nil
+	case StoreNone:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown STORE %q: use mongodb, bolt, memory or none", cfg.Store)
+	}
+}
+
+// CacheSchemaVersion is the layout of the project cache. Version 1 kept a full copy of every project
+// per search hash; version 2 keeps one document per (project, ref).
+const CacheSchemaVersion = 2
+
+// legacyInitialLoadHash is the version 1 search hash of the full sync, the only copy worth migrating
+const legacyInitialLoadHash = "initial_load_all_projects"
+
+// legacyCachedProject is a version 1 cache record
+type legacyCachedProject struct {
+	Project     domain.Project `bson:"project"`
+	SearchHash  string         `bson:"search_hash"`
+	ProjectHash string         `bson:"project_hash"`
+	CreatedAt   time.Time      `bson:"created_at"`
+	UpdatedAt   time.Time      `bson:"updated_at"`
+}
+
+// ProjectFilter selects cached project documents; the zero value matches every document
+type ProjectFilter struct {
+	ProjectID   int    // Only this project
+	Ref         string // Only records read from this ref
+	DefaultRef  bool   // Only records read from each project's default branch
+	WithOpenAPI bool   // Only records with at least one OpenAPI spec
+}
+
+// RefFilter selects the records read from ref, or from each project's default branch when ref is empty
+func RefFilter(ref string) ProjectFilter {
+	if ref == "" {
+		return ProjectFilter{DefaultRef: true}
+	}
+	return ProjectFilter{Ref: ref}
+}
+
+// Matches reports whether a project record passes the filter
+func (f ProjectFilter) Matches(project domain.Project) bool {
+	if f.ProjectID != 0 && project.ID != f.ProjectID {
+		return false
+	}
+	if f.Ref != "" && project.Ref != f.Ref {
+		return false
+	}
+	if f.DefaultRef && !project.OnRef("") {
+		return false
+	}
+	if f.WithOpenAPI && len(project.OpenAPISpecs) == 0 {
+		return false
+	}
+	return true
+}
+
+// The helpers below implement the queries of the embedded stores over decoded documents
+
+// upsertCached returns the document replacing existing (nil when there is none) with project.
+// It reports false when existing was fetched after fetchedAt, so a slow writer never replaces newer data.
+func upsertCached(existing *CachedProject, project domain.Project, projectHash string, fetchedAt, now time.Time) (CachedProject, bool) {
+	entry := CachedProject{
+		Key:         ProjectKey(project),
+		Project:     project,
+		ProjectHash: projectHash,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		FetchedAt:   fetchedAt,
+		Source:      "gitlab",
+	}
+	if existing != nil {
+		if existing.FetchedAt.After(fetchedAt) {
+			return *existing, false
+		}
+		entry.CreatedAt = existing.CreatedAt
+	}
+	return entry, true
+}
+
+// prunable reports whether PruneProjects(projectID, before) removes the document
+func prunable(entry CachedProject, projectID int, before time.Time) bool {
+	return (projectID == 0 || entry.Project.ID == projectID) && entry.FetchedAt.Before(before)
+}
+
+// sortCached orders documents by project path and ref, the order MongoDB returns them in
+func sortCached(entries []CachedProject) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Project.Path != entries[j].Project.Path {
+			return entries[i].Project.Path < entries[j].Project.Path
+		}
+		return entries[i].Project.Ref < entries[j].Project.Ref
+	})
+}
+
+// cachedProjects returns the projects of documents
+func cachedProjects(entries []CachedProject) []domain.Project {
+	var projects []domain.Project
+	for _, entry := range entries {
+		projects = append(projects, entry.Project)
+	}
+	return projects
+}
+
+// cacheStats summarizes cached documents the way GetCacheStats reports them for MongoDB
+func cacheStats(entries []CachedProject, cacheType string) map[string]interface{} {
+	projectIDs := make(map[int]bool)
+	refCounts := make(map[string]int)
+	for _, entry := range entries {
+		projectIDs[entry.Project.ID] = true
+		refCounts[entry.Project.Ref]++
+	}
+	refs := make([]map[string]interface{}, 0, len(refCounts))
+	for ref, count := range refCounts {
+		refs = append(refs, map[string]interface{}{"_id": ref, "count": count})
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i]["_id"].(string) < refs[j]["_id"].(string) })
+
+	return map[string]interface{}{
+		"total_cached_projects": len(entries),
+		"distinct_projects":     len(projectIDs),
+		"refs":                  refs,
+		"cache_type":            cacheType,
+		"schema_version":        CacheSchemaVersion,
+	}
+}
+
+// summarizeSnapshots groups snapshot records into snapshots, oldest first
+func summarizeSnapshots(records []domain.ProjectSnapshot) []domain.Snapshot {
+	index := make(map[string]int)
+	var snapshots []domain.Snapshot
+	for _, record := range records {
+		i, ok := index[record.SnapshotID]
+		if !ok {
+			i = len(snapshots)
+			index[record.SnapshotID] = i
+			snapshots = append(snapshots, domain.Snapshot{ID: record.SnapshotID, TakenAt: record.TakenAt})
+		}
+		if record.TakenAt.Before(snapshots[i].TakenAt) {
+			snapshots[i].TakenAt = record.TakenAt
+		}
+		snapshots[i].Projects++
+	}
+	sort.SliceStable(snapshots, func(i, j int) bool { return snapshots[i].TakenAt.Before(snapshots[j].TakenAt) })
+	return snapshots
+}
+
+// withoutLibraries returns a copy of a snapshot record without module libraries
+func withoutLibraries(record domain.ProjectSnapshot) domain.ProjectSnapshot {
+	modules := make([]domain.ModuleSnapshot, len(record.Modules))
+	for i, mod := range record.Modules {
+		mod.Libraries = nil
+		modules[i] = mod
+	}
+	record.Modules = modules
+	return record
+}
+
+// containsID reports whether ids contains id
+func containsID(ids []string, id string) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrJobNotFound is returned for a scheduled job the scheduler has not registered
+var ErrJobNotFound = errors.New("scheduled job not found")
+
+// registeredJob returns the state replacing existing (nil when there is none) with what the scheduler
+// registered; the pause flag and a pending run request set through the API are kept
+func registeredJob(existing *domain.ScheduledJob, job domain.ScheduledJob) domain.ScheduledJob {
+	job.Paused = false
+	job.TriggeredAt = nil
+	if existing != nil {
+		job.Paused = existing.Paused
+		job.TriggeredAt = existing.TriggeredAt
+	}
+	return job
+}
+
+// sortJobs orders job states by name, the order MongoDB returns them in
+func sortJobs(jobs []domain.ScheduledJob) {
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Name < jobs[j].Name })
+}
+
+// latestRuns orders runs newest first and keeps at most limit (all when limit is 0)
+func latestRuns(runs []domain.JobRun, limit int) []domain.JobRun {
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].StartedAt.After(runs[j].StartedAt) })
+	if limit > 0 && len(runs) > limit {
+		runs = runs[:limit]
+	}
+	return runs
+}
+
+// LeaseHeldError is returned by AcquireLease while another holder has the lease
+type LeaseHeldError struct {
+	Lease domain.Lease
+}
+
+func (e *LeaseHeldError) Error() string {
+	return fmt.Sprintf("lease %s is held by %s since %s", e.Lease.Name, e.Lease.Holder, e.Lease.AcquiredAt.Format(time.RFC3339))
+}
+
+// ErrLeaseLost is returned when a lease was released, or expired and taken over with a newer token
+var ErrLeaseLost = errors.New("lease lost")
+
+// Fence makes a cache write conditional on the lease Name still carrying Token, checked in the
+// same transaction as the write. The zero Fence writes unconditionally, for writers holding no lease.
+type Fence struct {
+	Lease string
+	Token int64
+}
+
+// check returns ErrLeaseLost unless current (nil when never taken) is held with the token of the fence
+func (f Fence) check(current *domain.Lease, now time.Time) error {
+	if f.Lease == "" {
+		return nil
+	}
+	if current == nil {
+		return fmt.Errorf("%w: %s", ErrLeaseLost, f.Lease)
+	}
+	if current.Token != f.Token || !current.HeldAt(now) {
+		return fmt.Errorf("%w: %s is at token %d, the writer has %d", ErrLeaseLost, f.Lease, current.Token, f.Token)
+	}
+	return nil
+}
+
+// acquiredLease returns the lease replacing existing (nil when never taken) for holder, with the next token
+func acquiredLease(existing *domain.Lease, name, holder string, ttl time.Duration, now time.Time) (domain.Lease, error) {
+	lease := domain.Lease{Name: name}
+	if existing != nil {
+		if existing.HeldAt(now) {
+			return *existing, &LeaseHeldError{Lease: *existing}
+		}
+		lease.Token = existing.Token
+	}
+	lease.Holder = holder
+	lease.Token++
+	lease.AcquiredAt = now
+	lease.RenewedAt = now
+	lease.ExpiresAt = now.Add(ttl)
+	return lease, nil
+}
+
+// renewedLease extends existing if it still carries token
+func renewedLease(existing *domain.Lease, token int64, ttl time.Duration, now time.Time) (domain.Lease, error) {
+	if existing == nil || existing.Token != token || existing.Holder == "" {
+		return domain.Lease{}, ErrLeaseLost
+	}
+	lease := *existing
+	lease.RenewedAt = now
+	lease.ExpiresAt = now.Add(ttl)
+	return lease, nil
+}
+
+// releasedLease frees existing if it still carries token; the token is kept so the next one is higher
+func releasedLease(existing *domain.Lease, token int64, now time.Time) (domain.Lease, bool) {
+	if existing == nil || existing.Token != token {
+		return domain.Lease{}, false
+	}
+	lease := *existing
+	lease.Holder = ""
+	lease.ExpiresAt = now
+	return lease, true
+}
+
+// sortLeases orders leases by name, the order MongoDB returns them in
+func sortLeases(leases []domain.Lease) {
+	sort.Slice(leases, func(i, j int) bool { return leases[i].Name < leases[j].Name })
+}
+
+// ErrCampaignNotFound is returned for an unknown campaign, or a project that is not part of it
+var ErrCampaignNotFound = errors.New("campaign not found")
+
+// replaceCampaignProject replaces the project with the same project ID and module directory
+func replaceCampaignProject(campaign *domain.Campaign, project domain.CampaignProject, now time.Time) error {
+	for i, existing := range campaign.Projects {
+		if existing.ProjectID == project.ProjectID && existing.ModuleDir == project.ModuleDir {
+			campaign.Projects[i] = project
+			campaign.UpdatedAt = now
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: project %d (%s) is not part of campaign %s", ErrCampaignNotFound, project.ProjectID, project.ModuleDir, campaign.ID)
+}
+
+// sortCampaigns orders campaigns newest first, the order MongoDB returns them in
+func sortCampaigns(campaigns []domain.Campaign) {
+	sort.Slice(campaigns, func(i, j int) bool { return campaigns[i].CreatedAt.After(campaigns[j].CreatedAt) })
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"gitlab-list/internal/configuration"
+	"gitlab-list/internal/gitlab"
 	"gitlab-list/internal/service/graph"
 	"gitlab-list/internal/service/scanner"
 	"io"
@@ -16,6 +17,7 @@ import (
 // App represents the archmap application
 type App struct {
 	config *configuration.Configuration
+	client *gitlab.Client
 }
 
 // NewApp creates a new archmap application instance
@@ -24,13 +26,13 @@ func NewApp() (*App, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &App{config: cfg}, nil
+	return &App{config: cfg, client: gitlab.NewClientFromConfig(cfg)}, nil
 }
 
 // Run executes the archmap application with the given parameters
 func (a *App) Run(ref, module string, radius int, ignores []string) error {
 	// ----- build full graph -----
-	arch, err := scanner.NewArchScanner(a.config).
+	arch, err := scanner.NewArchScanner(a.config, a.client).
 		SetRef(ref).
 		SetIgnore(ignores...).
 		ScanGraph()
@@ -86,7 +88,7 @@ func (a *App) GenerateGraph(ref, module string, radius int, ignores []string) (*
 // GenerateGraphWithOptions generates a graph with additional options
 func (a *App) GenerateGraphWithOptions(ref, module string, radius int, ignores []string, samePackageOnly bool) (*graph.Graph, error) {
 	// ----- build full graph -----
-	arch, err := scanner.NewArchScanner(a.config).
+	arch, err := scanner.NewArchScanner(a.config, a.client).
 		SetRef(ref).
 		SetIgnore(ignores...).
 		ScanGraph()
@@ -0,0 +1,652 @@
+// internal/service/campaign.go
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"gitlab-list/internal/domain"
+	"gitlab-list/internal/gomod"
+	"gitlab-list/internal/osv"
+	"gitlab-list/internal/repository"
+
+	"golang.org/x/mod/semver"
+)
+
+// ErrCampaignProjectNotFound is returned when a campaign has no project matching a retry or cancel
+var ErrCampaignProjectNotFound = errors.New("campaign project not found")
+
+// CampaignService runs dependency bump campaigns: one update job per selected project module, then
+// tracking of the merge requests until they are merged or closed
+type CampaignService struct {
+	store    repository.CacheStore
+	projects *ProjectService
+	updater  *LibraryUpdater
+	queue    *JobQueue // nil where campaigns are only tracked, as in cmd/scheduler
+
+	// mu serializes read-modify-write of campaign projects between queue workers and requests
+	mu sync.Mutex
+	// queueMu serializes queueing so a waiting project is queued once
+	queueMu sync.Mutex
+}
+
+// CampaignRequest defines a campaign: Module at Version, GoVersion, or both, in the projects matching Criteria
+type CampaignRequest struct {
+	Name       string                `json:"name"`
+	Module     string                `json:"module,omitempty"`
+	Version    string                `json:"version,omitempty"`
+	GoVersion  string                `json:"go_version,omitempty"`
+	Criteria   domain.SearchCriteria `json:"criteria"`
+	BranchName string                `json:"branch_name,omitempty"` // Defaults to bump-<module>-<version> or go-<version>
+}
+
+// CampaignRefresh counts what a refresh of the active campaigns saw
+type CampaignRefresh struct {
+	Campaigns int            `json:"campaigns"`
+	Completed int            `json:"completed"` // Campaigns whose projects all reached a final state
+	Projects  map[string]int `json:"projects"`  // Projects per state
+	Errors    []string       `json:"errors,omitempty"`
+}
+
+// NewCampaignService creates a campaign service; queue may be nil when no updates are started
+func NewCampaignService(store repository.CacheStore, projects *ProjectService, updater *LibraryUpdater, queue *JobQueue) *CampaignService {
+	return &CampaignService{
+		store:    store,
+		projects: projects,
+		updater:  updater,
+		queue:    queue,
+	}
+}
+
+// Create selects the projects of a campaign, saves it and queues an update job per project module
+// that is behind the target. Only modules requiring Module are selected when it is set. Modules that
+// do not fit the job queue stay pending and are queued as the campaign's jobs finish.
+func (s *CampaignService) Create(req CampaignRequest, token string) (*domain.Campaign, error) {
+	if s.store == nil {
+		return nil, repository.ErrNoStore
+	}
+	if s.queue == nil {
+		return nil, fmt.Errorf("campaign updates need the job queue")
+	}
+	if req.Module == "" && req.GoVersion == "" {
+		return nil, fmt.Errorf("invalid campaign: module and version, or go_version, is required")
+	}
+	if req.Module != "" && !semver.IsValid(req.Version) {
+		return nil, fmt.Errorf("invalid campaign: version %q of %s is not a semantic version", req.Version, req.Module)
+	}
+	if req.GoVersion != "" && osv.GoSemver(req.GoVersion) == "" {
+		return nil, fmt.Errorf("invalid campaign: go_version %q is not a Go version", req.GoVersion)
+	}
+
+	projects, err := s.projects.SearchProjects(req.Criteria, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select projects: %w", err)
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	campaign := domain.Campaign{
+		ID:         id,
+		Name:       req.Name,
+		Module:     req.Module,
+		Version:    req.Version,
+		GoVersion:  req.GoVersion,
+		Criteria:   req.Criteria,
+		BranchName: req.BranchName,
+		Status:     domain.CampaignActive,
+		Projects:   []domain.CampaignProject{},
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if campaign.BranchName == "" {
+		campaign.BranchName = campaignBranch(req)
+	}
+	if campaign.Name == "" {
+		campaign.Name = "Bump " + strings.TrimPrefix(campaign.BranchName, "bump-")
+	}
+
+	seen := make(map[string]bool)
+	for _, project := range projects {
+		if project.Archived {
+			continue
+		}
+		for _, module := range project.GoModules() {
+			from, ok := campaignTarget(campaign, module)
+			key := fmt.Sprintf("%d:%s", project.ID, module.Dir)
+			if !ok || seen[key] {
+				continue
+			}
+			seen[key] = true
+			campaign.Projects = append(campaign.Projects, domain.CampaignProject{
+				ProjectID:   project.ID,
+				ProjectName: project.Name,
+				Path:        project.Path,
+				ModuleDir:   module.Dir,
+				FromVersion: from,
+				Status:      domain.CampaignProjectPending,
+				UpdatedAt:   now,
+			})
+		}
+	}
+	if len(campaign.Projects) == 0 {
+		return nil, fmt.Errorf("invalid campaign: no selected project is behind the target")
+	}
+
+	if err := s.store.SaveCampaign(campaign); err != nil {
+		return nil, fmt.Errorf("failed to save campaign: %w", err)
+	}
+	if err := s.queueWaiting(campaign.ID, token); err != nil {
+		return nil, err
+	}
+	return s.Get(campaign.ID)
+}
+
+// campaignTarget reports whether a module is behind the campaign target and the version it moves from
+func campaignTarget(campaign domain.Campaign, module domain.Module) (string, bool) {
+	goBehind := campaign.GoVersion != "" && semver.Compare(osv.GoSemver(module.GoVersion), osv.GoSemver(campaign.GoVersion)) < 0
+	if campaign.Module == "" {
+		return module.GoVersion, goBehind
+	}
+	for _, lib := range module.Libraries {
+		if lib.Name != campaign.Module {
+			continue
+		}
+		if lib.Replace != nil {
+			return "", false // The replacement decides the version, not the requirement
+		}
+		return lib.Version, goBehind || semver.Compare(lib.Version, campaign.Version) < 0
+	}
+	return "", false
+}
+
+var branchUnsafe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// campaignBranch names the update branch after the target, e.g. bump-zap-v1.27.0 or go-1.23
+func campaignBranch(req CampaignRequest) string {
+	var name string
+	switch {
+	case req.Module != "" && req.GoVersion != "":
+		name = fmt.Sprintf("bump-%s-%s-go-%s", path.Base(req.Module), req.Version, req.GoVersion)
+	case req.Module != "":
+		name = fmt.Sprintf("bump-%s-%s", path.Base(req.Module), req.Version)
+	default:
+		name = "go-" + req.GoVersion
+	}
+	return branchUnsafe.ReplaceAllString(name, "-")
+}
+
+// projectBranch is the branch of one attempt of a campaign project; modules outside the root and
+// later attempts get a suffix so they never reuse a branch
+func projectBranch(campaign *domain.Campaign, project domain.CampaignProject) string {
+	branch := campaign.BranchName
+	if project.ModuleDir != "" && project.ModuleDir != gomod.RootDir {
+		branch += "-" + strings.Trim(branchUnsafe.ReplaceAllString(project.ModuleDir, "-"), "-")
+	}
+	if project.Attempts > 1 {
+		branch += fmt.Sprintf("-retry-%d", project.Attempts-1)
+	}
+	return branch
+}
+
+// waiting reports whether a campaign project waits for room in the job queue
+func waiting(project domain.CampaignProject) bool {
+	return project.Status == domain.CampaignProjectPending && project.JobID == "" && project.MergeRequest == nil
+}
+
+// queueWaiting queues the waiting projects of a campaign until the job queue is full; the rest
+// stay pending for the next call
+func (s *CampaignService) queueWaiting(id, token string) error {
+	if s.queue == nil {
+		return fmt.Errorf("campaign updates need the job queue")
+	}
+	s.queueMu.Lock()
+	defer s.queueMu.Unlock()
+
+	campaign, err := s.store.GetCampaign(id)
+	if err != nil {
+		return err
+	}
+	if campaign.Status == domain.CampaignCanceled {
+		return nil
+	}
+	for _, project := range campaign.Projects {
+		if !waiting(project) {
+			continue
+		}
+		if err := s.submit(campaign, project, token); err != nil {
+			if errors.Is(err, ErrQueueFull) {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// submit queues the next update attempt of a waiting campaign project; the caller holds s.queueMu.
+// A full queue leaves the project waiting.
+func (s *CampaignService) submit(campaign *domain.Campaign, project domain.CampaignProject, token string) error {
+
+	project.Attempts++
+	project.Status = domain.CampaignProjectPending
+	project.Error = ""
+	project.MergeRequest = nil
+	branch := projectBranch(campaign, project)
+
+	var updates []ProjectLibraryUpdate
+	if campaign.Module != "" {
+		updates = append(updates, ProjectLibraryUpdate{
+			ProjectID:     project.ProjectID,
+			LibraryName:   campaign.Module,
+			TargetVersion: campaign.Version,
+			UpdateType:    "upgrade",
+		})
+	}
+	goVersion := campaign.GoVersion
+	id, projectID, moduleDir := campaign.ID, project.ProjectID, project.ModuleDir
+
+	// Stored before queueing so a worker that starts right away sees the new attempt
+	if err := s.updateProject(id, projectID, moduleDir, func(p *domain.CampaignProject) { *p = project }); err != nil {
+		return err
+	}
+	job, err := s.queue.Submit(JobKindCampaign, id, func(ctx context.Context, progress ProgressFunc) (interface{}, error) {
+		s.updateProject(id, projectID, moduleDir, func(p *domain.CampaignProject) {
+			if p.Status == domain.CampaignProjectPending {
+				p.Status = domain.CampaignProjectUpdating
+			}
+		})
+
+		results, err := s.updater.UpdateProjectLibrariesContext(ctx, projectID, moduleDir, updates, goVersion, branch, token, s.updater.DefaultUpdateOptions(), progress)
+		var mr *MergeRequest
+		var failures []string
+		for _, result := range results {
+			if result.MergeRequest != nil {
+				mr = result.MergeRequest
+			}
+			if !result.Success {
+				failures = append(failures, result.Error)
+			}
+		}
+
+		s.updateProject(id, projectID, moduleDir, func(p *domain.CampaignProject) {
+			switch {
+			case p.Status == domain.CampaignProjectCanceled:
+				// Canceled while the branch was pushed; do not leave its merge request open
+				if mr != nil {
+					s.updater.CloseMergeRequest(projectID, mr.IID, "Closed because the campaign was canceled.", token)
+				}
+			case mr != nil:
+				p.Status = domain.CampaignProjectOpened
+				p.MergeRequest = &domain.CampaignMR{IID: mr.IID, WebURL: mr.WebURL, SourceBranch: branch, State: mr.State}
+				p.Error = strings.Join(failures, "; ")
+			case ctx.Err() != nil:
+				p.Status = domain.CampaignProjectCanceled
+			default:
+				p.Status = domain.CampaignProjectFailed
+				if err != nil {
+					failures = append(failures, err.Error())
+				}
+				p.Error = strings.Join(failures, "; ")
+			}
+		})
+
+		// The queue has room again for a project of this campaign that did not fit
+		if err := s.queueWaiting(id, token); err != nil {
+			log.Printf("Failed to queue waiting projects of campaign %s: %v", id, err)
+		}
+		return results, err
+	})
+	if err != nil {
+		s.updateProject(id, projectID, moduleDir, func(p *domain.CampaignProject) {
+			if errors.Is(err, ErrQueueFull) {
+				p.Attempts--
+				return
+			}
+			p.Status = domain.CampaignProjectFailed
+			p.Error = err.Error()
+		})
+		return fmt.Errorf("failed to queue update of project %d: %w", projectID, err)
+	}
+
+	return s.updateProject(id, projectID, moduleDir, func(p *domain.CampaignProject) {
+		p.JobID = job.ID
+	})
+}
+
+// updateProject applies update to one stored campaign project
+func (s *CampaignService) updateProject(id string, projectID int, moduleDir string, update func(p *domain.CampaignProject)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	campaign, err := s.store.GetCampaign(id)
+	if err != nil {
+		return err
+	}
+	for _, project := range campaign.Projects {
+		if project.ProjectID == projectID && project.ModuleDir == moduleDir {
+			update(&project)
+			return s.store.UpdateCampaignProject(id, project)
+		}
+	}
+	return fmt.Errorf("%w: %d in %s", ErrCampaignProjectNotFound, projectID, moduleDir)
+}
+
+// Get returns a campaign with its projects and their counts per state
+func (s *CampaignService) Get(id string) (*domain.Campaign, error) {
+	if s.store == nil {
+		return nil, repository.ErrNoStore
+	}
+	campaign, err := s.store.GetCampaign(id)
+	if err != nil {
+		return nil, err
+	}
+	summarizeCampaign(campaign)
+	return campaign, nil
+}
+
+// List returns every campaign, newest first, with the counts per state
+func (s *CampaignService) List() ([]domain.Campaign, error) {
+	if s.store == nil {
+		return nil, repository.ErrNoStore
+	}
+	campaigns, err := s.store.ListCampaigns()
+	if err != nil {
+		return nil, err
+	}
+	for i := range campaigns {
+		summarizeCampaign(&campaigns[i])
+	}
+	return campaigns, nil
+}
+
+// summarizeCampaign counts the projects per state; an active campaign whose projects are all final is completed
+func summarizeCampaign(campaign *domain.Campaign) {
+	campaign.Summary = make(map[string]int)
+	final := true
+	for _, project := range campaign.Projects {
+		campaign.Summary[project.Status]++
+		final = final && project.Final()
+	}
+	if campaign.Status == domain.CampaignActive && final {
+		campaign.Status = domain.CampaignCompleted
+	}
+}
+
+// Refresh reads the merge requests of the open projects of a campaign from GitLab, and catches up
+// with update jobs the queue no longer knows (e.g. after a restart)
+func (s *CampaignService) Refresh(id, token string) (*domain.Campaign, error) {
+	return s.RefreshContext(context.Background(), id, token)
+}
+
+// RefreshContext is Refresh stopping between projects once ctx is done
+func (s *CampaignService) RefreshContext(ctx context.Context, id, token string) (*domain.Campaign, error) {
+	campaign, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []string
+	for _, project := range campaign.Projects {
+		if project.Final() {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("refresh of campaign %s stopped: %w", id, err)
+		}
+		if err := s.refreshProject(id, project, token); err != nil {
+			errs = append(errs, fmt.Sprintf("%s (%s): %v", project.Path, project.ModuleDir, err))
+		}
+	}
+
+	if s.queue != nil {
+		// Catches up with projects left waiting when the jobs that would have queued them were lost
+		if err := s.queueWaiting(id, token); err != nil {
+			errs = append(errs, fmt.Sprintf("queueing waiting projects: %v", err))
+		}
+	}
+
+	campaign, err = s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if campaign.Status == domain.CampaignCompleted {
+		if err := s.store.SetCampaignStatus(id, domain.CampaignCompleted); err != nil {
+			return nil, fmt.Errorf("failed to complete campaign: %w", err)
+		}
+	}
+	if len(errs) > 0 {
+		return campaign, fmt.Errorf("failed to refresh %d projects: %s", len(errs), strings.Join(errs, "; "))
+	}
+	return campaign, nil
+}
+
+// refreshProject updates one campaign project from its merge request or queued job
+func (s *CampaignService) refreshProject(id string, project domain.CampaignProject, token string) error {
+	if project.MergeRequest == nil {
+		if s.queue == nil || project.JobID == "" {
+			return nil // Tracked by the process that queued the update, or waiting for the queue
+		}
+		job, err := s.queue.Get(project.JobID)
+		if err != nil || job.Finished() {
+			return s.updateProject(id, project.ProjectID, project.ModuleDir, func(p *domain.CampaignProject) {
+				if p.JobID != project.JobID || p.MergeRequest != nil || p.Final() {
+					return
+				}
+				switch {
+				case err != nil:
+					p.Status = domain.CampaignProjectFailed
+					p.Error = "update job was lost, e.g. by a restart"
+				case job.Status == domain.JobCanceled:
+					p.Status = domain.CampaignProjectCanceled
+				case job.Status == domain.JobFailed:
+					p.Status = domain.CampaignProjectFailed
+					p.Error = job.Error
+				}
+			})
+		}
+		return nil
+	}
+
+	mr, err := s.updater.GetMergeRequest(project.ProjectID, project.MergeRequest.IID, token)
+	if err != nil {
+		return err
+	}
+	return s.updateProject(id, project.ProjectID, project.ModuleDir, func(p *domain.CampaignProject) {
+		if p.MergeRequest == nil || p.MergeRequest.IID != mr.IID {
+			return
+		}
+		p.MergeRequest.State = mr.State
+		if mr.HeadPipeline != nil {
+			p.MergeRequest.PipelineID = mr.HeadPipeline.ID
+			p.MergeRequest.PipelineStatus = mr.HeadPipeline.Status
+		}
+		switch {
+		case p.Final():
+		case mr.State == "merged":
+			p.Status = domain.CampaignProjectMerged
+		case mr.State == "closed":
+			p.Status = domain.CampaignProjectClosed
+		case p.MergeRequest.PipelineStatus == "failed":
+			p.Status = domain.CampaignProjectPipelineFailed
+		default:
+			p.Status = domain.CampaignProjectOpened
+		}
+	})
+}
+
+// RefreshActive refreshes every active campaign, as the scheduled campaign tracking job does
+func (s *CampaignService) RefreshActive(token string) (*CampaignRefresh, error) {
+	return s.RefreshActiveContext(context.Background(), token)
+}
+
+// RefreshActiveContext is RefreshActive stopping between projects once ctx is done
+func (s *CampaignService) RefreshActiveContext(ctx context.Context, token string) (*CampaignRefresh, error) {
+	campaigns, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+
+	refresh := &CampaignRefresh{Projects: make(map[string]int)}
+	for _, campaign := range campaigns {
+		if campaign.Status != domain.CampaignActive && campaign.Status != domain.CampaignCompleted {
+			continue
+		}
+		if campaign.Status == domain.CampaignCompleted {
+			// Completed by the summary only; store it so it is no longer refreshed
+			stored, err := s.store.GetCampaign(campaign.ID)
+			if err != nil || stored.Status != domain.CampaignActive {
+				continue
+			}
+		}
+
+		if err := ctx.Err(); err != nil {
+			return refresh, fmt.Errorf("campaign refresh stopped: %w", err)
+		}
+		refresh.Campaigns++
+		updated, err := s.RefreshContext(ctx, campaign.ID, token)
+		if err != nil {
+			refresh.Errors = append(refresh.Errors, fmt.Sprintf("%s: %v", campaign.Name, err))
+		}
+		if updated == nil {
+			continue
+		}
+		if updated.Status == domain.CampaignCompleted {
+			refresh.Completed++
+		}
+		for status, count := range updated.Summary {
+			refresh.Projects[status] += count
+		}
+	}
+	return refresh, nil
+}
+
+// Retry starts another attempt of failed, canceled or closed projects and retries failed pipelines.
+// projectID 0 retries every such project; moduleDir narrows a project to one module.
+func (s *CampaignService) Retry(id string, projectID int, moduleDir, token string) (*domain.Campaign, error) {
+	campaign, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	retried := 0
+	for _, project := range matchingProjects(campaign, projectID, moduleDir) {
+		switch project.Status {
+		case domain.CampaignProjectFailed, domain.CampaignProjectCanceled, domain.CampaignProjectClosed:
+			if s.queue == nil {
+				return nil, fmt.Errorf("campaign updates need the job queue")
+			}
+			// Waits for the queue like a new project; queued below
+			if err := s.updateProject(id, project.ProjectID, project.ModuleDir, func(p *domain.CampaignProject) {
+				p.Status = domain.CampaignProjectPending
+				p.Error = ""
+				p.JobID = ""
+				p.MergeRequest = nil
+			}); err != nil {
+				return nil, err
+			}
+		case domain.CampaignProjectPipelineFailed:
+			pipeline, err := s.updater.RetryPipeline(project.ProjectID, project.MergeRequest.PipelineID, token)
+			if err != nil {
+				return nil, err
+			}
+			if err := s.updateProject(id, project.ProjectID, project.ModuleDir, func(p *domain.CampaignProject) {
+				p.Status = domain.CampaignProjectOpened
+				if p.MergeRequest != nil {
+					p.MergeRequest.PipelineID = pipeline.ID
+					p.MergeRequest.PipelineStatus = pipeline.Status
+				}
+			}); err != nil {
+				return nil, err
+			}
+		default:
+			continue
+		}
+		retried++
+	}
+	if retried == 0 {
+		return nil, fmt.Errorf("%w: nothing to retry", ErrCampaignProjectNotFound)
+	}
+
+	if campaign.Status != domain.CampaignActive {
+		if err := s.store.SetCampaignStatus(id, domain.CampaignActive); err != nil {
+			return nil, fmt.Errorf("failed to reopen campaign: %w", err)
+		}
+	}
+	if err := s.queueWaiting(id, token); err != nil {
+		return nil, err
+	}
+	return s.Get(id)
+}
+
+// Cancel stops the queued or running updates of a campaign and closes its open merge requests.
+// projectID 0 cancels the whole campaign; moduleDir narrows a project to one module.
+func (s *CampaignService) Cancel(id string, projectID int, moduleDir, token string) (*domain.Campaign, error) {
+	campaign, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	projects := matchingProjects(campaign, projectID, moduleDir)
+	if projectID != 0 && len(projects) == 0 {
+		return nil, fmt.Errorf("%w: %d", ErrCampaignProjectNotFound, projectID)
+	}
+	note := fmt.Sprintf("Closed because campaign %q was canceled.", campaign.Name)
+	for _, project := range projects {
+		if project.Final() {
+			continue
+		}
+		if err := s.updateProject(id, project.ProjectID, project.ModuleDir, func(p *domain.CampaignProject) {
+			p.Status = domain.CampaignProjectCanceled
+		}); err != nil {
+			return nil, err
+		}
+		if project.JobID != "" && s.queue != nil {
+			if _, err := s.queue.Cancel(project.JobID); err != nil && !errors.Is(err, ErrJobNotQueued) {
+				return nil, err
+			}
+		}
+		if mr := project.MergeRequest; mr != nil && mr.State == "opened" {
+			closed, err := s.updater.CloseMergeRequest(project.ProjectID, mr.IID, note, token)
+			if err != nil {
+				return nil, err
+			}
+			s.updateProject(id, project.ProjectID, project.ModuleDir, func(p *domain.CampaignProject) {
+				if p.MergeRequest != nil {
+					p.MergeRequest.State = closed.State
+				}
+			})
+		}
+	}
+
+	if projectID == 0 {
+		if err := s.store.SetCampaignStatus(id, domain.CampaignCanceled); err != nil {
+			return nil, fmt.Errorf("failed to cancel campaign: %w", err)
+		}
+	}
+	return s.Get(id)
+}
+
+// matchingProjects returns the projects of a campaign with projectID (all for 0) in moduleDir (any when empty)
+func matchingProjects(campaign *domain.Campaign, projectID int, moduleDir string) []domain.CampaignProject {
+	var projects []domain.CampaignProject
+	for _, project := range campaign.Projects {
+		if projectID != 0 && project.ProjectID != projectID {
+			continue
+		}
+		if moduleDir != "" && project.ModuleDir != moduleDir {
+			continue
+		}
+		projects = append(projects, project)
+	}
+	return projects
+}
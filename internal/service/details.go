@@ -0,0 +1,195 @@
+// internal/service/details.go
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gitlab-list/internal/domain"
+	"gitlab-list/internal/gitlab"
+	"gitlab-list/internal/repository"
+)
+
+// defaultDetailWorkers is used when no worker count is configured
+const defaultDetailWorkers = 8
+
+// DetailFetchSummary reports the outcome of fetching details for a batch of projects
+type DetailFetchSummary struct {
+	Total     int              `json:"total"`
+	Succeeded int              `json:"succeeded"`
+	Failed    int              `json:"failed"`
+	Skipped   int              `json:"skipped"`
+	Failures  []ProjectFailure `json:"failures,omitempty"`
+	Duration  string           `json:"duration"`
+}
+
+// ProjectFailure describes a project whose details could not be fetched
+type ProjectFailure struct {
+	ProjectID   int    `json:"project_id"`
+	ProjectName string `json:"project_name"`
+	Ref         string `json:"ref,omitempty"`
+	Error       string `json:"error"`
+}
+
+// detailJob asks for the details of one project on one ref ("" or "default" for the default branch)
+type detailJob struct {
+	Project domain.Project
+	Ref     string
+	Head    string // Head commit of Ref when already known, saving the branch lookup
+}
+
+// detailResult is the outcome for a single job
+type detailResult struct {
+	Project domain.Project
+	Err     error
+	Skipped bool
+}
+
+// detailJobs pairs every project with every ref, dropping refs that resolve to the same branch
+func detailJobs(projects []domain.Project, refs []string) []detailJob {
+	if len(refs) == 0 {
+		refs = []string{""}
+	}
+
+	var jobs []detailJob
+	for _, project := range projects {
+		seen := make(map[string]bool)
+		for _, ref := range refs {
+			resolved := resolveRef(project, ref)
+			if seen[resolved] {
+				continue
+			}
+			seen[resolved] = true
+			jobs = append(jobs, detailJob{Project: project, Ref: ref})
+		}
+	}
+	return jobs
+}
+
+// resolveRef maps "" and "default" to the project's default branch
+func resolveRef(project domain.Project, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || ref == "default" {
+		return project.DefaultBranch
+	}
+	return ref
+}
+
+// fetchProjectDetails fetches details for all jobs concurrently with a bounded worker pool.
+// Results keep the input order; a failed project keeps its basic info and never affects the others.
+func (s *ProjectService) fetchProjectDetails(repo repository.ProjectRepository, jobs []detailJob) ([]detailResult, *DetailFetchSummary) {
+	return s.fetchProjectDetailsContext(context.Background(), repo, jobs, nil)
+}
+
+// fetchProjectDetailsContext is fetchProjectDetails reporting every finished job to progress.
+// Once ctx is done the remaining jobs fail with its error instead of being fetched.
+func (s *ProjectService) fetchProjectDetailsContext(ctx context.Context, repo repository.ProjectRepository, jobs []detailJob, progress ProgressFunc) ([]detailResult, *DetailFetchSummary) {
+	start := time.Now()
+	results := make([]detailResult, len(jobs))
+
+	workers := s.detailWorkers
+	if workers <= 0 {
+		workers = defaultDetailWorkers
+	}
+	if workers > len(jobs) {
+		workers = len(jobs)
+	}
+
+	indexes := make(chan int)
+	var done atomic.Int64
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if err := ctx.Err(); err != nil {
+					results[i] = detailResult{Project: jobs[i].Project, Err: err}
+					continue
+				}
+				results[i] = s.fetchOneProjectDetails(repo, jobs[i])
+				project := results[i].Project
+				progress.report("Reading project details", project.Path+"@"+project.Ref, int(done.Add(1)), len(jobs))
+			}
+		}()
+	}
+
+	for i := range jobs {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	summary := &DetailFetchSummary{Total: len(jobs)}
+	for _, res := range results {
+		switch {
+		case res.Skipped:
+			summary.Skipped++
+		case res.Err != nil:
+			summary.Failed++
+			summary.Failures = append(summary.Failures, ProjectFailure{
+				ProjectID:   res.Project.ID,
+				ProjectName: res.Project.Name,
+				Ref:         res.Project.Ref,
+				Error:       res.Err.Error(),
+			})
+		default:
+			summary.Succeeded++
+		}
+	}
+	summary.Duration = time.Since(start).Round(time.Millisecond).String()
+
+	return results, summary
+}
+
+// fetchOneProjectDetails fetches a single project's details on the job's ref, turning panics into errors.
+// Projects without the ref (or without any branch at all) are skipped rather than failed.
+func (s *ProjectService) fetchOneProjectDetails(repo repository.ProjectRepository, job detailJob) (result detailResult) {
+	project := job.Project
+	project.Ref = resolveRef(project, job.Ref)
+	result.Project = project
+
+	// Empty repositories have no files to inspect
+	if project.EmptyRepo || project.Ref == "" {
+		result.Skipped = true
+		return result
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			result.Err = fmt.Errorf("panic while fetching details: %v", r)
+		}
+	}()
+
+	branch := &domain.Branch{Name: project.Ref, CommitSHA: job.Head}
+	var err error
+	if job.Head == "" {
+		branch, err = repo.GetBranch(project.ID, project.Ref)
+	}
+	if gitlab.IsNotFound(err) {
+		fmt.Printf("Skipping project %d (%s): no branch %s\n", project.ID, project.Name, project.Ref)
+		result.Skipped = true
+		return result
+	}
+	if err != nil {
+		fmt.Printf("Warning: Failed to get branch %s for project %d (%s): %v\n", project.Ref, project.ID, project.Name, err)
+		result.Err = err
+		return result
+	}
+
+	detailed, err := repo.GetProjectDetails(project.ID, project.Ref)
+	if err != nil {
+		fmt.Printf("Warning: Failed to get details for project %d (%s) on %s: %v\n", project.ID, project.Name, project.Ref, err)
+		result.Err = err
+		return result
+	}
+
+	detailed.CommitSHA = branch.CommitSHA
+	detailed.DetailsReadAt = time.Now()
+	result.Project = *detailed
+	return result
+}
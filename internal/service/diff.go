@@ -0,0 +1,416 @@
+// internal/service/diff.go
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gitlab-list/internal/domain"
+	"gitlab-list/internal/gomod"
+	"gitlab-list/internal/repository"
+
+	"golang.org/x/mod/semver"
+)
+
+// DiffSnapshots compares two snapshots on ref (each project's default branch when empty).
+// toID defaults to the newest snapshot and fromID to the one before toID; "latest" and
+// "previous" may be passed explicitly.
+func (s *ProjectService) DiffSnapshots(fromID, toID, ref string) (*domain.FleetDiff, error) {
+	if s.store == nil {
+		return nil, repository.ErrNoStore
+	}
+
+	snapshots, err := s.store.ListSnapshots()
+	if err != nil {
+		return nil, err
+	}
+
+	toIndex := len(snapshots) - 1
+	if toID != "" && toID != "latest" {
+		toIndex = snapshotIndex(snapshots, toID)
+		if toIndex < 0 {
+			return nil, fmt.Errorf("snapshot %s not found", toID)
+		}
+	}
+	fromIndex := toIndex - 1
+	if fromID != "" && fromID != "previous" {
+		fromIndex = snapshotIndex(snapshots, fromID)
+		if fromIndex < 0 {
+			return nil, fmt.Errorf("snapshot %s not found", fromID)
+		}
+	}
+	if toIndex < 0 || fromIndex < 0 {
+		return nil, fmt.Errorf("snapshot not found: at least two snapshots are needed for a diff")
+	}
+	from, to := snapshots[fromIndex], snapshots[toIndex]
+
+	records, err := s.store.GetSnapshotProjects([]string{from.ID, to.ID}, true)
+	if err != nil {
+		return nil, err
+	}
+	var fromRecords, toRecords []domain.ProjectSnapshot
+	for _, record := range records {
+		if !record.OnRef(ref) {
+			continue
+		}
+		// A snapshot diffed against itself lands on both sides
+		if record.SnapshotID == from.ID {
+			fromRecords = append(fromRecords, record)
+		}
+		if record.SnapshotID == to.ID {
+			toRecords = append(toRecords, record)
+		}
+	}
+
+	diff, err := s.diffRecords(fromRecords, toRecords)
+	if err != nil {
+		return nil, err
+	}
+	diff.From = domain.DiffSide{SnapshotID: from.ID, TakenAt: from.TakenAt, Ref: ref}
+	diff.To = domain.DiffSide{SnapshotID: to.ID, TakenAt: to.TakenAt, Ref: ref}
+	return diff, nil
+}
+
+// DiffProjectRefs compares a project on two refs, read live from GitLab.
+// An empty fromRef stands for the project's default branch.
+func (s *ProjectService) DiffProjectRefs(projectID int, fromRef, toRef, token string) (*domain.FleetDiff, error) {
+	if toRef == "" {
+		return nil, fmt.Errorf("invalid refs: the ref to compare against is required")
+	}
+
+	repo := s.repo
+	if token != "" {
+		repo = repo.WithToken(token)
+	}
+
+	from, err := projectOnRef(repo, projectID, fromRef)
+	if err != nil {
+		return nil, err
+	}
+	to, err := projectOnRef(repo, projectID, toRef)
+	if err != nil {
+		return nil, err
+	}
+
+	diff, err := s.diffRecords(
+		[]domain.ProjectSnapshot{projectSnapshot("", time.Time{}, *from)},
+		[]domain.ProjectSnapshot{projectSnapshot("", time.Time{}, *to)},
+	)
+	if err != nil {
+		return nil, err
+	}
+	diff.From = domain.DiffSide{Ref: from.Ref}
+	diff.To = domain.DiffSide{Ref: to.Ref}
+	return diff, nil
+}
+
+// projectOnRef reads a project's details on ref, with the head commit when ref is a branch
+func projectOnRef(repo repository.ProjectRepository, projectID int, ref string) (*domain.Project, error) {
+	project, err := repo.GetProjectDetails(projectID, ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project details on %s: %w", ref, err)
+	}
+	if branch, err := repo.GetBranch(projectID, project.Ref); err == nil {
+		project.CommitSHA = branch.CommitSHA
+	}
+	return project, nil
+}
+
+// snapshotIndex returns the position of the snapshot with id, or -1
+func snapshotIndex(snapshots []domain.Snapshot, id string) int {
+	for i, snapshot := range snapshots {
+		if snapshot.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// diffRecords compares two sets of project records, matched by project ID
+func (s *ProjectService) diffRecords(from, to []domain.ProjectSnapshot) (*domain.FleetDiff, error) {
+	diff := &domain.FleetDiff{
+		Added:    []domain.ProjectRef{},
+		Removed:  []domain.ProjectRef{},
+		Projects: []domain.ProjectDiff{},
+	}
+
+	before := make(map[int]domain.ProjectSnapshot, len(from))
+	for _, record := range from {
+		before[record.ProjectID] = record
+	}
+	after := make(map[int]bool, len(to))
+	for _, record := range to {
+		after[record.ProjectID] = true
+		old, ok := before[record.ProjectID]
+		if !ok {
+			diff.Added = append(diff.Added, projectRef(record))
+			continue
+		}
+		if changes, changed := diffProject(old, record); changed {
+			diff.Projects = append(diff.Projects, changes)
+		}
+	}
+	for _, record := range from {
+		if !after[record.ProjectID] {
+			diff.Removed = append(diff.Removed, projectRef(record))
+		}
+	}
+
+	edges, err := s.diffEdges(from, to)
+	if err != nil {
+		return nil, err
+	}
+	diff.Edges = edges
+
+	sort.Slice(diff.Added, func(i, j int) bool { return diff.Added[i].Path < diff.Added[j].Path })
+	sort.Slice(diff.Removed, func(i, j int) bool { return diff.Removed[i].Path < diff.Removed[j].Path })
+	sort.Slice(diff.Projects, func(i, j int) bool { return diff.Projects[i].Path < diff.Projects[j].Path })
+
+	summary := &diff.Summary
+	summary.ProjectsAdded = len(diff.Added)
+	summary.ProjectsRemoved = len(diff.Removed)
+	summary.ProjectsChanged = len(diff.Projects)
+	for _, project := range diff.Projects {
+		summary.GoVersionChanges += len(project.GoVersions)
+		summary.OpenAPIChanges += len(project.OpenAPI)
+		for _, lib := range project.Libraries {
+			switch lib.Change {
+			case domain.ChangeAdded:
+				summary.LibrariesAdded++
+			case domain.ChangeRemoved:
+				summary.LibrariesRemoved++
+			case domain.ChangeUpgraded:
+				summary.LibrariesUpgraded++
+			case domain.ChangeDowngraded:
+				summary.LibrariesDowngraded++
+			}
+		}
+	}
+	for _, edge := range diff.Edges {
+		switch edge.Change {
+		case domain.ChangeAdded:
+			summary.EdgesAdded++
+		case domain.ChangeRemoved:
+			summary.EdgesRemoved++
+		case domain.ChangeModified:
+			summary.EdgesModified++
+		}
+	}
+	return diff, nil
+}
+
+func projectRef(record domain.ProjectSnapshot) domain.ProjectRef {
+	return domain.ProjectRef{ProjectID: record.ProjectID, Name: record.Name, Path: record.Path, Ref: record.Ref}
+}
+
+// diffProject compares two records of one project and reports whether anything changed
+func diffProject(from, to domain.ProjectSnapshot) (domain.ProjectDiff, bool) {
+	diff := domain.ProjectDiff{
+		ProjectRef: projectRef(to),
+		FromCommit: from.CommitSHA,
+		ToCommit:   to.CommitSHA,
+	}
+
+	oldModules := make(map[string]domain.ModuleSnapshot, len(from.Modules))
+	for _, mod := range from.Modules {
+		oldModules[mod.Dir] = mod
+	}
+	newModules := make(map[string]bool, len(to.Modules))
+	for _, mod := range to.Modules {
+		newModules[mod.Dir] = true
+		old, ok := oldModules[mod.Dir]
+		if !ok {
+			diff.Modules = append(diff.Modules, domain.ModuleChange{ModuleDir: mod.Dir, Path: mod.Path, Change: domain.ChangeAdded})
+			continue
+		}
+		if old.GoVersion != mod.GoVersion {
+			change := domain.ChangeUpgraded
+			if semver.Compare("v"+mod.GoVersion, "v"+old.GoVersion) < 0 {
+				change = domain.ChangeDowngraded
+			}
+			diff.GoVersions = append(diff.GoVersions, domain.GoChange{ModuleDir: mod.Dir, From: old.GoVersion, To: mod.GoVersion, Change: change})
+		}
+		diff.Libraries = append(diff.Libraries, diffLibraries(mod.Dir, old.Libraries, mod.Libraries)...)
+	}
+	for _, mod := range from.Modules {
+		if !newModules[mod.Dir] {
+			diff.Modules = append(diff.Modules, domain.ModuleChange{ModuleDir: mod.Dir, Path: mod.Path, Change: domain.ChangeRemoved})
+		}
+	}
+
+	diff.OpenAPI = diffSpecs(from.OpenAPISpecs, to.OpenAPISpecs)
+
+	changed := len(diff.GoVersions) > 0 || len(diff.Modules) > 0 || len(diff.Libraries) > 0 || len(diff.OpenAPI) > 0
+	return diff, changed
+}
+
+// diffLibraries compares the requirements of one module
+func diffLibraries(dir string, from, to []domain.Library) []domain.LibraryChange {
+	var changes []domain.LibraryChange
+	old := make(map[string]domain.Library, len(from))
+	for _, lib := range from {
+		old[lib.Name] = lib
+	}
+	seen := make(map[string]bool, len(to))
+	for _, lib := range to {
+		seen[lib.Name] = true
+		prev, ok := old[lib.Name]
+		change := domain.LibraryChange{ModuleDir: dir, Module: lib.Name, From: prev.Version, To: lib.Version, Indirect: lib.Indirect}
+		switch {
+		case !ok:
+			change.From = ""
+			change.Change = domain.ChangeAdded
+		case prev.Version != lib.Version:
+			change.Change = domain.ChangeUpgraded
+			if semver.Compare(lib.Version, prev.Version) < 0 {
+				change.Change = domain.ChangeDowngraded
+			}
+			change.Bump = versionBump(prev.Version, lib.Version)
+		case replaceString(prev.Replace) != replaceString(lib.Replace):
+			change.Change = domain.ChangeModified
+		default:
+			continue
+		}
+		changes = append(changes, change)
+	}
+	for _, lib := range from {
+		if !seen[lib.Name] {
+			changes = append(changes, domain.LibraryChange{ModuleDir: dir, Module: lib.Name, From: lib.Version, Change: domain.ChangeRemoved, Indirect: lib.Indirect})
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Module < changes[j].Module })
+	return changes
+}
+
+func replaceString(replace *domain.Replacement) string {
+	if replace == nil {
+		return ""
+	}
+	return replace.Path + "@" + replace.Version
+}
+
+// versionBump names the most significant part that differs between two versions
+func versionBump(from, to string) string {
+	a, b := versionParts(from), versionParts(to)
+	switch {
+	case a[0] != b[0]:
+		return "major"
+	case a[1] != b[1]:
+		return "minor"
+	case a[2] != b[2]:
+		return "patch"
+	default:
+		return "prerelease"
+	}
+}
+
+// diffSpecs compares OpenAPI specs by path
+func diffSpecs(from, to []domain.OpenAPISnapshot) []domain.SpecChange {
+	var changes []domain.SpecChange
+	old := make(map[string]domain.OpenAPISnapshot, len(from))
+	for _, spec := range from {
+		old[spec.Path] = spec
+	}
+	seen := make(map[string]bool, len(to))
+	for _, spec := range to {
+		seen[spec.Path] = true
+		prev, ok := old[spec.Path]
+		switch {
+		case !ok:
+			changes = append(changes, domain.SpecChange{Path: spec.Path, Change: domain.ChangeAdded, ToVersion: spec.Version, Title: spec.Title})
+		case prev.Hash != spec.Hash:
+			changes = append(changes, domain.SpecChange{Path: spec.Path, Change: domain.ChangeModified, FromVersion: prev.Version, ToVersion: spec.Version, Title: spec.Title})
+		}
+	}
+	for _, spec := range from {
+		if !seen[spec.Path] {
+			changes = append(changes, domain.SpecChange{Path: spec.Path, Change: domain.ChangeRemoved, FromVersion: spec.Version, Title: spec.Title})
+		}
+	}
+	return changes
+}
+
+// diffEdges compares the service-to-client edges of the architecture graphs built from both sides;
+// an edge whose client version changed is modified
+func (s *ProjectService) diffEdges(from, to []domain.ProjectSnapshot) ([]domain.EdgeChange, error) {
+	before, err := s.architectureEdges(from)
+	if err != nil {
+		return nil, err
+	}
+	after, err := s.architectureEdges(to)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []domain.EdgeChange{}
+	for key, edge := range after {
+		old, ok := before[key]
+		switch {
+		case !ok:
+			edge.Change = domain.ChangeAdded
+			changes = append(changes, edge)
+		case old.Version != edge.Version:
+			edge.Change = domain.ChangeModified
+			edge.FromVersion = old.Version
+			changes = append(changes, edge)
+		}
+	}
+	for key, edge := range before {
+		if _, ok := after[key]; !ok {
+			edge.Change = domain.ChangeRemoved
+			changes = append(changes, edge)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].From != changes[j].From {
+			return changes[i].From < changes[j].From
+		}
+		return changes[i].To < changes[j].To
+	})
+	return changes, nil
+}
+
+// architectureEdges returns the edges of the cached-data architecture graph keyed by "from|to"
+func (s *ProjectService) architectureEdges(records []domain.ProjectSnapshot) (map[string]domain.EdgeChange, error) {
+	projects := make([]domain.Project, 0, len(records))
+	for _, record := range records {
+		project := domain.Project{ID: record.ProjectID, Name: record.Name, Path: record.Path, Ref: record.Ref}
+		for _, mod := range record.Modules {
+			project.Modules = append(project.Modules, domain.Module{
+				Path:      mod.Path,
+				Dir:       mod.Dir,
+				GoVersion: mod.GoVersion,
+				Libraries: mod.Libraries,
+			})
+		}
+		projects = append(projects, project)
+	}
+
+	g, err := s.generateArchitectureFromCacheWithOptions(projects, "", 0, nil, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build the architecture graph: %w", err)
+	}
+	edges := make(map[string]domain.EdgeChange, len(g.Edges))
+	labels := make(map[string]string, len(g.Nodes))
+	for _, node := range g.Nodes {
+		labels[node.ID] = node.Meta["label"]
+		if node.Meta["module"] != "" && strings.HasPrefix(node.ID, "dep:") {
+			labels[node.ID] = node.Meta["module"]
+		}
+	}
+	for _, edge := range g.Edges {
+		from, to := labels[edge.From], labels[edge.To]
+		edges[from+"|"+to] = domain.EdgeChange{From: from, To: to, Version: edge.Version}
+	}
+	return edges, nil
+}
+
+// moduleLabel names a module directory for output, "root" for the repository root
+func moduleLabel(dir string) string {
+	if dir == "" || dir == gomod.RootDir {
+		return "root"
+	}
+	return dir
+}
@@ -0,0 +1,135 @@
+// internal/service/diff_markdown.go
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"gitlab-list/internal/domain"
+)
+
+// DiffMarkdown renders a diff as a readable changelog
+func DiffMarkdown(diff *domain.FleetDiff) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# Changes from %s to %s\n\n", describeSide(diff.From), describeSide(diff.To))
+
+	sum := diff.Summary
+	fmt.Fprintf(&b, "%d projects added, %d removed, %d changed. ", sum.ProjectsAdded, sum.ProjectsRemoved, sum.ProjectsChanged)
+	fmt.Fprintf(&b, "Libraries: %d added, %d removed, %d upgraded, %d downgraded. ",
+		sum.LibrariesAdded, sum.LibrariesRemoved, sum.LibrariesUpgraded, sum.LibrariesDowngraded)
+	fmt.Fprintf(&b, "%d Go version changes, %d OpenAPI changes, %d architecture edges added, %d removed, %d modified.\n",
+		sum.GoVersionChanges, sum.OpenAPIChanges, sum.EdgesAdded, sum.EdgesRemoved, sum.EdgesModified)
+
+	if len(diff.Added) > 0 {
+		b.WriteString("\n## Added projects\n\n")
+		for _, project := range diff.Added {
+			fmt.Fprintf(&b, "- %s\n", describeProject(project))
+		}
+	}
+	if len(diff.Removed) > 0 {
+		b.WriteString("\n## Removed projects\n\n")
+		for _, project := range diff.Removed {
+			fmt.Fprintf(&b, "- %s\n", describeProject(project))
+		}
+	}
+
+	if len(diff.Projects) > 0 {
+		b.WriteString("\n## Changed projects\n")
+		for _, project := range diff.Projects {
+			fmt.Fprintf(&b, "\n### %s\n\n", describeProject(project.ProjectRef))
+			if project.FromCommit != "" && project.ToCommit != "" && project.FromCommit != project.ToCommit {
+				fmt.Fprintf(&b, "Commits `%s` → `%s`\n\n", shortSHA(project.FromCommit), shortSHA(project.ToCommit))
+			}
+			for _, change := range project.GoVersions {
+				fmt.Fprintf(&b, "- Go %s: `%s` → `%s` (%s)\n", change.Change, change.From, change.To, moduleLabel(change.ModuleDir))
+			}
+			for _, change := range project.Modules {
+				fmt.Fprintf(&b, "- Module %s: `%s` (%s)\n", change.Change, change.Path, moduleLabel(change.ModuleDir))
+			}
+			for _, change := range project.Libraries {
+				fmt.Fprintf(&b, "- %s\n", describeLibraryChange(change))
+			}
+			for _, change := range project.OpenAPI {
+				fmt.Fprintf(&b, "- OpenAPI %s: `%s`%s\n", change.Change, change.Path, describeSpecVersions(change))
+			}
+		}
+	}
+
+	if len(diff.Edges) > 0 {
+		b.WriteString("\n## Architecture\n\n")
+		for _, edge := range diff.Edges {
+			version := ""
+			if edge.FromVersion != "" {
+				version = " " + edge.FromVersion + " →"
+			}
+			if edge.Version != "" {
+				version += " " + edge.Version
+			}
+			fmt.Fprintf(&b, "- Edge %s: %s → `%s`%s\n", edge.Change, edge.From, edge.To, version)
+		}
+	}
+
+	if sum == (domain.DiffSummary{}) {
+		b.WriteString("\nNo changes.\n")
+	}
+	return b.String()
+}
+
+func describeSide(side domain.DiffSide) string {
+	switch {
+	case side.SnapshotID != "":
+		return fmt.Sprintf("snapshot %s (%s)", side.SnapshotID, side.TakenAt.Format("2006-01-02 15:04 MST"))
+	case side.Ref != "":
+		return "`" + side.Ref + "`"
+	default:
+		return "default branch"
+	}
+}
+
+func describeProject(project domain.ProjectRef) string {
+	if project.Ref != "" {
+		return fmt.Sprintf("%s (`%s`)", project.Path, project.Ref)
+	}
+	return project.Path
+}
+
+func describeLibraryChange(change domain.LibraryChange) string {
+	indirect := ""
+	if change.Indirect {
+		indirect = ", indirect"
+	}
+	where := moduleLabel(change.ModuleDir)
+	switch change.Change {
+	case domain.ChangeAdded:
+		return fmt.Sprintf("Added `%s` `%s` (%s%s)", change.Module, change.To, where, indirect)
+	case domain.ChangeRemoved:
+		return fmt.Sprintf("Removed `%s` `%s` (%s%s)", change.Module, change.From, where, indirect)
+	case domain.ChangeModified:
+		return fmt.Sprintf("Changed replace of `%s` `%s` (%s%s)", change.Module, change.To, where, indirect)
+	default:
+		// upgraded or downgraded
+		return fmt.Sprintf("%s%s `%s` `%s` → `%s` (%s, %s%s)", strings.ToUpper(change.Change[:1]), change.Change[1:],
+			change.Module, change.From, change.To, change.Bump, where, indirect)
+	}
+}
+
+func describeSpecVersions(change domain.SpecChange) string {
+	switch {
+	case change.FromVersion != "" && change.ToVersion != "" && change.FromVersion != change.ToVersion:
+		return fmt.Sprintf(" (%s → %s)", change.FromVersion, change.ToVersion)
+	case change.ToVersion != "":
+		return fmt.Sprintf(" (%s)", change.ToVersion)
+	case change.FromVersion != "":
+		return fmt.Sprintf(" (%s)", change.FromVersion)
+	default:
+		return ""
+	}
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 8 {
+		return sha[:8]
+	}
+	return sha
+}
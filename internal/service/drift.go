@@ -0,0 +1,202 @@
+// internal/service/drift.go
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gitlab-list/internal/domain"
+	"gitlab-list/internal/gomod"
+	"gitlab-list/internal/goproxy"
+	"gitlab-list/internal/repository"
+
+	"golang.org/x/mod/semver"
+)
+
+// SetModuleProxy sets the module proxy used to find the newest available library versions
+func (s *ProjectService) SetModuleProxy(proxy *goproxy.Client) {
+	s.proxy = proxy
+}
+
+// GetLibraryDrift aggregates the library versions required by every cached project module
+// and measures how far each version is behind the newest one
+func (s *ProjectService) GetLibraryDrift(opts domain.DriftOptions) (*domain.DriftReport, error) {
+	if s.store == nil {
+		return nil, repository.ErrNoStore
+	}
+
+	projects, err := s.getCachedProjectsOnRef(opts.Ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cached projects: %w", err)
+	}
+
+	libraries := make(map[string]*domain.LibraryDrift)
+	usages := make(map[string]map[string]*domain.VersionUsage)
+	consumers := make(map[string]map[int]bool)
+	for _, project := range projects {
+		for _, module := range project.GoModules() {
+			for _, lib := range module.Libraries {
+				if opts.DirectOnly && lib.Indirect {
+					continue
+				}
+				if opts.Prefix != "" && !strings.HasPrefix(lib.Name, opts.Prefix) {
+					continue
+				}
+				internal := s.proxy != nil && s.proxy.Hosted(lib.Name)
+				if opts.InternalOnly && !internal {
+					continue
+				}
+
+				if libraries[lib.Name] == nil {
+					libraries[lib.Name] = &domain.LibraryDrift{Module: lib.Name, Internal: internal}
+					usages[lib.Name] = make(map[string]*domain.VersionUsage)
+					consumers[lib.Name] = make(map[int]bool)
+				}
+				usage := usages[lib.Name][lib.Version]
+				if usage == nil {
+					usage = &domain.VersionUsage{Version: lib.Version}
+					usages[lib.Name][lib.Version] = usage
+				}
+
+				consumer := domain.LibraryConsumer{
+					ProjectID: project.ID,
+					Name:      project.Name,
+					Path:      project.Path,
+					Indirect:  lib.Indirect,
+				}
+				if module.Dir != gomod.RootDir {
+					consumer.ModuleDir = module.Dir
+				}
+				usage.Projects = append(usage.Projects, consumer)
+				consumers[lib.Name][project.ID] = true
+			}
+		}
+	}
+
+	report := &domain.DriftReport{
+		Ref:         opts.Ref,
+		GeneratedAt: time.Now(),
+		Projects:    len(projects),
+		Libraries:   make([]domain.LibraryDrift, 0, len(libraries)),
+	}
+	for name, library := range libraries {
+		for _, usage := range usages[name] {
+			library.Versions = append(library.Versions, *usage)
+		}
+		sort.Slice(library.Versions, func(i, j int) bool {
+			return semver.Compare(library.Versions[i].Version, library.Versions[j].Version) > 0
+		})
+		library.LatestInUse = library.Versions[0].Version
+		library.Projects = len(consumers[name])
+	}
+
+	s.lookupAvailableVersions(libraries)
+
+	for _, library := range libraries {
+		target, targetTime := library.LatestInUse, library.Versions[0].Time
+		if library.LatestAvailable != "" && semver.Compare(library.LatestAvailable, target) > 0 {
+			target, targetTime = library.LatestAvailable, library.LatestAvailableTime
+		}
+		for i := range library.Versions {
+			usage := &library.Versions[i]
+			usage.Drift = versionDrift(usage.Version, target)
+			if !usage.Time.IsZero() && targetTime.After(usage.Time) {
+				usage.Drift.DaysBehind = int(targetTime.Sub(usage.Time).Hours() / 24)
+			}
+			if usage.Drift.Score > library.MaxScore {
+				library.MaxScore = usage.Drift.Score
+			}
+		}
+		report.Libraries = append(report.Libraries, *library)
+	}
+
+	// Worst drift first, then by module path
+	sort.Slice(report.Libraries, func(i, j int) bool {
+		a, b := report.Libraries[i], report.Libraries[j]
+		if a.MaxScore != b.MaxScore {
+			return a.MaxScore > b.MaxScore
+		}
+		return a.Module < b.Module
+	})
+
+	return report, nil
+}
+
+// lookupAvailableVersions fills the newest available version of each library and the release
+// time of every version in use, querying the module proxy with a bounded number of workers
+func (s *ProjectService) lookupAvailableVersions(libraries map[string]*domain.LibraryDrift) {
+	if s.proxy == nil {
+		return
+	}
+
+	workers := s.detailWorkers
+	if workers <= 0 {
+		workers = defaultDetailWorkers
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, workers)
+	for _, library := range libraries {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(library *domain.LibraryDrift) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			// Each goroutine owns one library, so no locking is needed
+			if latest, err := s.proxy.Latest(library.Module); err == nil {
+				library.LatestAvailable = latest.Version
+				library.LatestAvailableTime = latest.Time
+			} else {
+				library.LookupError = err.Error()
+			}
+			for i := range library.Versions {
+				if info, err := s.proxy.Info(library.Module, library.Versions[i].Version); err == nil {
+					library.Versions[i].Time = info.Time
+				}
+			}
+		}(library)
+	}
+	wg.Wait()
+}
+
+// versionDrift measures how far version is behind target
+func versionDrift(version, target string) domain.VersionDrift {
+	drift := domain.VersionDrift{Target: target}
+	if semver.Compare(version, target) >= 0 {
+		return drift
+	}
+
+	v, t := versionParts(version), versionParts(target)
+	switch {
+	case t[0] != v[0]:
+		drift.Major = t[0] - v[0]
+	case t[1] != v[1]:
+		drift.Minor = t[1] - v[1]
+	default:
+		drift.Patch = t[2] - v[2]
+	}
+	if drift.Patch == 0 && drift.Minor == 0 && drift.Major == 0 {
+		// Same release, but version is a pre-release or older pseudo-version of it
+		drift.Patch = 1
+	}
+	drift.Score = drift.Major*100 + drift.Minor*10 + drift.Patch
+	return drift
+}
+
+// versionParts returns the major, minor and patch numbers of a semantic version
+func versionParts(version string) [3]int {
+	var parts [3]int
+	canonical := strings.TrimPrefix(semver.Canonical(version), "v")
+	if i := strings.IndexAny(canonical, "-+"); i >= 0 {
+		canonical = canonical[:i]
+	}
+	for i, field := range strings.SplitN(canonical, ".", 3) {
+		parts[i], _ = strconv.Atoi(field)
+	}
+	return parts
+}
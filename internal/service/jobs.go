@@ -0,0 +1,267 @@
+// internal/service/jobs.go
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gitlab-list/internal/configuration"
+	"gitlab-list/internal/domain"
+)
+
+// Names of the built-in scheduled jobs
+const (
+	JobSync             = "sync"
+	JobPolicy           = "policy"
+	JobVulnerabilities  = "vulnerabilities"
+	JobWeeklyReport     = "weekly-report"
+	JobCampaignTracking = "campaign-tracking"
+)
+
+// ConfiguredJobs returns the built-in jobs with the schedule, enable flag and timeout from cfg
+func ConfiguredJobs(cfg *configuration.Configuration, projects *ProjectService, policies *PolicyService, vulnerabilities *VulnerabilityService, campaigns *CampaignService) []JobDefinition {
+	return []JobDefinition{
+		{
+			Name:        JobSync,
+			Description: "Incremental sync of the project cache",
+			Schedule:    cfg.SyncSchedule,
+			Enabled:     cfg.SyncEnabled,
+			Timeout:     jobTimeout(cfg.SyncTimeout),
+			Run:         SyncJob(projects),
+		},
+		{
+			Name:        JobWeeklyReport,
+			Description: "Markdown changelog of the fleet over the last week",
+			Schedule:    cfg.ReportSchedule,
+			Enabled:     cfg.ReportEnabled,
+			Timeout:     jobTimeout(cfg.ReportTimeout),
+			Run:         WeeklyReportJob(projects, policies, vulnerabilities, cfg.ReportDir),
+		},
+		{
+			Name:        JobPolicy,
+			Description: "Evaluate POLICY_FILE against the cached projects",
+			Schedule:    cfg.PolicySchedule,
+			Enabled:     cfg.PolicyEnabled,
+			Timeout:     jobTimeout(cfg.PolicyTimeout),
+			Run:         PolicyJob(policies),
+		},
+		{
+			Name:        JobVulnerabilities,
+			Description: "Reload OSV_DB and match it against the cached projects",
+			Schedule:    cfg.VulnerabilitySchedule,
+			Enabled:     cfg.VulnerabilityEnabled,
+			Timeout:     jobTimeout(cfg.VulnerabilityTimeout),
+			Run:         VulnerabilityJob(vulnerabilities),
+		},
+		{
+			Name:        JobCampaignTracking,
+			Description: "Read the merge request states of the active campaigns; updates are queued by the API",
+			Schedule:    cfg.CampaignSchedule,
+			Enabled:     cfg.CampaignEnabled,
+			Timeout:     jobTimeout(cfg.CampaignTimeout),
+			Run:         CampaignTrackingJob(campaigns),
+		},
+	}
+}
+
+// jobTimeout parses a job timeout; invalid or negative values mean no timeout
+func jobTimeout(value string) time.Duration {
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return d
+	}
+	return 0
+}
+
+// SyncJob runs an incremental cache refresh
+func SyncJob(projects *ProjectService) JobFunc {
+	return func(ctx context.Context, out *JobLog) (JobResult, error) {
+		summary, err := projects.RefreshCacheContext(ctx, "", nil)
+		if err != nil {
+			return JobResult{}, err
+		}
+
+		for _, failure := range summary.Failures {
+			out.Printf("Failed to read %s@%s: %s", failure.ProjectName, failure.Ref, failure.Error)
+		}
+		out.Printf("%d records: %d unchanged, %d verified, %d re-read, %d failed, %d removed; %d API calls, %d saved",
+			summary.Records, summary.Unchanged, summary.Verified, summary.Refetched, summary.Failed, summary.Removed,
+			summary.APICalls, summary.APICallsSaved)
+		return JobResult{
+			Metrics: map[string]int64{
+				"projects":          int64(summary.Projects),
+				"records":           int64(summary.Records),
+				"unchanged":         int64(summary.Unchanged),
+				"verified":          int64(summary.Verified),
+				"refetched":         int64(summary.Refetched),
+				"failed":            int64(summary.Failed),
+				"removed":           summary.Removed,
+				"new_projects":      int64(summary.NewProjects),
+				"deleted_projects":  int64(summary.DeletedProjects),
+				"api_calls":         summary.APICalls,
+				"api_calls_saved":   summary.APICallsSaved,
+				"renamed_projects":  int64(summary.RenamedProjects),
+				"archived_projects": int64(summary.ArchivedProjects),
+			},
+			Partial: summary.Failed > 0,
+		}, nil
+	}
+}
+
+// PolicyJob evaluates the policy file against the cached projects on their default branch
+func PolicyJob(policies *PolicyService) JobFunc {
+	return func(ctx context.Context, out *JobLog) (JobResult, error) {
+		report, err := policies.EvaluateContext(ctx, "")
+		if err != nil {
+			return JobResult{}, err
+		}
+
+		for _, project := range report.Projects {
+			if project.Errors > 0 || project.Warnings > 0 {
+				out.Printf("%s: %d errors, %d warnings", project.Path, project.Errors, project.Warnings)
+			}
+		}
+		out.Printf("%d projects evaluated: %d errors, %d warnings", report.Evaluated, report.Errors, report.Warnings)
+		return JobResult{
+			Metrics: map[string]int64{
+				"evaluated": int64(report.Evaluated),
+				"errors":    int64(report.Errors),
+				"warnings":  int64(report.Warnings),
+			},
+		}, nil
+	}
+}
+
+// VulnerabilityJob reloads the OSV dump, so a replaced file is picked up, and matches the cached projects
+func VulnerabilityJob(vulnerabilities *VulnerabilityService) JobFunc {
+	return func(ctx context.Context, out *JobLog) (JobResult, error) {
+		db, err := vulnerabilities.Reload()
+		if err != nil {
+			return JobResult{}, err
+		}
+		report, err := vulnerabilities.GetReportContext(ctx, VulnerabilityOptions{})
+		if err != nil {
+			return JobResult{}, err
+		}
+
+		findings, possible := 0, 0
+		for _, project := range report.Projects {
+			findings += len(project.Findings)
+			for _, finding := range project.Findings {
+				if finding.PossiblyAffected {
+					possible++
+				}
+			}
+			out.Printf("%s: %d findings", project.Path, len(project.Findings))
+		}
+		out.Printf("%d advisories affect %d projects (%d entries in %s)", len(report.Advisories), len(report.Projects), db.Entries, db.Path)
+		return JobResult{
+			Metrics: map[string]int64{
+				"database_entries":  int64(db.Entries),
+				"advisories":        int64(len(report.Advisories)),
+				"affected_projects": int64(len(report.Projects)),
+				"findings":          int64(findings),
+				"possible_findings": int64(possible),
+			},
+		}, nil
+	}
+}
+
+// CampaignTrackingJob refreshes the merge request state of every active campaign with the configured
+// token. It starts no updates: those run on the job queue of the API that created the campaign.
+func CampaignTrackingJob(campaigns *CampaignService) JobFunc {
+	return func(ctx context.Context, out *JobLog) (JobResult, error) {
+		refresh, err := campaigns.RefreshActiveContext(ctx, "")
+		if err != nil {
+			return JobResult{}, err
+		}
+
+		for _, message := range refresh.Errors {
+			out.Printf("%s", message)
+		}
+		out.Printf("%d campaigns refreshed, %d completed: %d opened, %d pipeline failed, %d merged, %d closed",
+			refresh.Campaigns, refresh.Completed, refresh.Projects[domain.CampaignProjectOpened],
+			refresh.Projects[domain.CampaignProjectPipelineFailed], refresh.Projects[domain.CampaignProjectMerged],
+			refresh.Projects[domain.CampaignProjectClosed])
+		metrics := map[string]int64{
+			"campaigns": int64(refresh.Campaigns),
+			"completed": int64(refresh.Completed),
+		}
+		for status, count := range refresh.Projects {
+			metrics["projects_"+status] = int64(count)
+		}
+		return JobResult{
+			Metrics: metrics,
+			Partial: len(refresh.Errors) > 0,
+		}, nil
+	}
+}
+
+// WeeklyReportJob renders the changes between the newest snapshot and the newest one at least a week
+// older as Markdown, followed by the policy and vulnerability totals when POLICY_FILE and OSV_DB are set.
+// The report is written to dir when set and its headline kept in the job log.
+func WeeklyReportJob(projects *ProjectService, policies *PolicyService, vulnerabilities *VulnerabilityService, dir string) JobFunc {
+	return func(ctx context.Context, out *JobLog) (JobResult, error) {
+		snapshots, err := projects.GetSnapshots()
+		if err != nil {
+			return JobResult{}, err
+		}
+		if len(snapshots) < 2 {
+			return JobResult{}, fmt.Errorf("snapshot not found: at least two snapshots are needed for a report")
+		}
+		to := snapshots[len(snapshots)-1]
+		from := snapshots[0]
+		for _, snapshot := range snapshots[:len(snapshots)-1] {
+			if snapshot.TakenAt.After(to.TakenAt.AddDate(0, 0, -7)) {
+				break
+			}
+			from = snapshot
+		}
+
+		diff, err := projects.DiffSnapshots(from.ID, to.ID, "")
+		if err != nil {
+			return JobResult{}, err
+		}
+		if err := ctx.Err(); err != nil {
+			return JobResult{}, fmt.Errorf("report stopped: %w", err)
+		}
+
+		var b strings.Builder
+		b.WriteString(DiffMarkdown(diff))
+		metrics := map[string]int64{
+			"projects_added":     int64(diff.Summary.ProjectsAdded),
+			"projects_removed":   int64(diff.Summary.ProjectsRemoved),
+			"projects_changed":   int64(diff.Summary.ProjectsChanged),
+			"libraries_upgraded": int64(diff.Summary.LibrariesUpgraded),
+			"go_version_changes": int64(diff.Summary.GoVersionChanges),
+		}
+		if report, err := policies.EvaluateContext(ctx, ""); err == nil {
+			fmt.Fprintf(&b, "\n## Policy\n\n%d projects evaluated: %d errors, %d warnings.\n", report.Evaluated, report.Errors, report.Warnings)
+			metrics["policy_errors"] = int64(report.Errors)
+			metrics["policy_warnings"] = int64(report.Warnings)
+		}
+		if report, err := vulnerabilities.GetReportContext(ctx, VulnerabilityOptions{}); err == nil {
+			fmt.Fprintf(&b, "\n## Vulnerabilities\n\n%d advisories affect %d projects.\n", len(report.Advisories), len(report.Projects))
+			metrics["vulnerable_projects"] = int64(len(report.Projects))
+		}
+
+		sum := diff.Summary
+		out.Printf("Changes from %s to %s: %d projects added, %d removed, %d changed, %d libraries upgraded",
+			from.ID, to.ID, sum.ProjectsAdded, sum.ProjectsRemoved, sum.ProjectsChanged, sum.LibrariesUpgraded)
+
+		if dir != "" {
+			path := filepath.Join(dir, fmt.Sprintf("weekly-report-%s.md", to.TakenAt.Format("2006-01-02")))
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				return JobResult{Metrics: metrics}, fmt.Errorf("failed to create report directory: %w", err)
+			}
+			if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+				return JobResult{Metrics: metrics}, fmt.Errorf("failed to write report: %w", err)
+			}
+			out.Printf("Report written to %s", path)
+		}
+		return JobResult{Metrics: metrics}, nil
+	}
+}
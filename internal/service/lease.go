@@ -0,0 +1,162 @@
+// internal/service/lease.go
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"gitlab-list/internal/domain"
+	"gitlab-list/internal/repository"
+)
+
+// Lease names
+const (
+	LeaseCache     = "cache"     // Cache loads, refreshes and clears, manual or scheduled
+	LeaseScheduler = "scheduler" // The scheduler replica that runs the jobs
+)
+
+// leaseTTL is how long a lease outlives its last renewal; holders renew it three times per TTL
+const leaseTTL = 30 * time.Second
+
+// processID names this process in lease holders
+var processID = func() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s:%d", host, os.Getpid())
+}()
+
+// Lease is a held distributed lock, renewed in the background until Release
+type Lease struct {
+	store repository.CacheStore
+	name  string
+	token int64
+
+	mu   sync.Mutex
+	err  error // Set once a renewal fails; the holder must stop writing
+	stop chan struct{}
+	done chan struct{}
+}
+
+// AcquireLease takes the named lease for operation, or returns a *repository.LeaseHeldError
+func AcquireLease(store repository.CacheStore, name, operation string) (*Lease, error) {
+	return acquireLease(store, name, operation, leaseTTL)
+}
+
+func acquireLease(store repository.CacheStore, name, operation string, ttl time.Duration) (*Lease, error) {
+	held, err := store.AcquireLease(name, fmt.Sprintf("%s (%s)", processID, operation), ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	lease := &Lease{
+		store: store,
+		name:  name,
+		token: held.Token,
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go lease.renew(ttl)
+	return lease, nil
+}
+
+// renew extends the lease every third of ttl until Release or a failed renewal
+func (l *Lease) renew(ttl time.Duration) {
+	defer close(l.done)
+	ticker := time.NewTicker(ttl / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			if _, err := l.store.RenewLease(l.name, l.token, ttl); err != nil {
+				log.Printf("Lost lease %s (token %d): %v", l.name, l.token, err)
+				l.mu.Lock()
+				l.err = err
+				l.mu.Unlock()
+				return
+			}
+		}
+	}
+}
+
+// Token returns the fencing token of this holder
+func (l *Lease) Token() int64 {
+	return l.token
+}
+
+// Fence makes store writes conditional on this holder still having the lease
+func (l *Lease) Fence() repository.Fence {
+	return repository.Fence{Lease: l.name, Token: l.token}
+}
+
+// Check reports whether this holder still has the lease: it fails once a renewal failed, or when the
+// store holds a newer token because the lease expired and was taken over. Writes are fenced by the store
+// through Fence, since the lease can move on between Check and a write.
+func (l *Lease) Check() error {
+	l.mu.Lock()
+	err := l.err
+	l.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	leases, err := l.store.ListLeases()
+	if err != nil {
+		return err
+	}
+	for _, lease := range leases {
+		if lease.Name == l.name {
+			if lease.Token != l.token || !lease.HeldAt(time.Now()) {
+				return fmt.Errorf("%w: %s is at token %d, this holder has %d", repository.ErrLeaseLost, l.name, lease.Token, l.token)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", repository.ErrLeaseLost, l.name)
+}
+
+// Release stops the renewal and frees the lease
+func (l *Lease) Release() {
+	close(l.stop)
+	<-l.done
+	if err := l.store.ReleaseLease(l.name, l.token); err != nil {
+		log.Printf("Failed to release lease %s: %v", l.name, err)
+	}
+}
+
+// LeaseStatus is a lease as reported by the API
+type LeaseStatus struct {
+	domain.Lease
+	Held bool `json:"held"`
+}
+
+// GetLeases reports every lease, whether it is held and by whom since when
+func (s *ProjectService) GetLeases() ([]LeaseStatus, error) {
+	if s.store == nil {
+		return nil, repository.ErrNoStore
+	}
+
+	leases, err := s.store.ListLeases()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	statuses := make([]LeaseStatus, 0, len(leases))
+	for _, lease := range leases {
+		statuses = append(statuses, LeaseStatus{Lease: lease, Held: lease.HeldAt(now)})
+	}
+	return statuses, nil
+}
+
+// IsLeaseHeld reports whether err means another holder has a lease
+func IsLeaseHeld(err error) bool {
+	var held *repository.LeaseHeldError
+	return errors.As(err, &held)
+}
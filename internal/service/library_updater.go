@@ -1,26 +1,38 @@
 package service
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
-	"io"
-	"net/http"
+	"net/url"
 	"os"
-	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
 	"strings"
-	"time"
+	"sync"
 
 	"gitlab-list/internal/configuration"
 	"gitlab-list/internal/domain"
+	"gitlab-list/internal/gitlab"
+	"gitlab-list/internal/gomod"
+	"gitlab-list/internal/goproxy"
+	"gitlab-list/internal/textdiff"
+	"gitlab-list/internal/workspace"
+
+	"golang.org/x/mod/semver"
 )
 
 type LibraryUpdater struct {
-	config        *configuration.Configuration
-	gitlabToken   string
-	gitlabBaseURL string
+	config *configuration.Configuration
+	client *gitlab.Client
+	proxy  *goproxy.Client
 }
 
+// versionLookupWorkers bounds concurrent module proxy lookups per go.mod
+const versionLookupWorkers = 8
+
 type LibraryUpdate struct {
 	ProjectID      int    `json:"project_id"`
 	ProjectName    string `json:"project_name"`
@@ -28,17 +40,21 @@ type LibraryUpdate struct {
 	CurrentVersion string `json:"current_version"`
 	LatestVersion  string `json:"latest_version"`
 	UpdatePath     string `json:"update_path"`
+	ModuleDir      string `json:"module_dir,omitempty"` // go.mod directory, repository root when empty
 }
 
 type ProjectLibrary struct {
-	ProjectID         int      `json:"project_id"`
-	ProjectName       string   `json:"project_name"`
-	LibraryName       string   `json:"library_name"`
-	CurrentVersion    string   `json:"current_version"`
-	LatestVersion     string   `json:"latest_version"`
-	AvailableVersions []string `json:"available_versions,omitempty"`
-	IsUpdatable       bool     `json:"is_updatable"`
-	IsDowngradable    bool     `json:"is_downgradable"`
+	ProjectID         int                 `json:"project_id"`
+	ProjectName       string              `json:"project_name"`
+	LibraryName       string              `json:"library_name"`
+	CurrentVersion    string              `json:"current_version"`
+	LatestVersion     string              `json:"latest_version"`
+	AvailableVersions []string            `json:"available_versions,omitempty"`
+	IsUpdatable       bool                `json:"is_updatable"`
+	IsDowngradable    bool                `json:"is_downgradable"`
+	LookupError       string              `json:"lookup_error,omitempty"` // Why no versions could be found
+	Indirect          bool                `json:"indirect,omitempty"`
+	Replace           *domain.Replacement `json:"replace,omitempty"`
 }
 
 type ProjectLibraryUpdate struct {
@@ -57,43 +73,107 @@ type UpdateResult struct {
 	Error        string          `json:"error,omitempty"`
 	UpdatedFiles []string        `json:"updated_files,omitempty"`
 	Changes      *LibraryChanges `json:"changes,omitempty"`
+	Verification *Verification   `json:"verification,omitempty"`
+	DryRun       bool            `json:"dry_run,omitempty"`       // Nothing was committed or pushed
+	BranchReused bool            `json:"branch_reused,omitempty"` // The update branch existed and was rebuilt and force-pushed
+	Superseded   []MergeRequest  `json:"superseded,omitempty"`    // Older update merge requests closed in favour of this one
 }
 
 type MergeRequest struct {
-	ID          int    `json:"id"`
-	IID         int    `json:"iid"`
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	WebURL      string `json:"web_url"`
-	State       string `json:"state"`
+	ID           int       `json:"id"`
+	IID          int       `json:"iid"`
+	Title        string    `json:"title"`
+	Description  string    `json:"description"`
+	WebURL       string    `json:"web_url"`
+	State        string    `json:"state"`
+	SourceBranch string    `json:"source_branch,omitempty"`
+	HeadPipeline *Pipeline `json:"head_pipeline,omitempty"` // Only returned when a single merge request is read
 }
 
+// Pipeline is the latest pipeline of a merge request
+type Pipeline struct {
+	ID     int    `json:"id"`
+	Status string `json:"status"` // e.g. running, success or failed
+}
+
+// LibraryChanges are the unified diffs of the module files an update changed
 type LibraryChanges struct {
-	GoModChanges string   `json:"go_mod_changes"`
-	GoSumChanges string   `json:"go_sum_changes"`
-	FilesChanged []string `json:"files_changed"`
+	GoModChanges string          `json:"go_mod_changes"`
+	GoSumChanges string          `json:"go_sum_changes"` // Large hunks are trimmed to a summary
+	FilesChanged []string        `json:"files_changed"`
+	Diffs        []textdiff.Diff `json:"diffs,omitempty"`
+}
+
+// goSumHunkLines is the most changed lines of a go.sum hunk shown; larger hunks are summarized
+const goSumHunkLines = 40
+
+// readModuleFiles returns go.mod and go.sum of the module in dir; a missing go.sum is empty
+func readModuleFiles(dir string) (goMod, goSum string, err error) {
+	content, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read go.mod: %w", err)
+	}
+	sum, err := os.ReadFile(filepath.Join(dir, "go.sum"))
+	if err != nil && !os.IsNotExist(err) {
+		return "", "", fmt.Errorf("failed to read go.sum: %w", err)
+	}
+	return string(content), string(sum), nil
 }
 
-func NewLibraryUpdater(config *configuration.Configuration) *LibraryUpdater {
-	gitlabBaseURL := config.GitLabURL
+// moduleChanges diffs go.mod and go.sum of the module in dir against their contents before the update
+func moduleChanges(dir, moduleDir, goModBefore, goSumBefore string) (*LibraryChanges, error) {
+	goModAfter, goSumAfter, err := readModuleFiles(dir)
+	if err != nil {
+		return nil, err
+	}
 
+	changes := &LibraryChanges{FilesChanged: []string{}}
+	goMod := textdiff.Unified(gomod.GoModPath(moduleDir), goModBefore, goModAfter, textdiff.Options{})
+	goSum := textdiff.Unified(path.Join(moduleDir, "go.sum"), goSumBefore, goSumAfter, textdiff.Options{MaxHunkLines: goSumHunkLines})
+	for _, diff := range []textdiff.Diff{goMod, goSum} {
+		if diff.Text != "" {
+			changes.FilesChanged = append(changes.FilesChanged, diff.Path)
+			changes.Diffs = append(changes.Diffs, diff)
+		}
+	}
+	changes.GoModChanges = goMod.Text
+	changes.GoSumChanges = goSum.Text
+	return changes, nil
+}
+
+// NewLibraryUpdater creates a library updater that sends its GitLab requests through client
+func NewLibraryUpdater(config *configuration.Configuration, client *gitlab.Client) *LibraryUpdater {
 	return &LibraryUpdater{
-		config:        config,
-		gitlabToken:   config.Token,
-		gitlabBaseURL: gitlabBaseURL,
+		config: config,
+		client: client,
+		proxy:  goproxy.NewClient(goproxy.SettingsFromConfig(config), client),
 	}
 }
 
-// GetOutdatedLibraries finds libraries that can be updated
-func (lu *LibraryUpdater) GetOutdatedLibraries(projectID int) ([]LibraryUpdate, error) {
+// SetModuleProxy replaces the module proxy client, e.g. to share its cache with other services
+func (lu *LibraryUpdater) SetModuleProxy(proxy *goproxy.Client) {
+	lu.proxy = proxy
+}
+
+// GetOutdatedLibraries finds direct requirements of the module at moduleDir ("" for the root) with a newer version
+func (lu *LibraryUpdater) GetOutdatedLibraries(projectID int, moduleDir, token string) ([]LibraryUpdate, error) {
+	moduleDir, err := cleanModuleDir(moduleDir)
+	if err != nil {
+		return nil, err
+	}
+
 	// Get project details
-	project, err := lu.getProjectDetails(projectID)
+	project, err := lu.getProjectDetailsWithToken(projectID, token)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get project details: %w", err)
 	}
 
-	// Get go.mod content
-	goModContent, err := lu.getFileContent(projectID, "go.mod", "main")
+	// Get go.mod content from the default branch
+	ref := project.DefaultBranch
+	if ref == "" {
+		ref = "main"
+	}
+	goModContent, err := lu.getFileContentWithToken(projectID, gomod.GoModPath(moduleDir), ref, token)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get go.mod: %w", err)
 	}
@@ -104,76 +184,154 @@ func (lu *LibraryUpdater) GetOutdatedLibraries(projectID int) ([]LibraryUpdate,
 		return nil, fmt.Errorf("failed to analyze go.mod: %w", err)
 	}
 
+	for i := range updates {
+		updates[i].ProjectID = projectID
+		updates[i].ModuleDir = moduleDir
+	}
+
 	return updates, nil
 }
 
-// UpdateLibrary updates a specific library and creates a merge request
-func (lu *LibraryUpdater) UpdateLibrary(projectID int, libraryName, targetVersion, token string) (*UpdateResult, error) {
+// UpdateLibrary updates a specific library in the module at moduleDir ("" for the root) and creates a merge request
+func (lu *LibraryUpdater) UpdateLibrary(projectID int, moduleDir, libraryName, targetVersion, token string) (*UpdateResult, error) {
+	return lu.UpdateLibraryContext(context.Background(), projectID, moduleDir, libraryName, targetVersion, token, nil)
+}
+
+// UpdateLibraryContext is UpdateLibrary as a queued job: every step is reported to progress, and a
+// canceled ctx stops the update between steps and kills the running git or go command
+func (lu *LibraryUpdater) UpdateLibraryContext(ctx context.Context, projectID int, moduleDir, libraryName, targetVersion, token string, progress ProgressFunc) (*UpdateResult, error) {
+	moduleDir, err := cleanModuleDir(moduleDir)
+	if err != nil {
+		return nil, err
+	}
+
 	// Get project details
 	project, err := lu.getProjectDetailsWithToken(projectID, token)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get project details: %w", err)
 	}
 
-	// Create a new branch for the update
-	branchName := fmt.Sprintf("update-%s-to-%s", libraryName, targetVersion)
-	branchName = strings.ReplaceAll(branchName, "/", "-")
-	branchName = strings.ReplaceAll(branchName, ".", "-")
+	// The branch name only depends on the update, so repeating it reuses the branch and its merge request
+	branchName := libraryBranchPrefix(moduleDir, libraryName) + branchSafe(targetVersion)
 
-	// Clone the repository (using HTTPS URL with authentication)
-	// Extract domain from gitlabBaseURL (e.g., "https://git.prosoftke.sk" -> "git.prosoftke.sk")
-	gitlabDomain := strings.TrimPrefix(lu.gitlabBaseURL, "https://")
-	gitlabDomain = strings.TrimPrefix(gitlabDomain, "http://")
-	cloneURL := fmt.Sprintf("https://oauth2:%s@%s/%s.git", token, gitlabDomain, project.Path)
-	clonePath, err := lu.cloneRepository(cloneURL, branchName)
+	// Clone, the library, verification, commit and push; the merge request follows the push regardless
+	opts := lu.DefaultUpdateOptions()
+	steps := 4
+	if opts.Verify {
+		steps += len(verifyCommands(opts.Tests))
+	}
+	done := 0
+	step := func(name string) error {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("update stopped before %s: %w", strings.ToLower(name[:1])+name[1:], err)
+		}
+		progress.report(name, project.Path, done, steps)
+		done++
+		return nil
+	}
+
+	// Clone the repository into its own workspace (using the instance URL with token authentication)
+	if err := step("Cloning repository"); err != nil {
+		return nil, err
+	}
+	ws, branchExists, err := lu.openWorkspace(ctx, project.Path, token, branchName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to clone repository: %w", err)
 	}
-	defer os.RemoveAll(clonePath)
+	defer ws.Close()
+
+	modulePath := ws.Path(moduleDir)
+	goModBefore, goSumBefore, err := readModuleFiles(modulePath)
+	if err != nil {
+		return nil, fmt.Errorf("module directory %s: %w", moduleDir, err)
+	}
 
 	// Update the library
-	changes, err := lu.updateLibraryInRepo(clonePath, libraryName, targetVersion)
+	if err := step("Updating " + libraryName); err != nil {
+		return nil, err
+	}
+	changes, err := lu.updateLibraryInRepo(ctx, ws, moduleDir, libraryName, targetVersion)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update library: %w", err)
 	}
 
+	// Verify the module; go mod tidy may change go.mod and go.sum further, so the diff is taken again
+	var verification *Verification
+	if opts.Verify {
+		verification, err = verifyModule(ctx, ws, moduleDir, opts.Tests, step)
+		if err != nil {
+			return nil, err
+		}
+		if changes, err = moduleChanges(modulePath, moduleDir, goModBefore, goSumBefore); err != nil {
+			return nil, err
+		}
+		if opts.RequireGreen && !verification.Passed {
+			return nil, fmt.Errorf("verification failed (%s), nothing was pushed", strings.Join(verification.Failed(), ", "))
+		}
+	}
+
 	// Commit changes
 	commitMessage := fmt.Sprintf("Update %s to %s", libraryName, targetVersion)
-	if err := lu.commitChanges(clonePath, commitMessage); err != nil {
+	if moduleDir != gomod.RootDir {
+		commitMessage += " in " + moduleDir
+	}
+	if err := step("Committing changes"); err != nil {
+		return nil, err
+	}
+	if err := lu.commitChanges(ctx, ws, commitMessage); err != nil {
 		return nil, fmt.Errorf("failed to commit changes: %w", err)
 	}
 
 	// Push changes
-	if err := lu.pushChanges(clonePath, branchName); err != nil {
+	if err := step("Pushing " + branchName); err != nil {
+		return nil, err
+	}
+	if err := lu.pushChanges(ctx, ws, branchName, branchExists); err != nil {
 		return nil, fmt.Errorf("failed to push changes: %w", err)
 	}
 
-	// Create merge request
+	// Create merge request, or update the open one of the branch
 	targetBranch := project.DefaultBranch
 	if targetBranch == "" {
 		targetBranch = "main" // fallback if default branch is not set
 	}
-	mr, err := lu.createMergeRequest(projectID, branchName, libraryName, targetVersion, changes, token, targetBranch)
+	progress.report("Creating merge request", project.Path, done, steps)
+	marker := updateMarker{ModuleDir: moduleDir, Libraries: map[string]string{libraryName: targetVersion}}
+	mr, reused, err := lu.createMergeRequest(projectID, branchName, moduleDir, libraryName, targetVersion, changes, verification, marker, token, targetBranch)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create merge request: %w", err)
 	}
+	superseded := lu.supersedeMergeRequests(projectID, mr, targetBranch, marker, token)
 
 	return &UpdateResult{
 		ProjectID:    projectID,
 		ProjectName:  project.Name,
 		Success:      true,
-		Message:      fmt.Sprintf("Successfully updated %s to %s", libraryName, targetVersion),
+		Message:      updateMessage(fmt.Sprintf("Successfully updated %s to %s", libraryName, targetVersion), mr, reused, superseded),
 		MergeRequest: mr,
 		Changes:      changes,
+		Verification: verification,
+		BranchReused: branchExists,
+		Superseded:   superseded,
 	}, nil
 }
 
 // BatchUpdateLibraries updates multiple libraries in a single project
 func (lu *LibraryUpdater) BatchUpdateLibraries(projectID int, updates []LibraryUpdate, token string) ([]UpdateResult, error) {
+	return lu.BatchUpdateLibrariesContext(context.Background(), projectID, updates, token, nil)
+}
+
+// BatchUpdateLibrariesContext is BatchUpdateLibraries as a queued job: progress counts the finished
+// libraries, and a canceled ctx stops the running update and skips the rest
+func (lu *LibraryUpdater) BatchUpdateLibrariesContext(ctx context.Context, projectID int, updates []LibraryUpdate, token string, progress ProgressFunc) ([]UpdateResult, error) {
 	var results []UpdateResult
 
-	for _, update := range updates {
-		result, err := lu.UpdateLibrary(projectID, update.LibraryName, update.LatestVersion, token)
+	for i, update := range updates {
+		if err := ctx.Err(); err != nil {
+			return results, fmt.Errorf("batch update stopped before %s: %w", update.LibraryName, err)
+		}
+		progress.report("Updating "+update.LibraryName, update.ProjectName, i, len(updates))
+		result, err := lu.UpdateLibraryContext(ctx, projectID, update.ModuleDir, update.LibraryName, update.LatestVersion, token, nil)
 		if err != nil {
 			results = append(results, UpdateResult{
 				ProjectID:   projectID,
@@ -189,8 +347,14 @@ func (lu *LibraryUpdater) BatchUpdateLibraries(projectID int, updates []LibraryU
 	return results, nil
 }
 
-// GetProjectLibraries gets all libraries in a project with their versions
-func (lu *LibraryUpdater) GetProjectLibraries(projectID int, token string) ([]ProjectLibrary, error) {
+// GetProjectLibraries gets all libraries of the module at moduleDir ("" for the root) with their versions
+func (lu *LibraryUpdater) GetProjectLibraries(projectID int, moduleDir, token string) ([]ProjectLibrary, error) {
+	moduleDir, err := cleanModuleDir(moduleDir)
+	if err != nil {
+		return nil, err
+	}
+	goModPath := gomod.GoModPath(moduleDir)
+
 	// Get project details
 	project, err := lu.getProjectDetailsWithToken(projectID, token)
 	if err != nil {
@@ -203,19 +367,19 @@ func (lu *LibraryUpdater) GetProjectLibraries(projectID int, token string) ([]Pr
 	var goModErr error
 
 	for _, branch := range branches {
-		goModContent, goModErr = lu.getFileContentWithToken(projectID, "go.mod", branch, token)
+		goModContent, goModErr = lu.getFileContentWithToken(projectID, goModPath, branch, token)
 		if goModErr == nil {
 			break // Found go.mod file
 		}
 		// If it's not a 404, return the error immediately
-		if !strings.Contains(goModErr.Error(), "HTTP 404") {
+		if !gitlab.IsNotFound(goModErr) {
 			return nil, fmt.Errorf("failed to get go.mod: %w", goModErr)
 		}
 	}
 
 	if goModErr != nil {
 		// All branches failed with 404
-		return nil, fmt.Errorf("project does not have a go.mod file in any branch (main, master, develop, dev) - this project is not a Go module")
+		return nil, fmt.Errorf("project does not have %s in any branch (main, master, develop, dev) - this is not a Go module", goModPath)
 	}
 
 	// Parse go.mod and extract libraries
@@ -223,51 +387,102 @@ func (lu *LibraryUpdater) GetProjectLibraries(projectID int, token string) ([]Pr
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse go.mod: %w", err)
 	}
+	for i := range libraries {
+		libraries[i].ProjectID = projectID
+	}
 
 	return libraries, nil
 }
 
-// UpdateProjectLibraries updates multiple libraries in a project with custom versions
-func (lu *LibraryUpdater) UpdateProjectLibraries(projectID int, updates []ProjectLibraryUpdate, goVersion string, branchName string, token string) ([]UpdateResult, error) {
+// UpdateProjectLibraries updates multiple libraries of the module at moduleDir ("" for the root) with custom versions
+func (lu *LibraryUpdater) UpdateProjectLibraries(projectID int, moduleDir string, updates []ProjectLibraryUpdate, goVersion string, branchName string, token string) ([]UpdateResult, error) {
+	return lu.UpdateProjectLibrariesContext(context.Background(), projectID, moduleDir, updates, goVersion, branchName, token, lu.DefaultUpdateOptions(), nil)
+}
+
+// UpdateProjectLibrariesContext is UpdateProjectLibraries as a queued job: every step is reported to
+// progress, and a canceled ctx stops the update between steps. Once the branch is pushed the merge
+// request is created regardless, so no branch is left without one.
+//
+// With opts.Verify the changed module is checked with go mod tidy, go build, go vet and, with
+// opts.Tests, go test; the results go into the merge request, and with opts.RequireGreen a failure
+// stops the update before the commit. A dry run always verifies and reports without pushing.
+func (lu *LibraryUpdater) UpdateProjectLibrariesContext(ctx context.Context, projectID int, moduleDir string, updates []ProjectLibraryUpdate, goVersion string, branchName string, token string, opts UpdateOptions, progress ProgressFunc) ([]UpdateResult, error) {
 	var results []UpdateResult
 
+	moduleDir, err := cleanModuleDir(moduleDir)
+	if err != nil {
+		return nil, err
+	}
+
 	// Get project details
 	project, err := lu.getProjectDetailsWithToken(projectID, token)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get project details: %w", err)
 	}
 
-	// Use provided branch name or generate one
+	// Use provided branch name or derive one from the updates, so the same updates reuse their branch
 	if branchName == "" {
-		branchName = fmt.Sprintf("update-libraries-%d", time.Now().Unix())
+		branchName = batchBranchName(moduleDir, updates, goVersion)
 	}
 
-	// Clone the repository (using HTTPS URL with authentication)
-	// Extract domain from gitlabBaseURL (e.g., "https://git.prosoftke.sk" -> "git.prosoftke.sk")
-	gitlabDomain := strings.TrimPrefix(lu.gitlabBaseURL, "https://")
-	gitlabDomain = strings.TrimPrefix(gitlabDomain, "http://")
-	cloneURL := fmt.Sprintf("https://oauth2:%s@%s/%s.git", token, gitlabDomain, project.Path)
-	clonePath, err := lu.cloneRepository(cloneURL, branchName)
+	// Clone, the Go version, each library, verification, commit, push and the merge request
+	opts.Verify = opts.Verify || opts.DryRun
+	steps := len(updates) + 4
+	if goVersion != "" {
+		steps++
+	}
+	if opts.Verify {
+		steps += len(verifyCommands(opts.Tests))
+	}
+	if opts.DryRun {
+		steps = steps - 3
+	}
+	done := 0
+	step := func(name string) error {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("update stopped before %s: %w", strings.ToLower(name[:1])+name[1:], err)
+		}
+		progress.report(name, project.Path, done, steps)
+		done++
+		return nil
+	}
+
+	// Clone the repository (using the instance URL with token authentication)
+	if err := step("Cloning repository"); err != nil {
+		return nil, err
+	}
+	ws, branchExists, err := lu.openWorkspace(ctx, project.Path, token, branchName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to clone repository: %w", err)
 	}
-	defer os.RemoveAll(clonePath)
+	defer ws.Close()
+
+	// The merge request shows the module files as they were before any change
+	modulePath := ws.Path(moduleDir)
+	goModBefore, goSumBefore, err := readModuleFiles(modulePath)
+	if err != nil {
+		return nil, fmt.Errorf("module directory %s: %w", moduleDir, err)
+	}
 
 	// Update Go version if specified
 	if goVersion != "" {
-		if err := lu.updateGoVersionInRepo(clonePath, goVersion); err != nil {
+		if err := step("Updating Go version"); err != nil {
+			return nil, err
+		}
+		if err := lu.updateGoVersionInRepo(ctx, ws, moduleDir, goVersion); err != nil {
 			return nil, fmt.Errorf("failed to update Go version: %w", err)
 		}
 	}
 
 	// Update each library
-	var allChanges []string
-	var goModChanges, goSumChanges string
-
+	var applied []ProjectLibraryUpdate
 	for _, update := range updates {
+		if err := step("Updating " + update.LibraryName); err != nil {
+			return nil, err
+		}
+
 		// Update the library using go get
-		changes, err := lu.updateLibraryInRepo(clonePath, update.LibraryName, update.TargetVersion)
-		if err != nil {
+		if _, err := lu.updateLibraryInRepo(ctx, ws, moduleDir, update.LibraryName, update.TargetVersion); err != nil {
 			results = append(results, UpdateResult{
 				ProjectID:   projectID,
 				ProjectName: project.Name,
@@ -276,55 +491,115 @@ func (lu *LibraryUpdater) UpdateProjectLibraries(projectID int, updates []Projec
 			})
 			continue
 		}
+		applied = append(applied, update)
+	}
 
-		allChanges = append(allChanges, changes.FilesChanged...)
-		goModChanges += changes.GoModChanges + "\n"
-		goSumChanges += changes.GoSumChanges + "\n"
+	// Nothing changed: report the libraries that failed instead of an empty commit
+	if len(applied) == 0 && goVersion == "" {
+		return results, nil
+	}
+
+	// Check that the module still builds; go mod tidy may change the module files further
+	var verification *Verification
+	if opts.Verify {
+		verification, err = verifyModule(ctx, ws, moduleDir, opts.Tests, step)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// One diff per file over all updates
+	combinedChanges, err := moduleChanges(modulePath, moduleDir, goModBefore, goSumBefore)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.DryRun {
+		message := "Dry run: verification passed, nothing was pushed"
+		if !verification.Passed {
+			message = fmt.Sprintf("Dry run: %s failed, nothing was pushed", strings.Join(verification.Failed(), ", "))
+		}
+		results = append(results, UpdateResult{
+			ProjectID:    projectID,
+			ProjectName:  project.Name,
+			Success:      verification.Passed,
+			Message:      message,
+			Changes:      combinedChanges,
+			Verification: verification,
+			DryRun:       true,
+		})
+		return results, nil
+	}
+	if opts.RequireGreen && verification != nil && !verification.Passed {
+		results = append(results, UpdateResult{
+			ProjectID:    projectID,
+			ProjectName:  project.Name,
+			Success:      false,
+			Error:        fmt.Sprintf("Verification failed (%s), nothing was pushed", strings.Join(verification.Failed(), ", ")),
+			Changes:      combinedChanges,
+			Verification: verification,
+		})
+		return results, nil
 	}
 
 	// Commit all changes
 	var commitMessage string
-	if goVersion != "" && len(updates) > 0 {
-		commitMessage = fmt.Sprintf("Update Go version to %s and %d libraries", goVersion, len(updates))
+	if goVersion != "" && len(applied) > 0 {
+		commitMessage = fmt.Sprintf("Update Go version to %s and %d libraries", goVersion, len(applied))
 	} else if goVersion != "" {
 		commitMessage = fmt.Sprintf("Update Go version to %s", goVersion)
 	} else {
-		commitMessage = fmt.Sprintf("Update %d libraries", len(updates))
+		commitMessage = fmt.Sprintf("Update %d libraries", len(applied))
+	}
+	if moduleDir != gomod.RootDir {
+		commitMessage += " in " + moduleDir
 	}
 
-	if err := lu.commitChanges(clonePath, commitMessage); err != nil {
+	if err := step("Committing changes"); err != nil {
+		return nil, err
+	}
+	if err := lu.commitChanges(ctx, ws, commitMessage); err != nil {
 		return nil, fmt.Errorf("failed to commit changes: %w", err)
 	}
 
 	// Push changes
-	if err := lu.pushChanges(clonePath, branchName); err != nil {
-		return nil, fmt.Errorf("failed to push changes: %w", err)
+	if err := step("Pushing " + branchName); err != nil {
+		return nil, err
 	}
-
-	// Create merge request
-	combinedChanges := &LibraryChanges{
-		GoModChanges: goModChanges,
-		GoSumChanges: goSumChanges,
-		FilesChanged: allChanges,
+	if err := lu.pushChanges(ctx, ws, branchName, branchExists); err != nil {
+		return nil, fmt.Errorf("failed to push changes: %w", err)
 	}
 
+	// Create merge request, or update the open one of the branch
 	targetBranch := project.DefaultBranch
 	if targetBranch == "" {
 		targetBranch = "main" // fallback if default branch is not set
 	}
-	mr, err := lu.createBatchMergeRequest(projectID, branchName, updates, goVersion, combinedChanges, token, targetBranch)
+	progress.report("Creating merge request", project.Path, done, steps)
+	// Only the updates that applied count in the merge request and when older ones are superseded
+	marker := updateMarker{ModuleDir: moduleDir, Libraries: make(map[string]string, len(applied)), GoVersion: goVersion}
+	for _, update := range applied {
+		marker.Libraries[update.LibraryName] = update.TargetVersion
+	}
+	mr, reused, err := lu.createBatchMergeRequest(projectID, branchName, moduleDir, applied, goVersion, combinedChanges, verification, marker, token, targetBranch)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create merge request: %w", err)
 	}
 
+	// Older update merge requests changing nothing beyond this one are closed
+	superseded := lu.supersedeMergeRequests(projectID, mr, targetBranch, marker, token)
+
 	// Return success result
 	results = append(results, UpdateResult{
 		ProjectID:    projectID,
 		ProjectName:  project.Name,
 		Success:      true,
-		Message:      fmt.Sprintf("Successfully updated %d libraries", len(updates)),
+		Message:      updateMessage(fmt.Sprintf("Successfully updated %d libraries", len(applied)), mr, reused, superseded),
 		MergeRequest: mr,
 		Changes:      combinedChanges,
+		Verification: verification,
+		BranchReused: branchExists,
+		Superseded:   superseded,
 	})
 
 	return results, nil
@@ -333,58 +608,13 @@ func (lu *LibraryUpdater) UpdateProjectLibraries(projectID int, updates []Projec
 // Helper methods
 
 func (lu *LibraryUpdater) getProjectDetails(projectID int) (*domain.Project, error) {
-	url := fmt.Sprintf("%s/api/v4/projects/%d", lu.gitlabBaseURL, projectID)
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Set("Authorization", "Bearer "+lu.gitlabToken)
-
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to get project: HTTP %d", resp.StatusCode)
-	}
-
-	var project domain.Project
-	if err := json.NewDecoder(resp.Body).Decode(&project); err != nil {
-		return nil, err
-	}
-
-	return &project, nil
+	return lu.getProjectDetailsWithToken(projectID, lu.client.Token())
 }
 
 // getProjectDetailsWithToken gets project details using a specific token
 func (lu *LibraryUpdater) getProjectDetailsWithToken(projectID int, token string) (*domain.Project, error) {
-	url := fmt.Sprintf("%s/api/v4/projects/%d", lu.gitlabBaseURL, projectID)
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Set("Authorization", "Bearer "+token)
-
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to get project: HTTP %d", resp.StatusCode)
-	}
-
 	var project domain.Project
-	if err := json.NewDecoder(resp.Body).Decode(&project); err != nil {
+	if err := lu.client.WithToken(token).GetJSON(fmt.Sprintf("/projects/%d", projectID), &project); err != nil {
 		return nil, err
 	}
 
@@ -392,122 +622,158 @@ func (lu *LibraryUpdater) getProjectDetailsWithToken(projectID int, token string
 }
 
 func (lu *LibraryUpdater) getFileContent(projectID int, filePath, ref string) (string, error) {
-	url := fmt.Sprintf("%s/api/v4/projects/%d/repository/files/%s/raw",
-		lu.gitlabBaseURL, projectID, strings.ReplaceAll(filePath, "/", "%2F"))
+	return lu.getFileContentWithToken(projectID, filePath, ref, lu.client.Token())
+}
 
-	req, err := http.NewRequest("GET", url, nil)
+// getFileContentWithToken gets file content using a specific token
+func (lu *LibraryUpdater) getFileContentWithToken(projectID int, filePath, ref, token string) (string, error) {
+	content, err := lu.client.WithToken(token).GetRawFile(projectID, filePath, ref)
 	if err != nil {
 		return "", err
 	}
 
-	req.Header.Set("Authorization", "Bearer "+lu.gitlabToken)
-	q := req.URL.Query()
-	q.Add("ref", ref)
-	req.URL.RawQuery = q.Encode()
+	return string(content), nil
+}
 
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
+// cloneURL builds an authenticated HTTP(S) clone URL for a project on the configured GitLab instance
+func (lu *LibraryUpdater) cloneURL(projectPath, token string) string {
+	u, err := url.Parse(lu.client.BaseURL())
+	if err != nil || u.Host == "" {
+		return fmt.Sprintf("https://oauth2:%s@%s/%s.git", token, lu.client.Host(), projectPath)
 	}
-	defer resp.Body.Close()
+	u.User = url.UserPassword("oauth2", token)
+	u.Path = strings.TrimRight(u.Path, "/") + "/" + projectPath + ".git"
+	return u.String()
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("failed to get file: HTTP %d", resp.StatusCode)
+// cleanModuleDir validates a module directory from a request; it must stay inside the repository
+func cleanModuleDir(moduleDir string) (string, error) {
+	if strings.Contains(moduleDir, "\\") || strings.HasPrefix(moduleDir, "/") {
+		return "", fmt.Errorf("invalid module directory %q", moduleDir)
 	}
-
-	content, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
+	for _, elem := range strings.Split(moduleDir, "/") {
+		if elem == ".." {
+			return "", fmt.Errorf("invalid module directory %q", moduleDir)
+		}
 	}
-
-	return string(content), nil
+	return gomod.CleanDir(moduleDir), nil
 }
 
-// getFileContentWithToken gets file content using a specific token
-func (lu *LibraryUpdater) getFileContentWithToken(projectID int, filePath, ref, token string) (string, error) {
-	url := fmt.Sprintf("%s/api/v4/projects/%d/repository/files/%s/raw",
-		lu.gitlabBaseURL, projectID, strings.ReplaceAll(filePath, "/", "%2F"))
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return "", err
-	}
-
-	req.Header.Set("Authorization", "Bearer "+token)
-	q := req.URL.Query()
-	q.Add("ref", ref)
-	req.URL.RawQuery = q.Encode()
-
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
+func (lu *LibraryUpdater) analyzeGoMod(goModContent, projectName string) ([]LibraryUpdate, error) {
+	file, err := gomod.Parse("go.mod", []byte(goModContent))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("failed to get file: HTTP %d", resp.StatusCode)
+	// Only direct requirements are worth a merge request; indirect ones follow them
+	var paths []string
+	for _, req := range file.Requires {
+		if !req.Indirect {
+			paths = append(paths, req.Path)
+		}
 	}
+	lookups := lu.lookupVersions(paths)
 
-	content, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
+	var updates []LibraryUpdate
+	for _, req := range file.Requires {
+		if req.Indirect {
+			continue
+		}
+		lookup := lookups[req.Path]
+		if lookup.err != nil || lookup.latest == "" || semver.Compare(lookup.latest, req.Version) <= 0 {
+			continue
+		}
+		updates = append(updates, LibraryUpdate{
+			ProjectName:    projectName,
+			LibraryName:    req.Path,
+			CurrentVersion: req.Version,
+			LatestVersion:  lookup.latest,
+			UpdatePath:     req.Path + "@" + lookup.latest,
+		})
 	}
 
-	return string(content), nil
+	return updates, nil
 }
 
-func (lu *LibraryUpdater) analyzeGoMod(goModContent, projectName string) ([]LibraryUpdate, error) {
-	// This is a simplified analysis - in a real implementation, you'd want to:
-	// 1. Parse the go.mod file properly
-	// 2. Check for available updates using go list -m -u all
-	// 3. Query Go module proxy for latest versions
+// versionLookup is the module proxy's answer for one library
+type versionLookup struct {
+	latest   string
+	versions []string
+	err      error
+}
 
-	var updates []LibraryUpdate
+// lookupVersions queries the module proxy for the latest and available versions of each module path
+func (lu *LibraryUpdater) lookupVersions(paths []string) map[string]versionLookup {
+	results := make(map[string]versionLookup, len(paths))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, versionLookupWorkers)
+
+	for _, modPath := range paths {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(modPath string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			var lookup versionLookup
+			lookup.versions, lookup.err = lu.proxy.Versions(modPath)
+			if lookup.err == nil {
+				if info, err := lu.proxy.Latest(modPath); err == nil {
+					lookup.latest = info.Version
+				} else {
+					lookup.latest = goproxy.LatestOf(lookup.versions)
+				}
+			}
 
-	// For now, return a mock implementation
-	// In reality, you'd parse the go.mod and check for updates
-	lines := strings.Split(goModContent, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "require ") && !strings.Contains(line, "// indirect") {
-			// Parse require line and check for updates
-			// This is where you'd implement the actual update detection
-		}
+			mu.Lock()
+			results[modPath] = lookup
+			mu.Unlock()
+		}(modPath)
 	}
+	wg.Wait()
 
-	return updates, nil
+	return results
 }
 
-func (lu *LibraryUpdater) cloneRepository(repoURL, branchName string) (string, error) {
-	// Create temporary directory
-	tempDir, err := os.MkdirTemp("", "gitlab-update-*")
+// openWorkspace clones a project into a new workspace and checks out branchName from the default
+// branch; the caller closes it. exists reports whether the branch is already on the remote, in which
+// case the update is rebuilt on the current default branch and force-pushed over it.
+func (lu *LibraryUpdater) openWorkspace(ctx context.Context, projectPath, token, branchName string) (ws *workspace.Workspace, exists bool, err error) {
+	ws, err = workspace.New(workspace.Options{
+		CommandTimeout: jobTimeout(lu.config.UpdateCommandTimeout),
+		GoFlags:        lu.config.UpdateGoFlags,
+		Secrets:        []string{token},
+	})
 	if err != nil {
-		return "", err
+		return nil, false, err
 	}
 
-	// Clone repository
-	cmd := exec.Command("git", "clone", repoURL, tempDir)
-	if err := cmd.Run(); err != nil {
-		os.RemoveAll(tempDir)
-		return "", err
+	if err := run(ctx, ws, "", "git", "clone", lu.cloneURL(projectPath, token), "."); err != nil {
+		ws.Close()
+		return nil, false, err
 	}
-
-	// Create new branch
-	cmd = exec.Command("git", "checkout", "-b", branchName)
-	cmd.Dir = tempDir
-	if err := cmd.Run(); err != nil {
-		os.RemoveAll(tempDir)
-		return "", err
+	_, err = ws.Run(ctx, "", "git", "rev-parse", "--verify", "--quiet", "refs/remotes/origin/"+branchName)
+	exists = err == nil
+	if err := run(ctx, ws, "", "git", "checkout", "-b", branchName); err != nil {
+		ws.Close()
+		return nil, false, err
 	}
+	return ws, exists, nil
+}
 
-	return tempDir, nil
+// run runs a command in dir of the workspace; a failure carries the command output
+func run(ctx context.Context, ws *workspace.Workspace, dir, name string, args ...string) error {
+	output, err := ws.Run(ctx, dir, name, args...)
+	if err != nil && output != "" {
+		return fmt.Errorf("%w: %s", err, output)
+	}
+	return err
 }
 
-func (lu *LibraryUpdater) updateGoVersionInRepo(repoPath, goVersion string) error {
+func (lu *LibraryUpdater) updateGoVersionInRepo(ctx context.Context, ws *workspace.Workspace, moduleDir, goVersion string) error {
 	// Read go.mod file
-	goModPath := fmt.Sprintf("%s/go.mod", repoPath)
+	goModPath := filepath.Join(ws.Path(moduleDir), "go.mod")
 	content, err := os.ReadFile(goModPath)
 	if err != nil {
 		return fmt.Errorf("failed to read go.mod: %w", err)
@@ -539,216 +805,153 @@ func (lu *LibraryUpdater) updateGoVersionInRepo(repoPath, goVersion string) erro
 	}
 
 	// Run go mod tidy to update dependencies for the new Go version
-	cmd := exec.Command("go", "mod", "tidy")
-	cmd.Dir = repoPath
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("failed to run go mod tidy: %s", string(output))
+	if err := run(ctx, ws, moduleDir, "go", "mod", "tidy"); err != nil {
+		return fmt.Errorf("failed to run go mod tidy: %w", err)
 	}
 
 	return nil
 }
 
-func (lu *LibraryUpdater) updateLibraryInRepo(repoPath, libraryName, targetVersion string) (*LibraryChanges, error) {
-	// Change to repository directory
-	originalDir, err := os.Getwd()
+func (lu *LibraryUpdater) updateLibraryInRepo(ctx context.Context, ws *workspace.Workspace, moduleDir, libraryName, targetVersion string) (*LibraryChanges, error) {
+	// Get current go.mod and go.sum content
+	modulePath := ws.Path(moduleDir)
+	goModBefore, goSumBefore, err := readModuleFiles(modulePath)
 	if err != nil {
-		return nil, err
-	}
-	defer os.Chdir(originalDir)
-
-	if err := os.Chdir(repoPath); err != nil {
-		return nil, err
-	}
-
-	// Get current go.mod content
-	goModContent, err := os.ReadFile("go.mod")
-	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("module directory %s: %w", moduleDir, err)
 	}
 
 	// Update the library using go get
-	cmd := exec.Command("go", "get", fmt.Sprintf("%s@%s", libraryName, targetVersion))
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return nil, fmt.Errorf("failed to update library: %s", string(output))
+	if err := run(ctx, ws, moduleDir, "go", "get", fmt.Sprintf("%s@%s", libraryName, targetVersion)); err != nil {
+		return nil, fmt.Errorf("failed to update library: %w", err)
 	}
 
-	// Get updated go.mod content
-	updatedGoModContent, err := os.ReadFile("go.mod")
-	if err != nil {
-		return nil, err
+	return moduleChanges(modulePath, moduleDir, goModBefore, goSumBefore)
+}
+
+func (lu *LibraryUpdater) commitChanges(ctx context.Context, ws *workspace.Workspace, message string) error {
+	if err := run(ctx, ws, "", "git", "add", "."); err != nil {
+		return err
 	}
+	return run(ctx, ws, "", "git", "commit", "-m", message)
+}
 
-	// Get updated go.sum content
-	goSumContent, err := os.ReadFile("go.sum")
-	if err != nil {
-		// go.sum might not exist yet
-		goSumContent = []byte("")
+// pushChanges pushes the branch; an existing remote branch is replaced unless it changed since the clone
+func (lu *LibraryUpdater) pushChanges(ctx context.Context, ws *workspace.Workspace, branchName string, replace bool) error {
+	if replace {
+		return run(ctx, ws, "", "git", "push", "--force-with-lease="+branchName, "origin", branchName)
 	}
+	return run(ctx, ws, "", "git", "push", "origin", branchName)
+}
 
-	// Calculate changes
-	goModChanges := lu.calculateDiff(string(goModContent), string(updatedGoModContent))
-	goSumChanges := ""
+// branchSafe replaces the characters update branch names avoid
+func branchSafe(name string) string {
+	return strings.NewReplacer("/", "-", ".", "-").Replace(name)
+}
 
-	// Check if go.sum was updated
-	if len(goSumContent) > 0 {
-		originalGoSum, _ := os.ReadFile("go.sum")
-		goSumChanges = lu.calculateDiff(string(originalGoSum), string(goSumContent))
+// libraryBranchPrefix is the start of the branch names of single library updates, before the version
+func libraryBranchPrefix(moduleDir, libraryName string) string {
+	if moduleDir != gomod.RootDir {
+		return branchSafe(fmt.Sprintf("update-%s-%s-to-", moduleDir, libraryName))
 	}
-
-	return &LibraryChanges{
-		GoModChanges: goModChanges,
-		GoSumChanges: goSumChanges,
-		FilesChanged: []string{"go.mod", "go.sum"},
-	}, nil
+	return branchSafe(fmt.Sprintf("update-%s-to-", libraryName))
 }
 
-func (lu *LibraryUpdater) commitChanges(repoPath, message string) error {
-	cmd := exec.Command("git", "add", ".")
-	cmd.Dir = repoPath
-	if err := cmd.Run(); err != nil {
-		return err
+// batchBranchName names the branch of a project update after its module and a hash of its updates
+func batchBranchName(moduleDir string, updates []ProjectLibraryUpdate, goVersion string) string {
+	keys := make([]string, 0, len(updates)+1)
+	for _, update := range updates {
+		keys = append(keys, update.LibraryName+"@"+update.TargetVersion)
+	}
+	sort.Strings(keys)
+	if goVersion != "" {
+		keys = append(keys, "go@"+goVersion)
 	}
+	sum := sha256.Sum256([]byte(strings.Join(keys, "\n")))
 
-	cmd = exec.Command("git", "commit", "-m", message)
-	cmd.Dir = repoPath
-	return cmd.Run()
+	name := "update-libraries-"
+	if moduleDir != gomod.RootDir {
+		name += branchSafe(moduleDir) + "-"
+	}
+	return name + hex.EncodeToString(sum[:4])
 }
 
-func (lu *LibraryUpdater) pushChanges(repoPath, branchName string) error {
-	cmd := exec.Command("git", "push", "origin", branchName)
-	cmd.Dir = repoPath
-	return cmd.Run()
+// updateMessage adds the reused and superseded merge requests to the message of an update
+func updateMessage(message string, mr *MergeRequest, reused bool, superseded []MergeRequest) string {
+	if reused {
+		message += fmt.Sprintf(", updated the open merge request !%d", mr.IID)
+	}
+	if len(superseded) > 0 {
+		iids := make([]string, len(superseded))
+		for i, old := range superseded {
+			iids[i] = fmt.Sprintf("!%d", old.IID)
+		}
+		message += ", superseded " + strings.Join(iids, ", ")
+	}
+	return message
 }
 
-func (lu *LibraryUpdater) createMergeRequest(projectID int, branchName, libraryName, targetVersion string, changes *LibraryChanges, token, targetBranch string) (*MergeRequest, error) {
+func (lu *LibraryUpdater) createMergeRequest(projectID int, branchName, moduleDir, libraryName, targetVersion string, changes *LibraryChanges, verification *Verification, marker updateMarker, token, targetBranch string) (*MergeRequest, bool, error) {
 	title := fmt.Sprintf("Update %s to %s", libraryName, targetVersion)
+	if moduleDir != gomod.RootDir {
+		title += " in " + moduleDir
+	}
 	description := fmt.Sprintf(`
 ## Library Update
 
+**Module:** %s
 **Library:** %s
 **Version:** %s
-
-### Changes
-- Updated go.mod
-- Updated go.sum
-
-### Files Changed
-%s
-
-### Go.mod Changes
-`+"```"+`diff
+%s%s
 %s
-`+"```"+`
-
-### Go.sum Changes
-`+"```"+`diff
-%s
-`+"```"+`
-`, libraryName, targetVersion, strings.Join(changes.FilesChanged, ", "), changes.GoModChanges, changes.GoSumChanges)
-
-	url := fmt.Sprintf("%s/api/v4/projects/%d/merge_requests", lu.gitlabBaseURL, projectID)
-
-	data := map[string]interface{}{
-		"source_branch": branchName,
-		"target_branch": targetBranch,
-		"title":         title,
-		"description":   description,
-	}
-
-	jsonData, err := json.Marshal(data)
-	if err != nil {
-		return nil, err
-	}
+`, moduleDir, libraryName, targetVersion, changesMarkdown(changes), verificationMarkdown(verification), marker)
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Set("Authorization", "Bearer "+token)
-	req.Header.Set("Content-Type", "application/json")
+	return lu.submitMergeRequest(projectID, branchName, targetBranch, title, description, token)
+}
 
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
+func (lu *LibraryUpdater) parseGoModLibraries(goModContent, projectName string) ([]ProjectLibrary, error) {
+	file, err := gomod.Parse("go.mod", []byte(goModContent))
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusCreated {
-		return nil, fmt.Errorf("failed to create merge request: HTTP %d", resp.StatusCode)
-	}
 
-	var mr MergeRequest
-	if err := json.NewDecoder(resp.Body).Decode(&mr); err != nil {
-		return nil, err
+	paths := make([]string, 0, len(file.Requires))
+	for _, req := range file.Requires {
+		paths = append(paths, req.Path)
 	}
+	lookups := lu.lookupVersions(paths)
 
-	return &mr, nil
-}
-
-func (lu *LibraryUpdater) calculateDiff(original, updated string) string {
-	// Simple diff implementation - in production, use a proper diff library
-	if original == updated {
-		return ""
-	}
-
-	// This is a simplified diff - you'd want to use a proper diff algorithm
-	return fmt.Sprintf("Updated content (simplified diff)\n- %s\n+ %s", original, updated)
-}
-
-func (lu *LibraryUpdater) parseGoModLibraries(goModContent, projectName string) ([]ProjectLibrary, error) {
 	var libraries []ProjectLibrary
-
-	lines := strings.Split(goModContent, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-
-		// Skip comments and empty lines
-		if strings.HasPrefix(line, "//") || line == "" {
-			continue
+	for _, req := range file.Requires {
+		lookup := lookups[req.Path]
+		library := ProjectLibrary{
+			ProjectID:         0, // Will be set by caller
+			ProjectName:       projectName,
+			LibraryName:       req.Path,
+			CurrentVersion:    req.Version,
+			LatestVersion:     lookup.latest,
+			AvailableVersions: lookup.versions,
+			IsUpdatable:       lookup.latest != "" && semver.Compare(lookup.latest, req.Version) > 0,
+			Indirect:          req.Indirect,
 		}
-
-		// Parse require statements
-		if strings.HasPrefix(line, "require ") {
-			// Extract library name and version
-			parts := strings.Fields(line)
-			if len(parts) >= 3 {
-				libraryName := parts[1]
-				currentVersion := parts[2]
-
-				// Get latest version (simplified - in production, query Go module proxy)
-				latestVersion := lu.getLatestVersion(libraryName)
-
-				// Determine if updatable/downgradable
-				isUpdatable := currentVersion != latestVersion
-				isDowngradable := true // Allow downgrades
-
-				libraries = append(libraries, ProjectLibrary{
-					ProjectID:      0, // Will be set by caller
-					ProjectName:    projectName,
-					LibraryName:    libraryName,
-					CurrentVersion: currentVersion,
-					LatestVersion:  latestVersion,
-					IsUpdatable:    isUpdatable,
-					IsDowngradable: isDowngradable,
-				})
+		if lookup.err != nil {
+			library.LookupError = lookup.err.Error()
+		}
+		for _, v := range lookup.versions {
+			if semver.Compare(v, req.Version) < 0 {
+				library.IsDowngradable = true
+				break
 			}
 		}
+		if rep := file.Replacement(req.Path, req.Version); rep != nil {
+			library.Replace = &domain.Replacement{Path: rep.NewPath, Version: rep.NewVersion, Local: rep.Local()}
+		}
+		libraries = append(libraries, library)
 	}
 
 	return libraries, nil
 }
 
-func (lu *LibraryUpdater) getLatestVersion(libraryName string) string {
-	// Simplified version detection - in production, query Go module proxy
-	// For now, return a mock latest version
-	return "v1.0.0"
-}
-
-func (lu *LibraryUpdater) createBatchMergeRequest(projectID int, branchName string, updates []ProjectLibraryUpdate, goVersion string, changes *LibraryChanges, token, targetBranch string) (*MergeRequest, error) {
+func (lu *LibraryUpdater) createBatchMergeRequest(projectID int, branchName, moduleDir string, updates []ProjectLibraryUpdate, goVersion string, changes *LibraryChanges, verification *Verification, marker updateMarker, token, targetBranch string) (*MergeRequest, bool, error) {
 	// Create title based on what's being updated
 	var title string
 	if goVersion != "" && len(updates) > 0 {
@@ -758,9 +961,15 @@ func (lu *LibraryUpdater) createBatchMergeRequest(projectID int, branchName stri
 	} else {
 		title = fmt.Sprintf("Update %d libraries", len(updates))
 	}
+	if moduleDir != gomod.RootDir {
+		title += " in " + moduleDir
+	}
 
 	// Create detailed description
 	var descriptionParts []string
+	if moduleDir != gomod.RootDir {
+		descriptionParts = append(descriptionParts, fmt.Sprintf("**Module directory:** `%s`\n", moduleDir))
+	}
 
 	// Add Go version update if present
 	if goVersion != "" {
@@ -783,62 +992,30 @@ func (lu *LibraryUpdater) createBatchMergeRequest(projectID int, branchName stri
 %s`, len(updates), updateList.String()))
 	}
 
-	// Add file changes summary (no detailed diffs in description)
-	var changesList []string
-	if goVersion != "" {
-		changesList = append(changesList, "Updated Go version in go.mod")
-	}
-	if len(updates) > 0 {
-		changesList = append(changesList, "Updated library dependencies in go.mod")
-		changesList = append(changesList, "Updated go.sum")
-	}
-
-	descriptionParts = append(descriptionParts, fmt.Sprintf(`
-### Changes Made
-%s
-
-*See the "Changes" tab in GitLab to view detailed diffs.*
-`, strings.Join(changesList, "\n- ")))
+	// Add the diff of every changed file, the verification results and what later updates match on
+	descriptionParts = append(descriptionParts, changesMarkdown(changes)+verificationMarkdown(verification), marker.String())
 
 	description := strings.Join(descriptionParts, "\n")
 
-	url := fmt.Sprintf("%s/api/v4/projects/%d/merge_requests", lu.gitlabBaseURL, projectID)
-
-	data := map[string]interface{}{
-		"source_branch": branchName,
-		"target_branch": targetBranch,
-		"title":         title,
-		"description":   description,
-	}
-
-	jsonData, err := json.Marshal(data)
-	if err != nil {
-		return nil, err
-	}
-
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Set("Authorization", "Bearer "+token)
-	req.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+	return lu.submitMergeRequest(projectID, branchName, targetBranch, title, description, token)
+}
 
-	if resp.StatusCode != http.StatusCreated {
-		return nil, fmt.Errorf("failed to create merge request: HTTP %d", resp.StatusCode)
+// changesMarkdown renders the diff of every changed module file for a merge request description
+func changesMarkdown(changes *LibraryChanges) string {
+	if changes == nil || len(changes.Diffs) == 0 {
+		return "\n### Changes Made\n\nNo module files changed.\n"
 	}
 
-	var mr MergeRequest
-	if err := json.NewDecoder(resp.Body).Decode(&mr); err != nil {
-		return nil, err
+	var b strings.Builder
+	b.WriteString("\n### Changes Made\n")
+	for _, diff := range changes.Diffs {
+		fmt.Fprintf(&b, "\n**%s** (+%d -%d)", diff.Path, diff.Added, diff.Removed)
+		if diff.Trimmed {
+			b.WriteString(", large hunks trimmed; see the \"Changes\" tab for the full diff")
+		}
+		b.WriteString("\n\n```diff\n")
+		b.WriteString(diff.Text)
+		b.WriteString("```\n")
 	}
-
-	return &mr, nil
+	return b.String()
 }
@@ -0,0 +1,253 @@
+// internal/service/merge_request.go
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"gitlab-list/internal/gitlab"
+	"gitlab-list/internal/gomod"
+)
+
+// api returns the GitLab client for token; an empty token uses the configured one
+func (lu *LibraryUpdater) api(token string) *gitlab.Client {
+	if token == "" {
+		return lu.client
+	}
+	return lu.client.WithToken(token)
+}
+
+// GetMergeRequest reads a merge request with its head pipeline
+func (lu *LibraryUpdater) GetMergeRequest(projectID, iid int, token string) (*MergeRequest, error) {
+	var mr MergeRequest
+	path := fmt.Sprintf("/projects/%d/merge_requests/%d", projectID, iid)
+	if err := lu.api(token).GetJSON(path, &mr); err != nil {
+		return nil, fmt.Errorf("failed to get merge request !%d: %w", iid, err)
+	}
+	return &mr, nil
+}
+
+// CloseMergeRequest closes a merge request, leaving note as a comment first when it is not empty
+func (lu *LibraryUpdater) CloseMergeRequest(projectID, iid int, note, token string) (*MergeRequest, error) {
+	client := lu.api(token)
+	if note != "" {
+		path := fmt.Sprintf("/projects/%d/merge_requests/%d/notes", projectID, iid)
+		if err := client.SendJSON(http.MethodPost, path, map[string]string{"body": note}, nil); err != nil {
+			return nil, fmt.Errorf("failed to comment on merge request !%d: %w", iid, err)
+		}
+	}
+
+	var mr MergeRequest
+	path := fmt.Sprintf("/projects/%d/merge_requests/%d", projectID, iid)
+	if err := client.SendJSON(http.MethodPut, path, map[string]string{"state_event": "close"}, &mr); err != nil {
+		return nil, fmt.Errorf("failed to close merge request !%d: %w", iid, err)
+	}
+	return &mr, nil
+}
+
+// RetryPipeline retries the failed jobs of a pipeline
+func (lu *LibraryUpdater) RetryPipeline(projectID, pipelineID int, token string) (*Pipeline, error) {
+	var pipeline Pipeline
+	path := fmt.Sprintf("/projects/%d/pipelines/%d/retry", projectID, pipelineID)
+	if err := lu.api(token).SendJSON(http.MethodPost, path, nil, &pipeline); err != nil {
+		return nil, fmt.Errorf("failed to retry pipeline %d: %w", pipelineID, err)
+	}
+	return &pipeline, nil
+}
+
+// findOpenMergeRequest returns the open merge request of sourceBranch, or nil when there is none
+func (lu *LibraryUpdater) findOpenMergeRequest(projectID int, sourceBranch, token string) (*MergeRequest, error) {
+	var mrs []MergeRequest
+	path := fmt.Sprintf("/projects/%d/merge_requests?state=opened&source_branch=%s", projectID, url.QueryEscape(sourceBranch))
+	if err := lu.api(token).GetJSON(path, &mrs); err != nil {
+		return nil, fmt.Errorf("failed to look up merge requests of %s: %w", sourceBranch, err)
+	}
+	if len(mrs) == 0 {
+		return nil, nil
+	}
+	return &mrs[0], nil
+}
+
+// listOpenMergeRequests lists the open merge requests into targetBranch, following pagination
+func (lu *LibraryUpdater) listOpenMergeRequests(projectID int, targetBranch, token string) ([]MergeRequest, error) {
+	client := lu.api(token)
+	var out []MergeRequest
+	page := 1
+
+	for {
+		path := fmt.Sprintf("/projects/%d/merge_requests?state=opened&target_branch=%s&per_page=100&page=%d",
+			projectID, url.QueryEscape(targetBranch), page)
+		resp, err := client.Get(path)
+		if err != nil {
+			return out, fmt.Errorf("failed to list merge requests: %w", err)
+		}
+
+		var batch []MergeRequest
+		err = json.NewDecoder(resp.Body).Decode(&batch)
+		resp.Body.Close()
+		if err != nil {
+			return out, fmt.Errorf("failed to decode merge requests: %w", err)
+		}
+		out = append(out, batch...)
+
+		next, _ := strconv.Atoi(resp.Header.Get("X-Next-Page"))
+		if next <= page {
+			break
+		}
+		page = next
+	}
+
+	return out, nil
+}
+
+// submitMergeRequest creates the merge request of sourceBranch; when one is already open its title
+// and description are updated instead and reused is true
+func (lu *LibraryUpdater) submitMergeRequest(projectID int, sourceBranch, targetBranch, title, description, token string) (mr *MergeRequest, reused bool, err error) {
+	existing, err := lu.findOpenMergeRequest(projectID, sourceBranch, token)
+	if err != nil {
+		return nil, false, err
+	}
+
+	mr = &MergeRequest{}
+	if existing != nil {
+		data := map[string]interface{}{
+			"title":       title,
+			"description": description,
+		}
+		path := fmt.Sprintf("/projects/%d/merge_requests/%d", projectID, existing.IID)
+		if err := lu.api(token).SendJSON(http.MethodPut, path, data, mr); err != nil {
+			return nil, false, fmt.Errorf("failed to update merge request !%d: %w", existing.IID, err)
+		}
+		return mr, true, nil
+	}
+
+	data := map[string]interface{}{
+		"source_branch": sourceBranch,
+		"target_branch": targetBranch,
+		"title":         title,
+		"description":   description,
+	}
+	path := fmt.Sprintf("/projects/%d/merge_requests", projectID)
+	if err := lu.api(token).SendJSON(http.MethodPost, path, data, mr); err != nil {
+		return nil, false, fmt.Errorf("failed to create merge request: %w", err)
+	}
+	return mr, false, nil
+}
+
+// supersedeMergeRequests closes the other open update merge requests into targetBranch that marker
+// covers, with a comment linking mr. Failures are logged; the update itself already succeeded.
+func (lu *LibraryUpdater) supersedeMergeRequests(projectID int, mr *MergeRequest, targetBranch string, marker updateMarker, token string) []MergeRequest {
+	open, err := lu.listOpenMergeRequests(projectID, targetBranch, token)
+	if err != nil {
+		log.Printf("Project %d: not superseding older merge requests: %v", projectID, err)
+		return nil
+	}
+
+	var superseded []MergeRequest
+	note := fmt.Sprintf("Superseded by !%d (%s).", mr.IID, mr.WebURL)
+	for _, old := range open {
+		if old.IID == mr.IID || old.SourceBranch == mr.SourceBranch {
+			continue
+		}
+		if changed, ok := parseUpdateMarker(old); !ok || !marker.covers(changed) {
+			continue
+		}
+		closed, err := lu.CloseMergeRequest(projectID, old.IID, note, token)
+		if err != nil {
+			log.Printf("Project %d: %v", projectID, err)
+			continue
+		}
+		superseded = append(superseded, *closed)
+	}
+	return superseded
+}
+
+// updateMarkerPrefix starts the hidden comment that describes an update in its merge request
+const updateMarkerPrefix = "<!-- gitlab-list-update "
+
+// updateMarker is what an update merge request changes. It is kept in the description so later
+// updates can tell which open merge requests they replace.
+type updateMarker struct {
+	ModuleDir string            `json:"module_dir"`
+	Libraries map[string]string `json:"libraries,omitempty"` // Library -> target version
+	GoVersion string            `json:"go_version,omitempty"`
+}
+
+// String renders the marker as an HTML comment, invisible in the rendered description
+func (m updateMarker) String() string {
+	data, _ := json.Marshal(m)
+	return updateMarkerPrefix + string(data) + " -->"
+}
+
+// covers reports whether m changes everything old does: the same module, each of its libraries
+// (at any version) and its Go version, if it has one
+func (m updateMarker) covers(old updateMarker) bool {
+	if m.ModuleDir != old.ModuleDir || (len(old.Libraries) == 0 && old.GoVersion == "") {
+		return false
+	}
+	if old.GoVersion != "" && m.GoVersion == "" {
+		return false
+	}
+	for library := range old.Libraries {
+		if _, ok := m.Libraries[library]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// parseUpdateMarker reads what an update merge request changes. Merge requests opened before the
+// marker existed are recognised by their update-* or fix-vulnerabilities-* branch and the
+// description layout of the time.
+func parseUpdateMarker(mr MergeRequest) (updateMarker, bool) {
+	if i := strings.Index(mr.Description, updateMarkerPrefix); i >= 0 {
+		rest := mr.Description[i+len(updateMarkerPrefix):]
+		end := strings.Index(rest, " -->")
+		var marker updateMarker
+		if end < 0 || json.Unmarshal([]byte(rest[:end]), &marker) != nil {
+			return updateMarker{}, false
+		}
+		return marker, true
+	}
+	if !strings.HasPrefix(mr.SourceBranch, "update-") && !strings.HasPrefix(mr.SourceBranch, "fix-vulnerabilities-") {
+		return updateMarker{}, false
+	}
+
+	marker := updateMarker{ModuleDir: gomod.RootDir, Libraries: make(map[string]string)}
+	single := false
+	var library, version string
+	inList := false
+	for _, line := range strings.Split(mr.Description, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "**Library:** "):
+			single, library = true, strings.TrimPrefix(line, "**Library:** ")
+		case strings.HasPrefix(line, "**Version:** "):
+			version = strings.TrimPrefix(line, "**Version:** ")
+		case strings.HasPrefix(line, "**Module:** "):
+			marker.ModuleDir = strings.TrimPrefix(line, "**Module:** ")
+		case strings.HasPrefix(line, "**Module directory:** "):
+			marker.ModuleDir = strings.Trim(strings.TrimPrefix(line, "**Module directory:** "), "`")
+		case strings.HasPrefix(line, "**New Go Version:** "):
+			marker.GoVersion = strings.TrimPrefix(line, "**New Go Version:** ")
+		case strings.HasPrefix(line, "**Updated Libraries"):
+			inList = true
+		case strings.HasPrefix(line, "#"):
+			inList = false
+		case inList && strings.HasPrefix(line, "- **"):
+			if name, target, ok := strings.Cut(strings.TrimPrefix(line, "- **"), "**: "); ok {
+				marker.Libraries[name] = target
+			}
+		}
+	}
+	if single && library != "" {
+		marker.Libraries[library] = version
+	}
+	marker.ModuleDir = gomod.CleanDir(marker.ModuleDir)
+	return marker, len(marker.Libraries) > 0 || marker.GoVersion != ""
+}
@@ -0,0 +1,57 @@
+// internal/service/policy.go
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"gitlab-list/internal/policy"
+)
+
+// PolicyService evaluates the dependency policy file against cached projects
+type PolicyService struct {
+	projects *ProjectService
+	path     string
+}
+
+// NewPolicyService creates a policy service reading rules from path.
+// The file is re-read on every evaluation, so edits apply without a restart.
+func NewPolicyService(projects *ProjectService, path string) *PolicyService {
+	return &PolicyService{
+		projects: projects,
+		path:     path,
+	}
+}
+
+// Policy loads the configured policy file
+func (s *PolicyService) Policy() (*policy.Policy, error) {
+	if s.path == "" {
+		return nil, fmt.Errorf("policy not configured: set POLICY_FILE")
+	}
+	return policy.Load(s.path)
+}
+
+// Evaluate checks the cached projects on ref against the policy
+func (s *PolicyService) Evaluate(ref string) (*policy.Report, error) {
+	return s.EvaluateContext(context.Background(), ref)
+}
+
+// EvaluateContext is Evaluate giving up once ctx is done, e.g. after the cache was read
+func (s *PolicyService) EvaluateContext(ctx context.Context, ref string) (*policy.Report, error) {
+	p, err := s.Policy()
+	if err != nil {
+		return nil, err
+	}
+
+	projects, err := s.projects.GetCachedProjectsOnRef(ref)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("policy evaluation stopped: %w", err)
+	}
+
+	report := policy.Evaluate(p, projects)
+	report.Ref = ref
+	return report, nil
+}
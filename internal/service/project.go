@@ -2,6 +2,7 @@
 package service
 
 import (
+	"context"
 	"crypto/md5"
 	"fmt"
 	"os"
@@ -12,6 +13,8 @@ import (
 	"time"
 
 	"gitlab-list/internal/domain"
+	"gitlab-list/internal/gomod"
+	"gitlab-list/internal/goproxy"
 	"gitlab-list/internal/repository"
 	"gitlab-list/internal/service/archmap"
 	"gitlab-list/internal/service/graph"
@@ -19,8 +22,13 @@ import (
 
 // ProjectService handles project-related business logic
 type ProjectService struct {
-	repo      repository.ProjectRepository
-	mongoRepo *repository.MongoDBRepository
+	repo          repository.ProjectRepository
+	store         repository.CacheStore
+	detailWorkers int
+	branches      []string
+	proxy         *goproxy.Client
+	retention     *SnapshotRetention
+	cacheTTL      time.Duration // Age after which refreshes read unchanged records again; 0 never does
 }
 
 // NewProjectService creates a new project service
@@ -30,128 +38,55 @@ func NewProjectService(repo repository.ProjectRepository) *ProjectService {
 	}
 }
 
-// NewProjectServiceWithCache creates a new project service with MongoDB caching
-func NewProjectServiceWithCache(repo repository.ProjectRepository, mongoRepo *repository.MongoDBRepository) *ProjectService {
+// NewProjectServiceWithCache creates a new project service with a cache store (MongoDB, bolt or memory)
+func NewProjectServiceWithCache(repo repository.ProjectRepository, store repository.CacheStore) *ProjectService {
 	return &ProjectService{
-		repo:      repo,
-		mongoRepo: mongoRepo,
+		repo:  repo,
+		store: store,
 	}
 }
 
+// SetDetailWorkers sets how many projects have their details fetched concurrently
+func (s *ProjectService) SetDetailWorkers(workers int) {
+	s.detailWorkers = workers
+}
+
+// SetBranches sets the refs scanned for every project; "default" stands for the project's default branch
+func (s *ProjectService) SetBranches(branches []string) {
+	s.branches = branches
+}
+
 // SearchProjects searches for projects based on criteria
 func (s *ProjectService) SearchProjects(criteria domain.SearchCriteria, useCache bool) ([]domain.Project, error) {
-	// Generate search hash for caching
-	searchHash := s.generateSearchHash(criteria)
-
-	// Try cache first if enabled and available
-	if useCache && s.mongoRepo != nil {
-		// First try the specific search hash
-		if valid, err := s.mongoRepo.IsCacheValid(searchHash); err == nil && valid {
-			if cachedProjects, err := s.mongoRepo.GetCachedProjects(searchHash); err == nil {
-				return cachedProjects, nil
-			}
-		}
-
-		// If no specific cache found, try the initial load cache and filter
-		if valid, err := s.mongoRepo.IsCacheValid("initial_load_all_projects"); err == nil && valid {
-			if allCachedProjects, err := s.mongoRepo.GetCachedProjects("initial_load_all_projects"); err == nil {
-				// Filter the cached projects based on criteria
-				var filtered []domain.Project
-				for _, project := range allCachedProjects {
-					if s.matchesDetailedCriteria(project, criteria) {
-						filtered = append(filtered, project)
-					}
-				}
-				return filtered, nil
-			}
+	// Query the cached project documents first if enabled and available
+	if useCache {
+		if cached, ok := s.searchCache(criteria, false); ok {
+			return cached, nil
 		}
 	}
 
-	// Fetch from GitLab
+	// Fetch from GitLab; the cache is only written by syncs and project refreshes
 	projects, err := s.repo.GetProjects()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get projects: %w", err)
 	}
 
-	var filtered []domain.Project
-	for _, project := range projects {
-		if s.matchesCriteria(project, criteria) {
-			// Get detailed information if needed and not already available
-			if (criteria.GoVersion != "" || criteria.Library != "") && (project.GoVersion == "" && len(project.Libraries) == 0) {
-				detailed, err := s.repo.GetProjectDetails(project.ID, "")
-				if err == nil {
-					project = *detailed
-				}
-			}
-
-			if s.matchesDetailedCriteria(project, criteria) {
-				filtered = append(filtered, project)
-			}
-		}
-	}
-
-	// Cache the results if MongoDB is available
-	if s.mongoRepo != nil {
-		go func() {
-			// Calculate project hashes
-			projectHashes := make(map[int]string)
-			for _, project := range filtered {
-				projectHashes[project.ID] = s.calculateProjectHash(project)
-			}
-			if err := s.mongoRepo.CacheProjects(filtered, searchHash, projectHashes); err != nil {
-				fmt.Printf("Failed to cache projects: %v\n", err)
-			}
-		}()
-	}
-
-	return filtered, nil
+	return s.filterProjects(s.repo, projects, criteria), nil
 }
 
 // SearchProjectsWithToken searches for projects using a specific GitLab token
 func (s *ProjectService) SearchProjectsWithToken(criteria domain.SearchCriteria, useCache bool, forceCache bool, token string) ([]domain.Project, error) {
-	// Generate search hash for caching
-	searchHash := s.generateSearchHash(criteria)
-
-	// Try cache first if enabled and available
-	if useCache && s.mongoRepo != nil {
-		// First try the specific search hash
-		if valid, err := s.mongoRepo.IsCacheValid(searchHash); err == nil && valid {
-			if cachedProjects, err := s.mongoRepo.GetCachedProjects(searchHash); err == nil {
-				return cachedProjects, nil
-			}
-		}
-
-		// If no specific cache found, try the initial load cache and filter
-		if valid, err := s.mongoRepo.IsCacheValid("initial_load_all_projects"); err == nil && valid {
-			if allCachedProjects, err := s.mongoRepo.GetCachedProjects("initial_load_all_projects"); err == nil {
-				// Filter the cached projects based on criteria
-				var filtered []domain.Project
-				for _, project := range allCachedProjects {
-					if s.matchesDetailedCriteria(project, criteria) {
-						filtered = append(filtered, project)
-					}
-				}
-				return filtered, nil
-			}
+	// Query the cached project documents first if enabled and available
+	if useCache {
+		if cached, ok := s.searchCache(criteria, false); ok {
+			return cached, nil
 		}
 	}
 
 	// If force_cache is true, only use cache and don't call GitLab APIs
 	if forceCache {
-		// Try to get from the general "initial_load_all_projects" cache
-		if s.mongoRepo != nil {
-			if valid, err := s.mongoRepo.IsCacheValid("initial_load_all_projects"); err == nil && valid {
-				if allProjects, err := s.mongoRepo.GetCachedProjects("initial_load_all_projects"); err == nil {
-					// Filter the cached projects based on criteria
-					var filtered []domain.Project
-					for _, project := range allProjects {
-						if s.matchesCriteria(project, criteria) && s.matchesDetailedCriteria(project, criteria) {
-							filtered = append(filtered, project)
-						}
-					}
-					return filtered, nil
-				}
-			}
+		if cached, ok := s.searchCache(criteria, true); ok {
+			return cached, nil
 		}
 		// If no cache available and force_cache is true, return empty results
 		return []domain.Project{}, nil
@@ -160,167 +95,199 @@ func (s *ProjectService) SearchProjectsWithToken(criteria domain.SearchCriteria,
 	// Use provided token or fall back to default repository
 	var repo repository.ProjectRepository = s.repo
 	if token != "" {
-		repo = repository.NewGitLabRepositoryWithToken(token, s.repo.GetGroup(), s.repo.GetTag())
+		repo = s.repo.WithToken(token)
 	}
 
-	// Fetch from GitLab
+	// Fetch from GitLab; the cache is only written by syncs and project refreshes
 	projects, err := repo.GetProjects()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get projects: %w", err)
 	}
 
+	return s.filterProjects(repo, projects, criteria), nil
+}
+
+// searchCache runs a search over the cached documents of criteria.Ref, also applying the basic
+// (group, tag) criteria when basic is set. It reports false when nothing is cached.
+func (s *ProjectService) searchCache(criteria domain.SearchCriteria, basic bool) ([]domain.Project, bool) {
+	if s.store == nil {
+		return nil, false
+	}
+	if valid, err := s.store.IsCacheValid(); err != nil || !valid {
+		return nil, false
+	}
+	projects, err := s.store.GetCachedProjects(repository.RefFilter(criteria.Ref))
+	if err != nil {
+		return nil, false
+	}
+
 	var filtered []domain.Project
 	for _, project := range projects {
-		if s.matchesCriteria(project, criteria) {
-			// Get detailed information if needed and not already available
-			if (criteria.GoVersion != "" || criteria.Library != "") && (project.GoVersion == "" && len(project.Libraries) == 0) {
-				detailed, err := repo.GetProjectDetails(project.ID, "")
-				if err == nil {
-					project = *detailed
-				}
-			}
+		if basic && !s.matchesCriteria(project, criteria) {
+			continue
+		}
+		if s.matchesDetailedCriteria(project, criteria) {
+			filtered = append(filtered, project)
+		}
+	}
+	return filtered, true
+}
 
-			if s.matchesDetailedCriteria(project, criteria) {
-				filtered = append(filtered, project)
-			}
+// filterProjects returns the projects matching criteria, fetching details concurrently when the
+// criteria need them
+func (s *ProjectService) filterProjects(repo repository.ProjectRepository, projects []domain.Project, criteria domain.SearchCriteria) []domain.Project {
+	var candidates []domain.Project
+	for _, project := range projects {
+		if s.matchesCriteria(project, criteria) {
+			candidates = append(candidates, project)
 		}
 	}
 
-	// Cache the results if MongoDB is available
-	if s.mongoRepo != nil {
-		go func() {
-			// Calculate project hashes
-			projectHashes := make(map[int]string)
-			for _, project := range filtered {
-				projectHashes[project.ID] = s.calculateProjectHash(project)
+	// Get detailed information if needed; projects that fail keep their basic info,
+	// projects without the requested ref are dropped
+	if criteria.HasModuleFilters() || criteria.Ref != "" {
+		results, _ := s.fetchProjectDetails(repo, detailJobs(candidates, []string{criteria.Ref}))
+		candidates = candidates[:0]
+		for _, res := range results {
+			if !res.Skipped {
+				candidates = append(candidates, res.Project)
 			}
-			if err := s.mongoRepo.CacheProjects(filtered, searchHash, projectHashes); err != nil {
-				fmt.Printf("Failed to cache projects: %v\n", err)
-			}
-		}()
+		}
 	}
 
-	return filtered, nil
+	var filtered []domain.Project
+	for _, project := range candidates {
+		if s.matchesDetailedCriteria(project, criteria) {
+			filtered = append(filtered, project)
+		}
+	}
+	return filtered
 }
 
-// generateSearchHash creates a hash for caching search results
-func (s *ProjectService) generateSearchHash(criteria domain.SearchCriteria) string {
-	hashInput := fmt.Sprintf("%s|%s|%s|%s|%s|%s|%s",
-		criteria.GoVersion,
-		criteria.GoVersionComparison,
-		criteria.Library,
-		criteria.Version,
-		criteria.VersionComparison,
-		criteria.Group,
-		criteria.Tag,
-	)
-	hash := md5.Sum([]byte(hashInput))
-	return fmt.Sprintf("%x", hash)
+// LoadInitialCache loads all projects into cache with detailed information
+func (s *ProjectService) LoadInitialCache() (*DetailFetchSummary, error) {
+	return s.loadInitialCache(context.Background(), s.repo, nil)
 }
 
-// LoadInitialCache loads all projects into cache with detailed information
-func (s *ProjectService) LoadInitialCache() (int, error) {
-	if s.mongoRepo == nil {
-		return 0, fmt.Errorf("MongoDB repository not available")
-	}
+// LoadInitialCacheWithToken loads all projects into cache using a specific GitLab token with detailed information
+func (s *ProjectService) LoadInitialCacheWithToken(token string) (*DetailFetchSummary, error) {
+	return s.LoadInitialCacheContext(context.Background(), token, nil)
+}
 
-	// Get all projects from GitLab
-	projects, err := s.repo.GetProjects()
-	if err != nil {
-		return 0, fmt.Errorf("failed to get projects: %w", err)
+// LoadInitialCacheContext loads the cache as a queued job: progress follows the detail fetches, and a
+// canceled ctx stops the load before anything is written. An empty token uses the configured one.
+func (s *ProjectService) LoadInitialCacheContext(ctx context.Context, token string, progress ProgressFunc) (*DetailFetchSummary, error) {
+	repo := s.repo
+	if token != "" {
+		repo = repo.WithToken(token)
 	}
+	return s.loadInitialCache(ctx, repo, progress)
+}
 
-	// Get detailed information for each project (Go version and libraries)
-	var detailedProjects []domain.Project
-	for _, project := range projects {
-		detailed, err := s.repo.GetProjectDetails(project.ID, "")
-		if err != nil {
-			// If we can't get details, use the basic project info
-			fmt.Printf("Warning: Failed to get details for project %d (%s): %v\n", project.ID, project.Name, err)
-			detailedProjects = append(detailedProjects, project)
-		} else {
-			detailedProjects = append(detailedProjects, *detailed)
-		}
+// loadInitialCache fetches every project with details through repo and replaces the initial load cache
+func (s *ProjectService) loadInitialCache(ctx context.Context, repo repository.ProjectRepository, progress ProgressFunc) (*DetailFetchSummary, error) {
+	if s.store == nil {
+		return nil, repository.ErrNoStore
 	}
 
-	// Cache all projects with detailed information
-	searchHash := "initial_load_all_projects"
-	// Calculate project hashes
-	projectHashes := make(map[int]string)
-	for _, project := range detailedProjects {
-		projectHashes[project.ID] = s.calculateProjectHash(project)
-	}
-	err = s.mongoRepo.CacheProjects(detailedProjects, searchHash, projectHashes)
+	// One cache load, refresh or clear at a time across all API and scheduler replicas
+	lease, err := AcquireLease(s.store, LeaseCache, "cache load")
 	if err != nil {
-		return 0, fmt.Errorf("failed to cache projects: %w", err)
+		return nil, err
 	}
+	defer lease.Release()
 
-	return len(detailedProjects), nil
-}
+	// Records are stamped with the start of the sync, so project refreshes that finish meanwhile win
+	fetchedAt := time.Now()
 
-// LoadInitialCacheWithToken loads all projects into cache using a specific GitLab token with detailed information
-func (s *ProjectService) LoadInitialCacheWithToken(token string) (int, error) {
-	if s.mongoRepo == nil {
-		return 0, fmt.Errorf("MongoDB repository not available")
+	// Get all projects from GitLab
+	progress.report("Listing projects", "", 0, 0)
+	projects, err := repo.GetProjects()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get projects: %w", err)
 	}
 
-	// Create a temporary repository with the provided token
-	tempRepo := repository.NewGitLabRepositoryWithToken(token, s.repo.GetGroup(), s.repo.GetTag())
-
-	// Get all projects from GitLab using the provided token
-	projects, err := tempRepo.GetProjects()
+	// Get detailed information for each project on every configured ref (Go version and libraries).
+	// Projects that fail keep their cached record, or their basic info when they have none, so they
+	// still show up in the cache; projects that lack a configured branch are skipped.
+	results, summary := s.fetchProjectDetailsContext(ctx, repo, detailJobs(projects, s.branches), progress)
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("cache load stopped before saving: %w", err)
+	}
+	cachedRecords, err := s.store.GetCachedProjectsWithHashes(repository.ProjectFilter{})
 	if err != nil {
-		return 0, fmt.Errorf("failed to get projects: %w", err)
+		return nil, fmt.Errorf("failed to get cached projects: %w", err)
 	}
-
-	// Get detailed information for each project (Go version and libraries)
-	var detailedProjects []domain.Project
-	for _, project := range projects {
-		detailed, err := tempRepo.GetProjectDetails(project.ID, "")
-		if err != nil {
-			// If we can't get details, use the basic project info
-			fmt.Printf("Warning: Failed to get details for project %d (%s): %v\n", project.ID, project.Name, err)
-			detailedProjects = append(detailedProjects, project)
-		} else {
-			detailedProjects = append(detailedProjects, *detailed)
+	cached := make(map[string]domain.Project, len(cachedRecords))
+	for _, entry := range cachedRecords {
+		cached[entry.Key] = entry.Project
+	}
+	detailedProjects := make([]domain.Project, 0, len(results))
+	for _, res := range results {
+		if res.Skipped {
+			continue
+		}
+		// Keep what we had rather than replace details with basic info
+		if previous, ok := cached[repository.ProjectKey(res.Project)]; ok && res.Err != nil {
+			detailedProjects = append(detailedProjects, previous)
+			continue
 		}
+		detailedProjects = append(detailedProjects, res.Project)
 	}
 
-	// Cache all projects with detailed information
-	searchHash := "initial_load_all_projects"
-	// Calculate project hashes
-	projectHashes := make(map[int]string)
-	for _, project := range detailedProjects {
-		projectHashes[project.ID] = s.calculateProjectHash(project)
+	// Upsert one document per project and ref; both writes fail once the lease moved on to another holder
+	progress.report("Saving cache", "", summary.Total, summary.Total)
+	if err := s.store.UpsertProjects(detailedProjects, s.projectHashes(detailedProjects), fetchedAt, lease.Fence()); err != nil {
+		return nil, fmt.Errorf("failed to cache projects: %w", err)
 	}
-	err = s.mongoRepo.CacheProjects(detailedProjects, searchHash, projectHashes)
+
+	// Drop the documents of projects and refs that are gone since the previous sync
+	pruned, err := s.store.PruneProjects(0, fetchedAt, lease.Fence())
 	if err != nil {
-		return 0, fmt.Errorf("failed to cache projects: %w", err)
+		return nil, fmt.Errorf("failed to prune cache: %w", err)
+	}
+	if pruned > 0 {
+		fmt.Printf("Removed %d cached project records no longer found in GitLab\n", pruned)
 	}
 
-	return len(detailedProjects), nil
+	// Keep an immutable copy of this sync for trend queries
+	s.saveSnapshot(detailedProjects)
+
+	fmt.Printf("Initial cache loaded: %d projects (%d succeeded, %d failed, %d skipped) in %s\n",
+		summary.Total, summary.Succeeded, summary.Failed, summary.Skipped, summary.Duration)
+	return summary, nil
 }
 
 // ClearExpiredCache removes expired cache entries (deprecated - use ClearAllCache for hash-based system)
 func (s *ProjectService) ClearExpiredCache() error {
-	if s.mongoRepo == nil {
-		return fmt.Errorf("MongoDB repository not available")
+	if s.store == nil {
+		return repository.ErrNoStore
 	}
 	// In hash-based system, we clear all cache instead of just expired
-	return s.mongoRepo.ClearAllCache()
+	return s.store.ClearAllCache()
 }
 
 // ClearAllCache removes all cache entries
 func (s *ProjectService) ClearAllCache() error {
-	if s.mongoRepo == nil {
-		return fmt.Errorf("MongoDB repository not available")
+	if s.store == nil {
+		return repository.ErrNoStore
 	}
-	return s.mongoRepo.ClearAllCache()
+	lease, err := AcquireLease(s.store, LeaseCache, "cache clear")
+	if err != nil {
+		return err
+	}
+	defer lease.Release()
+	return s.store.ClearAllCache()
+}
+
+// CacheEnabled reports whether a cache store is configured
+func (s *ProjectService) CacheEnabled() bool {
+	return s.store != nil
 }
 
 // GetCacheStats returns cache statistics
 func (s *ProjectService) GetCacheStats() (map[string]interface{}, error) {
-	if s.mongoRepo == nil {
+	if s.store == nil {
 		return map[string]interface{}{
 			"total_cached_projects": 0,
 			"valid_cache_entries":   0,
@@ -329,7 +296,7 @@ func (s *ProjectService) GetCacheStats() (map[string]interface{}, error) {
 		}, nil
 	}
 
-	stats, err := s.mongoRepo.GetCacheStats()
+	stats, err := s.store.GetCacheStats()
 	if err != nil {
 		return nil, err
 	}
@@ -339,79 +306,89 @@ func (s *ProjectService) GetCacheStats() (map[string]interface{}, error) {
 }
 
 // TestCacheSave tests cache saving functionality
-func (s *ProjectService) TestCacheSave(projects []domain.Project, searchHash string, projectHashes map[int]string) error {
-	if s.mongoRepo == nil {
-		return fmt.Errorf("MongoDB repository not available")
+func (s *ProjectService) TestCacheSave(projects []domain.Project, projectHashes map[string]string) error {
+	if s.store == nil {
+		return repository.ErrNoStore
 	}
-	return s.mongoRepo.CacheProjects(projects, searchHash, projectHashes)
+	return s.store.UpsertProjects(projects, projectHashes, time.Now(), repository.Fence{})
 }
 
 // TestCacheGet tests cache retrieval functionality
-func (s *ProjectService) TestCacheGet(searchHash string) ([]domain.Project, error) {
-	if s.mongoRepo == nil {
-		return nil, fmt.Errorf("MongoDB repository not available")
+func (s *ProjectService) TestCacheGet(projectID int) ([]domain.Project, error) {
+	if s.store == nil {
+		return nil, repository.ErrNoStore
+	}
+	return s.store.GetCachedProjects(repository.ProjectFilter{ProjectID: projectID})
+}
+
+// TestCacheDelete removes the documents written by TestCacheSave
+func (s *ProjectService) TestCacheDelete(projectID int) error {
+	if s.store == nil {
+		return repository.ErrNoStore
 	}
-	return s.mongoRepo.GetCachedProjects(searchHash)
+	_, err := s.store.PruneProjects(projectID, time.Now().Add(time.Minute), repository.Fence{})
+	return err
 }
 
-// RefreshProjectInCache refreshes a specific project in the cache
+// RefreshProjectInCache refreshes a specific project in the cache on every configured ref
 func (s *ProjectService) RefreshProjectInCache(projectID int, token string) error {
-	if s.mongoRepo == nil {
-		return fmt.Errorf("MongoDB repository not available")
+	if s.store == nil {
+		return repository.ErrNoStore
 	}
 
+	// Stamp the records before reading them, so a concurrent refresh that read later is never overwritten
+	fetchedAt := time.Now()
+
 	// Create a temporary repository with the provided token
-	tempRepo := repository.NewGitLabRepositoryWithToken(token, s.repo.GetGroup(), s.repo.GetTag())
+	tempRepo := s.repo.WithToken(token)
 
-	// Get the specific project details
+	// Get the basic project info, needed to resolve the default branch
 	project, err := tempRepo.GetProjectDetails(projectID, "")
 	if err != nil {
 		return fmt.Errorf("failed to get project details: %w", err)
 	}
 
-	// Update the project in the "initial_load_all_projects" cache
-	// First, get all cached projects
-	allProjects, err := s.mongoRepo.GetCachedProjects("initial_load_all_projects")
-	if err != nil {
-		// If no cache exists, create a new one with just this project
-		allProjects = []domain.Project{*project}
-	} else {
-		// Update the specific project in the list
-		found := false
-		for i, p := range allProjects {
-			if p.ID == projectID {
-				allProjects[i] = *project
-				found = true
-				break
-			}
+	results, _ := s.fetchProjectDetails(tempRepo, detailJobs([]domain.Project{*project}, s.branches))
+	var refreshed []domain.Project
+	for _, res := range results {
+		if res.Err != nil {
+			return fmt.Errorf("failed to get project details on %s: %w", res.Project.Ref, res.Err)
 		}
-		if !found {
-			// If project not found in cache, add it
-			allProjects = append(allProjects, *project)
+		if !res.Skipped {
+			refreshed = append(refreshed, res.Project)
 		}
 	}
 
-	// Cache the updated projects
-	projectHashes := make(map[int]string)
-	for _, project := range allProjects {
-		projectHashes[project.ID] = s.calculateProjectHash(project)
+	// Upsert this project's documents, leaving every other project untouched
+	if err := s.store.UpsertProjects(refreshed, s.projectHashes(refreshed), fetchedAt, repository.Fence{}); err != nil {
+		return fmt.Errorf("failed to update project in cache: %w", err)
 	}
-	err = s.mongoRepo.CacheProjects(allProjects, "initial_load_all_projects", projectHashes)
-	if err != nil {
+
+	// Drop the refs the project no longer has
+	if _, err := s.store.PruneProjects(projectID, fetchedAt, repository.Fence{}); err != nil {
 		return fmt.Errorf("failed to update project in cache: %w", err)
 	}
 
 	return nil
 }
 
+// projectHashes calculates the content hash of every project record, keyed by ProjectKey
+func (s *ProjectService) projectHashes(projects []domain.Project) map[string]string {
+	hashes := make(map[string]string, len(projects))
+	for _, project := range projects {
+		hashes[repository.ProjectKey(project)] = s.calculateProjectHash(project)
+	}
+	return hashes
+}
+
 // SearchLibraries searches for library names from cached projects
 func (s *ProjectService) SearchLibraries(query string, limit int) ([]string, error) {
-	if s.mongoRepo == nil {
-		return nil, fmt.Errorf("MongoDB repository not available")
+	if s.store == nil {
+		return nil, repository.ErrNoStore
 	}
 
-	// Get all cached projects
-	projects, err := s.mongoRepo.GetCachedProjects("initial_load_all_projects")
+	// Get all cached projects on their default branch
+	projects, err := s.getCachedProjectsOnRef("")
 	if err != nil {
 		return nil, fmt.Errorf("failed to get cached projects: %w", err)
 	}
@@ -419,8 +396,10 @@ func (s *ProjectService) SearchLibraries(query string, limit int) ([]string, err
 	// Collect all unique library names
 	librarySet := make(map[string]bool)
 	for _, project := range projects {
-		for _, lib := range project.Libraries {
-			librarySet[lib.Name] = true
+		for _, module := range project.GoModules() {
+			for _, lib := range module.Libraries {
+				librarySet[lib.Name] = true
+			}
 		}
 	}
 
@@ -445,12 +424,12 @@ func (s *ProjectService) SearchLibraries(query string, limit int) ([]string, err
 
 // SearchGoVersions searches for Go versions from cached projects
 func (s *ProjectService) SearchGoVersions(query string, limit int) ([]string, error) {
-	if s.mongoRepo == nil {
-		return nil, fmt.Errorf("MongoDB repository not available")
+	if s.store == nil {
+		return nil, repository.ErrNoStore
 	}
 
-	// Get all cached projects
-	projects, err := s.mongoRepo.GetCachedProjects("initial_load_all_projects")
+	// Get all cached projects on their default branch
+	projects, err := s.getCachedProjectsOnRef("")
 	if err != nil {
 		return nil, fmt.Errorf("failed to get cached projects: %w", err)
 	}
@@ -458,8 +437,10 @@ func (s *ProjectService) SearchGoVersions(query string, limit int) ([]string, er
 	// Collect all unique Go versions
 	versionSet := make(map[string]bool)
 	for _, project := range projects {
-		if project.GoVersion != "" {
-			versionSet[project.GoVersion] = true
+		for _, module := range project.GoModules() {
+			if module.GoVersion != "" {
+				versionSet[module.GoVersion] = true
+			}
 		}
 	}
 
@@ -484,12 +465,12 @@ func (s *ProjectService) SearchGoVersions(query string, limit int) ([]string, er
 
 // SearchLibraryVersions searches for versions of a specific library from cached projects
 func (s *ProjectService) SearchLibraryVersions(libraryName, query string, limit int) ([]string, error) {
-	if s.mongoRepo == nil {
-		return nil, fmt.Errorf("MongoDB repository not available")
+	if s.store == nil {
+		return nil, repository.ErrNoStore
 	}
 
-	// Get all cached projects
-	projects, err := s.mongoRepo.GetCachedProjects("initial_load_all_projects")
+	// Get all cached projects on their default branch
+	projects, err := s.getCachedProjectsOnRef("")
 	if err != nil {
 		return nil, fmt.Errorf("failed to get cached projects: %w", err)
 	}
@@ -497,9 +478,11 @@ func (s *ProjectService) SearchLibraryVersions(libraryName, query string, limit
 	// Collect all unique versions for the specific library
 	versionSet := make(map[string]bool)
 	for _, project := range projects {
-		for _, lib := range project.Libraries {
-			if lib.Name == libraryName {
-				versionSet[lib.Version] = true
+		for _, module := range project.GoModules() {
+			for _, lib := range module.Libraries {
+				if lib.Name == libraryName {
+					versionSet[lib.Version] = true
+				}
 			}
 		}
 	}
@@ -525,12 +508,12 @@ func (s *ProjectService) SearchLibraryVersions(libraryName, query string, limit
 
 // SearchModules searches for module names from cached projects
 func (s *ProjectService) SearchModules(query string, limit int) ([]string, error) {
-	if s.mongoRepo == nil {
-		return nil, fmt.Errorf("MongoDB repository not available")
+	if s.store == nil {
+		return nil, repository.ErrNoStore
 	}
 
-	// Get all cached projects
-	projects, err := s.mongoRepo.GetCachedProjects("initial_load_all_projects")
+	// Get all cached projects on their default branch
+	projects, err := s.getCachedProjectsOnRef("")
 	if err != nil {
 		return nil, fmt.Errorf("failed to get cached projects: %w", err)
 	}
@@ -541,6 +524,11 @@ func (s *ProjectService) SearchModules(query string, limit int) ([]string, error
 		if project.Path != "" {
 			moduleSet[project.Path] = true
 		}
+		for _, module := range project.Modules {
+			if module.Path != "" {
+				moduleSet[module.Path] = true
+			}
+		}
 	}
 
 	// Convert to slice and filter by query
@@ -565,7 +553,7 @@ func (s *ProjectService) SearchModules(query string, limit int) ([]string, error
 // GetProjectHash calculates a hash for a project based on its content
 func (s *ProjectService) GetProjectHash(projectID int, token string) (string, error) {
 	// Create a temporary repository with the provided token
-	tempRepo := repository.NewGitLabRepositoryWithToken(token, s.repo.GetGroup(), s.repo.GetTag())
+	tempRepo := s.repo.WithToken(token)
 
 	// Get the specific project details
 	project, err := tempRepo.GetProjectDetails(projectID, "")
@@ -591,45 +579,44 @@ func (s *ProjectService) GetProjectHash(projectID int, token string) (string, er
 func (s *ProjectService) getLibrariesHash(libraries []domain.Library) string {
 	var libStrings []string
 	for _, lib := range libraries {
-		libStrings = append(libStrings, fmt.Sprintf("%s:%s", lib.Name, lib.Version))
+		entry := fmt.Sprintf("%s:%s", lib.Name, lib.Version)
+		if lib.Indirect {
+			entry += ":indirect"
+		}
+		if lib.Replace != nil {
+			entry += fmt.Sprintf("=>%s:%s", lib.Replace.Path, lib.Replace.Version)
+		}
+		libStrings = append(libStrings, entry)
 	}
 	sort.Strings(libStrings)
 	return strings.Join(libStrings, "|")
 }
 
-// GetChangedProjects returns projects that have changed since last cache build
+// GetChangedProjects returns the records whose branch head moved since the cache read them, and records
+// not cached yet. It reads the project list and the branch heads of active projects, not the details.
 func (s *ProjectService) GetChangedProjects(token string) ([]domain.Project, error) {
-	if s.mongoRepo == nil {
-		return nil, fmt.Errorf("MongoDB repository not available")
+	if s.store == nil {
+		return nil, repository.ErrNoStore
 	}
 
-	// Get all cached projects with their hashes
-	cachedProjectsWithHashes, err := s.mongoRepo.GetCachedProjectsWithHashes("initial_load_all_projects")
+	// Create a temporary repository with the provided token
+	tempRepo := s.repo.WithToken(token)
+
+	projects, err := tempRepo.GetProjects()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get cached projects: %w", err)
+		return nil, fmt.Errorf("failed to get projects: %w", err)
+	}
+	plan, err := s.planRefresh(context.Background(), tempRepo, projects, nil)
+	if err != nil {
+		return nil, err
 	}
-
-	// Create a temporary repository with the provided token
-	tempRepo := repository.NewGitLabRepositoryWithToken(token, s.repo.GetGroup(), s.repo.GetTag())
 
 	var changedProjects []domain.Project
-
-	// Check each cached project for changes
-	for _, cachedEntry := range cachedProjectsWithHashes {
-		// Get current project details
-		currentProject, err := tempRepo.GetProjectDetails(cachedEntry.Project.ID, "")
-		if err != nil {
-			// If we can't get current details, skip this project
-			continue
-		}
-
-		// Calculate current hash
-		currentHash := s.calculateProjectHash(*currentProject)
-
-		// If hashes are different, project has changed
-		if currentHash != cachedEntry.ProjectHash {
-			changedProjects = append(changedProjects, *currentProject)
-		}
+	for _, target := range plan.fetch {
+		project := target.job.Project
+		project.Ref = resolveRef(project, target.job.Ref)
+		project.CommitSHA = target.job.Head
+		changedProjects = append(changedProjects, project)
 	}
 
 	return changedProjects, nil
@@ -637,13 +624,24 @@ func (s *ProjectService) GetChangedProjects(token string) ([]domain.Project, err
 
 // calculateProjectHash calculates hash for a project (internal method)
 func (s *ProjectService) calculateProjectHash(project domain.Project) string {
-	hashInput := fmt.Sprintf("%d|%s|%s|%s|%s|%s",
+	var moduleHashes []string
+	for _, module := range project.GoModules() {
+		var excludes []string
+		for _, exc := range module.Excludes {
+			excludes = append(excludes, exc.Path+"@"+exc.Version)
+		}
+		moduleHashes = append(moduleHashes, fmt.Sprintf("%s:%s:%s:%s[%s]!%s!%s", module.Dir, module.Path, module.GoVersion, module.Toolchain,
+			s.getLibrariesHash(module.Libraries), strings.Join(excludes, ","), strings.Join(module.Retracts, ",")))
+	}
+
+	hashInput := fmt.Sprintf("%d|%s|%s|%s|%s|%s|%s",
 		project.ID,
+		project.Ref,
 		project.Name,
 		project.Path,
 		project.GoVersion,
 		project.UpdatedAt.Format(time.RFC3339),
-		s.getLibrariesHash(project.Libraries),
+		strings.Join(moduleHashes, ";"),
 	)
 
 	hash := md5.Sum([]byte(hashInput))
@@ -665,37 +663,74 @@ func (s *ProjectService) matchesCriteria(project domain.Project, criteria domain
 
 // matchesDetailedCriteria checks if a project matches detailed criteria (Go version, libraries)
 func (s *ProjectService) matchesDetailedCriteria(project domain.Project, criteria domain.SearchCriteria) bool {
+	// Cached records exist per (project, ref); only the requested ref counts
+	if !project.OnRef(criteria.Ref) {
+		return false
+	}
+
+	// A project matches when any of its modules does
+	for _, module := range project.GoModules() {
+		if s.moduleMatchesCriteria(module, criteria) {
+			return true
+		}
+	}
+	return false
+}
+
+// moduleMatchesCriteria checks if a single module matches detailed criteria (Go version, libraries)
+func (s *ProjectService) moduleMatchesCriteria(module domain.Module, criteria domain.SearchCriteria) bool {
 	// Check Go version comparison
 	if criteria.GoVersion != "" {
 		if criteria.GoVersionComparison == "" {
 			// Exact match if no comparison specified
-			if project.GoVersion != criteria.GoVersion {
+			if module.GoVersion != criteria.GoVersion {
 				return false
 			}
 		} else {
 			// Version comparison for Go version
-			if !s.compareVersions(project.GoVersion, criteria.GoVersion, criteria.GoVersionComparison) {
+			if !s.compareVersions(module.GoVersion, criteria.GoVersion, criteria.GoVersionComparison) {
+				return false
+			}
+		}
+	}
+
+	// Check toolchain criteria; "go1.22.1" and "1.22.1" are equivalent
+	if criteria.Toolchain != "" {
+		if module.Toolchain == "" {
+			return false
+		}
+		toolchain := strings.TrimPrefix(module.Toolchain, "go")
+		wanted := strings.TrimPrefix(criteria.Toolchain, "go")
+		if criteria.ToolchainComparison == "" {
+			if toolchain != wanted {
 				return false
 			}
+		} else if !s.compareVersions(toolchain, wanted, criteria.ToolchainComparison) {
+			return false
 		}
 	}
 
-	// Check library criteria
-	if criteria.Library != "" {
+	// Check exclude criteria
+	if criteria.Exclude != "" {
 		found := false
-		for _, lib := range project.Libraries {
-			// Use exact match for library name
-			if lib.Name == criteria.Library {
-				if criteria.Version == "" {
-					found = true
-					break
-				}
+		for _, exc := range module.Excludes {
+			if exc.Path == criteria.Exclude {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
 
-				// Check version comparison for library
-				if s.compareVersions(lib.Version, criteria.Version, criteria.VersionComparison) {
-					found = true
-					break
-				}
+	// Check library criteria; dependency and replace filters without a library match any requirement
+	if criteria.Library != "" || criteria.Dependency != "" || criteria.Replaced != "" {
+		found := false
+		for _, lib := range module.Libraries {
+			if s.libraryMatchesCriteria(lib, criteria) {
+				found = true
+				break
 			}
 		}
 		if !found {
@@ -706,6 +741,47 @@ func (s *ProjectService) matchesDetailedCriteria(project domain.Project, criteri
 	return true
 }
 
+// libraryMatchesCriteria checks if a single requirement matches the library criteria
+func (s *ProjectService) libraryMatchesCriteria(lib domain.Library, criteria domain.SearchCriteria) bool {
+	// Use exact match for library name
+	if criteria.Library != "" && lib.Name != criteria.Library {
+		return false
+	}
+
+	// Check version comparison for library
+	if criteria.Version != "" && !s.compareVersions(lib.Version, criteria.Version, criteria.VersionComparison) {
+		return false
+	}
+
+	switch criteria.Dependency {
+	case "direct":
+		if lib.Indirect {
+			return false
+		}
+	case "indirect":
+		if !lib.Indirect {
+			return false
+		}
+	}
+
+	switch criteria.Replaced {
+	case "true":
+		if lib.Replace == nil {
+			return false
+		}
+	case "false":
+		if lib.Replace != nil {
+			return false
+		}
+	case "local":
+		if lib.Replace == nil || !lib.Replace.Local {
+			return false
+		}
+	}
+
+	return true
+}
+
 // compareVersions compares two version strings based on the comparison type
 func (s *ProjectService) compareVersions(version1, version2, comparison string) bool {
 	if comparison == "" || comparison == "exact" {
@@ -832,13 +908,13 @@ func (s *ProjectService) GenerateFullArchitecture() (*domain.ArchitectureRespons
 
 // GenerateFullArchitectureWithIgnores generates architecture for all cached projects with ignore patterns
 func (s *ProjectService) GenerateFullArchitectureWithIgnores(ignores []string) (*domain.ArchitectureResponse, error) {
-	return s.GenerateFullArchitectureWithOptions(ignores, false)
+	return s.GenerateFullArchitectureWithOptions("", ignores, false)
 }
 
 // GenerateFullArchitectureWithOptions generates architecture for all cached projects with options
-func (s *ProjectService) GenerateFullArchitectureWithOptions(ignores []string, clientsOnly bool) (*domain.ArchitectureResponse, error) {
-	// Get all cached projects
-	projects, err := s.mongoRepo.GetCachedProjects("initial_load_all_projects")
+func (s *ProjectService) GenerateFullArchitectureWithOptions(ref string, ignores []string, clientsOnly bool) (*domain.ArchitectureResponse, error) {
+	// Get all cached projects on ref
+	projects, err := s.getCachedProjectsOnRef(ref)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get cached projects: %w", err)
 	}
@@ -885,8 +961,8 @@ func (s *ProjectService) GenerateArchitectureFromCache(ref, module string, radiu
 
 // GenerateArchitectureFromCacheWithOptions generates architecture for a specific module using cached data with options
 func (s *ProjectService) GenerateArchitectureFromCacheWithOptions(ref, module string, radius int, ignores []string, clientsOnly bool) (*domain.ArchitectureResponse, error) {
-	// Get all cached projects
-	projects, err := s.mongoRepo.GetCachedProjects("initial_load_all_projects")
+	// Get all cached projects on ref
+	projects, err := s.getCachedProjectsOnRef(ref)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get cached projects: %w", err)
 	}
@@ -953,49 +1029,60 @@ func (s *ProjectService) generateArchitectureFromCacheWithOptions(projects []dom
 			continue
 		}
 
-		// Skip if no Go version (not a Go project)
-		if p.GoVersion == "" {
-			continue
-		}
-
-		// Create service node
 		serviceShort := s.parseModuleID(p.Path) // Extract short name from path
-		svcID := "svc:" + serviceShort
-		addNode(svcID, graph.NodeService, map[string]string{
-			"module": p.Path,
-			"path":   p.Path,
-			"label":  serviceShort,
-		})
-
-		// Add dependencies from cached libraries
-		for _, lib := range p.Libraries {
-			// If clientsOnly is true, only show client modules
-			if clientsOnly && !s.isClientModule(lib.Name) {
-				continue // Skip normal libraries when clients only is enabled
+		for _, mod := range p.GoModules() {
+			// Skip if no Go version (not a Go module)
+			if mod.GoVersion == "" {
+				continue
 			}
 
-			// If clientsOnly is false, show all libraries (existing behavior)
-			if !clientsOnly && !s.isClientModule(lib.Name) {
-				continue // Skip normal libraries
+			// Create one service node per module; nested modules are named after their directory
+			svcID := "svc:" + serviceShort
+			label := serviceShort
+			if mod.Dir != gomod.RootDir {
+				svcID += "/" + mod.Dir
+				label += "/" + mod.Dir
 			}
-
-			// Check if this library should be ignored
-			if s.shouldIgnoreLibrary(lib.Name, ignores) {
-				continue // Skip ignored libraries
+			meta := map[string]string{
+				"path":  p.Path,
+				"dir":   mod.Dir,
+				"label": label,
+			}
+			if mod.Path != "" {
+				meta["module"] = mod.Path // Unknown for records cached before modules were tracked
 			}
+			addNode(svcID, graph.NodeService, meta)
+
+			// Add dependencies from cached libraries
+			for _, lib := range mod.Libraries {
+				// If clientsOnly is true, only show client modules
+				if clientsOnly && !s.isClientModule(lib.Name) {
+					continue // Skip normal libraries when clients only is enabled
+				}
+
+				// If clientsOnly is false, show all libraries (existing behavior)
+				if !clientsOnly && !s.isClientModule(lib.Name) {
+					continue // Skip normal libraries
+				}
+
+				// Check if this library should be ignored
+				if s.shouldIgnoreLibrary(lib.Name, ignores) {
+					continue // Skip ignored libraries
+				}
 
-			depID := "dep:" + lib.Name
-			addNode(depID, graph.NodeClient, map[string]string{
-				"module": lib.Name,
-				"label":  s.deriveClientLabel(lib.Name),
-			})
-			addEdge(graph.Edge{
-				From:     svcID,
-				To:       depID,
-				Rel:      "calls",
-				Version:  lib.Version,
-				Evidence: []graph.Evidence{{Hint: "require go.mod (cached)"}},
-			})
+				depID := "dep:" + lib.Name
+				addNode(depID, graph.NodeClient, map[string]string{
+					"module": lib.Name,
+					"label":  s.deriveClientLabel(lib.Name),
+				})
+				addEdge(graph.Edge{
+					From:     svcID,
+					To:       depID,
+					Rel:      "calls",
+					Version:  lib.Version,
+					Evidence: []graph.Evidence{{Hint: "require go.mod (cached)"}},
+				})
+			}
 		}
 	}
 
@@ -1246,14 +1333,21 @@ func (s *ProjectService) resolveTargetNodeID(g *graph.Graph, query string) strin
 		}
 	}
 
-	// 2) exact ID
+	// 2) exact project path, for services whose module path is unknown
+	for _, n := range g.Nodes {
+		if n.Type == graph.NodeService && n.Meta != nil && n.Meta["path"] == q {
+			return n.ID
+		}
+	}
+
+	// 3) exact ID
 	for _, n := range g.Nodes {
 		if n.ID == q {
 			return n.ID
 		}
 	}
 
-	// 3) last segment matches
+	// 4) last segment matches
 	short := s.lastSeg(q)
 	for _, n := range g.Nodes {
 		// prefer explicit label
@@ -1413,29 +1507,58 @@ func (s *ProjectService) GetArchitectureFile(filename string) ([]byte, string, e
 	return content, contentType, nil
 }
 
-// GetProjectOpenAPI retrieves OpenAPI specification for a specific project
-func (s *ProjectService) GetProjectOpenAPI(projectID int) (*domain.OpenAPI, error) {
-	if s.mongoRepo == nil {
-		return nil, fmt.Errorf("MongoDB repository not available")
+// GetProjectOpenAPISpecs retrieves the OpenAPI specifications of a specific project on ref
+func (s *ProjectService) GetProjectOpenAPISpecs(projectID int, ref string) ([]domain.OpenAPI, error) {
+	if s.store == nil {
+		return nil, repository.ErrNoStore
+	}
+
+	filter := repository.RefFilter(ref)
+	filter.ProjectID = projectID
+	projects, err := s.store.GetCachedProjects(filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(projects) == 0 {
+		if ref != "" {
+			return nil, fmt.Errorf("project %d not found in cache for ref %s", projectID, ref)
+		}
+		return nil, fmt.Errorf("project %d not found in cache", projectID)
+	}
+	return projects[0].OpenAPISpecs, nil
+}
+
+// GetProjectsWithOpenAPI retrieves all projects that have OpenAPI specifications on ref
+func (s *ProjectService) GetProjectsWithOpenAPI(ref string) ([]domain.Project, error) {
+	if s.store == nil {
+		return nil, repository.ErrNoStore
 	}
 
-	return s.mongoRepo.GetProjectOpenAPI(projectID)
+	filter := repository.RefFilter(ref)
+	filter.WithOpenAPI = true
+	return s.store.GetCachedProjects(filter)
 }
 
-// GetProjectsWithOpenAPI retrieves all projects that have OpenAPI specifications
-func (s *ProjectService) GetProjectsWithOpenAPI() ([]domain.Project, error) {
-	if s.mongoRepo == nil {
-		return nil, fmt.Errorf("MongoDB repository not available")
+// GetCachedProjectsOnRef retrieves the fully cached projects as read from ref
+// (each project's default branch when ref is empty)
+func (s *ProjectService) GetCachedProjectsOnRef(ref string) ([]domain.Project, error) {
+	if s.store == nil {
+		return nil, repository.ErrNoStore
 	}
 
-	return s.mongoRepo.GetProjectsWithOpenAPI()
+	return s.getCachedProjectsOnRef(ref)
+}
+
+// getCachedProjectsOnRef queries the cached documents of ref
+func (s *ProjectService) getCachedProjectsOnRef(ref string) ([]domain.Project, error) {
+	return s.store.GetCachedProjects(repository.RefFilter(ref))
 }
 
-// GetCachedProjects retrieves cached projects by search hash
-func (s *ProjectService) GetCachedProjects(searchHash string) ([]domain.Project, error) {
-	if s.mongoRepo == nil {
-		return nil, fmt.Errorf("MongoDB repository not available")
+// GetCachedProjects retrieves every cached project record, on all refs
+func (s *ProjectService) GetCachedProjects() ([]domain.Project, error) {
+	if s.store == nil {
+		return nil, repository.ErrNoStore
 	}
 
-	return s.mongoRepo.GetCachedProjects(searchHash)
+	return s.store.GetCachedProjects(repository.ProjectFilter{})
 }
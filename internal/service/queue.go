@@ -0,0 +1,273 @@
+// internal/service/queue.go
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"gitlab-list/internal/configuration"
+	"gitlab-list/internal/domain"
+)
+
+// Kinds of queued jobs
+const (
+	JobKindCacheLoad     = "cache-load"
+	JobKindCacheRefresh  = "cache-refresh"
+	JobKindProjectUpdate = "project-update"  // Library updates and vulnerability fixes; subject is the project ID
+	JobKindCampaign      = "campaign-update" // Subject is the campaign ID
+)
+
+// ErrQueueFull is returned by Submit when the queue holds as many waiting jobs as it can
+var ErrQueueFull = fmt.Errorf("job queue is full")
+
+// ErrJobNotQueued is returned for a job id the queue does not know, or no longer keeps
+var ErrJobNotQueued = fmt.Errorf("job not found")
+
+// QueueWork runs a queued job; ctx is canceled when the job is. The returned value becomes the job result.
+type QueueWork func(ctx context.Context, progress ProgressFunc) (interface{}, error)
+
+// ProgressFunc reports how far a job got: the step it is in, the project it works on and how many
+// of total items are done. A nil ProgressFunc ignores the reports.
+type ProgressFunc func(step, project string, done, total int)
+
+func (p ProgressFunc) report(step, project string, done, total int) {
+	if p != nil {
+		p(step, project, done, total)
+	}
+}
+
+// JobProgress is the last progress a job reported
+type JobProgress struct {
+	Step    string `json:"step,omitempty"`
+	Project string `json:"project,omitempty"`
+	Done    int    `json:"done"`
+	Total   int    `json:"total"`
+	Percent int    `json:"percent"`
+}
+
+// QueuedJob is the state of a job in the queue
+type QueuedJob struct {
+	ID         string      `json:"id"`
+	Kind       string      `json:"kind"`
+	Subject    string      `json:"subject,omitempty"` // What the job works on, e.g. the project id
+	Status     string      `json:"status"`
+	CreatedAt  time.Time   `json:"created_at"`
+	StartedAt  time.Time   `json:"started_at,omitzero"`
+	FinishedAt time.Time   `json:"finished_at,omitzero"`
+	Progress   JobProgress `json:"progress"`
+	Result     interface{} `json:"result,omitempty"`
+	Error      string      `json:"error,omitempty"`
+
+	work   QueueWork
+	cancel context.CancelFunc
+}
+
+// Finished reports whether the job reached a final state
+func (j *QueuedJob) Finished() bool {
+	switch j.Status {
+	case domain.JobQueued, domain.JobRunning:
+		return false
+	}
+	return true
+}
+
+// JobQueue runs long operations submitted through the API on a bounded pool of workers. Jobs are kept
+// in memory, with their progress and result, until retention after they finished.
+type JobQueue struct {
+	retention time.Duration
+
+	mu      sync.Mutex
+	jobs    map[string]*QueuedJob
+	pending chan *QueuedJob
+}
+
+// NewJobQueue starts workers that run jobs from a queue of capacity waiting jobs
+func NewJobQueue(workers, capacity int, retention time.Duration) *JobQueue {
+	if workers < 1 {
+		workers = 1
+	}
+	if capacity < 1 {
+		capacity = 1
+	}
+	q := &JobQueue{
+		retention: retention,
+		jobs:      make(map[string]*QueuedJob),
+		pending:   make(chan *QueuedJob, capacity),
+	}
+	for i := 0; i < workers; i++ {
+		go q.worker()
+	}
+	return q
+}
+
+// JobQueueFromConfig creates the job queue configured by JOB_WORKERS, JOB_QUEUE_SIZE and JOB_RETENTION
+func JobQueueFromConfig(cfg *configuration.Configuration) *JobQueue {
+	retention, err := time.ParseDuration(cfg.JobRetention)
+	if err != nil || retention <= 0 {
+		retention = 24 * time.Hour
+	}
+	log.Printf("Job queue: %d workers, %d waiting jobs, results kept for %s", cfg.JobWorkers, cfg.JobQueueSize, retention)
+	return NewJobQueue(cfg.JobWorkers, cfg.JobQueueSize, retention)
+}
+
+// Submit queues work and returns a copy of the queued job
+func (q *JobQueue) Submit(kind, subject string, work QueueWork) (QueuedJob, error) {
+	id, err := newJobID()
+	if err != nil {
+		return QueuedJob{}, err
+	}
+	job := &QueuedJob{
+		ID:        id,
+		Kind:      kind,
+		Subject:   subject,
+		Status:    domain.JobQueued,
+		CreatedAt: time.Now(),
+		work:      work,
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.prune()
+	select {
+	case q.pending <- job:
+	default:
+		return QueuedJob{}, ErrQueueFull
+	}
+	q.jobs[id] = job
+	return *job, nil
+}
+
+// Get returns a copy of a job
+func (q *JobQueue) Get(id string) (QueuedJob, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job, ok := q.jobs[id]
+	if !ok {
+		return QueuedJob{}, fmt.Errorf("%w: %s", ErrJobNotQueued, id)
+	}
+	return *job, nil
+}
+
+// List returns copies of the kept jobs, newest first, optionally only those of kind and subject
+func (q *JobQueue) List(kind, subject string) []QueuedJob {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.prune()
+
+	jobs := make([]QueuedJob, 0, len(q.jobs))
+	for _, job := range q.jobs {
+		if (kind == "" || job.Kind == kind) && (subject == "" || job.Subject == subject) {
+			jobs = append(jobs, *job)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].CreatedAt.After(jobs[j].CreatedAt) })
+	return jobs
+}
+
+// Cancel cancels a job. A waiting job never starts; a running one has its context canceled and is
+// marked canceled once its work returns. Canceling a finished job changes nothing.
+func (q *JobQueue) Cancel(id string) (QueuedJob, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job, ok := q.jobs[id]
+	if !ok {
+		return QueuedJob{}, fmt.Errorf("%w: %s", ErrJobNotQueued, id)
+	}
+	switch job.Status {
+	case domain.JobQueued:
+		job.Status = domain.JobCanceled
+		job.FinishedAt = time.Now()
+	case domain.JobRunning:
+		job.cancel()
+	}
+	return *job, nil
+}
+
+// worker runs waiting jobs one at a time
+func (q *JobQueue) worker() {
+	for job := range q.pending {
+		q.run(job)
+	}
+}
+
+// run runs one job and records its outcome; a panic fails the job instead of the process
+func (q *JobQueue) run(job *QueuedJob) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	q.mu.Lock()
+	if job.Status != domain.JobQueued {
+		q.mu.Unlock()
+		return
+	}
+	job.Status = domain.JobRunning
+	job.StartedAt = time.Now()
+	job.cancel = cancel
+	q.mu.Unlock()
+	log.Printf("Starting %s job %s %s", job.Kind, job.ID, job.Subject)
+
+	progress := func(step, project string, done, total int) {
+		percent := 0
+		if total > 0 {
+			percent = done * 100 / total
+		}
+		q.mu.Lock()
+		job.Progress = JobProgress{Step: step, Project: project, Done: done, Total: total, Percent: percent}
+		q.mu.Unlock()
+	}
+
+	var result interface{}
+	var err error
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("job panicked: %v", r)
+			}
+		}()
+		result, err = job.work(ctx, progress)
+	}()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job.FinishedAt = time.Now()
+	job.Result = result
+	job.work = nil
+	switch {
+	case ctx.Err() != nil:
+		job.Status = domain.JobCanceled
+		if err != nil {
+			job.Error = err.Error()
+		}
+	case err != nil:
+		job.Status = domain.JobFailed
+		job.Error = err.Error()
+	default:
+		job.Status = domain.JobSucceeded
+		job.Progress.Percent = 100
+	}
+	log.Printf("Job %s %s in %s", job.ID, job.Status, job.FinishedAt.Sub(job.StartedAt).Round(time.Millisecond))
+}
+
+// prune drops jobs that finished more than retention ago; the caller holds q.mu
+func (q *JobQueue) prune() {
+	cutoff := time.Now().Add(-q.retention)
+	for id, job := range q.jobs {
+		if job.Finished() && job.FinishedAt.Before(cutoff) {
+			delete(q.jobs, id)
+		}
+	}
+}
+
+func newJobID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate job id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,371 @@
+// internal/service/refresh.go
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gitlab-list/internal/configuration"
+	"gitlab-list/internal/domain"
+	"gitlab-list/internal/gitlab"
+	"gitlab-list/internal/repository"
+)
+
+// activityThrottle is how long GitLab may wait before it moves a project's last_activity_at again.
+// A record read less than this after the listed activity may predate a push that did not move it.
+const activityThrottle = time.Hour
+
+// minDetailCalls is the fewest API calls a detail fetch takes: branch, project, tree and one go.mod
+const minDetailCalls = 4
+
+// CacheTTLFromConfig reads CACHE_TTL; invalid or negative values disable forced refreshes
+func CacheTTLFromConfig(cfg *configuration.Configuration) time.Duration {
+	if d, err := time.ParseDuration(cfg.CacheTTL); err == nil && d >= 0 {
+		return d
+	}
+	return 0
+}
+
+// SetCacheTTL sets the age after which refreshes read a record again even though its branch head
+// did not move; 0 only re-reads moved heads
+func (s *ProjectService) SetCacheTTL(ttl time.Duration) {
+	s.cacheTTL = ttl
+}
+
+// RefreshSummary reports an incremental cache refresh
+type RefreshSummary struct {
+	Projects  int              `json:"projects"`  // Projects listed in GitLab
+	Records   int              `json:"records"`   // Cached (project, ref) records after the refresh
+	Unchanged int              `json:"unchanged"` // No activity since the record was read, or archived; no API call
+	Verified  int              `json:"verified"`  // Activity, but the branch head did not move; one API call
+	Expired   int              `json:"expired"`   // Read again because they are older than CACHE_TTL
+	Refetched int              `json:"refetched"` // New, expired and moved records read again
+	Skipped   int              `json:"skipped"`   // Empty repositories and missing branches
+	Failed    int              `json:"failed"`    // Records kept as they were after an error
+	Removed   int64            `json:"removed"`   // Records of projects and refs no longer found
+	Failures  []ProjectFailure `json:"failures,omitempty"`
+
+	NewProjects      int `json:"new_projects"`
+	RenamedProjects  int `json:"renamed_projects"`  // Path changed since the cached record
+	ArchivedProjects int `json:"archived_projects"` // Archived since the cached record
+	DeletedProjects  int `json:"deleted_projects"`  // Cached, but no longer listed
+
+	APICalls            int64  `json:"api_calls"`
+	FullRefreshAPICalls int64  `json:"full_refresh_api_calls"` // Estimate for reading every record again
+	APICallsSaved       int64  `json:"api_calls_saved"`
+	Duration            string `json:"duration"`
+}
+
+// refreshTarget is a (project, ref) record a refresh has to read again
+type refreshTarget struct {
+	job    detailJob
+	cached *repository.CachedProject // Nil for new records
+}
+
+// refreshPlan sorts the listed records by the work a refresh needs for them
+type refreshPlan struct {
+	keep      []domain.Project // Current records, with the listed metadata
+	fetch     []refreshTarget
+	unchanged int
+	verified  int
+	expired   int
+	skipped   int
+	failures  []ProjectFailure
+
+	newProjects, renamedProjects, archivedProjects, deletedProjects int
+}
+
+// RefreshCache brings the cache up to date in one pass over the project list. Details are only
+// read again for records whose branch head moved; the rest keep their cached details.
+func (s *ProjectService) RefreshCache(token string) (*RefreshSummary, error) {
+	return s.RefreshCacheContext(context.Background(), token, nil)
+}
+
+// RefreshCacheContext is RefreshCache as a queued or scheduled job: progress follows the branch checks
+// and detail fetches, and a canceled ctx stops the refresh before anything is written
+func (s *ProjectService) RefreshCacheContext(ctx context.Context, token string, progress ProgressFunc) (*RefreshSummary, error) {
+	if s.store == nil {
+		return nil, repository.ErrNoStore
+	}
+
+	lease, err := AcquireLease(s.store, LeaseCache, "cache refresh")
+	if err != nil {
+		return nil, err
+	}
+	defer lease.Release()
+
+	start := time.Now()
+	fetchedAt := start
+
+	repo := s.repo
+	if token != "" {
+		repo = repo.WithToken(token)
+	}
+	counter := &gitlab.CallCounter{}
+	repo = repo.WithCallCounter(counter)
+
+	progress.report("Listing projects", "", 0, 0)
+	projects, err := repo.GetProjects()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get projects: %w", err)
+	}
+	listCalls := counter.Calls()
+
+	plan, err := s.planRefresh(ctx, repo, projects, progress)
+	if err != nil {
+		return nil, err
+	}
+	summary := &RefreshSummary{
+		Projects:         len(projects),
+		Unchanged:        plan.unchanged,
+		Verified:         plan.verified,
+		Expired:          plan.expired,
+		Skipped:          plan.skipped,
+		Failures:         plan.failures,
+		NewProjects:      plan.newProjects,
+		RenamedProjects:  plan.renamedProjects,
+		ArchivedProjects: plan.archivedProjects,
+		DeletedProjects:  plan.deletedProjects,
+	}
+
+	beforeFetch := counter.Calls()
+	jobs := make([]detailJob, 0, len(plan.fetch))
+	for _, target := range plan.fetch {
+		jobs = append(jobs, target.job)
+	}
+	results, _ := s.fetchProjectDetailsContext(ctx, repo, jobs, progress)
+	fetchCalls := counter.Calls() - beforeFetch
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("cache refresh stopped before saving: %w", err)
+	}
+
+	records := plan.keep
+	for i, res := range results {
+		cached := plan.fetch[i].cached
+		switch {
+		case res.Skipped:
+			summary.Skipped++
+		case res.Err != nil:
+			summary.Failures = append(summary.Failures, ProjectFailure{
+				ProjectID:   res.Project.ID,
+				ProjectName: res.Project.Name,
+				Ref:         res.Project.Ref,
+				Error:       res.Err.Error(),
+			})
+			// Keep what we had rather than replace details with basic info
+			if cached != nil {
+				records = append(records, cached.Project)
+			} else {
+				records = append(records, res.Project)
+			}
+		default:
+			summary.Refetched++
+			records = append(records, res.Project)
+		}
+	}
+	summary.Failed = len(summary.Failures)
+
+	// Both writes fail once the lease moved on to another holder
+	if err := s.store.UpsertProjects(records, s.projectHashes(records), fetchedAt, lease.Fence()); err != nil {
+		return nil, fmt.Errorf("failed to cache projects: %w", err)
+	}
+	removed, err := s.store.PruneProjects(0, fetchedAt, lease.Fence())
+	if err != nil {
+		return nil, fmt.Errorf("failed to prune cache: %w", err)
+	}
+	summary.Removed = removed
+	summary.Records = len(records)
+
+	// Keep an immutable copy of this sync for trend queries
+	s.saveSnapshot(records)
+
+	// A full refresh lists the projects and reads every record; price a read at the average of this run
+	perRecord := int64(minDetailCalls)
+	if summary.Refetched > 0 && fetchCalls/int64(summary.Refetched) > perRecord {
+		perRecord = fetchCalls / int64(summary.Refetched)
+	}
+	summary.APICalls = counter.Calls()
+	summary.FullRefreshAPICalls = listCalls + int64(len(plan.keep)+len(plan.fetch))*perRecord
+	if saved := summary.FullRefreshAPICalls - summary.APICalls; saved > 0 {
+		summary.APICallsSaved = saved
+	}
+	summary.Duration = time.Since(start).Round(time.Millisecond).String()
+
+	fmt.Printf("Cache refreshed: %d records (%d unchanged, %d verified, %d refetched, %d failed, %d removed), %d API calls, %d saved\n",
+		summary.Records, summary.Unchanged, summary.Verified, summary.Refetched, summary.Failed, summary.Removed,
+		summary.APICalls, summary.APICallsSaved)
+	fmt.Printf("Projects: %d new, %d renamed, %d archived, %d deleted\n",
+		summary.NewProjects, summary.RenamedProjects, summary.ArchivedProjects, summary.DeletedProjects)
+	return summary, nil
+}
+
+// planRefresh compares the listed projects with the cache. Records without activity since they
+// were read are kept as is; the others have their branch head checked, concurrently.
+func (s *ProjectService) planRefresh(ctx context.Context, repo repository.ProjectRepository, projects []domain.Project, progress ProgressFunc) (*refreshPlan, error) {
+	cachedRecords, err := s.store.GetCachedProjectsWithHashes(repository.ProjectFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cached projects: %w", err)
+	}
+	cached := make(map[string]repository.CachedProject, len(cachedRecords))
+	for _, entry := range cachedRecords {
+		cached[entry.Key] = entry
+	}
+
+	plan := &refreshPlan{}
+	plan.countProjectChanges(projects, cachedRecords)
+
+	var checks []refreshTarget
+	for _, job := range detailJobs(projects, s.branches) {
+		ref := resolveRef(job.Project, job.Ref)
+		entry, ok := cached[repository.ProjectKey(domain.Project{ID: job.Project.ID, Ref: ref})]
+		switch {
+		case job.Project.EmptyRepo || ref == "":
+			plan.skipped++
+		case !ok || entry.Project.CommitSHA == "":
+			plan.fetch = append(plan.fetch, refreshTarget{job: job})
+		case job.Project.Archived:
+			// Archived repositories are read-only; their records never change
+			plan.unchanged++
+			plan.keep = append(plan.keep, withListedMetadata(entry.Project, job.Project))
+		case s.expired(entry.Project):
+			plan.expired++
+			plan.fetch = append(plan.fetch, refreshTarget{job: job, cached: &entry})
+		case inactiveSince(job.Project, entry):
+			plan.unchanged++
+			plan.keep = append(plan.keep, withListedMetadata(entry.Project, job.Project))
+		default:
+			checks = append(checks, refreshTarget{job: job, cached: &entry})
+		}
+	}
+
+	heads, errs := s.branchHeads(ctx, repo, checks, progress)
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("cache refresh stopped: %w", err)
+	}
+	for i, target := range checks {
+		switch {
+		case gitlab.IsNotFound(errs[i]):
+			// The branch is gone; its record is pruned
+			plan.skipped++
+		case errs[i] != nil:
+			plan.failures = append(plan.failures, ProjectFailure{
+				ProjectID:   target.job.Project.ID,
+				ProjectName: target.job.Project.Name,
+				Ref:         target.cached.Project.Ref,
+				Error:       errs[i].Error(),
+			})
+			plan.keep = append(plan.keep, target.cached.Project)
+		case heads[i] == target.cached.Project.CommitSHA:
+			plan.verified++
+			plan.keep = append(plan.keep, withListedMetadata(target.cached.Project, target.job.Project))
+		default:
+			target.job.Head = heads[i]
+			plan.fetch = append(plan.fetch, target)
+		}
+	}
+	return plan, nil
+}
+
+// countProjectChanges compares the listed projects with the cached ones by project ID
+func (p *refreshPlan) countProjectChanges(projects []domain.Project, cachedRecords []repository.CachedProject) {
+	cached := make(map[int]domain.Project)
+	for _, entry := range cachedRecords {
+		if _, ok := cached[entry.Project.ID]; !ok || entry.Project.OnRef("") {
+			cached[entry.Project.ID] = entry.Project
+		}
+	}
+
+	listed := make(map[int]bool, len(projects))
+	for _, project := range projects {
+		listed[project.ID] = true
+		old, ok := cached[project.ID]
+		switch {
+		case !ok:
+			p.newProjects++
+		case old.Path != project.Path:
+			p.renamedProjects++
+		}
+		if ok && project.Archived && !old.Archived {
+			p.archivedProjects++
+		}
+	}
+	for id := range cached {
+		if !listed[id] {
+			p.deletedProjects++
+		}
+	}
+}
+
+// expired reports whether a record's details are older than the cache TTL. Records cached
+// before the read time was tracked count as expired.
+func (s *ProjectService) expired(record domain.Project) bool {
+	return s.cacheTTL > 0 && time.Since(record.DetailsReadAt) > s.cacheTTL
+}
+
+// inactiveSince reports whether a listed project shows no activity the cached record could have missed
+func inactiveSince(listed domain.Project, entry repository.CachedProject) bool {
+	if listed.LastActivity.IsZero() || entry.Project.LastActivity.IsZero() {
+		return false
+	}
+	if listed.LastActivity.After(entry.Project.LastActivity) {
+		return false
+	}
+	return entry.FetchedAt.After(entry.Project.LastActivity.Add(activityThrottle))
+}
+
+// withListedMetadata updates a cached record with the project fields of the list, which catch
+// renames and moves without reading the details again
+func withListedMetadata(record, listed domain.Project) domain.Project {
+	record.Name = listed.Name
+	record.Path = listed.Path
+	record.WebURL = listed.WebURL
+	record.Description = listed.Description
+	record.DefaultBranch = listed.DefaultBranch
+	record.Archived = listed.Archived
+	record.LastActivity = listed.LastActivity
+	return record
+}
+
+// branchHeads reads the head commit of every target's branch with a bounded worker pool; once ctx is
+// done the remaining targets fail with its error
+func (s *ProjectService) branchHeads(ctx context.Context, repo repository.ProjectRepository, targets []refreshTarget, progress ProgressFunc) ([]string, []error) {
+	heads := make([]string, len(targets))
+	errs := make([]error, len(targets))
+
+	workers := s.detailWorkers
+	if workers <= 0 {
+		workers = defaultDetailWorkers
+	}
+	indexes := make(chan int)
+	var done atomic.Int64
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(targets); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if err := ctx.Err(); err != nil {
+					errs[i] = err
+					continue
+				}
+				branch, err := repo.GetBranch(targets[i].job.Project.ID, targets[i].cached.Project.Ref)
+				progress.report("Checking branch heads", targets[i].job.Project.Path, int(done.Add(1)), len(targets))
+				if err != nil {
+					errs[i] = err
+					continue
+				}
+				heads[i] = branch.CommitSHA
+			}
+		}()
+	}
+	for i := range targets {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return heads, errs
+}
@@ -9,19 +9,22 @@ import (
 	"strings"
 
 	"gitlab-list/internal/configuration"
+	"gitlab-list/internal/gitlab"
+	"gitlab-list/internal/gomod"
 
 	"golang.org/x/mod/modfile"
 )
 
 type ArchScanner struct {
 	cfg     *configuration.Configuration
+	client  *gitlab.Client
 	ref     string
 	ignores []string
 	roots   []string // only scan these dir prefixes in repo; default: cmd,internal,pkg
 }
 
-func NewArchScanner(cfg *configuration.Configuration) *ArchScanner {
-	return &ArchScanner{cfg: cfg, roots: []string{"cmd", "internal", "pkg"}}
+func NewArchScanner(cfg *configuration.Configuration, client *gitlab.Client) *ArchScanner {
+	return &ArchScanner{cfg: cfg, client: client, roots: []string{"cmd", "internal", "pkg"}}
 }
 
 func (s *ArchScanner) SetRef(ref string) *ArchScanner {
@@ -41,7 +44,7 @@ func (s *ArchScanner) SetRoots(roots ...string) *ArchScanner {
 
 func (s *ArchScanner) ScanGraph() (*graph.Graph, error) {
 	g := &graph.Graph{Nodes: []graph.Node{}, Edges: []graph.Edge{}}
-	projects := GetProjects(*s.cfg)
+	projects := GetProjects(s.client, s.cfg.Group)
 
 	// Handy indexers
 	nodeIdx := map[string]bool{}
@@ -60,39 +63,37 @@ func (s *ArchScanner) ScanGraph() (*graph.Graph, error) {
 		if shouldIgnore(p.Path, s.ignores) {
 			continue
 		}
-		goMod := GetGoMod(*s.cfg, p.ID, p.Name, s.ref)
-		if len(goMod) == 0 {
-			continue
-		}
+		// one service node per go.mod in the repository
+		for _, goMod := range GetGoMods(s.client, p.ID, s.ref) {
+			mod, serviceShort := parseModuleID(goMod.Data) // "git.prosoftke.sk/nghis/services/drg", "drg"
+			svcID := "svc:" + serviceShort
+			addNode(svcID, graph.NodeService, map[string]string{
+				"module": mod,
+				"path":   p.Path,
+				"dir":    goMod.Dir,
+				"label":  serviceShort, // used by Mermaid writer
+			})
 
-		// service
-		mod, serviceShort := parseModuleID(goMod) // "git.prosoftke.sk/nghis/services/drg", "drg"
-		svcID := "svc:" + serviceShort
-		addNode(svcID, graph.NodeService, map[string]string{
-			"module": mod,
-			"path":   p.Path,
-			"label":  serviceShort, // used by Mermaid writer
-		})
-
-		// --- Dependencies via go.mod (treat ALL requires as clients)
-		reqs, _ := parseRequiresEffective(goMod) // keep replace support
-		for mpath, ver := range reqs {
-			if !isClientModule(mpath) {
-				continue // skip normal libraries
-			}
+			// --- Dependencies via go.mod (treat ALL requires as clients)
+			reqs, _ := parseRequiresEffective(goMod.Data) // keep replace support
+			for mpath, ver := range reqs {
+				if !isClientModule(mpath) {
+					continue // skip normal libraries
+				}
 
-			depID := "dep:" + mpath
-			addNode(depID, graph.NodeClient, map[string]string{
-				"module": mpath,
-				"label":  deriveClientLabel(mpath), // e.g. "nghisclinicalclient/v2"
-			})
-			addEdge(graph.Edge{
-				From:     svcID, // svcID like "svc:drg"
-				To:       depID,
-				Rel:      "calls", // or "depends"
-				Version:  ver,
-				Evidence: []graph.Evidence{{Hint: "require go.mod"}},
-			})
+				depID := "dep:" + mpath
+				addNode(depID, graph.NodeClient, map[string]string{
+					"module": mpath,
+					"label":  deriveClientLabel(mpath), // e.g. "nghisclinicalclient/v2"
+				})
+				addEdge(graph.Edge{
+					From:     svcID, // svcID like "svc:drg"
+					To:       depID,
+					Rel:      "calls", // or "depends"
+					Version:  ver,
+					Evidence: []graph.Evidence{{Hint: "require " + gomod.GoModPath(goMod.Dir)}},
+				})
+			}
 		}
 
 		// --- Kafka topics via grep-like scanning
@@ -102,7 +103,7 @@ func (s *ArchScanner) ScanGraph() (*graph.Graph, error) {
 }
 
 func (s *ArchScanner) scanKafkaArch() {
-	//files := internal.ListRepoFiles(*s.cfg, p.ID, s.ref) // returns []internal.File {Path string, Type string}
+	//files := internal.ListRepoFiles(s.client, p.ID, s.ref) // returns []internal.File {Path string, Type string}
 	//for _, f := range files {
 	//	if f.Type != "blob" || !strings.HasSuffix(f.Path, ".go") {
 	//		continue
@@ -113,7 +114,7 @@ func (s *ArchScanner) scanKafkaArch() {
 	//	if shouldIgnore(f.Path, s.ignores) {
 	//		continue
 	//	}
-	//	src := internal.GetRawFileBytes(*s.cfg, p.ID, f.Path, s.ref)
+	//	src := internal.GetRawFileBytes(s.client, p.ID, f.Path, s.ref)
 	//	if len(src) == 0 {
 	//		continue
 	//	}
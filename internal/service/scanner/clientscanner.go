@@ -3,18 +3,20 @@ package scanner
 import (
 	"fmt"
 	"gitlab-list/internal/configuration"
+	"gitlab-list/internal/gitlab"
 	"strings"
 )
 
 type ClientScanner struct {
 	cfg           *configuration.Configuration
+	client        *gitlab.Client
 	prefixes      []string // module path prefixes that mark "clients"
 	ignores       []string // substrings to ignore in project path
 	printFullPath bool
 }
 
-func NewClientScanner(cfg *configuration.Configuration) *ClientScanner {
-	return &ClientScanner{cfg: cfg}
+func NewClientScanner(cfg *configuration.Configuration, client *gitlab.Client) *ClientScanner {
+	return &ClientScanner{cfg: cfg, client: client}
 }
 
 func (s *ClientScanner) SetPrefixes(prefixes ...string) *ClientScanner {
@@ -33,33 +35,31 @@ func (s *ClientScanner) SetPrintFullPath(v bool) *ClientScanner {
 }
 
 func (s *ClientScanner) Scan() {
-	projects := GetProjects(*s.cfg)
+	projects := GetProjects(s.client, s.cfg.Group)
 
 	for _, project := range projects {
 		if shouldIgnore(project.Path, s.ignores) {
 			continue
 		}
 
-		goMod := GetGoMod(*s.cfg, project.ID, project.Name)
-		if len(goMod) == 0 {
-			continue
-		}
-
-		reqs, err := parseRequireBytes(goMod)
-		if err != nil {
-			// If parsing fails, just skip this project; or log if you prefer.
-			continue
-		}
-
-		for modPath, modVersion := range reqs {
-			if !s.matchesAnyPrefix(modPath) {
+		for _, goMod := range GetGoMods(s.client, project.ID, "") {
+			reqs, err := parseRequireBytes(goMod.Data)
+			if err != nil {
+				// If parsing fails, just skip this module; or log if you prefer.
 				continue
 			}
-			label := deriveClientLabel(modPath)
-			if s.printFullPath {
-				fmt.Printf("%s : %s -> %s (module: %s)\n", project.Name, label, modVersion, modPath)
-			} else {
-				fmt.Printf("%s : %s -> %s\n", project.Name, label, modVersion)
+
+			name := goMod.Label(project.Name)
+			for modPath, modVersion := range reqs {
+				if !s.matchesAnyPrefix(modPath) {
+					continue
+				}
+				label := deriveClientLabel(modPath)
+				if s.printFullPath {
+					fmt.Printf("%s : %s -> %s (module: %s)\n", name, label, modVersion, modPath)
+				} else {
+					fmt.Printf("%s : %s -> %s\n", name, label, modVersion)
+				}
 			}
 		}
 	}
@@ -33,18 +33,6 @@ func parseRequireBytes(goMod []byte) (map[string]string, error) {
 	return out, nil
 }
 
-// parseGoVersionBytes returns the "go" directive version (e.g., "1.22.3") from go.mod bytes.
-func parseGoVersionBytes(goMod []byte) (string, error) {
-	f, err := modfile.Parse("go.mod", goMod, nil)
-	if err != nil {
-		return "", err
-	}
-	if f.Go != nil && f.Go.Version != "" {
-		return f.Go.Version, nil
-	}
-	return "", nil
-}
-
 // deriveClientLabel tries to extract a friendly label from a module path like
 // ".../openapi/clients/go/client/v3/user-service" -> "client/v3/user-service".
 // Falls back to the full module path if pattern not found.
@@ -1,12 +1,11 @@
 package scanner
 
 import (
-	"fmt"
 	"log"
 
 	"gitlab-list/internal/configuration"
-
-	"golang.org/x/mod/semver"
+	"gitlab-list/internal/gitlab"
+	"gitlab-list/internal/policy"
 )
 
 type Scanner interface {
@@ -16,11 +15,12 @@ type Scanner interface {
 type GoScanner struct {
 	minimalVersion string
 	cfg            *configuration.Configuration
+	client         *gitlab.Client
 	ignores        []string
 }
 
-func NewGoScanner(cfg *configuration.Configuration) *GoScanner {
-	return &GoScanner{cfg: cfg}
+func NewGoScanner(cfg *configuration.Configuration, client *gitlab.Client) *GoScanner {
+	return &GoScanner{cfg: cfg, client: client}
 }
 
 func (s *GoScanner) SetParams(minimalVersion string) *GoScanner {
@@ -33,35 +33,21 @@ func (s *GoScanner) SetIgnore(ignores ...string) *GoScanner {
 	return s
 }
 
+// Scan prints the modules whose go directive is below the minimal version.
+// It is a single go_version rule of the policy engine.
 func (s *GoScanner) Scan() {
 	if s.minimalVersion == "" {
 		return
 	}
-	projects := GetProjects(*s.cfg)
-
-	for _, project := range projects {
-		if shouldIgnore(project.Path, s.ignores) {
-			continue
-		}
-
-		goMod := GetGoMod(*s.cfg, project.ID, project.Name) // []byte
-		if len(goMod) == 0 {
-			continue
-		}
 
-		goVersion, err := parseGoVersionBytes(goMod)
-		if err != nil {
-			log.Printf("parse go.mod failed for %s: %v", project.Path, err)
-			continue
-		}
-		if goVersion == "" {
-			log.Printf("Could not find Go version in %s", project.Path)
-			continue
-		}
-
-		// semver.Compare expects a leading "v"
-		if semver.Compare("v"+goVersion, "v"+s.minimalVersion) < 0 {
-			fmt.Printf("%s : %s\n", project.Name, goVersion)
-		}
+	p, err := policy.New(policy.Rule{Name: "minimal-go", Type: policy.RuleGoVersion, Min: s.minimalVersion})
+	if err != nil {
+		log.Printf("invalid minimal Go version %q: %v", s.minimalVersion, err)
+		return
 	}
+
+	NewPolicyScanner(s.cfg, s.client).
+		SetPolicy(p).
+		SetIgnore(s.ignores...).
+		Evaluate()
 }
@@ -0,0 +1,87 @@
+package scanner
+
+import (
+	"fmt"
+	"log"
+
+	"gitlab-list/internal/configuration"
+	"gitlab-list/internal/domain"
+	"gitlab-list/internal/gitlab"
+	"gitlab-list/internal/gomod"
+	"gitlab-list/internal/policy"
+	"gitlab-list/internal/repository"
+)
+
+// PolicyScanner evaluates a dependency policy against the go.mod files of every project in the group
+type PolicyScanner struct {
+	cfg     *configuration.Configuration
+	client  *gitlab.Client
+	policy  *policy.Policy
+	ref     string
+	ignores []string
+}
+
+func NewPolicyScanner(cfg *configuration.Configuration, client *gitlab.Client) *PolicyScanner {
+	return &PolicyScanner{cfg: cfg, client: client}
+}
+
+func (s *PolicyScanner) SetPolicy(p *policy.Policy) *PolicyScanner {
+	s.policy = p
+	return s
+}
+
+func (s *PolicyScanner) SetRef(ref string) *PolicyScanner {
+	s.ref = ref
+	return s
+}
+
+func (s *PolicyScanner) SetIgnore(ignores ...string) *PolicyScanner {
+	s.ignores = append([]string{}, ignores...)
+	return s
+}
+
+// Scan prints every violation as "project[/dir] : [severity] rule: message"
+func (s *PolicyScanner) Scan() {
+	s.Evaluate()
+}
+
+// Evaluate reads the projects' go.mod files, prints the violations and returns the report
+func (s *PolicyScanner) Evaluate() *policy.Report {
+	if s.policy == nil {
+		return &policy.Report{}
+	}
+
+	var projects []domain.Project
+	for _, p := range GetProjects(s.client, s.cfg.Group) {
+		if shouldIgnore(p.Path, s.ignores) {
+			continue
+		}
+
+		project := domain.Project{ID: p.ID, Name: p.Name, Path: p.Path, Ref: s.ref}
+		for _, goMod := range GetGoMods(s.client, p.ID, s.ref) {
+			module, err := repository.ParseModule(gomod.GoModPath(goMod.Dir), goMod.Data)
+			if err != nil {
+				log.Printf("parse go.mod failed for %s (%s): %v", p.Path, goMod.Dir, err)
+				continue
+			}
+			module.Dir = goMod.Dir
+			project.Modules = append(project.Modules, module)
+		}
+		if len(project.Modules) > 0 {
+			projects = append(projects, project)
+		}
+	}
+
+	report := policy.Evaluate(s.policy, projects)
+	report.Ref = s.ref
+	for _, result := range report.Projects {
+		for _, v := range result.Violations {
+			dir := v.ModuleDir
+			if dir == "" {
+				dir = gomod.RootDir
+			}
+			fmt.Printf("%s : [%s] %s: %s\n", GoModFile{Dir: dir}.Label(result.Name), v.Severity, v.Rule, v.Message)
+		}
+	}
+	return report
+}
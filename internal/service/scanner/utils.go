@@ -2,48 +2,45 @@ package scanner
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
 	"net/url"
-	"strconv"
-	"strings"
 
-	"gitlab-list/internal/configuration"
+	"gitlab-list/internal/gitlab"
+	"gitlab-list/internal/gomod"
 
 	"golang.org/x/mod/modfile"
 )
 
-const gitlabAPI = "https://git.prosoftke.sk/api/v4"
-
 type Project struct {
 	ID   int    `json:"id"`
 	Name string `json:"name"`
 	Path string `json:"path_with_namespace"`
 }
 
-func GetProjects(cfg configuration.Configuration) []Project {
-
+// GetProjects lists the projects of group and its subgroups
+func GetProjects(client *gitlab.Client, group string) []Project {
 	var projects []Project
 	page := 1
 
 	for {
-		//groupPath := "nghis/services"
-		groupPath := "nghis"
-		escaped := url.PathEscape(groupPath) // results in "nghis%2Fservices"
-
-		url := fmt.Sprintf("%s/groups/%s/projects?include_subgroups=true&per_page=100&page=%d", gitlabAPI, escaped, page)
-		resp, err := GitlabRequest(cfg.Token, url)
-		defer resp.Body.Close()
+		groupPath := group
+		if groupPath == "" {
+			groupPath = "nghis"
+		}
+		escaped := url.PathEscape(groupPath) // e.g. "nghis/services" -> "nghis%2Fservices"
 
+		path := fmt.Sprintf("/groups/%s/projects?include_subgroups=true&per_page=100&page=%d", escaped, page)
+		resp, err := client.Get(path)
 		if err != nil {
-			log.Printf("There was a problem getting the data")
+			log.Printf("There was a problem getting the data: %v", err)
 			return nil
 		}
+
 		var pageProjects []Project
-		if err = json.NewDecoder(resp.Body).Decode(&pageProjects); err != nil {
+		err = json.NewDecoder(resp.Body).Decode(&pageProjects)
+		resp.Body.Close()
+		if err != nil {
 			log.Fatalf("Failed to parse projects: %v", err)
 		}
 
@@ -61,28 +58,78 @@ func GetProjects(cfg configuration.Configuration) []Project {
 	return projects
 }
 
-// internal/gitlab.go (or wherever GetGoMod lives)
-func GetGoMod(cfg configuration.Configuration, projectID int, projectName string, ref ...string) []byte {
-	u := fmt.Sprintf("%s/projects/%d/repository/files/%s/raw", gitlabAPI, projectID, url.PathEscape("go.mod"))
-	if len(ref) > 0 && ref[0] != "" {
-		u += "?ref=" + url.QueryEscape(ref[0])
+// GetGoMod downloads the root go.mod of a project, optionally at a specific ref
+func GetGoMod(client *gitlab.Client, projectID int, projectName string, ref ...string) []byte {
+	r := ""
+	if len(ref) > 0 {
+		r = ref[0]
 	}
-	resp, err := GitlabRequest(cfg.Token, u)
-	defer func() {
-		if resp != nil {
-			resp.Body.Close()
-		}
-	}()
+	body, err := client.GetRawFile(projectID, "go.mod", r)
 	if err != nil {
 		return nil
 	}
+	return body
+}
+
+// GoModFile is a go.mod found in a project's repository
+type GoModFile struct {
+	Dir  string // Directory relative to the repository root, "." for the root
+	Data []byte
+}
+
+// Label names the module for output: the project name, plus the directory for nested modules
+func (f GoModFile) Label(projectName string) string {
+	if f.Dir == gomod.RootDir {
+		return projectName
+	}
+	return projectName + "/" + f.Dir
+}
 
-	body, err := io.ReadAll(resp.Body)
+// GetGoMods downloads every go.mod of a project (root first), including modules named by a root go.work
+func GetGoMods(client *gitlab.Client, projectID int, ref string) []GoModFile {
+	tree, err := client.ListTree(projectID, ref)
 	if err != nil {
-		log.Printf("Failed to read go.mod for %s: %v", projectName, err)
+		log.Printf("GetGoMods: listing files failed for project %d: %v", projectID, err)
 		return nil
 	}
-	return body
+
+	var paths []string
+	files := make(map[string]bool, len(tree))
+	for _, entry := range tree {
+		if entry.Type == "blob" {
+			paths = append(paths, entry.Path)
+			files[entry.Path] = true
+		}
+	}
+
+	dirs := gomod.ModuleDirs(paths)
+	if files["go.work"] {
+		if data, err := client.GetRawFile(projectID, "go.work", ref); err == nil {
+			if ws, err := gomod.ParseWork(gomod.RootDir, data); err == nil {
+				seen := make(map[string]bool, len(dirs))
+				for _, dir := range dirs {
+					seen[dir] = true
+				}
+				for _, dir := range ws.Use {
+					if !seen[dir] && files[gomod.GoModPath(dir)] {
+						dirs = append(dirs, dir)
+						seen[dir] = true
+					}
+				}
+			}
+		}
+	}
+
+	var out []GoModFile
+	for _, dir := range dirs {
+		data, err := client.GetRawFile(projectID, gomod.GoModPath(dir), ref)
+		if err != nil {
+			log.Printf("GetGoMods: request failed for %s in project %d: %v", gomod.GoModPath(dir), projectID, err)
+			continue
+		}
+		out = append(out, GoModFile{Dir: dir, Data: data})
+	}
+	return out
 }
 
 //func ExtractModuleVersion(data []byte, module string) string {
@@ -100,23 +147,6 @@ func GetGoMod(cfg configuration.Configuration, projectID int, projectName string
 //	return ""
 //}
 
-func GitlabRequest(token, url string) (*http.Response, error) {
-	req, _ := http.NewRequest("GET", url, nil)
-	req.Header.Set("PRIVATE-TOKEN", token)
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, errors.New(fmt.Sprintf("Request failed: %v", err))
-
-	}
-
-	if resp.StatusCode >= 400 {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, errors.New(fmt.Sprintf("GitLab error: %s\nResponse: %s", resp.Status, string(body)))
-	}
-	return resp, nil
-}
-
 func ExtractGoVersion(data []byte) string {
 	f, err := modfile.Parse("go.mod", data, nil)
 	if err != nil {
@@ -127,89 +157,25 @@ func ExtractGoVersion(data []byte) string {
 }
 
 // File represents an entry from the GitLab repository tree API.
-type File struct {
-	ID   string `json:"id,omitempty"`
-	Path string `json:"path"`
-	Type string `json:"type"` // "blob" (file) or "tree" (directory)
-	Name string `json:"name,omitempty"`
-	Mode string `json:"mode,omitempty"`
-}
+type File = gitlab.TreeEntry
 
 // ListRepoFiles lists files (and directories) for a repo.
 // It walks the tree with ?recursive=true and handles pagination.
 // If ref is empty, GitLab uses the default branch.
-func ListRepoFiles(cfg configuration.Configuration, projectID int, ref string) []File {
-	perPage := 100
-	page := 1
-	var out []File
-
-	for {
-		u := fmt.Sprintf("%s/projects/%d/repository/tree?recursive=true&per_page=%d&page=%d",
-			gitlabAPI, projectID, perPage, page)
-		if strings.TrimSpace(ref) != "" {
-			u += "&ref=" + url.QueryEscape(ref)
-		}
-
-		resp, err := GitlabRequest(cfg.Token, u)
-		if err != nil {
-			log.Printf("ListRepoFiles: request failed for project %d: %v", projectID, err)
-			return out
-		}
-		func() {
-			defer resp.Body.Close()
-			if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-				b, _ := io.ReadAll(resp.Body)
-				log.Printf("ListRepoFiles: bad status %d for project %d: %s", resp.StatusCode, projectID, string(b))
-				return
-			}
-			var batch []File
-			if err := json.NewDecoder(resp.Body).Decode(&batch); err != nil {
-				log.Printf("ListRepoFiles: decode failed for project %d: %v", projectID, err)
-				return
-			}
-			out = append(out, batch...)
-		}()
-
-		// GitLab pagination via X-Next-Page header
-		next := resp.Header.Get("X-Next-Page")
-		if next == "" {
-			break
-		}
-		n, _ := strconv.Atoi(next)
-		if n <= page {
-			break
-		}
-		page = n
+func ListRepoFiles(client *gitlab.Client, projectID int, ref string) []File {
+	files, err := client.ListTree(projectID, ref)
+	if err != nil {
+		log.Printf("ListRepoFiles: request failed for project %d: %v", projectID, err)
 	}
-	return out
+	return files
 }
 
 // GetRawFileBytes downloads a file's raw content at path for a given ref (branch/commit/tag).
-// Path must be URL-encoded as a path segment; url.PathEscape handles slashes -> %2F.
-func GetRawFileBytes(cfg configuration.Configuration, projectID int, filePath, ref string) []byte {
-	escaped := url.PathEscape(filePath) // encodes "/" as %2F (required by GitLab API)
-	u := fmt.Sprintf("%s/projects/%d/repository/files/%s/raw", gitlabAPI, projectID, escaped)
-	if strings.TrimSpace(ref) != "" {
-		u += "?ref=" + url.QueryEscape(ref)
-	}
-
-	resp, err := GitlabRequest(cfg.Token, u)
+func GetRawFileBytes(client *gitlab.Client, projectID int, filePath, ref string) []byte {
+	b, err := client.GetRawFile(projectID, filePath, ref)
 	if err != nil {
 		log.Printf("GetRawFileBytes: request failed for %s: %v", filePath, err)
 		return nil
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		b, _ := io.ReadAll(resp.Body)
-		log.Printf("GetRawFileBytes: bad status %d for %s: %s", resp.StatusCode, filePath, string(b))
-		return nil
-	}
-
-	b, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Printf("GetRawFileBytes: read failed for %s: %v", filePath, err)
-		return nil
-	}
 	return b
 }
@@ -0,0 +1,108 @@
+// internal/service/schedule.go
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"gitlab-list/internal/domain"
+	"gitlab-list/internal/repository"
+)
+
+// ScheduleService lets the API inspect and steer the scheduled jobs. It works on the state the
+// scheduler shares through the store, so both may run in different processes.
+type ScheduleService struct {
+	store repository.CacheStore
+}
+
+// JobStatus is a scheduled job with its latest runs
+type JobStatus struct {
+	domain.ScheduledJob
+	LastRun *domain.JobRun  `json:"last_run,omitempty"`
+	Runs    []domain.JobRun `json:"runs,omitempty"`
+}
+
+// NewScheduleService creates a schedule service over store, which may be nil
+func NewScheduleService(store repository.CacheStore) *ScheduleService {
+	return &ScheduleService{store: store}
+}
+
+// ListJobs returns every registered job with its last run
+func (s *ScheduleService) ListJobs() ([]JobStatus, error) {
+	if s.store == nil {
+		return nil, repository.ErrNoStore
+	}
+
+	jobs, err := s.store.ListJobs()
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]JobStatus, 0, len(jobs))
+	for _, job := range jobs {
+		status := JobStatus{ScheduledJob: job}
+		runs, err := s.store.ListJobRuns(job.Name, 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(runs) > 0 {
+			status.LastRun = &runs[0]
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// GetJob returns one job with its latest runs, at most limit (0 = all)
+func (s *ScheduleService) GetJob(name string, limit int) (*JobStatus, error) {
+	job, err := s.job(name)
+	if err != nil {
+		return nil, err
+	}
+	runs, err := s.store.ListJobRuns(name, limit)
+	if err != nil {
+		return nil, err
+	}
+	status := &JobStatus{ScheduledJob: *job, Runs: runs}
+	if len(runs) > 0 {
+		status.LastRun = &runs[0]
+	}
+	return status, nil
+}
+
+// Trigger asks the scheduler to run a job now; it starts within SCHEDULER_POLL
+func (s *ScheduleService) Trigger(name string) (*domain.ScheduledJob, error) {
+	if s.store == nil {
+		return nil, repository.ErrNoStore
+	}
+	if err := s.store.RequestJobRun(name, time.Now()); err != nil {
+		return nil, err
+	}
+	return s.job(name)
+}
+
+// SetPaused pauses or resumes the scheduled runs of a job
+func (s *ScheduleService) SetPaused(name string, paused bool) (*domain.ScheduledJob, error) {
+	if s.store == nil {
+		return nil, repository.ErrNoStore
+	}
+	if err := s.store.SetJobPaused(name, paused); err != nil {
+		return nil, err
+	}
+	return s.job(name)
+}
+
+func (s *ScheduleService) job(name string) (*domain.ScheduledJob, error) {
+	if s.store == nil {
+		return nil, repository.ErrNoStore
+	}
+	jobs, err := s.store.ListJobs()
+	if err != nil {
+		return nil, err
+	}
+	for _, job := range jobs {
+		if job.Name == name {
+			return &job, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", repository.ErrJobNotFound, name)
+}
@@ -1,23 +1,73 @@
 package service
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"gitlab-list/internal/configuration"
+	"gitlab-list/internal/domain"
+	"gitlab-list/internal/repository"
 
 	"github.com/robfig/cron/v3"
 )
 
-// SchedulerService handles scheduled tasks
+// jobLogLines is how many lines of job output are kept with a run
+const jobLogLines = 50
+
+// JobFunc runs a scheduled job; ctx ends when the job's timeout expires
+type JobFunc func(ctx context.Context, out *JobLog) (JobResult, error)
+
+// JobResult is what a job reports besides its output
+type JobResult struct {
+	Metrics map[string]int64
+	Partial bool // Finished, but some items failed
+}
+
+// JobDefinition configures one named scheduled job
+type JobDefinition struct {
+	Name        string
+	Description string
+	Schedule    string        // Cron expression
+	Enabled     bool          // Disabled jobs are not scheduled but can still be triggered
+	Timeout     time.Duration // 0 means no timeout
+	Run         JobFunc
+}
+
+// scheduledJob is a registered job and its cron entry
+type scheduledJob struct {
+	JobDefinition
+	entry   cron.EntryID
+	running atomic.Bool
+}
+
+// SchedulerService runs the named jobs on their schedules and records every run in the store.
+// Pause flags and "run now" requests set through the API are picked up from the store. When several
+// replicas share a store, only the one holding the LeaseScheduler lease runs jobs.
 type SchedulerService struct {
-	projectService *ProjectService
-	cron           *cron.Cron
-	config         *configuration.Configuration
+	store  repository.CacheStore
+	cron   *cron.Cron
+	config *configuration.Configuration
+	poll   time.Duration
+
+	mu     sync.Mutex
+	jobs   []*scheduledJob
+	paused map[string]bool
+	leader *Lease    // Nil while another replica leads
+	heldAt time.Time // Last time the leader lease was known to be held
+
+	stop chan struct{}
+	runs sync.WaitGroup
 }
 
-// NewSchedulerService creates a new scheduler service
-func NewSchedulerService(projectService *ProjectService, config *configuration.Configuration) *SchedulerService {
+// NewSchedulerService creates a new scheduler service. store may be nil; runs are then only logged.
+func NewSchedulerService(store repository.CacheStore, config *configuration.Configuration) *SchedulerService {
 	// Create cron with timezone support
 	location, err := time.LoadLocation(config.Timezone)
 	if err != nil {
@@ -27,63 +77,344 @@ func NewSchedulerService(projectService *ProjectService, config *configuration.C
 
 	c := cron.New(cron.WithLocation(location))
 
+	poll, err := time.ParseDuration(config.SchedulerPoll)
+	if err != nil || poll <= 0 {
+		poll = 15 * time.Second
+	}
+
 	return &SchedulerService{
-		projectService: projectService,
-		cron:           c,
-		config:         config,
+		store:  store,
+		cron:   c,
+		config: config,
+		poll:   poll,
+		paused: make(map[string]bool),
+		stop:   make(chan struct{}),
 	}
 }
 
-// Start starts the scheduler
-func (s *SchedulerService) Start() error {
-	log.Printf("Starting scheduler with schedule: %s (timezone: %s)", s.config.SyncSchedule, s.config.Timezone)
+// Register adds a job; call it before Start
+func (s *SchedulerService) Register(def JobDefinition) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	// Add the sync job
-	_, err := s.cron.AddFunc(s.config.SyncSchedule, s.syncJob)
-	if err != nil {
-		return err
+	for _, job := range s.jobs {
+		if job.Name == def.Name {
+			return fmt.Errorf("job %s registered twice", def.Name)
+		}
+	}
+	job := &scheduledJob{JobDefinition: def}
+	if def.Enabled {
+		entry, err := s.cron.AddFunc(def.Schedule, func() { s.run(job, domain.TriggerSchedule) })
+		if err != nil {
+			return fmt.Errorf("invalid schedule %q of job %s: %w", def.Schedule, def.Name, err)
+		}
+		job.entry = entry
 	}
+	s.jobs = append(s.jobs, job)
+	return nil
+}
+
+// Start starts the scheduler
+func (s *SchedulerService) Start() error {
+	log.Printf("Starting scheduler with %d jobs (timezone: %s)", len(s.jobs), s.config.Timezone)
 
 	// Start the cron scheduler
 	s.cron.Start()
+	s.sync()
+	go s.pollLoop()
+
+	for _, job := range s.Jobs() {
+		if !job.Enabled {
+			log.Printf("Job %s is disabled", job.Name)
+			continue
+		}
+		log.Printf("Job %s scheduled at %q, next run %s", job.Name, job.Schedule, job.NextRun.Format("2006-01-02 15:04:05 MST"))
+	}
 
 	log.Println("Scheduler started successfully")
 	return nil
 }
 
-// Stop stops the scheduler
+// Stop stops the scheduler and waits for running jobs
 func (s *SchedulerService) Stop() {
 	log.Println("Stopping scheduler...")
-	s.cron.Stop()
+	close(s.stop)
+	<-s.cron.Stop().Done()
+	s.runs.Wait()
+
+	s.mu.Lock()
+	if s.leader != nil {
+		s.leader.Release()
+		s.leader = nil
+	}
+	s.mu.Unlock()
 	log.Println("Scheduler stopped")
 }
 
-// syncJob performs the synchronization task
-func (s *SchedulerService) syncJob() {
-	log.Println("Starting scheduled synchronization...")
-	startTime := time.Now()
+// Jobs returns the state of the registered jobs
+func (s *SchedulerService) Jobs() []domain.ScheduledJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs := make([]domain.ScheduledJob, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		state := domain.ScheduledJob{
+			Name:        job.Name,
+			Description: job.Description,
+			Schedule:    job.Schedule,
+			Enabled:     job.Enabled,
+			Timeout:     job.Timeout.String(),
+			Paused:      s.paused[job.Name],
+			UpdatedAt:   time.Now(),
+		}
+		if job.Enabled {
+			state.NextRun = s.cron.Entry(job.entry).Next
+		}
+		jobs = append(jobs, state)
+	}
+	return jobs
+}
+
+// pollLoop picks up pause flags and run requests until Stop
+func (s *SchedulerService) pollLoop() {
+	ticker := time.NewTicker(s.poll)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.sync()
+		}
+	}
+}
+
+// sync renews or takes the lead; the leader reads pause flags from the store, starts requested runs
+// and publishes the next run times
+func (s *SchedulerService) sync() {
+	if s.store == nil || !s.lead() {
+		return
+	}
 
-	// Perform cache refresh
-	err := s.projectService.ClearExpiredCache()
+	states, err := s.store.ListJobs()
 	if err != nil {
-		log.Printf("Error during scheduled sync: %v", err)
+		log.Printf("Failed to read job states: %v", err)
 		return
 	}
+	s.mu.Lock()
+	for _, state := range states {
+		s.paused[state.Name] = state.Paused
+	}
+	jobs := append([]*scheduledJob(nil), s.jobs...)
+	s.mu.Unlock()
+
+	for _, state := range s.Jobs() {
+		if err := s.store.RegisterJob(state); err != nil {
+			log.Printf("Failed to register job %s: %v", state.Name, err)
+		}
+	}
 
-	duration := time.Since(startTime)
-	log.Printf("Scheduled synchronization completed in %v", duration)
+	for _, job := range jobs {
+		requested, err := s.store.TakeJobRequest(job.Name)
+		if err != nil {
+			log.Printf("Failed to read run request of job %s: %v", job.Name, err)
+			continue
+		}
+		if requested {
+			s.runs.Add(1)
+			go func(job *scheduledJob) {
+				defer s.runs.Done()
+				s.run(job, domain.TriggerManual)
+			}(job)
+		}
+	}
 }
 
-// GetNextRunTime returns the next scheduled run time
+// lead keeps or takes the scheduler lease and reports whether this replica leads
+func (s *SchedulerService) lead() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// A lease outlives a few polls, so a leader survives a slow one
+	ttl := 3 * s.poll
+	if ttl < leaseTTL {
+		ttl = leaseTTL
+	}
+
+	if s.leader != nil {
+		err := s.leader.Check()
+		if err == nil {
+			s.heldAt = time.Now()
+			return true
+		}
+		if !errors.Is(err, repository.ErrLeaseLost) && time.Since(s.heldAt) < ttl {
+			// The store is unreachable; keep running and find out on the next poll
+			log.Printf("Failed to check scheduler lease: %v", err)
+			return true
+		}
+		// Past the TTL the lease has expired unrenewed, and another replica may lead by now
+		log.Printf("This replica no longer leads the scheduler: %v", err)
+		s.leader.Release()
+		s.leader = nil
+	}
+
+	lease, err := acquireLease(s.store, LeaseScheduler, "scheduler", ttl)
+	if err != nil {
+		if !IsLeaseHeld(err) {
+			log.Printf("Failed to acquire scheduler lease: %v", err)
+		}
+		return false
+	}
+	log.Printf("This replica leads the scheduler (fencing token %d)", lease.Token())
+	s.leader = lease
+	s.heldAt = time.Now()
+	return true
+}
+
+// leads reports whether this replica runs jobs; without a store there is nobody to share with
+func (s *SchedulerService) leads() bool {
+	if s.store == nil {
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.leader != nil
+}
+
+// run runs a job once and records the run. Scheduled runs of paused jobs are skipped, and a job never
+// runs twice at the same time. A run that times out is recorded as such and its context is canceled,
+// so the job stops at its next check; until it has, the next run is skipped.
+func (s *SchedulerService) run(job *scheduledJob, trigger string) {
+	if !s.leads() {
+		log.Printf("Job %s runs on the leading scheduler replica, skipping here", job.Name)
+		return
+	}
+	s.mu.Lock()
+	paused := s.paused[job.Name]
+	s.mu.Unlock()
+	if paused && trigger == domain.TriggerSchedule {
+		log.Printf("Job %s is paused, skipping scheduled run", job.Name)
+		return
+	}
+	if !job.running.CompareAndSwap(false, true) {
+		log.Printf("Job %s is still running, skipping %s run", job.Name, trigger)
+		return
+	}
+
+	started := time.Now()
+	record := domain.JobRun{
+		ID:        fmt.Sprintf("%s-%s", job.Name, started.UTC().Format("20060102T150405.000Z")),
+		Job:       job.Name,
+		Trigger:   trigger,
+		StartedAt: started,
+		Status:    domain.JobRunning,
+	}
+	s.saveRun(record)
+	log.Printf("Starting job %s (%s)", job.Name, trigger)
+
+	ctx, cancel := context.Background(), func() {}
+	if job.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, job.Timeout)
+	}
+	defer cancel()
+
+	type outcome struct {
+		result JobResult
+		err    error
+	}
+	out := &JobLog{job: job.Name}
+	done := make(chan outcome, 1)
+	go func() {
+		defer job.running.Store(false)
+		defer func() {
+			if r := recover(); r != nil {
+				done <- outcome{err: fmt.Errorf("job panicked: %v", r)}
+			}
+		}()
+		result, err := job.Run(ctx, out)
+		done <- outcome{result: result, err: err}
+	}()
+
+	var result outcome
+	select {
+	case result = <-done:
+	case <-ctx.Done():
+		select {
+		case result = <-done:
+		default:
+			result.err = fmt.Errorf("timed out after %s", job.Timeout)
+		}
+	}
+
+	record.FinishedAt = time.Now()
+	record.Duration = record.FinishedAt.Sub(record.StartedAt).Round(time.Millisecond).String()
+	record.Metrics = result.result.Metrics
+	switch {
+	case result.err != nil && ctx.Err() == context.DeadlineExceeded:
+		record.Status = domain.JobTimedOut
+		record.Error = result.err.Error()
+	case result.err != nil:
+		record.Status = domain.JobFailed
+		record.Error = result.err.Error()
+	case result.result.Partial:
+		record.Status = domain.JobPartial
+	default:
+		record.Status = domain.JobSucceeded
+	}
+	record.Log = out.String()
+	s.saveRun(record)
+
+	metrics, _ := json.Marshal(record.Metrics)
+	log.Printf("Job %s %s in %s: %s", job.Name, record.Status, record.Duration, metrics)
+	if record.Error != "" {
+		log.Printf("Job %s error: %s", job.Name, record.Error)
+	}
+}
+
+func (s *SchedulerService) saveRun(record domain.JobRun) {
+	if s.store == nil {
+		return
+	}
+	if err := s.store.SaveJobRun(record); err != nil {
+		log.Printf("Failed to record run of job %s: %v", record.Job, err)
+	}
+}
+
+// GetNextRunTime returns the earliest next run of the enabled jobs
 func (s *SchedulerService) GetNextRunTime() time.Time {
-	entries := s.cron.Entries()
-	if len(entries) > 0 {
-		return entries[0].Next
+	var next time.Time
+	for _, job := range s.Jobs() {
+		if !job.NextRun.IsZero() && (next.IsZero() || job.NextRun.Before(next)) {
+			next = job.NextRun
+		}
+	}
+	return next
+}
+
+// JobLog collects the output of a job run. Lines also go to the process log; the last jobLogLines
+// are stored with the run.
+type JobLog struct {
+	job   string
+	mu    sync.Mutex
+	lines []string
+}
+
+// Printf adds a line of output
+func (l *JobLog) Printf(format string, args ...interface{}) {
+	line := fmt.Sprintf(format, args...)
+	log.Printf("[%s] %s", l.job, line)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, line)
+	if len(l.lines) > jobLogLines {
+		l.lines = l.lines[len(l.lines)-jobLogLines:]
 	}
-	return time.Time{}
 }
 
-// GetSchedule returns the current schedule
-func (s *SchedulerService) GetSchedule() string {
-	return s.config.SyncSchedule
+// String returns the kept lines
+func (l *JobLog) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return strings.Join(l.lines, "\n")
 }
@@ -0,0 +1,400 @@
+// internal/service/snapshot.go
+package service
+
+import (
+	"crypto/md5"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"gitlab-list/internal/configuration"
+	"gitlab-list/internal/domain"
+	"gitlab-list/internal/gomod"
+	"gitlab-list/internal/repository"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// SnapshotRetention controls how old snapshots are compacted
+type SnapshotRetention struct {
+	KeepAll    time.Duration // Every snapshot younger than this is kept
+	KeepWeekly time.Duration // Older ones are thinned to the newest per week up to this age, then deleted; 0 keeps them forever
+}
+
+// SnapshotRetentionFromConfig reads SNAPSHOT_KEEP_ALL and SNAPSHOT_KEEP_WEEKLY
+func SnapshotRetentionFromConfig(cfg *configuration.Configuration) SnapshotRetention {
+	retention := SnapshotRetention{KeepAll: 30 * 24 * time.Hour, KeepWeekly: 365 * 24 * time.Hour}
+	if d, err := time.ParseDuration(cfg.SnapshotKeepAll); err == nil && d >= 0 {
+		retention.KeepAll = d
+	}
+	if d, err := time.ParseDuration(cfg.SnapshotKeepWeekly); err == nil && d >= 0 {
+		retention.KeepWeekly = d
+	}
+	return retention
+}
+
+// SetSnapshotRetention sets how old snapshots are compacted after every sync
+func (s *ProjectService) SetSnapshotRetention(retention SnapshotRetention) {
+	s.retention = &retention
+}
+
+// saveSnapshot stores the projects of a sync as a new snapshot and compacts old ones.
+// Failures are logged: history is a side effect and must not fail the sync.
+func (s *ProjectService) saveSnapshot(projects []domain.Project) {
+	takenAt := time.Now().UTC()
+	snapshotID := takenAt.Format("20060102T150405Z")
+
+	records := make([]domain.ProjectSnapshot, 0, len(projects))
+	for _, project := range projects {
+		records = append(records, projectSnapshot(snapshotID, takenAt, project))
+	}
+	if err := s.store.SaveSnapshot(records); err != nil {
+		fmt.Printf("Warning: failed to save snapshot %s: %v\n", snapshotID, err)
+		return
+	}
+	fmt.Printf("Snapshot %s saved: %d project records\n", snapshotID, len(records))
+
+	if s.retention != nil {
+		if _, err := s.CompactSnapshots(); err != nil {
+			fmt.Printf("Warning: failed to compact snapshots: %v\n", err)
+		}
+	}
+}
+
+// projectSnapshot copies the tracked state of a project
+func projectSnapshot(snapshotID string, takenAt time.Time, project domain.Project) domain.ProjectSnapshot {
+	snapshot := domain.ProjectSnapshot{
+		SnapshotID:    snapshotID,
+		TakenAt:       takenAt,
+		ProjectID:     project.ID,
+		Name:          project.Name,
+		Path:          project.Path,
+		Ref:           project.Ref,
+		DefaultBranch: project.DefaultBranch,
+		CommitSHA:     project.CommitSHA,
+		GoVersion:     project.GoVersion,
+		OpenAPIHash:   openAPIHash(project.OpenAPISpecs),
+	}
+	for _, spec := range project.OpenAPISpecs {
+		if spec.Found {
+			snapshot.OpenAPISpecs = append(snapshot.OpenAPISpecs, domain.OpenAPISnapshot{
+				Path:    spec.Path,
+				Version: spec.Version,
+				Title:   spec.Title,
+				Hash:    fmt.Sprintf("%x", md5.Sum([]byte(spec.Content))),
+			})
+		}
+	}
+	for _, mod := range project.GoModules() {
+		snapshot.Modules = append(snapshot.Modules, domain.ModuleSnapshot{
+			Dir:       mod.Dir,
+			Path:      mod.Path,
+			GoVersion: mod.GoVersion,
+			Toolchain: mod.Toolchain,
+			Libraries: mod.Libraries,
+		})
+	}
+	return snapshot
+}
+
+// openAPIHash hashes the paths and contents of a project's specs, "" when it has none
+func openAPIHash(specs []domain.OpenAPI) string {
+	var parts []string
+	for _, spec := range specs {
+		if spec.Found {
+			parts = append(parts, spec.Path+"\x00"+spec.Content)
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	sort.Strings(parts)
+	return fmt.Sprintf("%x", md5.Sum([]byte(strings.Join(parts, "\x00"))))
+}
+
+// GetSnapshots lists the stored snapshots, oldest first
+func (s *ProjectService) GetSnapshots() ([]domain.Snapshot, error) {
+	if s.store == nil {
+		return nil, repository.ErrNoStore
+	}
+	return s.store.ListSnapshots()
+}
+
+// CompactSnapshots applies the retention policy and returns the IDs of the deleted snapshots
+func (s *ProjectService) CompactSnapshots() ([]string, error) {
+	if s.store == nil {
+		return nil, repository.ErrNoStore
+	}
+	if s.retention == nil {
+		return nil, nil
+	}
+
+	snapshots, err := s.store.ListSnapshots()
+	if err != nil {
+		return nil, err
+	}
+
+	expired := expiredSnapshots(snapshots, *s.retention, time.Now())
+	if len(expired) == 0 {
+		return nil, nil
+	}
+	if _, err := s.store.DeleteSnapshots(expired); err != nil {
+		return nil, err
+	}
+	fmt.Printf("Compacted %d snapshots\n", len(expired))
+	return expired, nil
+}
+
+// expiredSnapshots returns the snapshots (sorted oldest first) that the retention policy drops
+func expiredSnapshots(snapshots []domain.Snapshot, retention SnapshotRetention, now time.Time) []string {
+	var expired []string
+	newestOfWeek := make(map[time.Time]string)
+	for _, snapshot := range snapshots {
+		age := now.Sub(snapshot.TakenAt)
+		switch {
+		case age < retention.KeepAll:
+		case retention.KeepWeekly > 0 && age >= retention.KeepWeekly:
+			expired = append(expired, snapshot.ID)
+		default:
+			// Snapshots are sorted, so a later one of the same week replaces the earlier
+			week := periodStart(snapshot.TakenAt, "week")
+			if previous, ok := newestOfWeek[week]; ok {
+				expired = append(expired, previous)
+			}
+			newestOfWeek[week] = snapshot.ID
+		}
+	}
+	return expired
+}
+
+// GetGoVersionTrend returns the share of projects on each Go version (major.minor) per period
+func (s *ProjectService) GetGoVersionTrend(opts domain.TrendOptions) (*domain.GoVersionTrend, error) {
+	periods, records, err := s.trendSnapshots(&opts, false)
+	if err != nil {
+		return nil, err
+	}
+
+	trend := &domain.GoVersionTrend{Ref: opts.Ref, Interval: opts.Interval, Periods: []domain.GoVersionPeriod{}}
+	for _, period := range periods {
+		point := domain.GoVersionPeriod{
+			Start:      period.start,
+			SnapshotID: period.snapshot.ID,
+			TakenAt:    period.snapshot.TakenAt,
+			Versions:   make(map[string]int),
+			Shares:     make(map[string]float64),
+		}
+		for _, record := range records[period.snapshot.ID] {
+			version := "unknown"
+			if mm := semver.MajorMinor("v" + strings.TrimPrefix(record.GoVersion, "go")); mm != "" {
+				version = strings.TrimPrefix(mm, "v")
+			}
+			point.Versions[version]++
+			point.Total++
+		}
+		for version, count := range point.Versions {
+			point.Shares[version] = percent(count, point.Total)
+		}
+		trend.Periods = append(trend.Periods, point)
+	}
+	return trend, nil
+}
+
+// GetLibraryAdoption returns when each project first required modPath at version or newer, and the
+// adoption curve per period. Major version suffixes are ignored, so "example.com/client" with
+// version v2.3.0 tracks "example.com/client/v2".
+func (s *ProjectService) GetLibraryAdoption(modPath, version string, opts domain.TrendOptions) (*domain.LibraryAdoption, error) {
+	if !semver.IsValid(version) {
+		return nil, fmt.Errorf("invalid version %q", version)
+	}
+
+	periods, records, err := s.trendSnapshots(&opts, true)
+	if err != nil {
+		return nil, err
+	}
+
+	adoption := &domain.LibraryAdoption{
+		Module:   modPath,
+		Version:  version,
+		Ref:      opts.Ref,
+		Interval: opts.Interval,
+		Curve:    []domain.AdoptionPoint{},
+		Projects: []domain.ProjectAdoption{},
+	}
+
+	// Per-project adoption dates use every snapshot in range, not only the last of each period
+	byProject := make(map[int]*domain.ProjectAdoption)
+	var order []int
+	var snapshotIDs []string
+	for id := range records {
+		snapshotIDs = append(snapshotIDs, id)
+	}
+	sort.Strings(snapshotIDs) // IDs are UTC timestamps
+	for _, id := range snapshotIDs {
+		for _, record := range records[id] {
+			required, dir := requiredVersion(record, modPath)
+			if required == "" {
+				continue
+			}
+			project := byProject[record.ProjectID]
+			if project == nil {
+				project = &domain.ProjectAdoption{ProjectID: record.ProjectID}
+				byProject[record.ProjectID] = project
+				order = append(order, record.ProjectID)
+			}
+			project.Name, project.Path = record.Name, record.Path
+			project.CurrentVersion, project.ModuleDir = required, dir
+			if project.AdoptedAt == nil && semver.Compare(required, version) >= 0 {
+				takenAt := record.TakenAt
+				project.AdoptedAt = &takenAt
+				project.AdoptedVersion = required
+			}
+		}
+	}
+	for _, id := range order {
+		adoption.Projects = append(adoption.Projects, *byProject[id])
+	}
+	sort.SliceStable(adoption.Projects, func(i, j int) bool {
+		a, b := adoption.Projects[i].AdoptedAt, adoption.Projects[j].AdoptedAt
+		if a == nil || b == nil {
+			return a != nil
+		}
+		return a.Before(*b)
+	})
+
+	for _, period := range periods {
+		point := domain.AdoptionPoint{
+			Start:      period.start,
+			SnapshotID: period.snapshot.ID,
+			TakenAt:    period.snapshot.TakenAt,
+		}
+		for _, record := range records[period.snapshot.ID] {
+			required, _ := requiredVersion(record, modPath)
+			if required == "" {
+				continue
+			}
+			point.Using++
+			if semver.Compare(required, version) >= 0 {
+				point.Adopted++
+			}
+		}
+		point.Share = percent(point.Adopted, point.Using)
+		adoption.Curve = append(adoption.Curve, point)
+	}
+	return adoption, nil
+}
+
+// trendPeriod is a period and the last snapshot taken in it
+type trendPeriod struct {
+	start    time.Time
+	snapshot domain.Snapshot
+}
+
+// trendSnapshots loads the snapshots in range, picks the last one of every period and returns
+// their project records on opts.Ref keyed by snapshot ID. With libraries set, every snapshot in range
+// is loaded (for adoption dates); otherwise only the period snapshots.
+func (s *ProjectService) trendSnapshots(opts *domain.TrendOptions, libraries bool) ([]trendPeriod, map[string][]domain.ProjectSnapshot, error) {
+	if s.store == nil {
+		return nil, nil, repository.ErrNoStore
+	}
+	switch opts.Interval {
+	case "":
+		opts.Interval = "week"
+	case "day", "week", "month":
+	default:
+		return nil, nil, fmt.Errorf("invalid interval %q: use day, week or month", opts.Interval)
+	}
+
+	snapshots, err := s.store.ListSnapshots()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var periods []trendPeriod
+	var ids []string
+	for _, snapshot := range snapshots {
+		if (!opts.From.IsZero() && snapshot.TakenAt.Before(opts.From)) || (!opts.To.IsZero() && snapshot.TakenAt.After(opts.To)) {
+			continue
+		}
+		if libraries {
+			ids = append(ids, snapshot.ID)
+		}
+		start := periodStart(snapshot.TakenAt, opts.Interval)
+		if n := len(periods); n > 0 && periods[n-1].start.Equal(start) {
+			periods[n-1].snapshot = snapshot
+			continue
+		}
+		periods = append(periods, trendPeriod{start: start, snapshot: snapshot})
+	}
+	if !libraries {
+		for _, period := range periods {
+			ids = append(ids, period.snapshot.ID)
+		}
+	}
+
+	projects, err := s.store.GetSnapshotProjects(ids, libraries)
+	if err != nil {
+		return nil, nil, err
+	}
+	records := make(map[string][]domain.ProjectSnapshot)
+	for _, project := range projects {
+		if project.OnRef(opts.Ref) {
+			records[project.SnapshotID] = append(records[project.SnapshotID], project)
+		}
+	}
+	return periods, records, nil
+}
+
+// requiredVersion returns the highest version of modPath (any major) required by a project snapshot
+// and the directory of the module requiring it
+func requiredVersion(record domain.ProjectSnapshot, modPath string) (string, string) {
+	var version, dir string
+	for _, mod := range record.Modules {
+		for _, lib := range mod.Libraries {
+			if stripMajorSuffix(lib.Name) != stripMajorSuffix(modPath) {
+				continue
+			}
+			if version == "" || semver.Compare(lib.Version, version) > 0 {
+				version = lib.Version
+				dir = ""
+				if mod.Dir != gomod.RootDir {
+					dir = mod.Dir
+				}
+			}
+		}
+	}
+	return version, dir
+}
+
+// stripMajorSuffix removes a /vN major version suffix from a module path
+func stripMajorSuffix(modPath string) string {
+	if prefix, _, ok := module.SplitPathVersion(modPath); ok {
+		return prefix
+	}
+	return modPath
+}
+
+// periodStart truncates t (in UTC) to the start of its day, ISO week (Monday) or month
+func periodStart(t time.Time, interval string) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch interval {
+	case "day":
+		return day
+	case "month":
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		offset := (int(day.Weekday()) + 6) % 7 // days since Monday
+		return day.AddDate(0, 0, -offset)
+	}
+}
+
+// percent returns part as a percentage of total, rounded to one decimal
+func percent(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(part)*1000/float64(total)) / 10
+}
@@ -0,0 +1,141 @@
+// internal/service/verify.go
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"gitlab-list/internal/workspace"
+)
+
+// UpdateOptions control how an update is checked before it is pushed
+type UpdateOptions struct {
+	DryRun       bool `json:"dry_run,omitempty"`       // Apply and verify in the clone, never commit or push
+	Verify       bool `json:"verify"`                  // Run go mod tidy, go build and go vet after the changes
+	Tests        bool `json:"tests,omitempty"`         // Also run go test ./...
+	RequireGreen bool `json:"require_green,omitempty"` // Do not push when a verification command fails
+}
+
+// VerifyStep is the outcome of one verification command
+type VerifyStep struct {
+	Command   string `json:"command"`
+	Passed    bool   `json:"passed"`
+	Output    string `json:"output,omitempty"` // Combined output; long output keeps its end
+	Truncated bool   `json:"truncated,omitempty"`
+	Duration  string `json:"duration"`
+}
+
+// Verification is the outcome of the verification commands of an update, in the order they ran
+type Verification struct {
+	Passed bool         `json:"passed"`
+	Steps  []VerifyStep `json:"steps"`
+}
+
+// verifyOutputLimit is the most output kept per command; compiler errors are at the end
+const verifyOutputLimit = 8 << 10
+
+// DefaultUpdateOptions returns the verification settings from the configuration
+func (lu *LibraryUpdater) DefaultUpdateOptions() UpdateOptions {
+	return UpdateOptions{
+		Verify:       lu.config.VerifyUpdates,
+		Tests:        lu.config.VerifyTests,
+		RequireGreen: lu.config.RequireGreenBuild,
+	}
+}
+
+// verifyCommands are the commands a verification runs, test last
+func verifyCommands(tests bool) [][]string {
+	commands := [][]string{
+		{"go", "mod", "tidy"},
+		{"go", "build", "./..."},
+		{"go", "vet", "./..."},
+	}
+	if tests {
+		commands = append(commands, []string{"go", "test", "./..."})
+	}
+	return commands
+}
+
+// verifyModule runs the verification commands in the module directory of the workspace. Every
+// command runs even after a failure so the report is complete; go mod tidy may change go.mod and
+// go.sum, which then become part of the update.
+func verifyModule(ctx context.Context, ws *workspace.Workspace, moduleDir string, tests bool, step func(name string) error) (*Verification, error) {
+	verification := &Verification{Passed: true}
+	for _, args := range verifyCommands(tests) {
+		command := strings.Join(args, " ")
+		if err := step("Running " + command); err != nil {
+			return nil, err
+		}
+
+		start := time.Now()
+		output, err := ws.Run(ctx, moduleDir, args[0], args[1:]...)
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("verification stopped during %s: %w", command, ctx.Err())
+		}
+
+		result := VerifyStep{
+			Command:  command,
+			Passed:   err == nil,
+			Output:   output,
+			Duration: time.Since(start).Round(time.Millisecond).String(),
+		}
+		if err != nil {
+			result.Output = strings.TrimSpace(output + "\n" + err.Error())
+		}
+		if len(result.Output) > verifyOutputLimit {
+			// Keep whole lines from the end
+			tail := result.Output[len(result.Output)-verifyOutputLimit:]
+			if i := strings.IndexByte(tail, '\n'); i >= 0 {
+				tail = tail[i+1:]
+			}
+			result.Output = tail
+			result.Truncated = true
+		}
+		verification.Passed = verification.Passed && result.Passed
+		verification.Steps = append(verification.Steps, result)
+	}
+	return verification, nil
+}
+
+// Failed returns the commands that failed
+func (v *Verification) Failed() []string {
+	var failed []string
+	for _, step := range v.Steps {
+		if !step.Passed {
+			failed = append(failed, step.Command)
+		}
+	}
+	return failed
+}
+
+// verificationMarkdown renders the verification results for a merge request description
+func verificationMarkdown(verification *Verification) string {
+	if verification == nil {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("\n### Verification\n\n| Command | Result | Time |\n|---------|--------|------|\n")
+	for _, step := range verification.Steps {
+		result := "✅ passed"
+		if !step.Passed {
+			result = "❌ failed"
+		}
+		fmt.Fprintf(&b, "| `%s` | %s | %s |\n", step.Command, result, step.Duration)
+	}
+	for _, step := range verification.Steps {
+		if step.Passed || step.Output == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "\n<details><summary>Output of <code>%s</code>", step.Command)
+		if step.Truncated {
+			b.WriteString(" (last lines)")
+		}
+		b.WriteString("</summary>\n\n```\n")
+		b.WriteString(strings.ReplaceAll(step.Output, "```", "` ` `"))
+		b.WriteString("\n```\n\n</details>\n")
+	}
+	return b.String()
+}
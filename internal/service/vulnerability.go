@@ -0,0 +1,424 @@
+// internal/service/vulnerability.go
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gitlab-list/internal/domain"
+	"gitlab-list/internal/gomod"
+	"gitlab-list/internal/osv"
+
+	"golang.org/x/mod/semver"
+)
+
+// VulnerabilityService matches cached project dependencies against an offline OSV database
+type VulnerabilityService struct {
+	projects *ProjectService
+	updater  *LibraryUpdater
+	dbPath   string
+
+	mu sync.RWMutex
+	db *osv.Database
+}
+
+// VulnerabilityReport lists the advisories affecting cached projects, per project and per advisory
+type VulnerabilityReport struct {
+	GeneratedAt time.Time                `json:"generated_at"`
+	Ref         string                   `json:"ref,omitempty"`
+	Database    VulnerabilityDatabase    `json:"database"`
+	Projects    []ProjectVulnerabilities `json:"projects,omitempty"`
+	Advisories  []AdvisoryImpact         `json:"advisories,omitempty"`
+}
+
+// VulnerabilityDatabase describes the loaded OSV dump
+type VulnerabilityDatabase struct {
+	Path     string    `json:"path"`
+	Entries  int       `json:"entries"`
+	LoadedAt time.Time `json:"loaded_at"`
+}
+
+// ProjectVulnerabilities lists the findings of one project and the upgrades that fix them
+type ProjectVulnerabilities struct {
+	ProjectID int                    `json:"project_id"`
+	Name      string                 `json:"name"`
+	Path      string                 `json:"path_with_namespace"`
+	Ref       string                 `json:"ref,omitempty"`
+	Findings  []VulnerabilityFinding `json:"findings"`
+	Upgrades  []ModuleUpgrade        `json:"upgrades,omitempty"`
+}
+
+// VulnerabilityFinding is one advisory affecting one requirement of a project module
+type VulnerabilityFinding struct {
+	AdvisoryID string   `json:"advisory_id"`
+	Aliases    []string `json:"aliases,omitempty"`
+	Summary    string   `json:"summary,omitempty"`
+	URL        string   `json:"url"`
+	Module     string   `json:"module"` // Affected module, "stdlib" or "toolchain" for Go itself
+	Version    string   `json:"version"`
+	FixedIn    string   `json:"fixed_in,omitempty"` // Empty when no fix is released
+	ModuleDir  string   `json:"module_dir"`         // Project module requiring it
+	Indirect   bool     `json:"indirect,omitempty"`
+	Replaced   bool     `json:"replaced,omitempty"` // Module is the target of a replace directive
+	// The go directive of a module without a toolchain line matched; it is only a minimum, so the
+	// release that builds the module may already have the fix
+	PossiblyAffected bool `json:"possibly_affected,omitempty"`
+}
+
+// ModuleUpgrade is the request body for LibraryUpdater.UpdateProjectLibraries that fixes a module's
+// findings. A Go fix is a "toolchain" update (go get toolchain@go1.22.5); the go directive is only
+// raised when the fix is in a newer minor release than it names.
+type ModuleUpgrade struct {
+	ModuleDir string                 `json:"module_dir"`
+	Updates   []ProjectLibraryUpdate `json:"updates,omitempty"`
+	GoVersion string                 `json:"go_version,omitempty"`
+}
+
+// AdvisoryImpact lists the projects affected by one advisory
+type AdvisoryImpact struct {
+	ID        string            `json:"id"`
+	Aliases   []string          `json:"aliases,omitempty"`
+	Summary   string            `json:"summary,omitempty"`
+	URL       string            `json:"url"`
+	Published time.Time         `json:"published,omitempty"`
+	Modified  time.Time         `json:"modified"`
+	Affected  []AffectedProject `json:"affected"`
+}
+
+// AffectedProject is a project module affected by an advisory
+type AffectedProject struct {
+	ProjectID        int    `json:"project_id"`
+	Name             string `json:"name"`
+	Path             string `json:"path_with_namespace"`
+	ModuleDir        string `json:"module_dir"`
+	Module           string `json:"module"`
+	Version          string `json:"version"`
+	FixedIn          string `json:"fixed_in,omitempty"`
+	PossiblyAffected bool   `json:"possibly_affected,omitempty"` // See VulnerabilityFinding
+}
+
+// VulnerabilityOptions filters the vulnerability report
+type VulnerabilityOptions struct {
+	Ref        string // Branch to report on; empty means each project's default branch
+	ProjectID  int    // Only this project when set
+	AdvisoryID string // Only this advisory (OSV ID or alias) when set
+}
+
+// NewVulnerabilityService creates a vulnerability service reading the OSV dump at dbPath.
+// The database is loaded lazily on first use, or explicitly with Reload.
+func NewVulnerabilityService(projects *ProjectService, updater *LibraryUpdater, dbPath string) *VulnerabilityService {
+	return &VulnerabilityService{
+		projects: projects,
+		updater:  updater,
+		dbPath:   dbPath,
+	}
+}
+
+// Reload (re)imports the OSV dump from disk
+func (s *VulnerabilityService) Reload() (*VulnerabilityDatabase, error) {
+	if s.dbPath == "" {
+		return nil, fmt.Errorf("vulnerability database not configured: set OSV_DB")
+	}
+
+	db, err := osv.Load(s.dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.db = db
+	s.mu.Unlock()
+
+	fmt.Printf("Loaded %d vulnerability entries from %s\n", db.Len(), db.Path)
+	return &VulnerabilityDatabase{Path: db.Path, Entries: db.Len(), LoadedAt: db.LoadedAt}, nil
+}
+
+// database returns the loaded database, loading it on first use
+func (s *VulnerabilityService) database() (*osv.Database, error) {
+	s.mu.RLock()
+	db := s.db
+	s.mu.RUnlock()
+	if db != nil {
+		return db, nil
+	}
+
+	if _, err := s.Reload(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.db, nil
+}
+
+// GetReport matches the cached projects on opts.Ref against the database
+func (s *VulnerabilityService) GetReport(opts VulnerabilityOptions) (*VulnerabilityReport, error) {
+	return s.GetReportContext(context.Background(), opts)
+}
+
+// GetReportContext is GetReport stopping between projects once ctx is done
+func (s *VulnerabilityService) GetReportContext(ctx context.Context, opts VulnerabilityOptions) (*VulnerabilityReport, error) {
+	db, err := s.database()
+	if err != nil {
+		return nil, err
+	}
+
+	projects, err := s.projects.GetCachedProjectsOnRef(opts.Ref)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &VulnerabilityReport{
+		GeneratedAt: time.Now(),
+		Ref:         opts.Ref,
+		Database:    VulnerabilityDatabase{Path: db.Path, Entries: db.Len(), LoadedAt: db.LoadedAt},
+	}
+	advisories := make(map[string]*AdvisoryImpact)
+
+	for _, project := range projects {
+		if opts.ProjectID != 0 && project.ID != opts.ProjectID {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("vulnerability matching stopped: %w", err)
+		}
+
+		pv := ProjectVulnerabilities{ProjectID: project.ID, Name: project.Name, Path: project.Path, Ref: project.Ref}
+		for _, module := range project.GoModules() {
+			for _, finding := range matchModule(db, module) {
+				if opts.AdvisoryID != "" && !matchesAdvisory(finding, opts.AdvisoryID) {
+					continue
+				}
+				pv.Findings = append(pv.Findings, finding)
+
+				impact := advisories[finding.AdvisoryID]
+				if impact == nil {
+					entry := db.Entry(finding.AdvisoryID)
+					impact = &AdvisoryImpact{
+						ID:        entry.ID,
+						Aliases:   entry.Aliases,
+						Summary:   entry.Summary,
+						URL:       entry.URL(),
+						Published: entry.Published,
+						Modified:  entry.Modified,
+					}
+					advisories[finding.AdvisoryID] = impact
+				}
+				impact.Affected = append(impact.Affected, AffectedProject{
+					ProjectID:        project.ID,
+					Name:             project.Name,
+					Path:             project.Path,
+					ModuleDir:        finding.ModuleDir,
+					Module:           finding.Module,
+					Version:          finding.Version,
+					FixedIn:          finding.FixedIn,
+					PossiblyAffected: finding.PossiblyAffected,
+				})
+			}
+		}
+		if len(pv.Findings) == 0 {
+			continue
+		}
+		pv.Upgrades = recommendedUpgrades(project.ID, project.GoModules(), pv.Findings)
+		report.Projects = append(report.Projects, pv)
+	}
+
+	for _, impact := range advisories {
+		report.Advisories = append(report.Advisories, *impact)
+	}
+
+	// Most affected first
+	sort.Slice(report.Projects, func(i, j int) bool {
+		if len(report.Projects[i].Findings) != len(report.Projects[j].Findings) {
+			return len(report.Projects[i].Findings) > len(report.Projects[j].Findings)
+		}
+		return report.Projects[i].Path < report.Projects[j].Path
+	})
+	sort.Slice(report.Advisories, func(i, j int) bool {
+		if len(report.Advisories[i].Affected) != len(report.Advisories[j].Affected) {
+			return len(report.Advisories[i].Affected) > len(report.Advisories[j].Affected)
+		}
+		return report.Advisories[i].ID < report.Advisories[j].ID
+	})
+
+	return report, nil
+}
+
+// FixProject opens a merge request upgrading the requirements of one project module to the versions
+// that fix its known vulnerabilities. Without branchName the branch is derived from the upgrades, so
+// repeating a fix reuses its merge request and a newer fix supersedes older ones.
+func (s *VulnerabilityService) FixProject(projectID int, moduleDir, ref, branchName, token string) ([]UpdateResult, error) {
+	return s.FixProjectContext(context.Background(), projectID, moduleDir, ref, branchName, token, nil)
+}
+
+// FixProjectContext is FixProject as a queued job, reporting and stopping like UpdateProjectLibrariesContext
+func (s *VulnerabilityService) FixProjectContext(ctx context.Context, projectID int, moduleDir, ref, branchName, token string, progress ProgressFunc) ([]UpdateResult, error) {
+	report, err := s.GetReportContext(ctx, VulnerabilityOptions{Ref: ref, ProjectID: projectID})
+	if err != nil {
+		return nil, err
+	}
+
+	moduleDir = gomod.CleanDir(moduleDir)
+	for _, pv := range report.Projects {
+		for _, upgrade := range pv.Upgrades {
+			if upgrade.ModuleDir != moduleDir {
+				continue
+			}
+			return s.updater.UpdateProjectLibrariesContext(ctx, projectID, upgrade.ModuleDir, upgrade.Updates, upgrade.GoVersion, branchName, token, s.updater.DefaultUpdateOptions(), progress)
+		}
+	}
+	return nil, fmt.Errorf("project %d has no fixable vulnerabilities in module %s", projectID, moduleDir)
+}
+
+// matchModule returns the findings for a module's requirements and Go version
+func matchModule(db *osv.Database, module domain.Module) []VulnerabilityFinding {
+	var findings []VulnerabilityFinding
+	add := func(m osv.Match, version string, indirect, replaced, possibly bool) {
+		findings = append(findings, VulnerabilityFinding{
+			AdvisoryID:       m.Entry.ID,
+			Aliases:          m.Entry.Aliases,
+			Summary:          m.Entry.Summary,
+			URL:              m.Entry.URL(),
+			Module:           m.Module,
+			Version:          version,
+			FixedIn:          m.FixedIn,
+			ModuleDir:        module.Dir,
+			Indirect:         indirect,
+			Replaced:         replaced,
+			PossiblyAffected: possibly,
+		})
+	}
+
+	for _, lib := range module.Libraries {
+		// Match what is actually built; local replacements cannot be versioned
+		name, version := lib.Name, lib.Version
+		if lib.Replace != nil {
+			if lib.Replace.Local {
+				continue
+			}
+			name, version = lib.Replace.Path, lib.Replace.Version
+		}
+		for _, m := range db.Query(name, version) {
+			add(m, version, lib.Indirect, lib.Replace != nil, false)
+		}
+	}
+
+	// The toolchain line, when present, names the Go release that builds the module. Without one
+	// only the go directive is known, a minimum, so its matches are possible rather than certain.
+	goVersion, possibly := module.Toolchain, false
+	if goVersion == "" {
+		goVersion, possibly = module.GoVersion, true
+	}
+	if goVersion != "" {
+		for _, m := range db.QueryGo(goVersion) {
+			add(m, goVersion, false, false, possibly)
+		}
+	}
+	return findings
+}
+
+// matchesAdvisory reports whether a finding is for the advisory with the given ID or alias
+func matchesAdvisory(finding VulnerabilityFinding, id string) bool {
+	if finding.AdvisoryID == id {
+		return true
+	}
+	for _, alias := range finding.Aliases {
+		if alias == id {
+			return true
+		}
+	}
+	return false
+}
+
+// recommendedUpgrades picks, per project module, the lowest version fixing every finding of each
+// requirement. Requirements with an unfixed finding or behind a replace directive are left out, as
+// are findings that only possibly affect the module.
+func recommendedUpgrades(projectID int, modules []domain.Module, findings []VulnerabilityFinding) []ModuleUpgrade {
+	type target struct {
+		version string
+		blocked bool
+	}
+	libraries := make(map[string]map[string]*target) // module dir -> library -> target
+	goVersions := make(map[string]string)            // module dir -> Go release with every Go fix
+	var dirs []string
+
+	for _, f := range findings {
+		if f.PossiblyAffected {
+			continue
+		}
+		if libraries[f.ModuleDir] == nil {
+			libraries[f.ModuleDir] = make(map[string]*target)
+			dirs = append(dirs, f.ModuleDir)
+		}
+
+		if f.Module == osv.ModuleStdlib || f.Module == osv.ModuleToolchain {
+			if f.FixedIn != "" && (goVersions[f.ModuleDir] == "" ||
+				semver.Compare(osv.GoSemver(f.FixedIn), osv.GoSemver(goVersions[f.ModuleDir])) > 0) {
+				goVersions[f.ModuleDir] = f.FixedIn
+			}
+			continue
+		}
+
+		t := libraries[f.ModuleDir][f.Module]
+		if t == nil {
+			t = &target{}
+			libraries[f.ModuleDir][f.Module] = t
+		}
+		if f.FixedIn == "" || f.Replaced {
+			t.blocked = true
+		} else if semver.Compare(f.FixedIn, t.version) > 0 {
+			t.version = f.FixedIn
+		}
+	}
+
+	directives := make(map[string]string, len(modules)) // module dir -> go directive
+	for _, module := range modules {
+		directives[module.Dir] = module.GoVersion
+	}
+
+	var upgrades []ModuleUpgrade
+	for _, dir := range dirs {
+		upgrade := ModuleUpgrade{ModuleDir: dir}
+		if fixed := goVersions[dir]; fixed != "" {
+			upgrade.Updates = append(upgrade.Updates, ProjectLibraryUpdate{
+				ProjectID:     projectID,
+				LibraryName:   osv.ModuleToolchain,
+				TargetVersion: "go" + fixed,
+				UpdateType:    "upgrade",
+			})
+			upgrade.GoVersion = goDirectiveFor(directives[dir], fixed)
+		}
+		for name, t := range libraries[dir] {
+			if t.blocked || t.version == "" {
+				continue
+			}
+			upgrade.Updates = append(upgrade.Updates, ProjectLibraryUpdate{
+				ProjectID:     projectID,
+				LibraryName:   name,
+				TargetVersion: t.version,
+				UpdateType:    "upgrade",
+			})
+		}
+		sort.Slice(upgrade.Updates, func(i, j int) bool {
+			return upgrade.Updates[i].LibraryName < upgrade.Updates[j].LibraryName
+		})
+		if len(upgrade.Updates) > 0 {
+			upgrades = append(upgrades, upgrade)
+		}
+	}
+	return upgrades
+}
+
+// goDirectiveFor returns the go directive a Go fix needs, "" when the current one can stay: the
+// toolchain line may name any newer release, so only a fix in a newer minor raises the directive,
+// to the first release of that minor
+func goDirectiveFor(directive, fixed string) string {
+	current, target := semver.MajorMinor(osv.GoSemver(directive)), semver.MajorMinor(osv.GoSemver(fixed))
+	if current == "" || target == "" || semver.Compare(target, current) <= 0 {
+		return ""
+	}
+	return strings.TrimPrefix(target, "v") + ".0"
+}
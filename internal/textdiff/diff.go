@@ -0,0 +1,125 @@
+// internal/textdiff/diff.go
+package textdiff
+
+import "strings"
+
+// Line kinds of an edit script, as they prefix lines in a unified diff
+const (
+	Equal  = ' '
+	Delete = '-'
+	Insert = '+'
+)
+
+// Line is one line of an edit script. Text keeps its trailing newline; only the last line of a
+// file that does not end in one lacks it.
+type Line struct {
+	Kind byte
+	Text string
+}
+
+// splitLines splits text after every newline
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// Lines returns the shortest edit script from before to after, line by line
+func Lines(before, after string) []Line {
+	a, b := splitLines(before), splitLines(after)
+
+	// Lines shared at both ends are not part of the search
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	script := make([]Line, 0, len(a)+len(b))
+	for _, text := range a[:prefix] {
+		script = append(script, Line{Equal, text})
+	}
+	script = append(script, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, text := range a[len(a)-suffix:] {
+		script = append(script, Line{Equal, text})
+	}
+	return script
+}
+
+// myers finds the shortest edit script with Myers' O(ND) algorithm. Only the diagonals reachable
+// with d edits are kept per d, so memory grows with the square of the edit distance, not the file size.
+func myers(a, b []string) []Line {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+
+	// trace[d][k+d] is the furthest x on diagonal k after d edits
+	var trace [][]int
+search:
+	for d := 0; d <= n+m; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+				break search
+			}
+		}
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+	}
+
+	// Walk back from the end, collecting the script in reverse
+	var script []Line
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		prev := trace[d-1]
+		at := func(k int) int { return prev[k+d-1] }
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			script = append(script, Line{Equal, a[x-1]})
+			x--
+			y--
+		}
+		if x == prevX {
+			script = append(script, Line{Insert, b[y-1]})
+			y--
+		} else {
+			script = append(script, Line{Delete, a[x-1]})
+			x--
+		}
+	}
+	for x > 0 && y > 0 {
+		script = append(script, Line{Equal, a[x-1]})
+		x--
+		y--
+	}
+
+	for i, j := 0, len(script)-1; i < j; i, j = i+1, j-1 {
+		script[i], script[j] = script[j], script[i]
+	}
+	return script
+}
@@ -0,0 +1,156 @@
+// internal/textdiff/unified.go
+package textdiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultContext is the number of unchanged lines around each change, as with diff -u
+const DefaultContext = 3
+
+// Options tune a unified diff
+type Options struct {
+	Context      int // Unchanged lines around each change; 0 uses DefaultContext, negative none
+	MaxHunkLines int // Hunks with more changed lines are trimmed to a summary; 0 keeps every hunk
+}
+
+// Hunk is a run of changes with its surrounding context. Starts are 1-based line numbers; a side
+// without lines starts at the line before the hunk, as in unified diffs.
+type Hunk struct {
+	OldStart, OldLines int
+	NewStart, NewLines int
+	Added, Removed     int
+	Lines              []Line
+	Trimmed            bool // Lines were dropped in favour of a summary
+
+	first int // Script index of the first line
+}
+
+// Header returns the @@ line of the hunk
+func (h Hunk) Header() string {
+	return fmt.Sprintf("@@ -%s +%s @@", lineRange(h.OldStart, h.OldLines), lineRange(h.NewStart, h.NewLines))
+}
+
+func lineRange(start, lines int) string {
+	if lines == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, lines)
+}
+
+// Diff is the unified diff of one file
+type Diff struct {
+	Path    string `json:"path"`
+	Added   int    `json:"added"`
+	Removed int    `json:"removed"`
+	Trimmed bool   `json:"trimmed,omitempty"` // Some hunks are summarized
+	Text    string `json:"diff"`
+	Hunks   []Hunk `json:"-"`
+}
+
+// Unified returns the diff of a file at path from before to after; it is empty when nothing changed
+func Unified(path, before, after string, opts Options) Diff {
+	diff := Diff{Path: path}
+	if before == after {
+		return diff
+	}
+
+	diff.Hunks = Hunks(Lines(before, after), opts.Context)
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- a/%s\n+++ b/%s\n", path, path)
+	for i := range diff.Hunks {
+		hunk := &diff.Hunks[i]
+		diff.Added += hunk.Added
+		diff.Removed += hunk.Removed
+		b.WriteString(hunk.Header())
+		b.WriteByte('\n')
+
+		if opts.MaxHunkLines > 0 && hunk.Added+hunk.Removed > opts.MaxHunkLines {
+			hunk.Trimmed = true
+			diff.Trimmed = true
+			fmt.Fprintf(&b, "... %d lines added, %d removed (hunk trimmed)\n", hunk.Added, hunk.Removed)
+			continue
+		}
+		for _, line := range hunk.Lines {
+			b.WriteByte(line.Kind)
+			b.WriteString(line.Text)
+			if !strings.HasSuffix(line.Text, "\n") {
+				b.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+	}
+	diff.Text = b.String()
+	return diff
+}
+
+// Hunks groups an edit script into hunks with context unchanged lines around each change. Changes
+// closer than twice the context share a hunk.
+func Hunks(script []Line, context int) []Hunk {
+	if context == 0 {
+		context = DefaultContext
+	}
+	if context < 0 {
+		context = 0
+	}
+
+	var hunks []Hunk
+	var hunk *Hunk
+	oldLine, newLine := 1, 1 // Line numbers of the next script line on each side
+	lastChange := -1         // Script index of the last change in hunk
+
+	for i, line := range script {
+		if line.Kind != Equal {
+			if hunk == nil || i-lastChange > 2*context+1 {
+				// Close the open hunk after its trailing context and open a new one with leading context
+				if hunk != nil {
+					hunk.close(script, lastChange, context)
+					hunks = append(hunks, *hunk)
+				}
+				start := max(0, i-context)
+				hunk = &Hunk{OldStart: oldLine - (i - start), NewStart: newLine - (i - start), first: start}
+			}
+			lastChange = i
+		}
+		switch line.Kind {
+		case Equal:
+			oldLine++
+			newLine++
+		case Delete:
+			oldLine++
+		case Insert:
+			newLine++
+		}
+	}
+	if hunk != nil {
+		hunk.close(script, lastChange, context)
+		hunks = append(hunks, *hunk)
+	}
+	return hunks
+}
+
+// close fills in the lines of a hunk that starts at script index first and ends context lines after
+// its last change
+func (h *Hunk) close(script []Line, lastChange, context int) {
+	end := min(len(script), lastChange+context+1)
+	h.Lines = script[h.first:end]
+	for _, line := range h.Lines {
+		switch line.Kind {
+		case Equal:
+			h.OldLines++
+			h.NewLines++
+		case Delete:
+			h.OldLines++
+			h.Removed++
+		case Insert:
+			h.NewLines++
+			h.Added++
+		}
+	}
+	if h.OldLines == 0 {
+		h.OldStart--
+	}
+	if h.NewLines == 0 {
+		h.NewStart--
+	}
+}
@@ -0,0 +1,179 @@
+// internal/textdiff/unified_test.go
+package textdiff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// numbered returns lines 1 to n, each its own number unless replaced by changes
+func numbered(n int, changes map[int]string) string {
+	var b strings.Builder
+	for i := 1; i <= n; i++ {
+		if line, ok := changes[i]; ok {
+			b.WriteString(line + "\n")
+			continue
+		}
+		fmt.Fprintf(&b, "%d\n", i)
+	}
+	return b.String()
+}
+
+func TestUnified(t *testing.T) {
+	const header = "--- a/go.mod\n+++ b/go.mod\n"
+	tests := []struct {
+		name    string
+		before  string
+		after   string
+		opts    Options
+		want    string
+		added   int
+		removed int
+	}{
+		{
+			name:   "unchanged",
+			before: numbered(3, nil),
+			after:  numbered(3, nil),
+			want:   "",
+		},
+		{
+			name:   "one change with context",
+			before: numbered(10, nil),
+			after:  numbered(10, map[int]string{5: "five"}),
+			want: header + "@@ -2,7 +2,7 @@\n" +
+				" 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+			added: 1, removed: 1,
+		},
+		{
+			name:   "change at the start",
+			before: numbered(10, nil),
+			after:  numbered(10, map[int]string{1: "one"}),
+			want: header + "@@ -1,4 +1,4 @@\n" +
+				"-1\n+one\n 2\n 3\n 4\n",
+			added: 1, removed: 1,
+		},
+		{
+			name:   "changes twice the context apart share a hunk",
+			before: numbered(20, nil),
+			after:  numbered(20, map[int]string{5: "five", 12: "twelve"}),
+			want: header + "@@ -2,14 +2,14 @@\n" +
+				" 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n 9\n 10\n 11\n-12\n+twelve\n 13\n 14\n 15\n",
+			added: 2, removed: 2,
+		},
+		{
+			name:   "changes further apart get their own hunks",
+			before: numbered(20, nil),
+			after:  numbered(20, map[int]string{5: "five", 13: "thirteen"}),
+			want: header + "@@ -2,7 +2,7 @@\n" +
+				" 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n" +
+				"@@ -10,7 +10,7 @@\n" +
+				" 10\n 11\n 12\n-13\n+thirteen\n 14\n 15\n 16\n",
+			added: 2, removed: 2,
+		},
+		{
+			name:   "insertion shifts the new side",
+			before: numbered(10, nil),
+			after:  strings.Replace(numbered(10, nil), "3\n", "3\nnew\n", 1),
+			opts:   Options{Context: 1},
+			want: header + "@@ -3,2 +3,3 @@\n" +
+				" 3\n+new\n 4\n",
+			added: 1,
+		},
+		{
+			name:   "smaller context",
+			before: numbered(10, nil),
+			after:  numbered(10, map[int]string{5: "five"}),
+			opts:   Options{Context: 1},
+			want: header + "@@ -4,3 +4,3 @@\n" +
+				" 4\n-5\n+five\n 6\n",
+			added: 1, removed: 1,
+		},
+		{
+			name:   "no context",
+			before: numbered(10, nil),
+			after:  numbered(10, map[int]string{5: "five"}),
+			opts:   Options{Context: -1},
+			want:   header + "@@ -5 +5 @@\n-5\n+five\n",
+			added:  1, removed: 1,
+		},
+		{
+			name:   "new file",
+			before: "",
+			after:  "a\nb\n",
+			want:   header + "@@ -0,0 +1,2 @@\n+a\n+b\n",
+			added:  2,
+		},
+		{
+			name:    "emptied file",
+			before:  "a\nb\n",
+			after:   "",
+			want:    header + "@@ -1,2 +0,0 @@\n-a\n-b\n",
+			removed: 2,
+		},
+		{
+			name:   "no newline at end of either file",
+			before: "a\nb",
+			after:  "a\nc",
+			want: header + "@@ -1,2 +1,2 @@\n" +
+				" a\n-b\n\\ No newline at end of file\n+c\n\\ No newline at end of file\n",
+			added: 1, removed: 1,
+		},
+		{
+			name:   "newline added at end of file",
+			before: "a\nb",
+			after:  "a\nb\n",
+			want: header + "@@ -1,2 +1,2 @@\n" +
+				" a\n-b\n\\ No newline at end of file\n+b\n",
+			added: 1, removed: 1,
+		},
+		{
+			name:   "unchanged last line without newline",
+			before: "a\nb",
+			after:  "c\nb",
+			want: header + "@@ -1,2 +1,2 @@\n" +
+				"-a\n+c\n b\n\\ No newline at end of file\n",
+			added: 1, removed: 1,
+		},
+		{
+			name:   "trimmed hunk",
+			before: numbered(10, nil),
+			after:  numbered(10, map[int]string{5: "five", 6: "six"}),
+			opts:   Options{MaxHunkLines: 3},
+			want: header + "@@ -2,8 +2,8 @@\n" +
+				"... 2 lines added, 2 removed (hunk trimmed)\n",
+			added: 2, removed: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := Unified("go.mod", tt.before, tt.after, tt.opts)
+			if diff.Text != tt.want {
+				t.Errorf("Text =\n%s\nwant\n%s", diff.Text, tt.want)
+			}
+			if diff.Added != tt.added || diff.Removed != tt.removed {
+				t.Errorf("Added, Removed = %d, %d, want %d, %d", diff.Added, diff.Removed, tt.added, tt.removed)
+			}
+			if trimmed := tt.opts.MaxHunkLines > 0 && tt.want != ""; diff.Trimmed != trimmed {
+				t.Errorf("Trimmed = %t, want %t", diff.Trimmed, trimmed)
+			}
+		})
+	}
+}
+
+func TestHunkHeader(t *testing.T) {
+	tests := []struct {
+		hunk Hunk
+		want string
+	}{
+		{Hunk{OldStart: 2, OldLines: 7, NewStart: 2, NewLines: 8}, "@@ -2,7 +2,8 @@"},
+		{Hunk{OldStart: 5, OldLines: 1, NewStart: 5, NewLines: 1}, "@@ -5 +5 @@"},
+		{Hunk{OldStart: 0, OldLines: 0, NewStart: 1, NewLines: 3}, "@@ -0,0 +1,3 @@"},
+		{Hunk{OldStart: 4, OldLines: 2, NewStart: 3, NewLines: 0}, "@@ -4,2 +3,0 @@"},
+	}
+	for _, tt := range tests {
+		if got := tt.hunk.Header(); got != tt.want {
+			t.Errorf("Header() = %q, want %q", got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+// internal/workspace/workspace.go
+package workspace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Options configure the commands of a workspace
+type Options struct {
+	CommandTimeout time.Duration // Longest run of a single command; 0 means no limit
+	GoFlags        string        // Added to the inherited GOFLAGS, which always get -modcacherw so the module cache can be removed
+	Secrets        []string      // Replaced by *** in command output and errors, e.g. the token of a clone URL
+}
+
+// Workspace is a temporary directory for one update: a clone of the repository and its own GOPATH
+// and module cache. Commands run with an explicit working directory and environment, never the
+// process working directory, so workspaces can be used concurrently.
+type Workspace struct {
+	root string
+	repo string
+	env  []string
+	opts Options
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// waitDelay is how long a killed command may keep its output pipes open, e.g. through child processes
+const waitDelay = 10 * time.Second
+
+// New creates an empty workspace; Close removes it with everything in it
+func New(opts Options) (*Workspace, error) {
+	root, err := os.MkdirTemp("", "gitlab-update-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create workspace: %w", err)
+	}
+	w := &Workspace{root: root, repo: filepath.Join(root, "repo"), opts: opts}
+
+	gopath := filepath.Join(root, "gopath")
+	for _, dir := range []string{w.repo, gopath} {
+		if err := os.Mkdir(dir, 0o755); err != nil {
+			os.RemoveAll(root)
+			return nil, fmt.Errorf("failed to create workspace: %w", err)
+		}
+	}
+	w.env = []string{
+		"GOPATH=" + gopath,
+		"GOMODCACHE=" + filepath.Join(gopath, "pkg", "mod"),
+		"GOFLAGS=" + strings.Join(strings.Fields("-modcacherw "+os.Getenv("GOFLAGS")+" "+opts.GoFlags), " "),
+		"GIT_TERMINAL_PROMPT=0", // Fail instead of waiting for credentials
+	}
+	return w, nil
+}
+
+// Repo returns the directory of the clone
+func (w *Workspace) Repo() string {
+	return w.repo
+}
+
+// Path returns the absolute path of a slash-separated path inside the clone
+func (w *Workspace) Path(rel string) string {
+	return filepath.Join(w.repo, filepath.FromSlash(rel))
+}
+
+// Run runs a command in dir, a slash-separated directory inside the clone ("" or "." for its root),
+// and returns its combined output. A command that exceeds the command timeout is killed.
+func (w *Workspace) Run(ctx context.Context, dir, name string, args ...string) (string, error) {
+	if w.opts.CommandTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, w.opts.CommandTimeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = w.Path(dir)
+	cmd.Env = append(os.Environ(), w.env...)
+	cmd.WaitDelay = waitDelay
+	output, err := cmd.CombinedOutput()
+	text := w.redact(strings.TrimSpace(string(output)))
+
+	command := w.redact(strings.Join(append([]string{name}, args...), " "))
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded) && w.opts.CommandTimeout > 0:
+		return text, fmt.Errorf("%s timed out after %s", command, w.opts.CommandTimeout)
+	case ctx.Err() != nil:
+		return text, fmt.Errorf("%s stopped: %w", command, ctx.Err())
+	case err != nil:
+		return text, fmt.Errorf("%s failed: %s", command, w.redact(err.Error()))
+	}
+	return text, nil
+}
+
+// redact hides the secrets of the workspace in text
+func (w *Workspace) redact(text string) string {
+	for _, secret := range w.opts.Secrets {
+		if secret != "" {
+			text = strings.ReplaceAll(text, secret, "***")
+		}
+	}
+	return text
+}
+
+// Close removes the workspace; it is safe to call more than once
+func (w *Workspace) Close() error {
+	w.closeOnce.Do(func() {
+		w.closeErr = os.RemoveAll(w.root)
+	})
+	return w.closeErr
+}
@@ -0,0 +1,215 @@
+// Dependency bump campaign dashboard
+
+const CAMPAIGN_STATUS_COLORS = {
+    pending: '#6c757d',
+    updating: '#17a2b8',
+    opened: '#007bff',
+    pipeline_failed: '#fd7e14',
+    merged: '#28a745',
+    closed: '#343a40',
+    failed: '#dc3545',
+    canceled: '#6c757d',
+    active: '#007bff',
+    completed: '#28a745'
+};
+
+function campaignEscape(text) {
+    return String(text ?? '').replace(/&/g, '&amp;').replace(/</g, '&lt;').replace(/>/g, '&gt;').replace(/"/g, '&quot;');
+}
+
+function campaignBadge(status) {
+    const color = CAMPAIGN_STATUS_COLORS[status] || '#6c757d';
+    return `<span style="background: ${color}; color: white; padding: 2px 8px; border-radius: 10px; font-size: 12px;">${campaignEscape(status.replace('_', ' '))}</span>`;
+}
+
+function campaignToken() {
+    const token = document.getElementById('gitlab-token').value;
+    if (!token.trim()) {
+        showError('Please configure your GitLab API token first');
+        return null;
+    }
+    return token;
+}
+
+// The project selector is the search form above
+function campaignCriteria() {
+    const value = id => document.getElementById(id).value.trim();
+    return {
+        go_version: value('search-go-version'),
+        go_version_comparison: value('go-version-comparison'),
+        library: value('search-library'),
+        version: value('search-library-version'),
+        version_comparison: value('search-library-version') ? value('version-comparison') : '',
+        group: value('search-group'),
+        tag: value('search-tag')
+    };
+}
+
+async function createCampaign() {
+    const token = campaignToken();
+    if (!token) return;
+
+    const request = {
+        name: document.getElementById('campaign-name').value.trim(),
+        module: document.getElementById('campaign-module').value.trim(),
+        version: document.getElementById('campaign-version').value.trim(),
+        go_version: document.getElementById('campaign-go-version').value.trim(),
+        branch_name: document.getElementById('campaign-branch').value.trim(),
+        criteria: campaignCriteria()
+    };
+    if (!request.go_version && !(request.module && request.version)) {
+        showError('Please enter a module and version, or a Go version');
+        return;
+    }
+
+    showLoading(true, 'Starting campaign...');
+    try {
+        const response = await fetch(`${API_BASE}/campaigns`, {
+            method: 'POST',
+            headers: {
+                'Authorization': `Bearer ${token}`,
+                'Content-Type': 'application/json'
+            },
+            body: JSON.stringify(request)
+        });
+        if (!response.ok) {
+            throw new Error(await response.text());
+        }
+        const campaign = await response.json();
+        showSuccess(`Campaign "${campaign.name}" started for ${campaign.projects.length} project modules`);
+        renderCampaign(campaign);
+        loadCampaigns();
+    } catch (error) {
+        showError('Failed to start campaign: ' + error.message);
+    } finally {
+        showLoading(false);
+    }
+}
+
+async function loadCampaigns() {
+    try {
+        const response = await fetch(`${API_BASE}/campaigns`);
+        if (!response.ok) {
+            throw new Error(await response.text());
+        }
+        const data = await response.json();
+        const list = document.getElementById('campaign-list');
+        list.style.display = 'block';
+        if (data.count === 0) {
+            list.innerHTML = '<p style="color: #7f8c8d;">No campaigns yet</p>';
+            return;
+        }
+
+        let html = '<h4>Campaigns</h4><table style="width: 100%; border-collapse: collapse;">';
+        html += '<tr><th align="left">Name</th><th align="left">Target</th><th align="left">Status</th><th align="left">Projects</th><th></th></tr>';
+        data.campaigns.forEach(campaign => {
+            const target = [campaign.module ? `${campaign.module}@${campaign.version}` : '', campaign.go_version ? `go ${campaign.go_version}` : '']
+                .filter(Boolean).join(', ');
+            const summary = Object.entries(campaign.summary || {}).map(([status, count]) => `${count} ${status.replace('_', ' ')}`).join(', ');
+            html += `<tr style="border-top: 1px solid #dee2e6;">
+                <td>${campaignEscape(campaign.name)}</td>
+                <td><code>${campaignEscape(target)}</code></td>
+                <td>${campaignBadge(campaign.status)}</td>
+                <td>${campaignEscape(summary)}</td>
+                <td><button onclick="showCampaign('${campaign.id}', false)">Open</button></td>
+            </tr>`;
+        });
+        html += '</table>';
+        list.innerHTML = html;
+    } catch (error) {
+        showError('Failed to load campaigns: ' + error.message);
+    }
+}
+
+async function showCampaign(id, refresh) {
+    const token = document.getElementById('gitlab-token').value;
+    try {
+        const response = await fetch(`${API_BASE}/campaigns/${id}${refresh ? '?refresh=true' : ''}`, {
+            headers: token ? { 'Authorization': `Bearer ${token}` } : {}
+        });
+        if (!response.ok) {
+            throw new Error(await response.text());
+        }
+        const data = await response.json();
+        if (data.error) {
+            showError(data.error);
+        }
+        renderCampaign(data.campaign || data);
+    } catch (error) {
+        showError('Failed to load campaign: ' + error.message);
+    }
+}
+
+function renderCampaign(campaign) {
+    const details = document.getElementById('campaign-details');
+    details.style.display = 'block';
+
+    const open = campaign.status !== 'canceled';
+    let html = `<h4>${campaignEscape(campaign.name)} ${campaignBadge(campaign.status)}</h4>`;
+    html += `<p style="font-size: 13px; color: #6c757d;">Branch <code>${campaignEscape(campaign.branch_name)}</code> · ` +
+        Object.entries(campaign.summary || {}).map(([status, count]) => `${count} ${campaignEscape(status.replace('_', ' '))}`).join(' · ') + '</p>';
+    html += `<button onclick="showCampaign('${campaign.id}', true)">🔄 Refresh</button> `;
+    if (open) {
+        html += `<button onclick="campaignAction('${campaign.id}', 'retry')">🔁 Retry All</button> `;
+        html += `<button onclick="campaignAction('${campaign.id}', 'cancel')" class="btn-clear-changes">❌ Cancel Campaign</button>`;
+    }
+
+    html += '<table style="width: 100%; border-collapse: collapse; margin-top: 10px;">';
+    html += '<tr><th align="left">Project</th><th align="left">Module</th><th align="left">From</th><th align="left">Status</th><th align="left">Merge Request</th><th></th></tr>';
+    campaign.projects.forEach(project => {
+        const mr = project.merge_request;
+        const mrCell = mr
+            ? `<a href="${campaignEscape(mr.web_url)}" target="_blank">!${mr.iid}</a>${mr.pipeline_status ? ` · pipeline ${campaignEscape(mr.pipeline_status)}` : ''}`
+            : '';
+        const args = `'${campaign.id}', '%s', ${project.project_id}, '${project.module_dir}'`;
+        let actions = '';
+        if (['failed', 'canceled', 'closed', 'pipeline_failed'].includes(project.status)) {
+            actions += `<button onclick="campaignAction(${args.replace('%s', 'retry')})">Retry</button> `;
+        }
+        if (!['merged', 'closed', 'failed', 'canceled'].includes(project.status)) {
+            actions += `<button onclick="campaignAction(${args.replace('%s', 'cancel')})">Cancel</button>`;
+        }
+        html += `<tr style="border-top: 1px solid #dee2e6;">
+            <td>${campaignEscape(project.path)}</td>
+            <td><code>${campaignEscape(project.module_dir)}</code></td>
+            <td><code>${campaignEscape(project.from_version)}</code></td>
+            <td>${campaignBadge(project.status)}${project.error ? `<div style="font-size: 12px; color: #dc3545;">${campaignEscape(project.error)}</div>` : ''}</td>
+            <td>${mrCell}</td>
+            <td>${actions}</td>
+        </tr>`;
+    });
+    html += '</table>';
+    details.innerHTML = html;
+}
+
+async function campaignAction(id, action, projectId, moduleDir) {
+    const token = campaignToken();
+    if (!token) return;
+    if (action === 'cancel' && !confirm(projectId ? 'Cancel this project and close its merge request?' : 'Cancel the whole campaign and close its open merge requests?')) {
+        return;
+    }
+
+    const params = new URLSearchParams();
+    if (projectId) params.append('project_id', projectId);
+    if (moduleDir) params.append('module_dir', moduleDir);
+
+    showLoading(true, action === 'retry' ? 'Retrying...' : 'Canceling...');
+    try {
+        const response = await fetch(`${API_BASE}/campaigns/${id}/${action}?${params}`, {
+            method: 'POST',
+            headers: {
+                'Authorization': `Bearer ${token}`
+            }
+        });
+        if (!response.ok) {
+            throw new Error(await response.text());
+        }
+        const data = await response.json();
+        renderCampaign(data.campaign || data);
+        loadCampaigns();
+    } catch (error) {
+        showError(`Failed to ${action}: ` + error.message);
+    } finally {
+        showLoading(false);
+    }
+}
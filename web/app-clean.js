@@ -68,6 +68,33 @@ function showLoading(show, text = 'Loading...') {
     }
 }
 
+// Follow a queued job (the 202 answer of /api/cache/load or /api/library/project-update) until it
+// finishes, showing its progress, and return its result
+async function waitForJob(queued, label) {
+    const statusUrl = queued.status_url || `${API_BASE}/jobs/${queued.job_id}`;
+    while (true) {
+        const response = await fetch(statusUrl);
+        if (!response.ok) {
+            throw new Error(`Failed to follow job ${queued.job_id}: ${await response.text()}`);
+        }
+        const job = await response.json();
+        if (job.status === 'succeeded') {
+            return job.result;
+        }
+        if (job.status === 'failed' || job.status === 'canceled') {
+            throw new Error(job.error || `Job ${job.status}`);
+        }
+
+        const progress = job.progress || {};
+        let text = job.status === 'queued' ? `${label} (waiting in queue)` : `${label} ${progress.percent || 0}%`;
+        if (progress.step) text += ` - ${progress.step}`;
+        if (progress.project) text += ` (${progress.project})`;
+        showLoading(true, text);
+
+        await new Promise(resolve => setTimeout(resolve, 1000));
+    }
+}
+
 // Autocomplete functionality
 let autocompleteTimeout;
 let cachedElements = {};
@@ -371,11 +398,11 @@ async function loadCacheStats() {
             if (statsDiv) {
                 const totalCached = document.getElementById('total-cached');
                 const cacheType = document.getElementById('cache-type');
-                const searchHashes = document.getElementById('search-hashes');
+                const cacheRefs = document.getElementById('cache-refs');
                 
                 if (totalCached) totalCached.textContent = stats.total_cached_projects || 0;
                 if (cacheType) cacheType.textContent = stats.cache_type || 'Hash-Based';
-                if (searchHashes) searchHashes.textContent = stats.search_hashes ? stats.search_hashes.length : 0;
+                if (cacheRefs) cacheRefs.textContent = stats.refs ? stats.refs.length : 0;
                 statsDiv.style.display = 'block';
             }
         }
@@ -685,6 +712,11 @@ function displayProjectLibrariesInResults(projectId, projectName, data) {
                         style="background: #28a745; color: white; border: none; padding: 8px 16px; border-radius: 4px; cursor: pointer;">
                     🚀 Update Selected Libraries
                 </button>
+                <button onclick="updateSelectedLibrariesInProject(${projectId}, true)" 
+                        title="Apply the updates in a clone, run go mod tidy, build and vet, and show the result without pushing"
+                        style="background: #17a2b8; color: white; border: none; padding: 8px 16px; border-radius: 4px; cursor: pointer;">
+                    🧪 Dry Run
+                </button>
                 <button onclick="selectAllLibrariesInProject(${projectId})" 
                         style="background: #007bff; color: white; border: none; padding: 8px 16px; border-radius: 4px; cursor: pointer;">
                     ✅ Select All
@@ -737,7 +769,7 @@ function clearAllSelectionsInProject(projectId) {
     });
 }
 
-async function updateSelectedLibrariesInProject(projectId) {
+async function updateSelectedLibrariesInProject(projectId, dryRun = false) {
     const token = document.getElementById('gitlab-token').value;
     
     if (!token.trim()) {
@@ -766,11 +798,11 @@ async function updateSelectedLibrariesInProject(projectId) {
         return;
     }
     
-    if (!confirm(`Are you sure you want to update ${selectedUpdates.length} libraries? This will create a merge request.`)) {
+    if (!dryRun && !confirm(`Are you sure you want to update ${selectedUpdates.length} libraries? This will create a merge request.`)) {
         return;
     }
     
-    showLoading(true, `Updating ${selectedUpdates.length} libraries...`);
+    showLoading(true, dryRun ? `Verifying ${selectedUpdates.length} libraries...` : `Updating ${selectedUpdates.length} libraries...`);
     
     try {
         const response = await fetch(`${API_BASE}/library/project-update`, {
@@ -781,12 +813,13 @@ async function updateSelectedLibrariesInProject(projectId) {
             },
             body: JSON.stringify({
                 project_id: parseInt(projectId),
-                updates: selectedUpdates
+                updates: selectedUpdates,
+                dry_run: dryRun
             })
         });
         
         if (response.ok) {
-            const results = await response.json();
+            const results = await waitForJob(await response.json(), 'Updating libraries...');
             displayProjectUpdateResultsInProject(projectId, results);
         } else {
             const errorData = await response.json();
@@ -836,7 +869,7 @@ async function updateSingleLibraryInProject(projectId, index) {
         });
         
         if (response.ok) {
-            const results = await response.json();
+            const results = await waitForJob(await response.json(), 'Updating libraries...');
             displayProjectUpdateResultsInProject(projectId, results);
         } else {
             const errorData = await response.json();
@@ -878,6 +911,16 @@ function displayProjectUpdateResultsInProject(projectId, results) {
                 </div>
             `;
         }
+
+        // Older update merge requests closed in favour of this one
+        if (result.superseded && result.superseded.length > 0) {
+            const links = result.superseded.map(mr => `<a href="${mr.web_url}" target="_blank" style="color: #6c757d;">#${mr.iid}</a>`);
+            html += `
+                <div style="margin-top: 8px;">
+                    <strong>Superseded:</strong> ${links.join(', ')}
+                </div>
+            `;
+        }
         
         if (result.changes && result.changes.files_changed) {
             html += `
@@ -886,6 +929,18 @@ function displayProjectUpdateResultsInProject(projectId, results) {
                 </div>
             `;
         }
+
+        html += verificationHtml(result.verification);
+
+        (result.changes && result.changes.diffs || []).forEach(diff => {
+            const text = diff.diff.replace(/&/g, '&amp;').replace(/</g, '&lt;').replace(/>/g, '&gt;');
+            html += `
+                <details style="margin-top: 8px;">
+                    <summary><strong>${diff.path}</strong> (+${diff.added} -${diff.removed}${diff.trimmed ? ', large hunks trimmed' : ''})</summary>
+                    <pre style="overflow-x: auto; background: #fff; padding: 8px; font-size: 12px;">${text}</pre>
+                </details>
+            `;
+        });
         
         html += `</div>`;
     });
@@ -893,6 +948,25 @@ function displayProjectUpdateResultsInProject(projectId, results) {
     contentDiv.innerHTML = html;
 }
 
+// Render the verification commands of an update, with the output of failed ones
+function verificationHtml(verification) {
+    if (!verification) return '';
+
+    let html = '<div style="margin-top: 8px;"><strong>Verification:</strong> ';
+    html += verification.steps.map(step => `${step.passed ? '✅' : '❌'} <code>${step.command}</code>`).join(' ');
+    html += '</div>';
+    verification.steps.filter(step => !step.passed && step.output).forEach(step => {
+        const text = step.output.replace(/&/g, '&amp;').replace(/</g, '&lt;').replace(/>/g, '&gt;');
+        html += `
+            <details style="margin-top: 8px;" open>
+                <summary>Output of <code>${step.command}</code>${step.truncated ? ' (last lines)' : ''}</summary>
+                <pre style="overflow-x: auto; background: #fff; padding: 8px; font-size: 12px;">${text}</pre>
+            </details>
+        `;
+    });
+    return html;
+}
+
 function closeProjectLibraries(projectId) {
     const librariesDiv = document.getElementById(`libraries-${projectId}`);
     librariesDiv.style.display = 'none';
@@ -138,7 +138,7 @@ async function updateSingleLibrary(projectId, libIndex, libraryName) {
         });
         
         if (response.ok) {
-            const result = await response.json();
+            const result = await waitForJob(await response.json(), `Updating ${libraryName}...`);
             showSuccess(`✅ Successfully updated ${libraryName} to ${targetVersion}`);
             console.log('Update result:', result);
         } else {
@@ -202,7 +202,7 @@ async function updateSelectedLibraries(projectId) {
         });
         
         if (response.ok) {
-            const result = await response.json();
+            const result = await waitForJob(await response.json(), `Updating ${updates.length} libraries...`);
             showSuccess(`✅ Successfully updated ${updates.length} libraries. Check merge request for details.`);
             console.log('Batch update result:', result);
         } else {
@@ -108,6 +108,33 @@ function showLoading(show, text = 'Loading...') {
     }
 }
 
+// Follow a queued job (the 202 answer of /api/cache/load, /api/cache/refresh or a library update) until it
+// finishes, showing its progress, and return its result
+async function waitForJob(queued, label) {
+    const statusUrl = queued.status_url || `${API_BASE}/jobs/${queued.job_id}`;
+    while (true) {
+        const response = await fetch(statusUrl);
+        if (!response.ok) {
+            throw new Error(`Failed to follow job ${queued.job_id}: ${await response.text()}`);
+        }
+        const job = await response.json();
+        if (job.status === 'succeeded') {
+            return job.result;
+        }
+        if (job.status === 'failed' || job.status === 'canceled') {
+            throw new Error(job.error || `Job ${job.status}`);
+        }
+
+        const progress = job.progress || {};
+        let text = job.status === 'queued' ? `${label} (waiting in queue)` : `${label} ${progress.percent || 0}%`;
+        if (progress.step) text += ` - ${progress.step}`;
+        if (progress.project) text += ` (${progress.project})`;
+        showLoading(true, text);
+
+        await new Promise(resolve => setTimeout(resolve, 1000));
+    }
+}
+
 // Autocomplete functionality
 let autocompleteTimeout;
 let cachedElements = {};
@@ -412,11 +439,11 @@ async function loadCacheStats() {
             if (statsDiv) {
                 const totalCached = document.getElementById('total-cached');
                 const cacheType = document.getElementById('cache-type');
-                const searchHashes = document.getElementById('search-hashes');
+                const cacheRefs = document.getElementById('cache-refs');
                 
                 if (totalCached) totalCached.textContent = stats.total_cached_projects || 0;
                 if (cacheType) cacheType.textContent = stats.cache_type || 'Hash-Based';
-                if (searchHashes) searchHashes.textContent = stats.search_hashes ? stats.search_hashes.length : 0;
+                if (cacheRefs) cacheRefs.textContent = stats.refs ? stats.refs.length : 0;
                 statsDiv.style.display = 'block';
             }
         }
@@ -969,6 +996,14 @@ function displayUpdateResult(projectId, results, changes) {
     }
     
     html += '</div>';
+
+    // Verification of the update; a failed one may have kept it from being pushed
+    if (result && !result.success && result.error) {
+        html += `<div class="result-item" style="color: #721c24;">❌ ${result.error}</div>`;
+    }
+    if (result) {
+        html += verificationHtml(result.verification);
+    }
     
     // Show merge request link as a prominent button
     if (result.merge_request && result.merge_request.web_url) {
@@ -982,7 +1017,12 @@ function displayUpdateResult(projectId, results, changes) {
     // Show branch info
     if (result.merge_request && result.merge_request.source_branch) {
         html += `<div class="result-branch-info">
-            <strong>Branch:</strong> <code>${result.merge_request.source_branch}</code>
+            <strong>Branch:</strong> <code>${result.merge_request.source_branch}</code>${result.branch_reused ? ' (existing branch, rebuilt and force-pushed)' : ''}
+        </div>`;
+    }
+    if (result.superseded && result.superseded.length > 0) {
+        html += `<div class="result-branch-info">
+            <strong>Superseded:</strong> ${result.superseded.map(mr => `<a href="${mr.web_url}" target="_blank">#${mr.iid}</a>`).join(', ')}
         </div>`;
     }
     
@@ -1061,9 +1101,14 @@ async function applyAllChanges(projectId) {
         });
         
         if (response.ok) {
-            const data = await response.json();
-            showSuccess(`✅ Successfully applied all changes!`);
-            clearChanges(projectId);
+            const data = await waitForJob(await response.json(), `Applying ${changeCount} changes...`);
+            const failed = (data.results || []).find(result => !result.success);
+            if (failed) {
+                showError(`Failed to apply changes: ${failed.error}`);
+            } else {
+                showSuccess(`✅ Successfully applied all changes!`);
+                clearChanges(projectId);
+            }
             // Pass the actual results array from the response
             displayUpdateResult(projectId, data.results || data, changes);
             console.log('Update result:', data);
@@ -1160,8 +1205,8 @@ async function updateLibraryVersion(projectId, libraryName, currentVersion) {
         });
         
         if (response.ok) {
-            const result = await response.json();
-            showSuccess(`✅ Successfully updated ${libraryName} to ${newVersion}`);
+            const result = await waitForJob(await response.json(), `Updating ${libraryName} to ${newVersion}...`);
+            showSuccess(`✅ ${result.message || `Successfully updated ${libraryName} to ${newVersion}`}`);
             // Refresh the project libraries display
             loadProjectLibrariesForProject(projectId, '');
         } else {
@@ -1229,6 +1274,11 @@ function displayProjectLibrariesInResults(projectId, projectName, data) {
                         style="background: #28a745; color: white; border: none; padding: 8px 16px; border-radius: 4px; cursor: pointer;">
                     🚀 Update Selected Libraries
                 </button>
+                <button onclick="updateSelectedLibrariesInProject(${projectId}, true)" 
+                        title="Apply the updates in a clone, run go mod tidy, build and vet, and show the result without pushing"
+                        style="background: #17a2b8; color: white; border: none; padding: 8px 16px; border-radius: 4px; cursor: pointer;">
+                    🧪 Dry Run
+                </button>
                 <button onclick="selectAllLibrariesInProject(${projectId})" 
                         style="background: #007bff; color: white; border: none; padding: 8px 16px; border-radius: 4px; cursor: pointer;">
                     ✅ Select All
@@ -1281,7 +1331,7 @@ function clearAllSelectionsInProject(projectId) {
     });
 }
 
-async function updateSelectedLibrariesInProject(projectId) {
+async function updateSelectedLibrariesInProject(projectId, dryRun = false) {
     const token = document.getElementById('gitlab-token').value;
     
     if (!token.trim()) {
@@ -1314,11 +1364,11 @@ async function updateSelectedLibrariesInProject(projectId) {
         return;
     }
     
-    if (!confirm(`Are you sure you want to update ${selectedUpdates.length} libraries? This will create a merge request.`)) {
+    if (!dryRun && !confirm(`Are you sure you want to update ${selectedUpdates.length} libraries? This will create a merge request.`)) {
         return;
     }
 
-    showLoading(true, `Updating ${selectedUpdates.length} libraries...`);
+    showLoading(true, dryRun ? `Verifying ${selectedUpdates.length} libraries...` : `Updating ${selectedUpdates.length} libraries...`);
 
     try {
         const response = await fetch(`${API_BASE}/library/project-update`, {
@@ -1329,12 +1379,13 @@ async function updateSelectedLibrariesInProject(projectId) {
             },
             body: JSON.stringify({
                 project_id: parseInt(projectId),
-                updates: selectedUpdates
+                updates: selectedUpdates,
+                dry_run: dryRun
             })
         });
 
         if (response.ok) {
-            const results = await response.json();
+            const results = await waitForJob(await response.json(), 'Updating libraries...');
             displayProjectUpdateResultsInProject(projectId, results);
         } else {
             const errorData = await response.json();
@@ -1382,7 +1433,7 @@ async function updateSingleLibraryInProject(projectId, index, libraryName) {
         });
         
         if (response.ok) {
-            const results = await response.json();
+            const results = await waitForJob(await response.json(), 'Updating libraries...');
             displayProjectUpdateResultsInProject(projectId, results);
         } else {
             const errorData = await response.json();
@@ -1424,6 +1475,16 @@ function displayProjectUpdateResultsInProject(projectId, results) {
                 </div>
             `;
         }
+
+        // Older update merge requests closed in favour of this one
+        if (result.superseded && result.superseded.length > 0) {
+            const links = result.superseded.map(mr => `<a href="${mr.web_url}" target="_blank" style="color: #6c757d;">#${mr.iid}</a>`);
+            html += `
+                <div style="margin-top: 8px;">
+                    <strong>Superseded:</strong> ${links.join(', ')}
+                </div>
+            `;
+        }
         
         if (result.changes && result.changes.files_changed) {
             html += `
@@ -1432,6 +1493,18 @@ function displayProjectUpdateResultsInProject(projectId, results) {
             </div>
         `;
         }
+
+        html += verificationHtml(result.verification);
+
+        (result.changes && result.changes.diffs || []).forEach(diff => {
+            const text = diff.diff.replace(/&/g, '&amp;').replace(/</g, '&lt;').replace(/>/g, '&gt;');
+            html += `
+                <details style="margin-top: 8px;">
+                    <summary><strong>${diff.path}</strong> (+${diff.added} -${diff.removed}${diff.trimmed ? ', large hunks trimmed' : ''})</summary>
+                    <pre style="overflow-x: auto; background: #fff; padding: 8px; font-size: 12px;">${text}</pre>
+                </details>
+            `;
+        });
         
         html += `</div>`;
     });
@@ -1439,6 +1512,25 @@ function displayProjectUpdateResultsInProject(projectId, results) {
     contentDiv.innerHTML = html;
 }
 
+// Render the verification commands of an update, with the output of failed ones
+function verificationHtml(verification) {
+    if (!verification) return '';
+
+    let html = '<div style="margin-top: 8px;"><strong>Verification:</strong> ';
+    html += verification.steps.map(step => `${step.passed ? '✅' : '❌'} <code>${step.command}</code>`).join(' ');
+    html += '</div>';
+    verification.steps.filter(step => !step.passed && step.output).forEach(step => {
+        const text = step.output.replace(/&/g, '&amp;').replace(/</g, '&lt;').replace(/>/g, '&gt;');
+        html += `
+            <details style="margin-top: 8px;" open>
+                <summary>Output of <code>${step.command}</code>${step.truncated ? ' (last lines)' : ''}</summary>
+                <pre style="overflow-x: auto; background: #fff; padding: 8px; font-size: 12px;">${text}</pre>
+            </details>
+        `;
+    });
+    return html;
+}
+
 function closeProjectLibraries(projectId) {
     const librariesDiv = document.getElementById(`libraries-${projectId}`);
     librariesDiv.style.display = 'none';
@@ -1520,8 +1612,8 @@ async function loadInitialCache() {
         });
 
         if (response.ok) {
-            const data = await response.json();
-            showSuccess(`Cache loaded successfully! ${data.count || 0} projects cached.`);
+            const data = await waitForJob(await response.json(), 'Loading initial cache...');
+            showSuccess(`Cache loaded successfully! ${data.projects_cached || 0} projects cached.`);
     } else {
             const errorData = await response.json();
             showError(`Failed to load cache: ${errorData.message || 'Unknown error'}`);
@@ -1552,8 +1644,8 @@ async function refreshCache() {
         });
         
         if (response.ok) {
-        const data = await response.json();
-            showSuccess(`Cache refreshed successfully! ${data.count || 0} projects updated.`);
+            const data = await waitForJob(await response.json(), 'Refreshing cache...');
+            showSuccess(data.message || 'Cache refreshed successfully!');
         } else {
             const errorData = await response.json();
             showError(`Failed to refresh cache: ${errorData.message || 'Unknown error'}`);